@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// clientServer is the entry that adds a stdio server to an MCP client
+type clientServer struct {
+	Type    string   `json:"type,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// mcpClient is an MCP client and the shape of its config
+type mcpClient struct {
+	// id is the name given to --client
+	id    string
+	title string
+	// location is where the client keeps the config on this OS
+	location func() string
+	// block returns the config that adds a server named name
+	block func(name string, server clientServer) any
+}
+
+// mcpClients are the clients configs are printed for, in order
+var mcpClients = []mcpClient{
+	{
+		id:    "claude-desktop",
+		title: "Claude Desktop",
+		location: func() string {
+			return byOS("~/Library/Application Support/Claude/claude_desktop_config.json",
+				`%APPDATA%\Claude\claude_desktop_config.json`,
+				"~/.config/Claude/claude_desktop_config.json")
+		},
+		block: mcpServersBlock,
+	},
+	{
+		id:       "cursor",
+		title:    "Cursor",
+		location: func() string { return "~/.cursor/mcp.json, or .cursor/mcp.json in a project" },
+		block:    mcpServersBlock,
+	},
+	{
+		id:    "cline",
+		title: "Cline",
+		location: func() string {
+			const settings = "Code/User/globalStorage/saoudrizwan.claude-dev/settings/cline_mcp_settings.json"
+			return byOS("~/Library/Application Support/"+settings,
+				`%APPDATA%\`+filepath.FromSlash(settings),
+				"~/.config/"+settings)
+		},
+		block: func(name string, server clientServer) any {
+			type clineServer struct {
+				clientServer
+				Disabled    bool     `json:"disabled"`
+				AutoApprove []string `json:"autoApprove"`
+			}
+			return map[string]any{"mcpServers": map[string]any{name: clineServer{server, false, []string{}}}}
+		},
+	},
+	{
+		id:    "zed",
+		title: "Zed",
+		location: func() string {
+			return byOS("~/.config/zed/settings.json", `%APPDATA%\Zed\settings.json`, "~/.config/zed/settings.json")
+		},
+		block: func(name string, server clientServer) any {
+			type zedServer struct {
+				Source  string            `json:"source"`
+				Command string            `json:"command"`
+				Args    []string          `json:"args"`
+				Env     map[string]string `json:"env"`
+			}
+			return map[string]any{"context_servers": map[string]any{name: zedServer{"custom", server.Command, server.Args, map[string]string{}}}}
+		},
+	},
+	{
+		id:       "vscode",
+		title:    "VS Code",
+		location: func() string { return ".vscode/mcp.json in a project" },
+		block: func(name string, server clientServer) any {
+			server.Type = "stdio"
+			return map[string]any{"servers": map[string]any{name: server}}
+		},
+	},
+}
+
+// mcpServersBlock is the "mcpServers" config most clients share
+func mcpServersBlock(name string, server clientServer) any {
+	return map[string]any{"mcpServers": map[string]any{name: server}}
+}
+
+// byOS picks the value for macOS, Windows or other systems
+func byOS(darwin, windows, other string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return darwin
+	case "windows":
+		return windows
+	}
+	return other
+}
+
+// clientIDs lists the ids --client accepts
+func clientIDs() string {
+	ids := make([]string, len(mcpClients))
+	for i, client := range mcpClients {
+		ids[i] = client.id
+	}
+	return strings.Join(ids, ", ")
+}
+
+// runPrintClientConfig prints the config blocks that add the server to MCP
+// clients; flags after "--" are passed to the server
+func runPrintClientConfig(args []string) int {
+	flags := flag.NewFlagSet("print-client-config", flag.ExitOnError)
+	opts := addWorkspaceFlags(flags)
+	client := flags.String("client", "", "Print only the config of this client: "+clientIDs()+" (default: all)")
+	name := flags.String("name", "filesystem", "Name of the server in the client's config")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: mcp-filesystem print-client-config --workspace PATH [flags] [-- server flags]\n\n")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	workspace, remote := opts.remote()
+	if !remote {
+		workspace = opts.workspace()
+	}
+	serverArgs := []string{"--workspace", workspace}
+	if *opts.configPath != "" {
+		path, err := filepath.Abs(*opts.configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get absolute path for config: %v\n", err)
+			return 1
+		}
+		serverArgs = append(serverArgs, "--config", path)
+	}
+	if *opts.profile != "" {
+		serverArgs = append(serverArgs, "--profile", *opts.profile)
+	}
+	if *opts.debug {
+		serverArgs = append(serverArgs, "--debug")
+	}
+	serverArgs = append(serverArgs, flags.Args()...)
+
+	if *client == "" {
+		printClientConfigs(os.Stdout, *name, serverArgs)
+		return 0
+	}
+	for _, c := range mcpClients {
+		if c.id == *client {
+			printJSON(os.Stdout, c.block(*name, clientServer{Command: serverCommand(), Args: serverArgs}))
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown client %q (expected %s)\n", *client, clientIDs())
+	return 2
+}
+
+// printClientConfigs prints the config block of every client with where
+// it goes
+func printClientConfigs(w io.Writer, name string, serverArgs []string) {
+	server := clientServer{Command: serverCommand(), Args: serverArgs}
+	for i, client := range mcpClients {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s (%s):\n\n", client.title, client.location())
+		printJSON(w, client.block(name, server))
+	}
+	fmt.Fprintln(w, "\nMerge the block into the client's config and restart the client to connect.")
+}
+
+// serverCommand returns the absolute path of this binary, which clients
+// launched from a desktop may not find on their PATH
+func serverCommand() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return "mcp-filesystem"
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	// Binaries run with "go run" are deleted when it exits
+	if strings.Contains(executable, string(filepath.Separator)+"go-build") {
+		return "mcp-filesystem"
+	}
+	return executable
+}
+
+// printJSON prints a value as indented JSON
+func printJSON(w io.Writer, v any) {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
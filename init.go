@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// buildDirs are directories of build output and dependencies that are
+// suggested as extra ignores when a workspace has them and nothing ignores
+// them yet
+var buildDirs = []string{"dist", "build", "out", "target", "vendor", "coverage", "__pycache__", "venv", "bin", "obj"}
+
+// prompter asks questions on a terminal, or takes every default with --yes
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	yes bool
+}
+
+// ask asks for a line of text, returning def for an empty answer
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if p.yes {
+		fmt.Fprintln(p.out)
+		return def
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(p.out)
+	}
+	if line = strings.TrimSpace(line); line != "" {
+		return line
+	}
+	return def
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		switch strings.ToLower(p.ask(question+" ("+hint+")", "")) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// runInit interactively writes a config for a workspace and prints the
+// snippets that add the server to MCP clients
+func runInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	workspaceDir := flags.String("workspace", "", "Workspace to set up (default: asked, suggesting the current directory)")
+	configPath := flags.String("config", "", "Where to write the config (default: <workspace>/"+config.DefaultFileName+")")
+	yes := flags.Bool("yes", false, "Take the default answer to every question instead of asking")
+	force := flags.Bool("force", false, "Overwrite an existing config without asking")
+	_ = flags.Parse(args)
+
+	p := &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, yes: *yes}
+
+	// Workspace
+	workspacePath := *workspaceDir
+	if workspacePath == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			log.Fatalf("Failed to get the current directory: %v", err)
+		}
+		workspacePath = p.ask("Workspace directory", cwd)
+	}
+	workspacePath, err := filepath.Abs(workspacePath)
+	if err != nil {
+		log.Fatalf("Failed to get absolute path for workspace: %v", err)
+	}
+	if info, err := os.Stat(workspacePath); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "%s is not a directory\n", workspacePath)
+		return 1
+	}
+
+	// Access and extra ignores
+	readOnly := !p.confirm("Let agents change files in the workspace?", true)
+	// Files .gitignore leaves out are already left out
+	suggested := strings.Join(suggestIgnores(workspacePath), ", ")
+	ignores := splitList(p.ask("Extra patterns to ignore, comma-separated, \"-\" for none", suggested))
+
+	content := initConfig(readOnly, ignores)
+	path := *configPath
+	if path == "" {
+		path = config.DefaultPath(workspacePath)
+	}
+	// The config must load as written
+	if _, err := config.Parse([]byte(content), path, workspacePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Generated an invalid config: %v\n", err)
+		return 1
+	}
+
+	if _, err := os.Stat(path); err == nil && !*force {
+		if *yes || !p.confirm(fmt.Sprintf("%s exists. Overwrite it?", path), false) {
+			fmt.Fprintf(os.Stderr, "Kept %s; pass --force to replace it\n", path)
+			return 1
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write config: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(p.out, "\nWrote %s\n\n", path)
+
+	serverArgs := []string{"--workspace", workspacePath}
+	if path != config.DefaultPath(workspacePath) {
+		serverArgs = append(serverArgs, "--config", path)
+	}
+	printClientConfigs(p.out, "filesystem", serverArgs)
+	return 0
+}
+
+// suggestIgnores returns the build and dependency directories at the top of
+// a workspace that nothing ignores yet
+func suggestIgnores(workspacePath string) []string {
+	matcher, err := gitignore.NewMatcher(workspacePath, config.Default())
+	if err != nil {
+		return nil
+	}
+	var suggested []string
+	for _, name := range buildDirs {
+		dir := filepath.Join(workspacePath, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() && !matcher.ShouldIgnoreDir(dir) {
+			suggested = append(suggested, name+"/")
+		}
+	}
+	return suggested
+}
+
+// splitList splits a comma-separated answer; "-" is an empty list
+func splitList(answer string) []string {
+	if strings.TrimSpace(answer) == "-" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// initConfig returns the text of a generated config
+func initConfig(readOnly bool, ignores []string) string {
+	var b strings.Builder
+	b.WriteString("# mcp-filesystem config, generated by \"mcp-filesystem init\". The README\n")
+	b.WriteString("# lists every setting.\n\n")
+	b.WriteString("# Refuse every change to the workspace and leave out the tools that make\n")
+	b.WriteString("# changes\n")
+	fmt.Fprintf(&b, "readOnly: %t\n\n", readOnly)
+	b.WriteString("# .gitignore patterns for files left out on top of the repository's own\n")
+	if len(ignores) == 0 {
+		b.WriteString("ignore: []\n")
+	} else {
+		b.WriteString("ignore:\n")
+		for _, pattern := range ignores {
+			// JSON strings are valid double-quoted YAML scalars
+			quoted, _ := json.Marshal(pattern)
+			fmt.Fprintf(&b, "  - %s\n", quoted)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/generated"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/manifest"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// inspectLimit is how many ignored paths are listed without --all
+const inspectLimit = 20
+
+// inspectReport describes what the server would expose for a workspace
+type inspectReport struct {
+	Workspace   string            `json:"workspace"`
+	Config      string            `json:"config"`
+	ConfigFound bool              `json:"configFound"`
+	WatchMode   string            `json:"watchMode"`
+	NetworkFS   string            `json:"networkFilesystem,omitempty"`
+	Formatters  map[string]string `json:"formatters,omitempty"`
+	Files       int               `json:"files"`
+	Bytes       int64             `json:"bytes"`
+	Extensions  map[string]int    `json:"extensions"`
+	Manifests   []string          `json:"manifests,omitempty"`
+	Generated   map[string]string `json:"generated,omitempty"`
+	Ignored     []string          `json:"ignored"`
+	// IgnoreReasons explains each ignored path with --explain-ignores
+	IgnoreReasons map[string]string  `json:"ignoreReasons,omitempty"`
+	Diagnostics   diagnostics.Report `json:"diagnostics"`
+}
+
+// runInspect prints what would be exposed for a workspace without serving it
+func runInspect(args []string) {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	opts := addWorkspaceFlags(flags)
+	jsonOutput := flags.Bool("json", false, "Print the report as JSON")
+	all := flags.Bool("all", false, fmt.Sprintf("List every ignored path instead of the first %d", inspectLimit))
+	explain := flags.Bool("explain-ignores", false, "Give the rule that excludes each ignored path, or explain only the paths given as arguments")
+	_ = flags.Parse(args)
+
+	workspacePath := opts.workspace()
+
+	if *explain && flags.NArg() > 0 {
+		if err := explainPaths(os.Stdout, workspacePath, opts, flags.Args(), *jsonOutput); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	report, err := inspectWorkspace(workspacePath, opts, *explain)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	printInspectReport(os.Stdout, report, *all)
+}
+
+// inspectWorkspace walks a workspace the way the server does and records
+// the result, with the rule excluding each ignored path if explain is set
+func inspectWorkspace(workspacePath string, opts *workspaceFlags, explain bool) (*inspectReport, error) {
+	report := &inspectReport{
+		Workspace:  workspacePath,
+		Config:     opts.configFile(workspacePath),
+		Extensions: make(map[string]int),
+	}
+
+	if _, err := os.Stat(report.Config); err == nil {
+		report.ConfigFound = true
+	}
+	cfg, err := opts.load(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	report.Formatters = cfg.Formatters
+
+	report.WatchMode, report.NetworkFS, err = watcher.ResolveMode(workspacePath, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := gitignore.NewMatcher(workspacePath, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gitignore matcher: %v", err)
+	}
+	diag := diagnostics.New(workspacePath)
+	files := fsys.OS(workspacePath)
+	if explain {
+		report.IgnoreReasons = make(map[string]string)
+	}
+
+	err = files.Walk(workspacePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == workspacePath {
+				return err
+			}
+			if os.IsPermission(err) {
+				diag.Add(diagnostics.PermissionDenied, path, err.Error())
+			}
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, _ := filepath.Rel(workspacePath, path)
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if matcher.ShouldIgnoreDir(path) {
+				report.Ignored = append(report.Ignored, rel+"/")
+				if explain {
+					report.IgnoreReasons[rel+"/"] = matcher.ExplainDir(path).String()
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if matcher.ShouldIgnore(path) {
+			report.Ignored = append(report.Ignored, rel)
+			if explain {
+				report.IgnoreReasons[rel] = matcher.Explain(path).String()
+			}
+			return nil
+		}
+		if special := diagnostics.SpecialFileType(path, info, os.Stat); special != "" {
+			diag.Add(diagnostics.SpecialFile, path, special)
+			return nil
+		}
+
+		report.Files++
+		report.Bytes += info.Size()
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == "" {
+			ext = "(none)"
+		}
+		report.Extensions[ext]++
+		if manifest.IsManifest(path) {
+			report.Manifests = append(report.Manifests, rel)
+		} else if cfg.DetectGenerated {
+			if reason := generated.Detect(path, files.Open); reason != "" {
+				if report.Generated == nil {
+					report.Generated = make(map[string]string)
+				}
+				report.Generated[rel] = reason
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan workspace: %v", err)
+	}
+
+	report.Diagnostics = diag.Report()
+	return report, nil
+}
+
+// printInspectReport prints a report for people
+func printInspectReport(w io.Writer, report *inspectReport, all bool) {
+	fmt.Fprintf(w, "Workspace:  %s\n", report.Workspace)
+	if report.ConfigFound {
+		fmt.Fprintf(w, "Config:     %s\n", report.Config)
+	} else {
+		fmt.Fprintf(w, "Config:     %s (not found, using defaults)\n", report.Config)
+	}
+	if report.NetworkFS != "" {
+		fmt.Fprintf(w, "Watch mode: %s (%s network filesystem detected)\n", report.WatchMode, report.NetworkFS)
+	} else {
+		fmt.Fprintf(w, "Watch mode: %s\n", report.WatchMode)
+	}
+	for _, ext := range sortedKeys(report.Formatters) {
+		fmt.Fprintf(w, "Formatter:  %s -> %s\n", ext, report.Formatters[ext])
+	}
+
+	fmt.Fprintf(w, "\nExposed files: %d (%d bytes)\n", report.Files, report.Bytes)
+	exts := sortedKeys(report.Extensions)
+	sort.SliceStable(exts, func(i, j int) bool {
+		return report.Extensions[exts[i]] > report.Extensions[exts[j]]
+	})
+	for _, ext := range exts {
+		fmt.Fprintf(w, "  %-12s %d\n", ext, report.Extensions[ext])
+	}
+	if len(report.Manifests) > 0 {
+		fmt.Fprintf(w, "\nManifests (listed first):\n")
+		for _, path := range report.Manifests {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+
+	if len(report.Generated) > 0 {
+		fmt.Fprintf(w, "\nGenerated (listed last):\n")
+		for _, path := range sortedKeys(report.Generated) {
+			fmt.Fprintf(w, "  %s (%s)\n", path, report.Generated[path])
+		}
+	}
+
+	fmt.Fprintf(w, "\nIgnored paths: %d\n", len(report.Ignored))
+	for i, path := range report.Ignored {
+		if !all && i == inspectLimit {
+			fmt.Fprintf(w, "  ... %d more (use --all to list them)\n", len(report.Ignored)-inspectLimit)
+			break
+		}
+		if reason, ok := report.IgnoreReasons[path]; ok {
+			fmt.Fprintf(w, "  %s\n", reason)
+		} else {
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+
+	for _, category := range sortedKeys(report.Diagnostics.Entries) {
+		fmt.Fprintf(w, "\nSkipped (%s): %d\n", category, report.Diagnostics.Counts[category])
+		for _, entry := range report.Diagnostics.Entries[category] {
+			fmt.Fprintf(w, "  %s: %s\n", entry.Path, entry.Detail)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// explainPaths prints which rule excludes each of the given paths, which
+// may be relative to the workspace or absolute
+func explainPaths(w io.Writer, workspacePath string, opts *workspaceFlags, paths []string, jsonOutput bool) error {
+	cfg, err := opts.load(workspacePath)
+	if err != nil {
+		return err
+	}
+	matcher, err := gitignore.NewMatcher(workspacePath, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create gitignore matcher: %v", err)
+	}
+
+	explanations := make([]gitignore.Explanation, 0, len(paths))
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspacePath, path)
+		}
+		path = filepath.Clean(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			explanations = append(explanations, matcher.ExplainDir(path))
+		} else {
+			explanations = append(explanations, matcher.Explain(path))
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(explanations)
+	}
+	for _, explanation := range explanations {
+		fmt.Fprintln(w, explanation)
+	}
+	return nil
+}
@@ -0,0 +1,858 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the config file looked up in the workspace root
+const DefaultFileName = ".mcp-filesystem.yaml"
+
+// aliasPattern restricts workspace aliases to characters valid in a URI host
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// reservedNames are the workspace:// names of server resources, which
+// workspace aliases and mounts can't take
+var reservedNames = map[string]bool{"diagnostics": true, "hot-files": true, "session-changes": true, "sample": true}
+
+// Reserved reports whether name is taken by a server resource such as
+// workspace://diagnostics
+func Reserved(name string) bool {
+	return reservedNames[name]
+}
+
+// authorPattern matches a git identity such as "Jane Doe <jane@example.com>"
+var authorPattern = regexp.MustCompile(`^[^<>]+ <[^<>]*>$`)
+
+// Snapshot modes for recording the state before a tool modifies files
+const (
+	// SnapshotOff takes no snapshots
+	SnapshotOff = "off"
+	// SnapshotStash adds the working tree state to the git stash list
+	SnapshotStash = "stash"
+	// SnapshotTag points a lightweight tag at the working tree state
+	SnapshotTag = "tag"
+)
+
+// Watch modes
+const (
+	// WatchModeAuto uses notify unless the workspace is on a network filesystem
+	WatchModeAuto = "auto"
+	// WatchModeNotify uses native filesystem notifications (inotify, FSEvents, ...)
+	WatchModeNotify = "notify"
+	// WatchModePoll periodically compares modification times and sizes
+	WatchModePoll = "poll"
+	// WatchModeWatchman uses a running Watchman daemon, avoiding inotify limits
+	WatchModeWatchman = "watchman"
+)
+
+// Submodule handling
+const (
+	// SubmodulesRecurse walks submodules using each submodule's own .gitignore
+	SubmodulesRecurse = "recurse"
+	// SubmodulesSkip leaves submodules out of the workspace entirely
+	SubmodulesSkip = "skip"
+)
+
+// URI schemes for file resources
+const (
+	// URISchemeFile uses file:///absolute/path URIs
+	URISchemeFile = "file"
+	// URISchemeWorkspace uses workspace://<alias>/<relative/path> URIs, which
+	// are stable across machines and don't reveal local paths
+	URISchemeWorkspace = "workspace"
+)
+
+// DefaultPageSize is how many resources or tool results are returned per page
+const DefaultPageSize = 1000
+
+// Preview defaults: files above DefaultPreviewThreshold bytes are read as a
+// preview of their first DefaultPreviewSize bytes
+const (
+	DefaultPreviewThreshold = 1 << 20
+	DefaultPreviewSize      = 64 << 10
+)
+
+// DefaultExecTimeout bounds how long run_command lets a command run
+const DefaultExecTimeout = 5 * time.Minute
+
+// Notification defaults: changes are batched for DefaultBatchWindow and at
+// most DefaultMaxNotificationsPerSecond notifications are sent per second
+const (
+	DefaultBatchWindow               = 100 * time.Millisecond
+	DefaultMaxNotificationsPerSecond = 20
+)
+
+// DefaultIndexMaxFileSize is the largest file the search index holds
+const DefaultIndexMaxFileSize = 1 << 20
+
+// Background I/O defaults: background work reads at most
+// DefaultBackgroundBytesPerSecond and opens or lists at most
+// DefaultBackgroundOpsPerSecond files or directories a second
+const (
+	DefaultBackgroundBytesPerSecond = "32MiB"
+	DefaultBackgroundOpsPerSecond   = 1000
+)
+
+// Sensitive-file policies
+const (
+	// PolicyHide treats the files as if they didn't exist
+	PolicyHide = "hide"
+	// PolicyRedact lists the files but withholds their content
+	PolicyRedact = "redact"
+	// PolicyReadOnly serves the files but refuses changes to them
+	PolicyReadOnly = "readOnly"
+	// PolicyElicit needs the user's approval for each access
+	PolicyElicit = "elicit"
+)
+
+// Dotenv file handling
+const (
+	// DotEnvMask serves the keys of .env files with their values masked
+	DotEnvMask = "mask"
+	// DotEnvReveal serves .env files as they are
+	DotEnvReveal = "reveal"
+)
+
+// DefaultPollInterval is how often the workspace is rescanned in poll mode
+const DefaultPollInterval = 2 * time.Second
+
+// DefaultEditorArtifacts are the temporary files of common editors: Vim's
+// swap files, backups and write test file (4913), Emacs lock and autosave
+// files, and JetBrains safe-write files
+var DefaultEditorArtifacts = []string{
+	"4913",
+	"*.swp",
+	"*.swo",
+	"*.swx",
+	"*~",
+	".#*",
+	"\\#*#",
+	"*___jb_tmp___",
+	"*___jb_old___",
+}
+
+// DefaultSettleDelay is how long a changed file must stay unchanged
+// before its change is applied
+const DefaultSettleDelay = 100 * time.Millisecond
+
+// DefaultDemoteAfter is how long a subtree goes unread before tiered
+// watching stops watching it
+const DefaultDemoteAfter = 10 * time.Minute
+
+// Config holds user configuration loaded from YAML
+type Config struct {
+	// Path is the file the config was loaded from, or would have been if
+	// it existed; empty for a config that isn't from a file
+	Path string `yaml:"-"`
+
+	// Formatters maps a file extension (".go") to a formatter command run
+	// after a tool modifies a file. "{file}" is replaced with the file path;
+	// without it the path is appended as the last argument.
+	Formatters map[string]string `yaml:"formatters"`
+
+	// Checkers maps a file extension (".md") to lint, spell or style check
+	// commands whose diagnostics the check_file tool reports. "{file}" is
+	// replaced as for formatters.
+	Checkers map[string][]string `yaml:"checkers"`
+
+	// Watch controls how the workspace is watched for changes
+	Watch WatchConfig `yaml:"watch"`
+
+	// Git controls how git repository structure affects what is exposed
+	Git GitConfig `yaml:"git"`
+
+	// Resources controls how files are exposed as resources
+	Resources ResourcesConfig `yaml:"resources"`
+
+	// Notifications limits the change notifications sent to clients
+	Notifications NotificationsConfig `yaml:"notifications"`
+
+	// Index controls the full-text search index
+	Index IndexConfig `yaml:"index"`
+
+	// Background paces background disk work
+	Background BackgroundConfig `yaml:"background"`
+
+	// PageSize is the most items returned by one resources/list request or
+	// list-style tool call; 0 returns everything at once
+	PageSize int `yaml:"pageSize"`
+
+	// DetectGenerated marks lockfiles, minified bundles and other generated
+	// files and lists them after hand-written ones
+	DetectGenerated bool `yaml:"detectGenerated"`
+
+	// Exec lists the commands the run_command tool may run
+	Exec ExecConfig `yaml:"exec"`
+
+	// Templates maps a name to a file skeleton for create_from_template
+	Templates map[string]Template `yaml:"templates"`
+
+	// Scaffolds maps a name to a directory skeleton for scaffold_project
+	Scaffolds map[string]Scaffold `yaml:"scaffolds"`
+
+	// MemoryLimit is the memory the server aims to stay under, such as
+	// "512MiB"; caches are dropped as it is approached. Empty is unlimited.
+	MemoryLimit string `yaml:"memoryLimit"`
+
+	// Mounts attach other directories and remote filesystems to the
+	// workspace, each as a top-level directory
+	Mounts []MountConfig `yaml:"mounts"`
+
+	// Scratch gives each client session a private directory under
+	// scratch/ for temporary files, deleted when the session ends
+	Scratch bool `yaml:"scratch"`
+
+	// Quotas limit what each client session can write through tools
+	Quotas QuotaConfig `yaml:"quotas"`
+
+	// AuditLog is an absolute path that writes refused by quotas are
+	// appended to, one JSON object per line; empty records them in the
+	// server log
+	AuditLog string `yaml:"auditLog"`
+
+	// Sensitive classifies files such as keys and credentials, each class
+	// with a policy applied wherever its files would be exposed
+	Sensitive []SensitiveClass `yaml:"sensitive"`
+
+	// DotEnv is "mask" (the default) or "reveal": whether the values of
+	// .env files are masked wherever their content is served, leaving the
+	// keys visible
+	DotEnv string `yaml:"dotenv"`
+
+	// DotEnvIgnored serves .env files that .gitignore excludes too; they
+	// are hidden by default like other ignored files
+	DotEnvIgnored bool `yaml:"dotenvIgnored"`
+
+	// Sandbox adds paths the server may use when confined with --sandbox
+	Sandbox SandboxConfig `yaml:"sandbox"`
+
+	// ReadOnly refuses every change to the workspace and its mounts, and
+	// leaves out the tools that make changes, run_command and the git
+	// write tools
+	ReadOnly bool `yaml:"readOnly"`
+
+	// Ignore are .gitignore patterns for files left out of the workspace on
+	// top of the repositories' own rules
+	Ignore []string `yaml:"ignore"`
+
+	// Tools limits the tools offered to clients
+	Tools ToolsConfig `yaml:"tools"`
+
+	// Profiles are named bundles of settings chosen with --profile and
+	// applied over the rest of the config
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Profile is the profile applied with ApplyProfile, if any
+	Profile string `yaml:"-"`
+
+	// Simulate is set by the --simulate flag: tools change an in-memory
+	// overlay that can't be applied to the workspace
+	Simulate bool `yaml:"-"`
+}
+
+// ToolsConfig picks the tools offered to clients by name or by pattern,
+// such as "git_*"
+type ToolsConfig struct {
+	// Enabled are the only tools offered; empty offers every tool
+	Enabled []string `yaml:"enabled"`
+	// Disabled are tools left out, even if enabled
+	Disabled []string `yaml:"disabled"`
+}
+
+// Profile is a named bundle of tool enablement, ignore patterns and
+// policies, such as a read-only "safe" profile, so a session's risk level
+// can be chosen with --profile instead of editing the config
+type Profile struct {
+	// Description tells users what the profile is for
+	Description string `yaml:"description"`
+	// ReadOnly replaces readOnly when set
+	ReadOnly *bool `yaml:"readOnly"`
+	// Tools replaces tools when set
+	Tools *ToolsConfig `yaml:"tools"`
+	// Ignore adds patterns to ignore
+	Ignore []string `yaml:"ignore"`
+	// Sensitive classes come before the config's own, so they decide for
+	// the files they match
+	Sensitive []SensitiveClass `yaml:"sensitive"`
+	// DotEnv replaces dotenv when set
+	DotEnv string `yaml:"dotenv"`
+}
+
+// SandboxConfig lists paths beyond the workspace, config and state that a
+// sandboxed server may use, such as toolchains a formatter needs
+type SandboxConfig struct {
+	// Read are paths that may be read and executed
+	Read []string `yaml:"read"`
+	// Write are paths that may also be written
+	Write []string `yaml:"write"`
+}
+
+// SensitiveClass is a set of sensitive files and the policy for them
+type SensitiveClass struct {
+	// Name identifies the class in errors and logs
+	Name string `yaml:"name"`
+	// Patterns select the files, in .gitignore syntax
+	Patterns []string `yaml:"patterns"`
+	// Policy is one of "hide", "redact", "readOnly" or "elicit"
+	Policy string `yaml:"policy"`
+}
+
+// QuotaConfig limits what one client session can write through tools, so a
+// misbehaving agent can't fill the disk. Zero values are unlimited.
+type QuotaConfig struct {
+	// MaxBytesPerSession caps the bytes a session writes, such as "100MiB"
+	MaxBytesPerSession string `yaml:"maxBytesPerSession"`
+	// MaxNewFiles caps the files a session creates
+	MaxNewFiles int `yaml:"maxNewFiles"`
+	// MaxFileSize caps the size a write may leave a file at, such as "10MiB"
+	MaxFileSize string `yaml:"maxFileSize"`
+}
+
+// MountConfig attaches a filesystem to the workspace
+type MountConfig struct {
+	// Name is the top-level directory the mount appears as, and its alias
+	// in workspace:// URIs
+	Name string `yaml:"name"`
+	// Source is a directory, relative to the workspace or absolute, or an
+	// sftp://, s3:// or docker:// URL
+	Source string `yaml:"source"`
+	// ReadOnly refuses changes to the mount's files
+	ReadOnly bool `yaml:"readOnly"`
+}
+
+// WatchConfig selects the change notification backend
+type WatchConfig struct {
+	// Mode is one of "auto", "notify", "poll" or "watchman"
+	Mode string `yaml:"mode"`
+	// PollInterval is the rescan interval in poll mode, such as "5s"
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// SettleDelay is how long a changed file's size and modification time
+	// must stay the same before the change is applied, so files still being
+	// written aren't served half-written; 0 applies changes right away
+	SettleDelay time.Duration `yaml:"settleDelay"`
+	// Paths limits watching to these workspace-relative subtrees; files
+	// elsewhere aren't listed but can still be read. Empty watches the
+	// whole workspace.
+	Paths []string `yaml:"paths"`
+	// Tiers stops watching subtrees nobody reads, in notify mode
+	Tiers TiersConfig `yaml:"tiers"`
+	// EditorArtifacts are .gitignore patterns for the swap, backup and
+	// atomic-save files editors create next to the files they edit, which
+	// are left out of the workspace like ignored files
+	EditorArtifacts []string `yaml:"editorArtifacts"`
+}
+
+// TiersConfig controls tiered watching: subtrees clients read are watched
+// in real time (hot), others are only checked when read (cold)
+type TiersConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Depth is how deep the directories that are promoted and demoted as a
+	// whole are; 1 is the workspace's top-level directories
+	Depth int `yaml:"depth"`
+	// DemoteAfter is how long a subtree must go unread to become cold
+	DemoteAfter time.Duration `yaml:"demoteAfter"`
+	// PromoteAfter is how many reads within DemoteAfter make a cold
+	// subtree hot again
+	PromoteAfter int `yaml:"promoteAfter"`
+}
+
+// NotificationsConfig batches and rate-limits resource notifications
+type NotificationsConfig struct {
+	// BatchWindow is how long changes are collected before notifications
+	// for them are sent, such as "100ms"; 0 sends them right away
+	BatchWindow time.Duration `yaml:"batchWindow"`
+	// MaxPerSecond caps the notifications sent per second; 0 is unlimited.
+	// A batch with more changed resources than the cap allows is summarized
+	// as one notification for their common directory.
+	MaxPerSecond int `yaml:"maxPerSecond"`
+}
+
+// IndexConfig controls the trigram index behind the search tool
+type IndexConfig struct {
+	// Persist saves the index when the server stops so search is fast right
+	// after a restart; files changed in the meantime are reindexed
+	Persist bool `yaml:"persist"`
+	// CacheDir is where persisted indexes are kept; it defaults to
+	// mcp-filesystem in the user cache directory
+	CacheDir string `yaml:"cacheDir"`
+	// MaxFileSize is the largest file indexed, in bytes; larger files are
+	// searched by reading them every time
+	MaxFileSize int64 `yaml:"maxFileSize"`
+}
+
+// BackgroundConfig budgets the disk work done in the background: indexing
+// and rescans in poll mode. Reads and tool calls aren't paced.
+type BackgroundConfig struct {
+	// BytesPerSecond caps what background work reads, such as "32MiB";
+	// empty is unlimited
+	BytesPerSecond string `yaml:"bytesPerSecond"`
+	// OpsPerSecond caps the files background work opens and directories it
+	// lists each second; 0 is unlimited
+	OpsPerSecond int `yaml:"opsPerSecond"`
+}
+
+// GitConfig controls handling of git repository features
+type GitConfig struct {
+	// Submodules is "recurse" (the default) or "skip"
+	Submodules string `yaml:"submodules"`
+	// WriteEnabled is set by the --enable-git-write flag and adds the
+	// git_add and git_commit tools
+	WriteEnabled bool `yaml:"-"`
+	// Author is the "Name <email>" commits made by git_commit are
+	// attributed to; git's configured identity is used when empty
+	Author string `yaml:"author"`
+	// Snapshots is "off" (the default), "stash" or "tag": how the state of
+	// a repository is saved before a tool modifies files in it, so
+	// revert_last_operation can restore it
+	Snapshots string `yaml:"snapshots"`
+	// StrictIgnore asks git check-ignore whether each path is ignored
+	// instead of matching .gitignore patterns in the server, for patterns
+	// the built-in matcher gets wrong; also set by --strict-gitignore
+	StrictIgnore bool `yaml:"strictIgnore"`
+}
+
+// ResourcesConfig controls the URIs and reads of file resources
+type ResourcesConfig struct {
+	// URIScheme is "file" (the default) or "workspace"
+	URIScheme string `yaml:"uriScheme"`
+	// Alias names the workspace in workspace:// URIs; it defaults to the
+	// workspace directory name
+	Alias string `yaml:"alias"`
+	// PreviewThreshold is the size in bytes above which reading a file
+	// resource returns a preview instead of the whole file; 0 disables previews
+	PreviewThreshold int64 `yaml:"previewThreshold"`
+	// PreviewSize is how many bytes from the start of a file a preview holds
+	PreviewSize int64 `yaml:"previewSize"`
+	// LineNumbers prefixes every line of file content returned by resource
+	// reads and read tools with its line number
+	LineNumbers bool `yaml:"lineNumbers"`
+	// DirectoriesFirst lists directory resources before the files of the
+	// same priority; otherwise both are listed in path order
+	DirectoriesFirst bool `yaml:"directoriesFirst"`
+}
+
+// ExecConfig controls the run_command tool
+type ExecConfig struct {
+	// Enabled is set by the --enable-exec flag; a config file alone can't
+	// turn command execution on
+	Enabled bool `yaml:"-"`
+	// Allow lists permitted commands. An entry is a program name ("make") or
+	// a program with leading arguments ("go test") that a command must start with.
+	Allow []string `yaml:"allow"`
+	// Timeout is the longest a command may run, such as "10m"
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Template is a file skeleton written in Go's text/template syntax
+type Template struct {
+	// Description tells clients what the template is for
+	Description string `yaml:"description"`
+	// Content is the template text; File names a workspace-relative file
+	// holding it instead
+	Content string `yaml:"content"`
+	File    string `yaml:"file"`
+	// Header names another template, such as a license header, that is
+	// filled in and placed before this one
+	Header string `yaml:"header"`
+}
+
+// Scaffold is a directory skeleton, such as a new service or module. Its
+// paths and contents are templates like those of Template.
+type Scaffold struct {
+	// Description tells clients what the scaffold is for
+	Description string `yaml:"description"`
+	// Vars names the variables a caller must supply
+	Vars []string `yaml:"vars"`
+	// Dirs are directories to create, even if no file is put in them
+	Dirs []string `yaml:"dirs"`
+	// Files are the files to create
+	Files []ScaffoldFile `yaml:"files"`
+}
+
+// ScaffoldFile is a file of a scaffold. Its content is given inline or
+// taken from a named template.
+type ScaffoldFile struct {
+	Path     string `yaml:"path"`
+	Content  string `yaml:"content"`
+	Template string `yaml:"template"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Formatters: map[string]string{},
+		Watch: WatchConfig{
+			Mode:         WatchModeAuto,
+			PollInterval: DefaultPollInterval,
+			SettleDelay:  DefaultSettleDelay,
+			Tiers: TiersConfig{
+				Depth:        1,
+				DemoteAfter:  DefaultDemoteAfter,
+				PromoteAfter: 3,
+			},
+			EditorArtifacts: append([]string(nil), DefaultEditorArtifacts...),
+		},
+		Git: GitConfig{
+			Submodules: SubmodulesRecurse,
+			Snapshots:  SnapshotOff,
+		},
+		Resources: ResourcesConfig{
+			URIScheme:        URISchemeFile,
+			PreviewThreshold: DefaultPreviewThreshold,
+			PreviewSize:      DefaultPreviewSize,
+		},
+		PageSize:        DefaultPageSize,
+		DetectGenerated: true,
+		DotEnv:          DotEnvMask,
+		Notifications: NotificationsConfig{
+			BatchWindow:  DefaultBatchWindow,
+			MaxPerSecond: DefaultMaxNotificationsPerSecond,
+		},
+		Index: IndexConfig{
+			Persist:     true,
+			MaxFileSize: DefaultIndexMaxFileSize,
+		},
+		Background: BackgroundConfig{
+			BytesPerSecond: DefaultBackgroundBytesPerSecond,
+			OpsPerSecond:   DefaultBackgroundOpsPerSecond,
+		},
+		Exec: ExecConfig{
+			Timeout: DefaultExecTimeout,
+		},
+	}
+}
+
+// DefaultPath returns the default config file location for a workspace
+func DefaultPath(workspacePath string) string {
+	return filepath.Join(workspacePath, DefaultFileName)
+}
+
+// Load reads a config file for a workspace, returning defaults if it
+// doesn't exist
+func Load(path, workspacePath string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		cfg.Path = path
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	return Parse(data, path, workspacePath)
+}
+
+// Parse parses config file contents; path identifies the file in errors,
+// and workspacePath is what ${WORKSPACE} expands to
+func Parse(data []byte, path, workspacePath string) (*Config, error) {
+	cfg := Default()
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+
+	if err := cfg.expandVariables(workspacePath); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	cfg.Path = path
+	return cfg, nil
+}
+
+// validate checks config values that can't be expressed in the YAML types
+func (c *Config) validate() error {
+	for ext, command := range c.Formatters {
+		if len(ext) < 2 || ext[0] != '.' {
+			return fmt.Errorf("formatter extension must start with '.': %q", ext)
+		}
+		if command == "" {
+			return fmt.Errorf("formatter for %s has no command", ext)
+		}
+	}
+	for ext, commands := range c.Checkers {
+		if len(ext) < 2 || ext[0] != '.' {
+			return fmt.Errorf("checker extension must start with '.': %q", ext)
+		}
+		if len(commands) == 0 {
+			return fmt.Errorf("checkers for %s have no command", ext)
+		}
+		for _, command := range commands {
+			if command == "" {
+				return fmt.Errorf("checker for %s has no command", ext)
+			}
+		}
+	}
+	if c.PageSize < 0 {
+		return fmt.Errorf("page size can't be negative: %d", c.PageSize)
+	}
+	if err := c.Watch.validate(); err != nil {
+		return err
+	}
+	switch c.Git.Submodules {
+	case SubmodulesRecurse, SubmodulesSkip:
+	default:
+		return fmt.Errorf("unknown submodules setting %q (expected recurse or skip)", c.Git.Submodules)
+	}
+	switch c.Git.Snapshots {
+	case SnapshotOff, SnapshotStash, SnapshotTag:
+	default:
+		return fmt.Errorf("unknown snapshots setting %q (expected off, stash or tag)", c.Git.Snapshots)
+	}
+	if err := validateDotEnv(c.DotEnv); err != nil {
+		return err
+	}
+	if err := c.Tools.validate(); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(c.Profiles) {
+		if err := c.Profiles[name].validate(); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+	if author := c.Git.Author; author != "" && !authorPattern.MatchString(author) {
+		return fmt.Errorf("invalid git author %q (expected \"Name <email>\")", author)
+	}
+	switch c.Resources.URIScheme {
+	case URISchemeFile, URISchemeWorkspace:
+	default:
+		return fmt.Errorf("unknown URI scheme %q (expected file or workspace)", c.Resources.URIScheme)
+	}
+	if alias := c.Resources.Alias; alias != "" && (!aliasPattern.MatchString(alias) || Reserved(alias)) {
+		return fmt.Errorf("invalid workspace alias %q: use letters, digits, '.', '_' and '-' (and not the name of a server resource such as \"diagnostics\")", alias)
+	}
+	if c.Scratch && c.Resources.Alias == "scratch" {
+		return fmt.Errorf("the workspace alias can't be scratch while scratch directories are enabled")
+	}
+	mounts := make(map[string]bool)
+	for _, mount := range c.Mounts {
+		if !aliasPattern.MatchString(mount.Name) || Reserved(mount.Name) {
+			return fmt.Errorf("invalid mount name %q: use letters, digits, '.', '_' and '-' (and not the name of a server resource such as \"diagnostics\")", mount.Name)
+		}
+		if mounts[mount.Name] {
+			return fmt.Errorf("mount %s is listed twice", mount.Name)
+		}
+		if mount.Name == c.Resources.Alias {
+			return fmt.Errorf("mount %s has the name of the workspace alias", mount.Name)
+		}
+		if mount.Source == "" {
+			return fmt.Errorf("mount %s has no source", mount.Name)
+		}
+		if c.Scratch && mount.Name == "scratch" {
+			return fmt.Errorf("mount scratch has the name of the scratch directory")
+		}
+		mounts[mount.Name] = true
+	}
+	for _, entry := range c.Exec.Allow {
+		if len(strings.Fields(entry)) == 0 {
+			return fmt.Errorf("exec allow list has an empty entry")
+		}
+	}
+	if c.Notifications.BatchWindow < 0 {
+		return fmt.Errorf("notification batch window can't be negative: %v", c.Notifications.BatchWindow)
+	}
+	if c.Notifications.MaxPerSecond < 0 {
+		return fmt.Errorf("notifications per second can't be negative: %d", c.Notifications.MaxPerSecond)
+	}
+	if c.Index.MaxFileSize < 0 {
+		return fmt.Errorf("index max file size can't be negative: %d", c.Index.MaxFileSize)
+	}
+	if _, err := ParseSize(c.Background.BytesPerSecond); err != nil {
+		return fmt.Errorf("invalid background bytes per second: %v", err)
+	}
+	if c.Background.OpsPerSecond < 0 {
+		return fmt.Errorf("background operations per second can't be negative: %d", c.Background.OpsPerSecond)
+	}
+	if _, err := ParseSize(c.MemoryLimit); err != nil {
+		return fmt.Errorf("invalid memory limit: %v", err)
+	}
+	if _, err := ParseSize(c.Quotas.MaxBytesPerSession); err != nil {
+		return fmt.Errorf("invalid quota of bytes per session: %v", err)
+	}
+	if _, err := ParseSize(c.Quotas.MaxFileSize); err != nil {
+		return fmt.Errorf("invalid quota of file size: %v", err)
+	}
+	if c.Quotas.MaxNewFiles < 0 {
+		return fmt.Errorf("quota of new files can't be negative: %d", c.Quotas.MaxNewFiles)
+	}
+	if c.AuditLog != "" && !filepath.IsAbs(c.AuditLog) {
+		return fmt.Errorf("audit log must be an absolute path: %s", c.AuditLog)
+	}
+	if err := validateSensitive(c.Sensitive); err != nil {
+		return err
+	}
+	if c.Exec.Timeout <= 0 {
+		return fmt.Errorf("exec timeout must be positive: %v", c.Exec.Timeout)
+	}
+	for name, tmpl := range c.Templates {
+		if (tmpl.Content == "") == (tmpl.File == "") {
+			return fmt.Errorf("template %s needs exactly one of content or file", name)
+		}
+		if tmpl.Content != "" {
+			if _, err := template.New(name).Parse(tmpl.Content); err != nil {
+				return fmt.Errorf("template %s: %v", name, err)
+			}
+		}
+		if tmpl.Header != "" {
+			header, ok := c.Templates[tmpl.Header]
+			if !ok {
+				return fmt.Errorf("template %s: unknown header template %q", name, tmpl.Header)
+			}
+			if header.Header != "" {
+				return fmt.Errorf("template %s: header template %s can't have a header itself", name, tmpl.Header)
+			}
+		}
+	}
+	for name, scaffold := range c.Scaffolds {
+		if len(scaffold.Files) == 0 && len(scaffold.Dirs) == 0 {
+			return fmt.Errorf("scaffold %s has no files or dirs", name)
+		}
+		for _, dir := range scaffold.Dirs {
+			if _, err := template.New(name).Parse(dir); err != nil {
+				return fmt.Errorf("scaffold %s: dir %q: %v", name, dir, err)
+			}
+		}
+		for _, file := range scaffold.Files {
+			if file.Path == "" {
+				return fmt.Errorf("scaffold %s has a file without a path", name)
+			}
+			if _, err := template.New(name).Parse(file.Path); err != nil {
+				return fmt.Errorf("scaffold %s: path %q: %v", name, file.Path, err)
+			}
+			if file.Content != "" && file.Template != "" {
+				return fmt.Errorf("scaffold %s: file %s can't have both content and template", name, file.Path)
+			}
+			if file.Template != "" {
+				if _, ok := c.Templates[file.Template]; !ok {
+					return fmt.Errorf("scaffold %s: file %s: unknown template %q", name, file.Path, file.Template)
+				}
+			}
+			if _, err := template.New(name).Parse(file.Content); err != nil {
+				return fmt.Errorf("scaffold %s: file %s: %v", name, file.Path, err)
+			}
+		}
+	}
+	if c.Resources.PreviewThreshold < 0 {
+		return fmt.Errorf("preview threshold can't be negative: %d", c.Resources.PreviewThreshold)
+	}
+	if c.Resources.PreviewSize <= 0 {
+		return fmt.Errorf("preview size must be positive: %d", c.Resources.PreviewSize)
+	}
+	return nil
+}
+
+// validateSensitive checks sensitive file classes
+func validateSensitive(sensitive []SensitiveClass) error {
+	classes := make(map[string]bool)
+	for _, class := range sensitive {
+		if class.Name == "" {
+			return fmt.Errorf("sensitive file class has no name")
+		}
+		if classes[class.Name] {
+			return fmt.Errorf("sensitive file class %s is listed twice", class.Name)
+		}
+		if len(class.Patterns) == 0 {
+			return fmt.Errorf("sensitive file class %s has no patterns", class.Name)
+		}
+		switch class.Policy {
+		case PolicyHide, PolicyRedact, PolicyReadOnly, PolicyElicit:
+		default:
+			return fmt.Errorf("unknown policy %q for sensitive file class %s (expected hide, redact, readOnly or elicit)", class.Policy, class.Name)
+		}
+		classes[class.Name] = true
+	}
+	return nil
+}
+
+// validateDotEnv checks a dotenv setting
+func validateDotEnv(setting string) error {
+	switch setting {
+	case DotEnvMask, DotEnvReveal:
+		return nil
+	}
+	return fmt.Errorf("unknown dotenv setting %q (expected mask or reveal)", setting)
+}
+
+// sizeUnits are the suffixes ParseSize accepts, with their multipliers
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+}
+
+// sizePattern splits a size into its number and unit
+var sizePattern = regexp.MustCompile(`^\s*(\d+)\s*([a-zA-Z]*)\s*$`)
+
+// ParseSize parses a byte count such as "1048576", "512M" or "2GiB"; an
+// empty string is 0
+func ParseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	match := sizePattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("%q is not a size (expected a number of bytes such as 512MiB)", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q in %q", match[2], s)
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return n * unit, nil
+}
+
+// validate checks the watch mode and poll interval
+func (w *WatchConfig) validate() error {
+	switch w.Mode {
+	case WatchModeAuto, WatchModeNotify, WatchModePoll, WatchModeWatchman:
+	default:
+		return fmt.Errorf("unknown watch mode %q (expected auto, notify, poll or watchman)", w.Mode)
+	}
+	if w.PollInterval < 100*time.Millisecond {
+		return fmt.Errorf("poll interval must be at least 100ms: %v", w.PollInterval)
+	}
+	if w.SettleDelay < 0 {
+		return fmt.Errorf("settle delay can't be negative: %v", w.SettleDelay)
+	}
+	for _, path := range w.Paths {
+		clean := filepath.Clean(filepath.FromSlash(path))
+		if path == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("watch path must be relative to the workspace and inside it: %q", path)
+		}
+	}
+	if w.Tiers.Depth < 1 {
+		return fmt.Errorf("watch tier depth must be at least 1: %d", w.Tiers.Depth)
+	}
+	if w.Tiers.DemoteAfter < time.Second {
+		return fmt.Errorf("watch tier demoteAfter must be at least 1s: %v", w.Tiers.DemoteAfter)
+	}
+	if w.Tiers.PromoteAfter < 1 {
+		return fmt.Errorf("watch tier promoteAfter must be at least 1: %d", w.Tiers.PromoteAfter)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// variablePattern matches ${NAME} and ${env:NAME} in config values
+var variablePattern = regexp.MustCompile(`\$\{([^{}]*)\}`)
+
+// expander replaces the variables of config values, remembering the first
+// error
+type expander struct {
+	workspace string
+	err       error
+}
+
+// expandVariables replaces ${HOME}, ${WORKSPACE} and ${env:VAR} in the
+// values that name paths, patterns or commands, so one config can be
+// shared across machines and users
+func (c *Config) expandVariables(workspacePath string) error {
+	x := &expander{workspace: workspacePath}
+
+	for _, ext := range sortedKeys(c.Formatters) {
+		c.Formatters[ext] = x.expand("formatters."+ext, c.Formatters[ext])
+	}
+	for _, ext := range sortedKeys(c.Checkers) {
+		x.expandAll("checkers."+ext, c.Checkers[ext])
+	}
+	x.expandAll("watch.paths", c.Watch.Paths)
+	x.expandAll("watch.editorArtifacts", c.Watch.EditorArtifacts)
+	c.Index.CacheDir = x.expand("index.cacheDir", c.Index.CacheDir)
+	for _, name := range sortedKeys(c.Templates) {
+		tmpl := c.Templates[name]
+		tmpl.File = x.expand("templates."+name+".file", tmpl.File)
+		c.Templates[name] = tmpl
+	}
+	for i := range c.Mounts {
+		c.Mounts[i].Source = x.expand(fmt.Sprintf("mounts[%d].source", i), c.Mounts[i].Source)
+	}
+	for i := range c.Sensitive {
+		x.expandAll(fmt.Sprintf("sensitive[%d].patterns", i), c.Sensitive[i].Patterns)
+	}
+	x.expandAll("ignore", c.Ignore)
+	for _, name := range sortedKeys(c.Profiles) {
+		profile := c.Profiles[name]
+		x.expandAll("profiles."+name+".ignore", profile.Ignore)
+		for i := range profile.Sensitive {
+			x.expandAll(fmt.Sprintf("profiles.%s.sensitive[%d].patterns", name, i), profile.Sensitive[i].Patterns)
+		}
+	}
+	x.expandAll("sandbox.read", c.Sandbox.Read)
+	x.expandAll("sandbox.write", c.Sandbox.Write)
+	return x.err
+}
+
+// expandAll expands each value of a list in place
+func (x *expander) expandAll(field string, values []string) {
+	for i, value := range values {
+		values[i] = x.expand(fmt.Sprintf("%s[%d]", field, i), value)
+	}
+}
+
+// expand replaces the variables of one value; field names it in errors
+func (x *expander) expand(field, value string) string {
+	return variablePattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := match[2 : len(match)-1]
+		expanded, err := x.variable(name)
+		if err != nil && x.err == nil {
+			x.err = fmt.Errorf("%s: %v", field, err)
+		}
+		return expanded
+	})
+}
+
+// variable returns the value of a variable
+func (x *expander) variable(name string) (string, error) {
+	if env, ok := strings.CutPrefix(name, "env:"); ok {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s isn't set", env)
+		}
+		return value, nil
+	}
+	switch name {
+	case "HOME":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("can't expand ${HOME}: %v", err)
+		}
+		return home, nil
+	case "WORKSPACE":
+		if x.workspace == "" {
+			return "", fmt.Errorf("${WORKSPACE} isn't known here")
+		}
+		return x.workspace, nil
+	}
+	return "", fmt.Errorf("unknown variable ${%s} (expected ${HOME}, ${WORKSPACE} or ${env:NAME})", name)
+}
+
+// sortedKeys returns the keys of a map in order, so the first error
+// reported doesn't depend on map iteration
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ApplyProfile applies a named profile over the config
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: the config defines no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q (expected one of %s)", name, strings.Join(sortedKeys(c.Profiles), ", "))
+	}
+
+	if profile.ReadOnly != nil {
+		c.ReadOnly = *profile.ReadOnly
+	}
+	if profile.Tools != nil {
+		c.Tools = *profile.Tools
+	}
+	c.Ignore = append(append([]string(nil), c.Ignore...), profile.Ignore...)
+	c.Sensitive = append(append([]SensitiveClass(nil), profile.Sensitive...), c.Sensitive...)
+	if profile.DotEnv != "" {
+		c.DotEnv = profile.DotEnv
+	}
+	c.Profile = name
+
+	// The profile's classes may clash with the config's own
+	if err := validateSensitive(c.Sensitive); err != nil {
+		return fmt.Errorf("profile %s: %v", name, err)
+	}
+	return nil
+}
+
+// Offers reports whether a tool is enabled and not disabled
+func (t ToolsConfig) Offers(name string) bool {
+	return (len(t.Enabled) == 0 || matchesTool(t.Enabled, name)) && !matchesTool(t.Disabled, name)
+}
+
+// matchesTool reports whether a tool name matches one of patterns
+func matchesTool(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the tool name patterns
+func (t ToolsConfig) validate() error {
+	for _, pattern := range append(append([]string(nil), t.Enabled...), t.Disabled...) {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid tool pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// validate checks a profile's settings on their own
+func (p Profile) validate() error {
+	if p.Tools != nil {
+		if err := p.Tools.validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateSensitive(p.Sensitive); err != nil {
+		return err
+	}
+	if p.DotEnv != "" {
+		return validateDotEnv(p.DotEnv)
+	}
+	return nil
+}
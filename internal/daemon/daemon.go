@@ -0,0 +1,231 @@
+// Package daemon runs the server in the background on a socket so one
+// long-lived process can serve a workspace to many short-lived clients.
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// childEnv marks the re-executed background process
+const childEnv = "MCP_FILESYSTEM_DAEMON_CHILD"
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+// DefaultAddress returns the unix socket a workspace's daemon listens on
+// by default, in the user's runtime directory
+func DefaultAddress(workspacePath string) (string, error) {
+	base, err := runtimeBase(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return "unix:" + base + ".sock", nil
+}
+
+// DefaultPidFile returns the default pid file for a workspace's daemon
+func DefaultPidFile(workspacePath string) (string, error) {
+	base, err := runtimeBase(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return base + ".pid", nil
+}
+
+// DefaultLogFile returns where a detached daemon writes its log
+func DefaultLogFile(workspacePath string) (string, error) {
+	base, err := runtimeBase(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return base + ".log", nil
+}
+
+// parseAddress splits "unix:PATH" or "tcp:HOST:PORT" into a network and address
+func parseAddress(addr string) (string, string, error) {
+	network, address, ok := strings.Cut(addr, ":")
+	if !ok || address == "" {
+		return "", "", fmt.Errorf("invalid address %q (expected unix:PATH or tcp:HOST:PORT)", addr)
+	}
+	switch network {
+	case "unix", "tcp":
+		return network, address, nil
+	}
+	return "", "", fmt.Errorf("unsupported network %q in address %q (expected unix or tcp)", network, addr)
+}
+
+// IsChild reports whether this process is the background half of --daemon
+func IsChild() bool {
+	return os.Getenv(childEnv) != ""
+}
+
+// SocketActivated reports whether systemd passed a listening socket to this process
+func SocketActivated() bool {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return false
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	return err == nil && fds > 0
+}
+
+// Listen returns the socket activation listener if systemd provided one,
+// otherwise it listens on addr. A stale unix socket left by a crashed daemon
+// is removed; a live one is reported as an error. TCP clients must send the
+// token from serverToken first.
+func Listen(addr, tokenFile string) (net.Listener, error) {
+	var listener net.Listener
+	if SocketActivated() {
+		file := os.NewFile(listenFdsStart, "LISTEN_FD_3")
+		activated, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to use socket activation listener: %v", err)
+		}
+		listener = activated
+	} else {
+		network, address, err := parseAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		if network == "unix" {
+			if err := removeStaleSocket(addr, address); err != nil {
+				return nil, err
+			}
+		}
+		listener, err = net.Listen(network, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %v", addr, err)
+		}
+		if network == "unix" {
+			_ = os.Chmod(address, 0600)
+		}
+	}
+
+	if listener.Addr().Network() != "tcp" {
+		return listener, nil
+	}
+	token, err := serverToken(tokenFile)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return requireToken(listener, token), nil
+}
+
+// removeStaleSocket removes a unix socket no daemon listens on anymore.
+// Anything else at its path, or a socket of another user, is left alone.
+func removeStaleSocket(addr, address string) error {
+	info, err := os.Lstat(address)
+	if err != nil {
+		return nil
+	}
+	if conn, err := net.Dial("unix", address); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", addr)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and isn't a socket", address)
+	}
+	if err := checkOwner(info); err != nil {
+		return fmt.Errorf("socket %s %v", address, err)
+	}
+	if err := os.Remove(address); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %v", err)
+	}
+	return nil
+}
+
+// Detach starts this program again in the background with the same
+// arguments and returns the child's pid. The child's output goes to logPath.
+func Detach(logPath string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find executable: %v", err)
+	}
+
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND|oNoFollow, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachAttr()
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start daemon: %v", err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	return pid, nil
+}
+
+// WritePidFile records the current process id. The file is created anew,
+// so a pid file left by a crashed daemon is replaced rather than written
+// through.
+func WritePidFile(path string) error {
+	if err := createFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write pid file: %v", err)
+	}
+	return nil
+}
+
+// RemovePidFile removes the pid file if it still belongs to this process
+func RemovePidFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+// Connect bridges stdin and stdout to a daemon, for MCP clients that can
+// only launch a command. Over TCP it authenticates with the token from
+// TokenEnv or tokenFile.
+func Connect(addr, tokenFile string) error {
+	network, address, err := parseAddress(addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon at %s: %v", addr, err)
+	}
+	if network == "tcp" {
+		if err := sendToken(conn, tokenFile); err != nil {
+			_ = conn.Close()
+			return err
+		}
+	}
+	return Bridge(conn)
+}
+
+// Bridge copies stdin to a connection and the connection to stdout until
+// the connection ends, then closes it
+func Bridge(conn net.Conn) error {
+	defer conn.Close()
+
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		// Let the daemon see end of input while responses are still read
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			_ = closer.CloseWrite()
+		}
+	}()
+
+	if _, err := io.Copy(os.Stdout, conn); err != nil {
+		return fmt.Errorf("connection to daemon failed: %v", err)
+	}
+	return nil
+}
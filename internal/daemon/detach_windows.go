@@ -0,0 +1,14 @@
+package daemon
+
+import "syscall"
+
+// Process creation flags that detach the child from the console
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// detachAttr starts the child without a console so it outlives the terminal
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned by Lock when another server process holds a
+// workspace's lock
+var ErrLocked = errors.New("another server process holds the workspace lock")
+
+// InstanceLock is held by the one server process that watches and writes a
+// workspace, so others launched for it can defer to that process
+type InstanceLock struct {
+	file *os.File
+}
+
+// DefaultLockFile returns the lock file of a workspace's server
+// processes, in the user's runtime directory
+func DefaultLockFile(workspacePath string) (string, error) {
+	base, err := runtimeBase(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return base + ".lock", nil
+}
+
+// Lock takes the lock in path without waiting, returning ErrLocked while
+// another process holds it. The lock is released when the process exits,
+// however it exits. A symlink or another user's file at path is refused,
+// since locking it would write to or defer to someone else's file.
+func Lock(path string) (*InstanceLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|oNoFollow, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+	info, err := file.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = fmt.Errorf("isn't a regular file")
+	} else if err == nil {
+		err = checkOwner(info)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock file %s %v", path, err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	// The pid is only for telling people which process holds the lock
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &InstanceLock{file: file}, nil
+}
+
+// Release releases the lock. The file stays, since removing it would let
+// a process that opened it before lock a file no one else can find.
+func (l *InstanceLock) Release() {
+	_ = unlockFile(l.file)
+	_ = l.file.Close()
+}
+
+// LockHolder returns the pid of the process holding the lock in path, or 0
+// if it isn't known
+func LockHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// DialWhenReady connects to the server process listening on addr, retrying
+// for up to wait while a process that just took the lock starts listening.
+// Over TCP it authenticates with the token from TokenEnv or tokenFile.
+func DialWhenReady(addr string, wait time.Duration, tokenFile string) (net.Conn, error) {
+	network, address, err := parseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		conn, err := net.Dial(network, address)
+		if err == nil {
+			if network == "tcp" {
+				if err := sendToken(conn, tokenFile); err != nil {
+					_ = conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !windows
+
+package daemon
+
+import "os"
+
+// oNoFollow is not supported on this platform
+const oNoFollow = 0
+
+// lockFile is not supported on this platform; every process gets the lock
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is not supported on this platform
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockedRange is the byte range locked, far past the pid written at the
+// start of the file so other processes can still read it
+const lockedRange = 1 << 62
+
+// oNoFollow is not supported on this platform
+const oNoFollow = 0
+
+// lockFile locks a file exclusively without waiting
+func lockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockedRange >> 32}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %v", file.Name(), err)
+	}
+	return nil
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockedRange >> 32}
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// userID names the current user in runtime directory names
+func userID() string {
+	return strconv.Itoa(os.Getuid())
+}
+
+// checkOwner returns an error if a file belongs to another user
+func checkOwner(info fs.FileInfo) error {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("belongs to another user (uid %d)", stat.Uid)
+	}
+	return nil
+}
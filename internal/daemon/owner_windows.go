@@ -0,0 +1,22 @@
+package daemon
+
+import (
+	"io/fs"
+	"os/user"
+	"strings"
+)
+
+// userID names the current user in runtime directory names
+func userID() string {
+	current, err := user.Current()
+	if err != nil {
+		return "user"
+	}
+	return strings.NewReplacer(`\`, "-", "/", "-", ":", "-").Replace(current.Uid)
+}
+
+// checkOwner is not needed on Windows, where the temp directory belongs to
+// the user
+func checkOwner(info fs.FileInfo) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runtimeDir returns the directory of this user's daemon sockets and pid,
+// log and token files, creating it. Names in a shared directory such as
+// /tmp could be taken first by another user to redirect the writes or the
+// socket, so it is private to the user: in $XDG_RUNTIME_DIR when that is
+// set, otherwise in the temp directory.
+func runtimeDir() (string, error) {
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(xdg) {
+		dir := filepath.Join(xdg, "mcp-filesystem")
+		if err := privateDir(dir); err == nil {
+			return dir, nil
+		}
+	}
+	dir := filepath.Join(os.TempDir(), "mcp-filesystem-"+userID())
+	if err := privateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// privateDir creates a directory only the current user can use, or checks
+// that an existing one is such a directory
+func privateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create runtime directory: %v", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check runtime directory: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("runtime directory %s isn't a directory", dir)
+	}
+	if err := checkOwner(info); err != nil {
+		return fmt.Errorf("runtime directory %s %v", dir, err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("runtime directory %s can be used by other users (mode %04o)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// runtimeBase returns a per-workspace file name prefix in the runtime
+// directory
+func runtimeBase(workspacePath string) (string, error) {
+	dir, err := runtimeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(workspacePath))
+	return filepath.Join(dir, fmt.Sprintf("workspace-%x", sum[:6])), nil
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TokenEnv holds the token clients of a daemon on TCP authenticate with.
+// Without it, the daemon makes one up and keeps it in its token file for
+// clients on the same machine.
+const TokenEnv = "MCP_FILESYSTEM_TOKEN"
+
+// tokenTimeout bounds how long a TCP client may take to send its token
+const tokenTimeout = 5 * time.Second
+
+// maxTokenLine caps the first line a TCP client sends
+const maxTokenLine = 256
+
+// DefaultTokenFile returns where a workspace's daemon keeps the token of
+// its TCP listener
+func DefaultTokenFile(workspacePath string) (string, error) {
+	base, err := runtimeBase(workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return base + ".token", nil
+}
+
+// serverToken returns the token TCP clients must send: TokenEnv if set,
+// otherwise a new random one written to tokenFile
+func serverToken(tokenFile string) (string, error) {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token, nil
+	}
+	if tokenFile == "" {
+		return "", fmt.Errorf("a TCP listener needs a token: set %s", TokenEnv)
+	}
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to make a token: %v", err)
+	}
+	token := hex.EncodeToString(random)
+	if err := createFile(tokenFile, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write token file: %v", err)
+	}
+	return token, nil
+}
+
+// clientToken returns the token to send a TCP daemon: TokenEnv if set,
+// otherwise the one in the daemon's token file
+func clientToken(tokenFile string) (string, error) {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token, nil
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("no token for the daemon: set %s or run on the daemon's machine (%v)", TokenEnv, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// createFile writes a new file at path, replacing whatever is there rather
+// than writing through it, as a symlink would be
+func createFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|oNoFollow, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// sendToken authenticates a connection to a TCP daemon
+func sendToken(conn net.Conn, tokenFile string) error {
+	token, err := clientToken(tokenFile)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write([]byte(token + "\n")); err != nil {
+		return fmt.Errorf("failed to send token: %v", err)
+	}
+	return nil
+}
+
+// tokenListener accepts the connections whose first line is the token,
+// closing the others
+type tokenListener struct {
+	net.Listener
+	token  string
+	conns  chan net.Conn
+	closed chan struct{}
+	err    error // why accepting stopped, set before closed is closed
+}
+
+// requireToken wraps a listener so that only clients sending token are
+// accepted
+func requireToken(listener net.Listener, token string) net.Listener {
+	l := &tokenListener{Listener: listener, token: token, conns: make(chan net.Conn), closed: make(chan struct{})}
+	go l.acceptLoop()
+	return l
+}
+
+// acceptLoop authenticates each connection without holding up the others
+func (l *tokenListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			close(l.closed)
+			return
+		}
+		go l.authenticate(conn)
+	}
+}
+
+// authenticate hands a connection to Accept if it starts with the token
+func (l *tokenListener) authenticate(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(tokenTimeout))
+	line, err := readLine(conn)
+	if err != nil || subtle.ConstantTimeCompare([]byte(line), []byte(l.token)) != 1 {
+		log.Printf("Refused a connection from %s: missing or wrong token", conn.RemoteAddr())
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		_ = conn.Close()
+	}
+}
+
+// Accept implements net.Listener
+func (l *tokenListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, l.err
+	}
+}
+
+// readLine reads a line byte by byte, so nothing after it is consumed
+func readLine(conn net.Conn) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for len(line) < maxTokenLine {
+		if _, err := conn.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, buf[0])
+	}
+	return "", errors.New("line too long")
+}
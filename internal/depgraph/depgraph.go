@@ -0,0 +1,351 @@
+package depgraph
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Graph holds import edges between workspace files
+type Graph struct {
+	// Imports maps a file to the workspace files it imports
+	Imports map[string][]string
+	// ImportedBy maps a file to the workspace files that import it
+	ImportedBy map[string][]string
+}
+
+// Language identifiers for files with supported import syntax
+const (
+	LangGo     = "go"
+	LangJS     = "js"
+	LangPython = "python"
+)
+
+var (
+	// JS/TS import forms: import ... from "x", export ... from "x", import "x", require("x"), import("x")
+	jsFromPattern    = regexp.MustCompile(`(?m)^\s*(?:import|export)\s[^'"]*?\bfrom\s*['"]([^'"]+)['"]`)
+	jsBarePattern    = regexp.MustCompile(`(?m)^\s*import\s*['"]([^'"]+)['"]`)
+	jsRequirePattern = regexp.MustCompile(`\b(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+
+	// Python import forms: import a.b, c and from .x import y
+	pyImportPattern = regexp.MustCompile(`(?m)^\s*import\s+([\w.]+(?:\s+as\s+\w+)?(?:\s*,\s*[\w.]+(?:\s+as\s+\w+)?)*)`)
+	pyFromPattern   = regexp.MustCompile(`(?m)^\s*from\s+(\.*[\w.]*)\s+import\s+(?:\(([^)]*)\)|([\w \t,*]+))`)
+
+	// Extensions tried, in order, when resolving extensionless JS/TS specifiers
+	jsResolveExts = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".d.ts"}
+)
+
+// LanguageOf returns the language of a file based on its extension, or "" if unsupported
+func LanguageOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return LangGo
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		return LangJS
+	case ".py", ".pyi":
+		return LangPython
+	}
+	return ""
+}
+
+// ParseImports returns the raw import specifiers found in a file
+func ParseImports(path string, data []byte) []string {
+	switch LanguageOf(path) {
+	case LangGo:
+		return parseGoImports(path, data)
+	case LangJS:
+		return parseJSImports(data)
+	case LangPython:
+		return parsePythonImports(data)
+	}
+	return nil
+}
+
+// parseGoImports extracts import paths from Go source
+func parseGoImports(path string, data []byte) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), path, data, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		if value, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, value)
+		}
+	}
+	return imports
+}
+
+// parseJSImports extracts module specifiers from JavaScript and TypeScript source
+func parseJSImports(data []byte) []string {
+	var imports []string
+	for _, pattern := range []*regexp.Regexp{jsFromPattern, jsBarePattern, jsRequirePattern} {
+		for _, match := range pattern.FindAllSubmatch(data, -1) {
+			imports = append(imports, string(match[1]))
+		}
+	}
+	return imports
+}
+
+// parsePythonImports extracts module names from Python source
+// Relative imports keep their leading dots; "from . import x" yields ".x"
+func parsePythonImports(data []byte) []string {
+	var imports []string
+
+	for _, match := range pyImportPattern.FindAllSubmatch(data, -1) {
+		for _, part := range strings.Split(string(match[1]), ",") {
+			fields := strings.Fields(part)
+			if len(fields) > 0 {
+				imports = append(imports, fields[0])
+			}
+		}
+	}
+
+	for _, match := range pyFromPattern.FindAllSubmatch(data, -1) {
+		module := string(match[1])
+		if strings.Trim(module, ".") != "" {
+			imports = append(imports, module)
+			continue
+		}
+		// "from . import a, b" imports sibling modules
+		names := string(match[2]) + string(match[3])
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" && name != "*" {
+				imports = append(imports, module+strings.Fields(name)[0])
+			}
+		}
+	}
+
+	return imports
+}
+
+// Build parses every supported file and resolves imports to other files in the list
+func Build(workspacePath string, files []string) *Graph {
+	g := &Graph{
+		Imports:    make(map[string][]string),
+		ImportedBy: make(map[string][]string),
+	}
+
+	known := make(map[string]bool, len(files))
+	goDirs := make(map[string][]string)
+	for _, file := range files {
+		known[file] = true
+		if LanguageOf(file) == LangGo {
+			dir := filepath.Dir(file)
+			goDirs[dir] = append(goDirs[dir], file)
+		}
+	}
+
+	r := &resolver{
+		workspacePath: workspacePath,
+		known:         known,
+		goDirs:        goDirs,
+		goModules:     findGoModules(workspacePath, files),
+	}
+
+	for _, file := range files {
+		if LanguageOf(file) == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, spec := range ParseImports(file, data) {
+			for _, target := range r.resolve(file, spec) {
+				if target == file || seen[target] {
+					continue
+				}
+				seen[target] = true
+				g.Imports[file] = append(g.Imports[file], target)
+				g.ImportedBy[target] = append(g.ImportedBy[target], file)
+			}
+		}
+	}
+
+	for _, edges := range []map[string][]string{g.Imports, g.ImportedBy} {
+		for _, targets := range edges {
+			sort.Strings(targets)
+		}
+	}
+
+	return g
+}
+
+// Walk returns every file reachable from start by repeatedly following edges,
+// mapped to its distance from start. A maxDepth of 0 means unlimited.
+func Walk(edges map[string][]string, start string, maxDepth int) map[string]int {
+	distances := map[string]int{}
+	queue := []string{start}
+	depth := map[string]int{start: 0}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if maxDepth > 0 && depth[current] >= maxDepth {
+			continue
+		}
+
+		for _, next := range edges[current] {
+			if _, ok := depth[next]; ok {
+				continue
+			}
+			depth[next] = depth[current] + 1
+			distances[next] = depth[next]
+			queue = append(queue, next)
+		}
+	}
+
+	return distances
+}
+
+// goModule is a Go module rooted somewhere in the workspace
+type goModule struct {
+	dir  string
+	path string
+}
+
+// findGoModules reads the module path of every go.mod in the file list
+func findGoModules(workspacePath string, files []string) []goModule {
+	var modules []goModule
+
+	candidates := []string{filepath.Join(workspacePath, "go.mod")}
+	for _, file := range files {
+		if filepath.Base(file) == "go.mod" && file != candidates[0] {
+			candidates = append(candidates, file)
+		}
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		if modulePath := modfile.ModulePath(data); modulePath != "" {
+			modules = append(modules, goModule{dir: filepath.Dir(candidate), path: modulePath})
+		}
+	}
+
+	// Prefer the longest module path so nested modules win
+	sort.Slice(modules, func(i, j int) bool {
+		return len(modules[i].path) > len(modules[j].path)
+	})
+
+	return modules
+}
+
+// resolver maps import specifiers to workspace files
+type resolver struct {
+	workspacePath string
+	known         map[string]bool
+	goDirs        map[string][]string
+	goModules     []goModule
+}
+
+// resolve returns the workspace files an import specifier refers to
+func (r *resolver) resolve(from, spec string) []string {
+	switch LanguageOf(from) {
+	case LangGo:
+		return r.resolveGo(from, spec)
+	case LangJS:
+		return r.resolveJS(from, spec)
+	case LangPython:
+		return r.resolvePython(from, spec)
+	}
+	return nil
+}
+
+// resolveGo maps a Go import path to the files of the matching package directory
+func (r *resolver) resolveGo(from, spec string) []string {
+	for _, module := range r.goModules {
+		if spec != module.path && !strings.HasPrefix(spec, module.path+"/") {
+			continue
+		}
+
+		dir := filepath.Join(module.dir, filepath.FromSlash(strings.TrimPrefix(spec, module.path)))
+		var targets []string
+		for _, file := range r.goDirs[dir] {
+			// Test files are never imported by other packages
+			if !strings.HasSuffix(file, "_test.go") {
+				targets = append(targets, file)
+			}
+		}
+		return targets
+	}
+	return nil
+}
+
+// resolveJS maps a relative JS/TS specifier to a file, trying common extensions and index files
+func (r *resolver) resolveJS(from, spec string) []string {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") && !strings.HasPrefix(spec, "/") {
+		// Bare specifiers refer to packages outside the workspace
+		return nil
+	}
+
+	base := filepath.Join(filepath.Dir(from), filepath.FromSlash(spec))
+	if strings.HasPrefix(spec, "/") {
+		base = filepath.Join(r.workspacePath, filepath.FromSlash(spec))
+	}
+
+	candidates := []string{base}
+	// TypeScript sources are commonly imported with a .js extension
+	if ext := filepath.Ext(base); ext == ".js" || ext == ".jsx" {
+		stem := strings.TrimSuffix(base, ext)
+		candidates = append(candidates, stem+".ts", stem+".tsx")
+	}
+	for _, ext := range jsResolveExts {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range jsResolveExts {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+
+	for _, candidate := range candidates {
+		if r.known[candidate] {
+			return []string{candidate}
+		}
+	}
+	return nil
+}
+
+// resolvePython maps a dotted module name to a .py file or package __init__.py
+func (r *resolver) resolvePython(from, spec string) []string {
+	var bases []string
+
+	trimmed := strings.TrimLeft(spec, ".")
+	if dots := len(spec) - len(trimmed); dots > 0 {
+		// Each leading dot beyond the first walks up one package
+		dir := filepath.Dir(from)
+		for i := 1; i < dots; i++ {
+			dir = filepath.Dir(dir)
+		}
+		bases = append(bases, dir)
+	} else {
+		// Absolute imports resolve from the workspace root or a src/ layout
+		bases = append(bases, r.workspacePath, filepath.Join(r.workspacePath, "src"))
+	}
+
+	rel := filepath.FromSlash(strings.ReplaceAll(trimmed, ".", "/"))
+	for _, base := range bases {
+		modulePath := filepath.Join(base, rel)
+		for _, candidate := range []string{modulePath + ".py", modulePath + ".pyi", filepath.Join(modulePath, "__init__.py")} {
+			if r.known[candidate] {
+				return []string{candidate}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package diagnostics
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// Categories of recorded conditions
+const (
+	// SpecialFile is a FIFO, socket, device or other non-regular file that is never served
+	SpecialFile = "specialFiles"
+	// PermissionDenied is a path the server couldn't read or watch
+	PermissionDenied = "permissionDenied"
+)
+
+// Report is a snapshot of all recorded conditions
+type Report struct {
+	Counts  map[string]int     `json:"counts"`
+	Entries map[string][]Entry `json:"entries"`
+}
+
+// Entry is a single recorded condition for a path
+type Entry struct {
+	Path   string `json:"path"`
+	Detail string `json:"detail"`
+}
+
+// Diagnostics collects conditions found while scanning and serving the
+// workspace that would otherwise only be visible in debug logs
+type Diagnostics struct {
+	workspacePath string
+	entries       map[string]map[string]string
+	mu            sync.RWMutex
+}
+
+// New creates an empty diagnostics collector for a workspace
+func New(workspacePath string) *Diagnostics {
+	return &Diagnostics{
+		workspacePath: workspacePath,
+		entries:       make(map[string]map[string]string),
+	}
+}
+
+// Add records a condition for a path, replacing any previous detail
+func (d *Diagnostics) Add(category, path, detail string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.entries[category] == nil {
+		d.entries[category] = make(map[string]string)
+	}
+	d.entries[category][d.relPath(path)] = detail
+}
+
+// Remove clears a recorded condition for a path
+func (d *Diagnostics) Remove(category, path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.entries[category], d.relPath(path))
+}
+
+// Count returns the number of paths recorded in a category
+func (d *Diagnostics) Count(category string) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.entries[category])
+}
+
+// Report returns all recorded conditions grouped by category and sorted by path
+func (d *Diagnostics) Report() Report {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	report := Report{
+		Counts:  make(map[string]int, len(d.entries)),
+		Entries: make(map[string][]Entry, len(d.entries)),
+	}
+	for category, paths := range d.entries {
+		if len(paths) == 0 {
+			continue
+		}
+		entries := make([]Entry, 0, len(paths))
+		for path, detail := range paths {
+			entries = append(entries, Entry{Path: path, Detail: detail})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Path < entries[j].Path
+		})
+		report.Counts[category] = len(entries)
+		report.Entries[category] = entries
+	}
+	return report
+}
+
+// relPath returns a workspace-relative path for display
+func (d *Diagnostics) relPath(path string) string {
+	relPath, err := filepath.Rel(d.workspacePath, path)
+	if err != nil {
+		return path
+	}
+	return pathnorm.NFC(filepath.ToSlash(relPath))
+}
+
+// SpecialFileType describes files that must not be served as resources,
+// returning "" for regular files and symlinks to regular files. Stat
+// follows symlinks on the filesystem the file is on.
+func SpecialFileType(path string, info os.FileInfo, stat func(string) (os.FileInfo, error)) string {
+	mode := info.Mode()
+
+	if mode&os.ModeSymlink != 0 {
+		target, err := stat(path)
+		if err != nil {
+			return "broken symlink"
+		}
+		if target.IsDir() {
+			return "symlink to directory"
+		}
+		if special := SpecialFileType(path, target, stat); special != "" {
+			return "symlink to " + special
+		}
+		return ""
+	}
+
+	switch {
+	case mode.IsRegular():
+		return ""
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeCharDevice != 0:
+		return "character device"
+	case mode&os.ModeDevice != 0:
+		return "block device"
+	}
+	return "irregular file"
+}
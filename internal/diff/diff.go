@@ -0,0 +1,245 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a line-level edit
+type Op int
+
+// Edit operations
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Line is a single line of an edit script
+type Line struct {
+	Op   Op
+	Text string
+	// OldLine and NewLine are 1-based line numbers; 0 when the line is absent on that side
+	OldLine int
+	NewLine int
+}
+
+// SplitLines splits text into lines, keeping line endings so that joining
+// the result reproduces the input exactly
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes a minimal line edit script turning a into b (Myers' algorithm)
+func Lines(a, b []string) []Line {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// v[k+offset] holds the furthest x reached on diagonal k; trace keeps a copy per step
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script
+	var script []Line
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			script = append(script, Line{Op: Equal, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				script = append(script, Line{Op: Insert, Text: b[y-1], NewLine: y})
+			} else {
+				script = append(script, Line{Op: Delete, Text: a[x-1], OldLine: x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// Reverse into forward order
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
+
+// Hunk is a group of nearby changes with surrounding context
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Hunks groups an edit script into hunks with the given number of context lines
+func Hunks(script []Line, context int) []Hunk {
+	var hunks []Hunk
+
+	i := 0
+	for i < len(script) {
+		// Find the next change
+		for i < len(script) && script[i].Op == Equal {
+			i++
+		}
+		if i >= len(script) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		// Extend while changes are within 2*context lines of each other
+		for end < len(script) {
+			if script[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(script) && script[run].Op == Equal {
+				run++
+			}
+			if run < len(script) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			end += context
+			if end > len(script) {
+				end = len(script)
+			}
+			break
+		}
+
+		hunk := Hunk{Lines: script[start:end]}
+		for _, line := range hunk.Lines {
+			if line.Op != Insert {
+				if hunk.OldStart == 0 {
+					hunk.OldStart = line.OldLine
+				}
+				hunk.OldLines++
+			}
+			if line.Op != Delete {
+				if hunk.NewStart == 0 {
+					hunk.NewStart = line.NewLine
+				}
+				hunk.NewLines++
+			}
+		}
+		// Empty sides point at the line before the hunk, as in diff -u
+		if hunk.OldLines == 0 {
+			hunk.OldStart = previousLine(script, start, true)
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart = previousLine(script, start, false)
+		}
+
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
+
+// previousLine returns the last old or new line number before index i
+func previousLine(script []Line, i int, old bool) int {
+	for j := i - 1; j >= 0; j-- {
+		if old && script[j].OldLine > 0 {
+			return script[j].OldLine
+		}
+		if !old && script[j].NewLine > 0 {
+			return script[j].NewLine
+		}
+	}
+	return 0
+}
+
+// Unified returns a unified diff between two texts, or "" if they are equal
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	hunks := Hunks(Lines(SplitLines(oldText), SplitLines(newText)), 3)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			prefix := " "
+			switch line.Op {
+			case Insert:
+				prefix = "+"
+			case Delete:
+				prefix = "-"
+			}
+			sb.WriteString(prefix)
+			sb.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange formats a hunk range header component
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
@@ -0,0 +1,218 @@
+package dockerfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultHost is the Docker daemon's socket when DOCKER_HOST isn't set
+const defaultHost = "unix:///var/run/docker.sock"
+
+// apiClient talks to the Docker Engine API
+type apiClient struct {
+	http *http.Client
+	base string // scheme and host requests are sent to
+}
+
+// newAPIClient connects to the daemon named by DOCKER_HOST
+func newAPIClient() (*apiClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultHost
+	}
+	if os.Getenv("DOCKER_TLS_VERIFY") != "" {
+		return nil, fmt.Errorf("TLS connections to the Docker daemon are not supported")
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %v", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &apiClient{http: &http.Client{Transport: transport}, base: "http://docker"}, nil
+	case "tcp", "http":
+		return &apiClient{http: &http.Client{}, base: "http://" + u.Host}, nil
+	}
+	return nil, fmt.Errorf("unsupported DOCKER_HOST %q: expected unix:// or tcp://", host)
+}
+
+// do sends a request and returns the response of a successful one
+func (c *apiClient) do(method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to reach the Docker daemon: %v", err)
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, responseError(resp)
+}
+
+// cancelOnClose releases a request's context with its body
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// apiError is an error response from the daemon
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// Unwrap maps missing paths and refused access to the io/fs errors
+func (e *apiError) Unwrap() error {
+	switch e.status {
+	case http.StatusNotFound:
+		return fs.ErrNotExist
+	case http.StatusForbidden:
+		return fs.ErrPermission
+	}
+	return nil
+}
+
+// responseError converts a failed response to an error
+func responseError(resp *http.Response) error {
+	err := &apiError{status: resp.StatusCode, message: resp.Status}
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		err.message = body.Message
+	}
+	return err
+}
+
+// containerInfo is the part of a container's inspection used here
+type containerInfo struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+	Config struct {
+		WorkingDir string `json:"WorkingDir"`
+	} `json:"Config"`
+}
+
+// inspect describes a container by name or ID
+func (c *apiClient) inspect(container string) (*containerInfo, error) {
+	resp, err := c.do(http.MethodGet, "/containers/"+url.PathEscape(container)+"/json", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var info containerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse container details: %v", err)
+	}
+	return &info, nil
+}
+
+// exec runs a command in a container and returns an error with its
+// stderr if it fails
+func (c *apiClient) exec(container string, cmd ...string) error {
+	create, _ := json.Marshal(map[string]any{"Cmd": cmd, "AttachStdout": true, "AttachStderr": true})
+	resp, err := c.do(http.MethodPost, "/containers/"+container+"/exec", nil, "application/json", bytes.NewReader(create))
+	if err != nil {
+		return err
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to parse exec response: %v", err)
+	}
+
+	start, _ := json.Marshal(map[string]any{"Detach": false, "Tty": false})
+	resp, err = c.do(http.MethodPost, "/exec/"+created.ID+"/start", nil, "application/json", bytes.NewReader(start))
+	if err != nil {
+		return err
+	}
+	stderr := demuxStderr(resp.Body)
+	resp.Body.Close()
+
+	resp, err = c.do(http.MethodGet, "/exec/"+created.ID+"/json", nil, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		ExitCode int `json:"ExitCode"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to parse exec result: %v", err)
+	}
+	if result.ExitCode != 0 {
+		if message := strings.TrimSpace(stderr); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+		return fmt.Errorf("%s exited with status %d", cmd[0], result.ExitCode)
+	}
+	return nil
+}
+
+// demuxStderr reads an attached exec stream, where each frame starts with
+// its stream number and length, and returns what was written to stderr
+func demuxStderr(r io.Reader) string {
+	var stderr strings.Builder
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return stderr.String()
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if header[0] == 2 && stderr.Len() < 64<<10 {
+			io.CopyN(&stderr, r, size)
+		} else {
+			io.CopyN(io.Discard, r, size)
+		}
+	}
+}
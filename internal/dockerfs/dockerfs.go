@@ -0,0 +1,522 @@
+// Package dockerfs serves a directory inside a running Docker container
+// through the Docker Engine API, so files in a container can be inspected
+// without copying them out. It needs nothing installed in the container
+// to read; writes, which must be enabled, use rm and mv in the container
+// to remove and rename files.
+//
+// The API has no directory listing, so listing a directory fetches an
+// archive of it. The entries of the whole tree below it are kept for a
+// moment, so walking the workspace transfers it once.
+package dockerfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+)
+
+// treeTTL is how long a fetched tree answers Stat and ReadDir
+const treeTTL = time.Second
+
+// IsURL reports whether a workspace argument names a container
+func IsURL(workspace string) bool {
+	return strings.HasPrefix(workspace, "docker://")
+}
+
+// Parse parses docker://container[/path], returning the container name or
+// ID and the absolute path in it; the path is empty when not given
+func Parse(workspace string) (string, string, error) {
+	u, err := url.Parse(workspace)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid workspace URL: %v", err)
+	}
+	if u.Scheme != "docker" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid workspace URL %q: expected docker://container/path", workspace)
+	}
+	dir := u.Path
+	if dir != "" {
+		dir = path.Clean(dir)
+	}
+	return u.Host, dir, nil
+}
+
+// FS is a directory in a running container
+type FS struct {
+	api       *apiClient
+	container string // container ID
+	root      string // absolute path in the container
+
+	tree *tree // the last fetched tree, if any
+	mu   sync.Mutex
+}
+
+var _ fsys.WriteFS = (*FS)(nil)
+var _ fsys.LstatFS = (*FS)(nil)
+
+// tree is the listing of a directory and everything below it
+type tree struct {
+	dir     string                 // name of the listed directory
+	infos   map[string]fs.FileInfo // by name, including dir itself
+	fetched time.Time
+}
+
+// Dial opens a directory in a running container; without a path the
+// container's working directory is served
+func Dial(container, dir string) (*FS, error) {
+	api, err := newAPIClient()
+	if err != nil {
+		return nil, err
+	}
+	info, err := api.inspect(container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find container %s: %v", container, err)
+	}
+	if !info.State.Running {
+		return nil, fmt.Errorf("container %s is not running", container)
+	}
+	if dir == "" {
+		dir = info.Config.WorkingDir
+	}
+	if dir == "" {
+		dir = "/"
+	}
+
+	f := &FS{api: api, container: info.ID, root: dir}
+	stat, err := f.Stat(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to access %s in container %s: %v", dir, container, err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("%s in container %s is not a directory", dir, container)
+	}
+	return f, nil
+}
+
+// Mount opens a docker:// workspace URL, read-only unless writable is
+// set. The workspace path is the path in the container.
+func Mount(workspace string, writable bool) (*fsys.Workspace, error) {
+	container, dir, err := Parse(workspace)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Dial(container, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !writable {
+		return fsys.Mount(f.root, readOnly{f}), nil
+	}
+	return fsys.Mount(f.root, f), nil
+}
+
+// readOnly serves an FS without its write methods
+type readOnly struct {
+	fs *FS
+}
+
+var _ fsys.LstatFS = readOnly{}
+
+func (r readOnly) Open(name string) (fs.File, error)          { return r.fs.Open(name) }
+func (r readOnly) Stat(name string) (fs.FileInfo, error)      { return r.fs.Stat(name) }
+func (r readOnly) Lstat(name string) (fs.FileInfo, error)     { return r.fs.Lstat(name) }
+func (r readOnly) ReadFile(name string) ([]byte, error)       { return r.fs.ReadFile(name) }
+func (r readOnly) ReadDir(name string) ([]fs.DirEntry, error) { return r.fs.ReadDir(name) }
+
+// containerPath returns the path of a name in the container
+func (f *FS) containerPath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(f.root, name), nil
+}
+
+// cached returns what a fresh tree knows about a name: its info, or
+// whether it's known not to exist
+func (f *FS) cached(name string) (fs.FileInfo, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.tree
+	if t == nil || time.Since(t.fetched) > treeTTL || !within(name, t.dir) {
+		return nil, false
+	}
+	return t.infos[name], true
+}
+
+// within reports whether name is dir or below it
+func within(name, dir string) bool {
+	return dir == "." || name == dir || strings.HasPrefix(name, dir+"/")
+}
+
+// invalidate drops the cached tree after a change
+func (f *FS) invalidate() {
+	f.mu.Lock()
+	f.tree = nil
+	f.mu.Unlock()
+}
+
+// archive requests a tar archive of a path in the container
+func (f *FS) archive(method, p string) (*http.Response, error) {
+	return f.api.do(method, "/containers/"+f.container+"/archive", url.Values{"path": {p}}, "", nil)
+}
+
+// pathStat is the description of a path the archive endpoint returns in
+// a header
+type pathStat struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	Mode       fs.FileMode `json:"mode"`
+	Mtime      time.Time   `json:"mtime"`
+	LinkTarget string      `json:"linkTarget"`
+}
+
+// Stat implements fs.StatFS
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := f.resolve(name)
+	return info, err
+}
+
+// resolve follows a final symbolic link, returning the container path of
+// what name refers to and its description. The archive endpoint serves
+// links themselves, so files are read from the resolved path.
+func (f *FS) resolve(name string) (string, fs.FileInfo, error) {
+	p, err := f.containerPath("stat", name)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := f.Lstat(name)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return p, info, err
+	}
+	target := info.(fileInfo).linkTarget
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(p), target)
+	}
+	resp, err := f.archive(http.MethodHead, target)
+	if err != nil {
+		return "", nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	resolved, err := decodeStat(resp)
+	if err != nil {
+		return "", nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	resolved.name = path.Base(name)
+	return target, resolved, nil
+}
+
+// Lstat implements fsys.LstatFS
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	p, err := f.containerPath("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	if info, ok := f.cached(name); ok {
+		if info == nil {
+			return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+		}
+		return info, nil
+	}
+	resp, err := f.archive(http.MethodHead, p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	resp.Body.Close()
+	info, err := decodeStat(resp)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	info.name = path.Base(name)
+	return info, nil
+}
+
+// decodeStat reads the path description header of an archive response
+func decodeStat(resp *http.Response) (fileInfo, error) {
+	header := resp.Header.Get("X-Docker-Container-Path-Stat")
+	data, err := base64.StdEncoding.DecodeString(header)
+	if err != nil || header == "" {
+		return fileInfo{}, fmt.Errorf("missing path description in archive response")
+	}
+	var stat pathStat
+	if err := json.Unmarshal(data, &stat); err != nil {
+		return fileInfo{}, fmt.Errorf("failed to parse path description: %v", err)
+	}
+	return fileInfo{name: stat.Name, size: stat.Size, mode: stat.Mode, modTime: stat.Mtime, linkTarget: stat.LinkTarget}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. Entries describe symbolic links rather
+// than their targets, as os.ReadDir does.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if _, err := f.containerPath("readdir", name); err != nil {
+		return nil, err
+	}
+	t, err := f.fetchTree(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	info, ok := t.infos[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var entries []fs.DirEntry
+	for child, info := range t.infos {
+		if child != name && path.Dir(child) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// fetchTree returns a fresh tree containing the directory name, fetching
+// an archive of it if needed. Only headers are kept; file contents are
+// skipped as the archive streams by.
+func (f *FS) fetchTree(name string) (*tree, error) {
+	f.mu.Lock()
+	if t := f.tree; t != nil && time.Since(t.fetched) <= treeTTL && within(name, t.dir) {
+		f.mu.Unlock()
+		return t, nil
+	}
+	f.mu.Unlock()
+
+	p, _ := f.containerPath("readdir", name)
+	resp, err := f.archive(http.MethodGet, p)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	t := &tree{dir: name, infos: make(map[string]fs.FileInfo), fetched: time.Now()}
+	reader := tar.NewReader(resp.Body)
+	base := ""
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %v", err)
+		}
+		// Entries are named below the base name of the archived path, or
+		// "." for the container's root
+		entry := strings.Trim(path.Clean(header.Name), "/")
+		if base == "" {
+			base, _, _ = strings.Cut(entry, "/")
+		}
+		rel := entry
+		if base != "." {
+			var ok bool
+			if rel, ok = strings.CutPrefix(entry, base); !ok || rel != "" && rel[0] != '/' {
+				continue
+			}
+		}
+		child := path.Join(name, strings.TrimPrefix(rel, "/"))
+		info := header.FileInfo()
+		t.infos[child] = fileInfo{name: path.Base(child), size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), linkTarget: header.Linkname}
+	}
+
+	f.mu.Lock()
+	f.tree = t
+	f.mu.Unlock()
+	return t, nil
+}
+
+// Open implements fs.FS
+func (f *FS) Open(name string) (fs.File, error) {
+	p, info, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := f.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return fsys.DirFile(info, entries), nil
+	}
+	resp, err := f.archive(http.MethodGet, p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	reader := tar.NewReader(resp.Body)
+	if _, err := reader.Next(); err != nil {
+		resp.Body.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fmt.Errorf("failed to read archive: %v", err)}
+	}
+	return &file{info: info, Reader: reader, body: resp.Body}, nil
+}
+
+// ReadFile implements fs.ReadFileFS
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, _ := file.Stat()
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, info.Size()))
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return buf.Bytes(), nil
+}
+
+// extract unpacks a tar archive built by fill into a directory of the
+// container
+func (f *FS) extract(dir string, fill func(*tar.Writer) error) error {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	if err := fill(writer); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	resp, err := f.api.do(http.MethodPut, "/containers/"+f.container+"/archive", url.Values{"path": {dir}}, "application/x-tar", &buf)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// WriteFile implements fsys.WriteFS; an existing file keeps its permissions
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	p, err := f.containerPath("write", name)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(name); err == nil {
+		if info.IsDir() {
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+		}
+		perm = info.Mode().Perm()
+	}
+	defer f.invalidate()
+	err = f.extract(path.Dir(p), func(w *tar.Writer) error {
+		header := &tar.Header{Name: path.Base(p), Mode: int64(perm.Perm()), Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll implements fsys.WriteFS
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if _, err := f.containerPath("mkdir", name); err != nil {
+		return err
+	}
+	if info, err := f.Stat(name); err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	defer f.invalidate()
+	err := f.extract(f.root, func(w *tar.Writer) error {
+		dir := ""
+		for _, part := range strings.Split(name, "/") {
+			dir = path.Join(dir, part)
+			header := &tar.Header{Name: dir + "/", Mode: int64(perm.Perm()), ModTime: time.Now(), Typeflag: tar.TypeDir}
+			if err := w.WriteHeader(header); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Remove implements fsys.WriteFS by running rm or rmdir in the container
+func (f *FS) Remove(name string) error {
+	p, err := f.containerPath("remove", name)
+	if err != nil {
+		return err
+	}
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	command := "rm"
+	if info.IsDir() {
+		command = "rmdir"
+	}
+	defer f.invalidate()
+	if err := f.api.exec(f.container, command, "--", p); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename implements fsys.WriteFS by running mv in the container
+func (f *FS) Rename(oldname, newname string) error {
+	oldpath, err := f.containerPath("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newpath, err := f.containerPath("rename", newname)
+	if err != nil {
+		return err
+	}
+	defer f.invalidate()
+	if err := f.api.exec(f.container, "mv", "-f", "--", oldpath, newpath); err != nil {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: err}
+	}
+	return nil
+}
+
+// fileInfo describes a path in the container
+type fileInfo struct {
+	name       string
+	size       int64
+	mode       fs.FileMode
+	modTime    time.Time
+	linkTarget string
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() any           { return nil }
+
+// file is a file being read from its archive
+type file struct {
+	*tar.Reader
+	info fs.FileInfo
+	body io.Closer
+}
+
+// Stat implements fs.File
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Close implements fs.File
+func (f *file) Close() error { return f.body.Close() }
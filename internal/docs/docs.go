@@ -0,0 +1,192 @@
+// Package docs reads the metadata of Markdown documents: their front
+// matter, headings, links and an excerpt of their text, for querying and
+// maintaining notes and documentation workspaces.
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// maxExcerptBytes caps the excerpt kept for each document
+const maxExcerptBytes = 240
+
+// Document is the metadata of a Markdown document
+type Document struct {
+	// Title is the front matter title, or the text of the first level 1
+	// heading
+	Title string `json:"title,omitempty"`
+	// Fields holds the front matter with keys lower-cased and every value
+	// as strings: lists give one string per item and nested tables are
+	// flattened to keys like "author.name"
+	Fields   map[string][]string `json:"fields,omitempty"`
+	Headings []Heading           `json:"headings,omitempty"`
+	// Excerpt is the start of the first paragraph after the front matter
+	Excerpt string `json:"excerpt,omitempty"`
+	Links   []Link `json:"links,omitempty"`
+}
+
+// Heading is an ATX heading such as "## Design"
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	Line  int    `json:"line"`
+}
+
+// Supported reports whether a file is a Markdown document
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdx":
+		return true
+	}
+	return false
+}
+
+// headingPattern matches an ATX heading, capturing its markers and text
+var headingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+
+// Parse reads the metadata of a Markdown document. Front matter that
+// doesn't parse is left out rather than failing the document.
+func Parse(data []byte) *Document {
+	doc := &Document{}
+	body, startLine := doc.parseFrontMatter(data)
+
+	lines := strings.Split(string(body), "\n")
+	var paragraph []string
+	fence := ""
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		doc.Links = append(doc.Links, parseLinks(line, startLine+i)...)
+		if match := headingPattern.FindStringSubmatch(line); match != nil {
+			heading := Heading{Level: len(match[1]), Text: strings.TrimSpace(match[2]), Line: startLine + i}
+			doc.Headings = append(doc.Headings, heading)
+			if doc.Title == "" && heading.Level == 1 {
+				doc.Title = heading.Text
+			}
+			continue
+		}
+		if doc.Excerpt != "" {
+			continue
+		}
+		switch {
+		case trimmed == "" && len(paragraph) > 0:
+			doc.Excerpt = excerpt(paragraph)
+		case trimmed != "" && !strings.HasPrefix(trimmed, "<!--"):
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	if doc.Excerpt == "" && len(paragraph) > 0 {
+		doc.Excerpt = excerpt(paragraph)
+	}
+	return doc
+}
+
+// parseFrontMatter reads YAML front matter between "---" lines or TOML
+// between "+++" lines, returning the rest of the document and the line it
+// starts on
+func (doc *Document) parseFrontMatter(data []byte) ([]byte, int) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	var delimiter string
+	switch {
+	case bytes.HasPrefix(data, []byte("---\n")), bytes.HasPrefix(data, []byte("---\r\n")):
+		delimiter = "---"
+	case bytes.HasPrefix(data, []byte("+++\n")), bytes.HasPrefix(data, []byte("+++\r\n")):
+		delimiter = "+++"
+	default:
+		return data, 1
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimRight(lines[i], "\r\n")) == delimiter {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return data, 1
+	}
+	raw := bytes.Join(lines[1:end], nil)
+	body := bytes.Join(lines[end+1:], nil)
+
+	values := map[string]any{}
+	var err error
+	if delimiter == "---" {
+		err = yaml.Unmarshal(raw, &values)
+	} else {
+		_, err = toml.Decode(string(raw), &values)
+	}
+	if err == nil && len(values) > 0 {
+		doc.Fields = make(map[string][]string)
+		flatten(doc.Fields, "", values)
+		if titles := doc.Fields["title"]; len(titles) > 0 {
+			doc.Title = titles[0]
+		}
+	}
+	return body, end + 2
+}
+
+// flatten adds front matter values to fields as strings
+func flatten(fields map[string][]string, key string, value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := strings.ToLower(k)
+			if key != "" {
+				name = key + "." + name
+			}
+			flatten(fields, name, value[k])
+		}
+	case []any:
+		for _, item := range value {
+			flatten(fields, key, item)
+		}
+	case []map[string]any:
+		for _, item := range value {
+			flatten(fields, key, item)
+		}
+	case nil:
+	case time.Time:
+		if value.Equal(value.Truncate(24*time.Hour)) && value.Location() == time.UTC {
+			fields[key] = append(fields[key], value.Format("2006-01-02"))
+		} else {
+			fields[key] = append(fields[key], value.Format(time.RFC3339))
+		}
+	default:
+		fields[key] = append(fields[key], fmt.Sprint(value))
+	}
+}
+
+// excerpt joins the lines of a paragraph, cut to maxExcerptBytes
+func excerpt(lines []string) string {
+	text := strings.Join(lines, " ")
+	if len(text) > maxExcerptBytes {
+		text = strings.ToValidUTF8(text[:maxExcerptBytes], "") + "..."
+	}
+	return text
+}
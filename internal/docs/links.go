@@ -0,0 +1,113 @@
+package docs
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Link is a link or image in a Markdown document
+type Link struct {
+	// Target is the destination as written, such as "../api.md#errors",
+	// or the page name of a wiki link
+	Target string `json:"target"`
+	Line   int    `json:"line"`
+	Image  bool   `json:"image,omitempty"`
+	// Wiki is set for [[Page]] links, which name a document anywhere in
+	// the workspace by its file name
+	Wiki bool `json:"wiki,omitempty"`
+}
+
+var (
+	// codeSpanPattern matches inline code, whose brackets aren't links
+	codeSpanPattern = regexp.MustCompile("`+[^`]*`+")
+	// inlineLinkPattern matches [text](target "title") and images,
+	// capturing the "!", the target in or out of angle brackets
+	inlineLinkPattern = regexp.MustCompile(`(!?)\[(?:[^\[\]]|\[[^\]]*\])*\]\(\s*(?:<([^>]*)>|([^()\s]*(?:\([^()\s]*\)[^()\s]*)*))(?:\s+(?:"[^"]*"|'[^']*'|\([^)]*\)))?\s*\)`)
+	// referencePattern matches a reference definition such as "[id]: target"
+	referencePattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*(?:<([^>]*)>|(\S+))`)
+	// wikiLinkPattern matches [[Page]], [[Page#Heading]] and [[Page|text]]
+	wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|]+?)\s*(?:\|[^\[\]]*)?\]\]`)
+)
+
+// parseLinks returns the links on a line of a document
+func parseLinks(line string, number int) []Link {
+	if !strings.Contains(line, "[") {
+		return nil
+	}
+	line = codeSpanPattern.ReplaceAllStringFunc(line, func(code string) string {
+		return strings.Repeat(" ", len(code))
+	})
+
+	var links []Link
+	if match := referencePattern.FindStringSubmatch(line); match != nil {
+		return []Link{{Target: match[1] + match[2], Line: number}}
+	}
+	for _, match := range wikiLinkPattern.FindAllStringSubmatch(line, -1) {
+		links = append(links, Link{Target: strings.TrimSpace(match[2]), Line: number, Image: match[1] == "!", Wiki: true})
+	}
+	line = wikiLinkPattern.ReplaceAllString(line, "")
+	for _, match := range inlineLinkPattern.FindAllStringSubmatch(line, -1) {
+		links = append(links, Link{Target: match[2] + match[3], Line: number, Image: match[1] == "!"})
+	}
+	return links
+}
+
+// schemePattern matches the scheme of an absolute URL such as "https:"
+var schemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// External reports whether a link leaves the workspace, such as a web URL
+// or a mailto: link
+func (l Link) External() bool {
+	return !l.Wiki && (schemePattern.MatchString(l.Target) || strings.HasPrefix(l.Target, "//"))
+}
+
+// Split returns the file part of a link's target, percent-decoded, and its
+// fragment. The file part is "" for links within the same document.
+func (l Link) Split() (file, fragment string, err error) {
+	file, fragment, _ = strings.Cut(l.Target, "#")
+	if l.Wiki {
+		return strings.TrimSpace(file), strings.TrimSpace(fragment), nil
+	}
+	file, _, _ = strings.Cut(file, "?")
+	decoded, err := url.PathUnescape(file)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid link target %q: %v", l.Target, err)
+	}
+	return decoded, fragment, nil
+}
+
+// Anchors returns the fragments that link to the document's headings, as
+// GitHub generates them: "## Error Codes" twice gives "error-codes" and
+// "error-codes-1"
+func (doc *Document) Anchors() map[string]bool {
+	anchors := make(map[string]bool, len(doc.Headings))
+	seen := make(map[string]int)
+	for _, heading := range doc.Headings {
+		slug := Slug(heading.Text)
+		anchor := slug
+		if n := seen[slug]; n > 0 {
+			anchor = fmt.Sprintf("%s-%d", slug, n)
+		}
+		seen[slug]++
+		anchors[anchor] = true
+	}
+	return anchors
+}
+
+// Slug turns heading text into the fragment GitHub links it with: lower
+// case, with spaces as hyphens and other punctuation dropped
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,354 @@
+package docs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed document filter such as
+// "tag:design AND modified:>2024-01-01". Terms are joined with AND (also
+// implied between adjacent terms), OR and NOT, and grouped with
+// parentheses. A term is a bare word, matched in titles, headings and
+// excerpts, or field:value with an optional comparison (>, >=, <, <=) before
+// the value. The fields are:
+//
+//   - modified: the file's modification time
+//   - title: text in the title
+//   - heading: text in any heading
+//   - tag: an item of the tags (or tag) front matter field
+//   - has: the name of a front matter field the document sets
+//   - any other name: a front matter field, such as status:draft or
+//     author.name:ada
+//
+// Values compare as dates when both sides are dates, as numbers when both
+// are numbers and as case-insensitive text otherwise.
+type Query struct {
+	root node
+}
+
+// Target is a document a query is matched against
+type Target struct {
+	Doc      *Document
+	Modified time.Time
+}
+
+// node is a part of a parsed query
+type node interface {
+	match(t Target) bool
+}
+
+type andNode []node
+type orNode []node
+type notNode struct{ node }
+
+// termNode is a bare word or a field:value term
+type termNode struct {
+	field string // "" for a bare word
+	op    string // "=", ">", ">=", "<" or "<="
+	value string
+}
+
+func (n andNode) match(t Target) bool {
+	for _, child := range n {
+		if !child.match(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) match(t Target) bool {
+	for _, child := range n {
+		if child.match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) match(t Target) bool {
+	return !n.node.match(t)
+}
+
+// ParseQuery parses a document filter
+func ParseQuery(text string) (*Query, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in query", p.tokens[p.pos])
+	}
+	return &Query{root: root}, nil
+}
+
+// Match reports whether a document matches the query
+func (q *Query) Match(t Target) bool {
+	return q.root.match(t)
+}
+
+// tokenize splits a query into parentheses and words; double quotes keep
+// spaces and parentheses in a word and are removed
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+			i++
+		default:
+			var word strings.Builder
+			quoted := false
+			for ; i < len(runes); i++ {
+				r := runes[i]
+				if r == '"' {
+					quoted = !quoted
+					continue
+				}
+				if !quoted && (unicode.IsSpace(r) || r == '(' || r == ')') {
+					break
+				}
+				word.WriteRune(r)
+			}
+			if quoted {
+				return nil, fmt.Errorf("unterminated quote in query")
+			}
+			tokens = append(tokens, word.String())
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over query tokens
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or "" at the end
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) or() (node, error) {
+	var children orNode
+	for {
+		child, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		if p.peek() != "OR" {
+			break
+		}
+		p.pos++
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return children, nil
+}
+
+func (p *parser) and() (node, error) {
+	var children andNode
+	for {
+		if p.peek() == "AND" {
+			if len(children) == 0 {
+				return nil, fmt.Errorf("AND needs a term before it")
+			}
+			p.pos++
+		}
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+		if next := p.peek(); next == "" || next == ")" || next == "OR" {
+			break
+		}
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return children, nil
+}
+
+func (p *parser) unary() (node, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("query ends where a term was expected")
+	case token == "NOT":
+		p.pos++
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	case strings.HasPrefix(token, "-") && len(token) > 1:
+		p.tokens[p.pos] = token[1:]
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child}, nil
+	case token == "(":
+		p.pos++
+		child, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in query")
+		}
+		p.pos++
+		return child, nil
+	case token == ")" || token == "AND" || token == "OR":
+		return nil, fmt.Errorf("unexpected %q in query", token)
+	}
+	p.pos++
+	return parseTerm(token)
+}
+
+// parseTerm parses a bare word or field:value term
+func parseTerm(token string) (node, error) {
+	field, value, ok := strings.Cut(token, ":")
+	if !ok {
+		return termNode{op: "=", value: strings.ToLower(token)}, nil
+	}
+	term := termNode{field: strings.ToLower(field), op: "="}
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, op); ok {
+			term.op, value = op, rest
+			break
+		}
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%s: needs a value", field)
+	}
+	term.value = value
+	if term.field == "modified" {
+		if _, ok := parseDate(value); !ok {
+			return nil, fmt.Errorf("modified: needs a date such as 2024-01-31, got %q", value)
+		}
+	}
+	return term, nil
+}
+
+func (n termNode) match(t Target) bool {
+	doc := t.Doc
+	switch n.field {
+	case "":
+		if contains(doc.Title, n.value) || contains(doc.Excerpt, n.value) {
+			return true
+		}
+		for _, heading := range doc.Headings {
+			if contains(heading.Text, n.value) {
+				return true
+			}
+		}
+		return false
+	case "modified":
+		date, _ := parseDate(n.value)
+		return compareOK(t.Modified.Compare(date), n.op)
+	case "title":
+		if n.op == "=" {
+			return contains(doc.Title, n.value)
+		}
+		return compareOK(compare(doc.Title, n.value), n.op)
+	case "heading":
+		for _, heading := range doc.Headings {
+			if contains(heading.Text, n.value) {
+				return true
+			}
+		}
+		return false
+	case "has":
+		_, ok := doc.Fields[strings.ToLower(n.value)]
+		return ok
+	case "tag", "tags":
+		return n.matchValues(append(doc.Fields["tags"], doc.Fields["tag"]...))
+	}
+	return n.matchValues(doc.Fields[n.field])
+}
+
+// matchValues reports whether any of a field's values satisfies the term
+func (n termNode) matchValues(values []string) bool {
+	for _, value := range values {
+		if compareOK(compare(value, n.value), n.op) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders two values as dates, numbers or case-insensitive text
+func compare(a, b string) int {
+	if x, ok := parseDate(a); ok {
+		if y, ok := parseDate(b); ok {
+			return x.Compare(y)
+		}
+	}
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareOK reports whether the result of a comparison satisfies op
+func compareOK(cmp int, op string) bool {
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// dateLayouts are the date formats values are compared as
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// parseDate parses a date or time, in local time unless it has a zone
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// contains reports whether text contains a value, ignoring case
+func contains(text, value string) bool {
+	return strings.Contains(strings.ToLower(text), strings.ToLower(value))
+}
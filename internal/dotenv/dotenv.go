@@ -0,0 +1,130 @@
+// Package dotenv recognizes dotenv files and masks the values they assign,
+// so their keys can be read without the secrets they usually hold.
+package dotenv
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces masked values
+const Placeholder = "<masked>"
+
+// exampleSuffixes mark templates of dotenv files, which are meant to be
+// read and committed, such as .env.example
+var exampleSuffixes = []string{".example", ".sample", ".template", ".dist", ".defaults"}
+
+// Is reports whether a file is a dotenv file: .env, .env.local and the
+// like, or a name ending in .env such as prod.env. Templates such as
+// .env.example aren't, since they hold no real values.
+func Is(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return dotenvName(name) && !example(name)
+}
+
+// IsExample reports whether a file is a template of a dotenv file, such as
+// .env.example
+func IsExample(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return dotenvName(name) && example(name)
+}
+
+// dotenvName reports whether a lowercase file name is that of a dotenv
+// file or its template
+func dotenvName(name string) bool {
+	return name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")
+}
+
+// example reports whether a lowercase file name has a template suffix
+func example(name string) bool {
+	for _, suffix := range exampleSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// assignment matches KEY=value, KEY: value and export KEY=value
+var assignment = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_.-]*\s*[=:]\s*)(.*)$`)
+
+// Mask replaces the values of the assignments in dotenv text with
+// Placeholder, keeping keys, comments, blank lines and line endings.
+// Empty values stay empty, so unset keys can be told apart. The lines of
+// multi-line values, and lines that aren't assignments, are masked whole.
+func Mask(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var quote byte // of a multi-line value the lines are in
+	for i, line := range lines {
+		body := strings.TrimRight(line, "\r\n")
+		if quote != 0 {
+			if closingQuote(body, quote) >= 0 {
+				quote = 0
+			}
+			if body != "" {
+				lines[i] = Placeholder + line[len(body):]
+			}
+			continue
+		}
+		lines[i] = MaskLine(body) + line[len(body):]
+		if match := assignment.FindStringSubmatch(body); match != nil && match[2] != "" {
+			if q := match[2][0]; strings.IndexByte("\"'`", q) >= 0 && closingQuote(match[2][1:], q) < 0 {
+				quote = q
+			}
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// MaskLine masks one line of dotenv text without its line ending, without
+// knowing whether it continues a multi-line value
+func MaskLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return line
+	}
+	match := assignment.FindStringSubmatch(line)
+	if match == nil {
+		return Placeholder
+	}
+	value := match[2]
+	if strings.TrimSpace(value) == "" {
+		return line
+	}
+	return match[1] + Placeholder + trailingComment(value)
+}
+
+// closingQuote returns the offset of the quote closing a value in text, or
+// -1 if there is none, skipping backslash escapes in double-quoted values
+func closingQuote(text string, quote byte) int {
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && quote == '"' {
+			i++
+		} else if text[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// trailingComment returns the comment after a value, with the space before
+// it, or "" if there is none
+func trailingComment(value string) string {
+	rest := value
+	if quote := value[0]; strings.IndexByte("\"'`", quote) >= 0 {
+		end := closingQuote(value[1:], quote)
+		if end < 0 {
+			// The value continues on the next lines
+			return ""
+		}
+		rest = value[end+2:]
+	}
+	if i := strings.Index(rest, " #"); i >= 0 {
+		return rest[i:]
+	}
+	if i := strings.Index(rest, "\t#"); i >= 0 {
+		return rest[i:]
+	}
+	return ""
+}
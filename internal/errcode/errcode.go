@@ -0,0 +1,156 @@
+// Package errcode gives tool and resource errors machine-readable codes, so
+// clients can branch on a failure instead of parsing its message.
+//
+// Errors carry their code through the server as an Error, or as any error
+// with an ErrorCode method. The MCP library only passes on error messages,
+// so at its boundary Tagged makes the code a "CODE: " prefix of the
+// message, which the server's transport then parses back into structured
+// error data. Errors may also carry a remediation: what the client can do
+// instead, such as reading a binary file as a blob.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Code identifies the kind of a failure
+type Code string
+
+// Error codes
+const (
+	// NotFound: the file, directory or symbol doesn't exist
+	NotFound Code = "NOT_FOUND"
+	// OutsideWorkspace: the path is outside the workspace or resolves there
+	OutsideWorkspace Code = "OUTSIDE_WORKSPACE"
+	// TooLarge: the file or content is over a size limit
+	TooLarge Code = "TOO_LARGE"
+	// Binary: the file isn't text
+	Binary Code = "BINARY"
+	// ReadOnly: the file or filesystem can't be written
+	ReadOnly Code = "READ_ONLY"
+	// Conflict: the change would clobber a file or someone else's changes
+	Conflict Code = "CONFLICT"
+	// RateLimited: a quota on what a session may do is used up
+	RateLimited Code = "RATE_LIMITED"
+	// Locked: another process holds a lock on the file
+	Locked Code = "LOCKED"
+)
+
+// codes are the known codes, for parsing messages
+var codes = []Code{NotFound, OutsideWorkspace, TooLarge, Binary, ReadOnly, Conflict, RateLimited, Locked}
+
+// remediationPrefix introduces the remediation in a tagged message
+const remediationPrefix = "\nremediation: "
+
+// Error is an error with a code. Its message is the wrapped error's.
+type Error struct {
+	Code Code
+	Err  error
+	// Remediation tells the client how to get what it asked for instead
+	Remediation string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the error's code
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// ErrorRemediation returns the error's remediation
+func (e *Error) ErrorRemediation() string {
+	return e.Remediation
+}
+
+// New returns an error with a code and a formatted message
+func New(code Code, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Remediate returns an error with a code, a formatted message and a
+// remediation
+func Remediate(code Code, remediation, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...), Remediation: remediation}
+}
+
+// Wrapf returns an error with a formatted message describing cause, keeping
+// the code and remediation of cause
+func Wrapf(cause error, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	code := Of(cause)
+	if code == "" {
+		return err
+	}
+	return &Error{Code: code, Err: err, Remediation: RemediationOf(cause)}
+}
+
+// Of returns the code of an error: the code of the first error in its chain
+// that has one, else NOT_FOUND for errors about missing files, else ""
+func Of(err error) Code {
+	var coded interface{ ErrorCode() Code }
+	if errors.As(err, &coded) {
+		if code := coded.ErrorCode(); code != "" {
+			return code
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return NotFound
+	}
+	return ""
+}
+
+// RemediationOf returns the remediation of the first error in the chain of
+// err that has one, or ""
+func RemediationOf(err error) string {
+	var remedied interface{ ErrorRemediation() string }
+	if errors.As(err, &remedied) {
+		return remedied.ErrorRemediation()
+	}
+	return ""
+}
+
+// Tagged returns err with its code, if it has one, as a prefix of its
+// message and any remediation on a last line, for errors passed to the MCP
+// library. Tagged errors are returned as they are.
+func Tagged(err error) error {
+	if err == nil {
+		return nil
+	}
+	code, remediation := Of(err), RemediationOf(err)
+	message := err.Error()
+	if prefixed, _, _ := Parse(message); code == "" || prefixed != "" {
+		return err
+	}
+	message = string(code) + ": " + message
+	if remediation != "" {
+		message += remediationPrefix + remediation
+	}
+	return &Error{Code: code, Err: errors.New(message), Remediation: remediation}
+}
+
+// Parse splits a message made by Tagged into its code, the message without
+// it and its remediation. Messages without a code are returned as they are.
+func Parse(message string) (code Code, text, remediation string) {
+	for _, known := range codes {
+		if rest, ok := strings.CutPrefix(message, string(known)+": "); ok {
+			code, message = known, rest
+			break
+		}
+	}
+	if code == "" {
+		return "", message, ""
+	}
+	if i := strings.LastIndex(message, remediationPrefix); i >= 0 {
+		return code, message[:i], message[i+len(remediationPrefix):]
+	}
+	return code, message, ""
+}
@@ -0,0 +1,45 @@
+package fsys
+
+import (
+	"io"
+	"io/fs"
+)
+
+// dirFile is an open directory whose entries were listed when it was opened
+type dirFile struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+// DirFile returns an open directory serving entries, for filesystems that
+// list a directory when it's opened
+func DirFile(info fs.FileInfo, entries []fs.DirEntry) fs.ReadDirFile {
+	return &dirFile{info: info, entries: entries}
+}
+
+// Stat implements fs.File
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+// Read implements fs.File; directories can't be read
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: fs.ErrInvalid}
+}
+
+// Close implements fs.File
+func (d *dirFile) Close() error { return nil }
+
+// ReadDir implements fs.ReadDirFile
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,617 @@
+// Package fsys is the filesystem a workspace is served from: normally the
+// operating system's, but also an in-memory or read-only embedded one, so
+// virtual workspaces can be served and the server can run without touching
+// the disk.
+//
+// Filesystems follow io/fs, with slash-separated names relative to their
+// root. A Workspace mounts one at the workspace path and takes the absolute
+// paths the rest of the server works with.
+package fsys
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// ErrReadOnly is returned when writing to a filesystem that can't be changed
+var ErrReadOnly error = &errcode.Error{Code: errcode.ReadOnly, Err: errors.New("read-only filesystem")}
+
+// ErrCrossMount is returned when moving a file between mounts
+var ErrCrossMount = errors.New("can't move files between mounts")
+
+// FS is a filesystem that can be read
+type FS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	fs.ReadFileFS
+}
+
+// WriteFS is a filesystem that can also be changed
+type WriteFS interface {
+	FS
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	Rename(oldname, newname string) error
+}
+
+// LstatFS is a filesystem with symbolic links; Lstat describes a link
+// instead of following it
+type LstatFS interface {
+	FS
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// Guard refuses reads or writes of paths, for policies that apply to every
+// access to the workspace
+type Guard interface {
+	// CanRead returns an error if the content of path may not be read
+	CanRead(path string) error
+	// CanWrite returns an error if path may not be changed
+	CanWrite(path string) error
+}
+
+// Workspace is a filesystem mounted at the workspace path, with other
+// workspaces optionally attached at its top-level directories
+type Workspace struct {
+	root     string
+	fsys     FS
+	onDisk   bool
+	readOnly bool
+	mounts   []*mount
+	guard    Guard
+}
+
+// mount is a workspace attached at a top-level directory of another; paths
+// below it are served by the attached workspace, in front of anything with
+// that name in the workspace itself
+type mount struct {
+	name   string
+	path   string // the workspace root joined with name
+	ws     *Workspace
+	hidden bool // left out of listings and walks of the root, and can't be listed itself
+}
+
+// inner returns the path in the attached workspace of a path below the
+// mount point
+func (m *mount) inner(path string) string {
+	rel, _ := filepath.Rel(m.path, path)
+	return filepath.Join(m.ws.root, rel)
+}
+
+// outer returns the path below the mount point of a path in the attached
+// workspace
+func (m *mount) outer(path string) string {
+	rel, _ := filepath.Rel(m.ws.root, path)
+	return filepath.Join(m.path, rel)
+}
+
+// pathError reports an error from the attached workspace with the path
+// below the mount point
+func (m *mount) pathError(err error) error {
+	pathErr, ok := err.(*fs.PathError)
+	if !ok {
+		return err
+	}
+	path := pathErr.Path
+	if filepath.IsAbs(path) {
+		path = m.outer(path)
+	} else {
+		// Filesystems other than the OS's report io/fs names
+		path = filepath.Join(m.path, filepath.FromSlash(path))
+	}
+	return &fs.PathError{Op: pathErr.Op, Path: path, Err: pathErr.Err}
+}
+
+// OS returns the workspace at root on the operating system's filesystem
+func OS(root string) *Workspace {
+	return &Workspace{root: root, fsys: osFS{root: root}, onDisk: true}
+}
+
+// Mount returns a workspace serving fsys at root. Root only names the
+// workspace; nothing at that path on disk is used.
+func Mount(root string, fsys FS) *Workspace {
+	return &Workspace{root: root, fsys: fsys}
+}
+
+// SetGuard checks every read and write of the workspace and its mounts
+// with guard
+func (w *Workspace) SetGuard(guard Guard) {
+	w.guard = guard
+}
+
+// check asks the guard whether path may be read or written
+func (w *Workspace) check(op, path string, write bool) error {
+	if w.guard == nil {
+		return nil
+	}
+	check := w.guard.CanRead
+	if write {
+		check = w.guard.CanWrite
+	}
+	if err := check(path); err != nil {
+		return &fs.PathError{Op: op, Path: path, Err: err}
+	}
+	return nil
+}
+
+// Attach mounts another workspace at the top-level directory name, which
+// shadows any directory of that name in this workspace. A read-only mount
+// refuses writes whatever its filesystem allows.
+func (w *Workspace) Attach(name string, ws *Workspace, readOnly bool) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid mount name %q", name)
+	}
+	for _, m := range w.mounts {
+		if m.name == name {
+			return fmt.Errorf("%s is already mounted", name)
+		}
+	}
+	if readOnly {
+		ws.readOnly = true
+	}
+	w.mounts = append(w.mounts, &mount{name: name, path: filepath.Join(w.root, name), ws: ws})
+	return nil
+}
+
+// SetReadOnly refuses writes to the workspace and the mounts attached so
+// far
+func (w *Workspace) SetReadOnly() {
+	w.readOnly = true
+	for _, m := range w.mounts {
+		m.ws.readOnly = true
+	}
+}
+
+// AttachHidden mounts another workspace at the top-level directory name
+// without listing it: it is left out of listings and walks of the root,
+// and its own top level can't be listed, so the names in it are only
+// reachable by those who know them
+func (w *Workspace) AttachHidden(name string, ws *Workspace) error {
+	if err := w.Attach(name, ws, false); err != nil {
+		return err
+	}
+	w.mounts[len(w.mounts)-1].hidden = true
+	return nil
+}
+
+// MountPoint returns the name of the mount a path is in and the path's
+// slash-separated location inside it
+func (w *Workspace) MountPoint(path string) (name, rel string, ok bool) {
+	m := w.mountFor(path)
+	if m == nil {
+		return "", "", false
+	}
+	rel, _ = filepath.Rel(m.path, path)
+	return m.name, filepath.ToSlash(rel), true
+}
+
+// MountPath returns where a mount appears in the workspace
+func (w *Workspace) MountPath(name string) (string, bool) {
+	for _, m := range w.mounts {
+		if m.name == name {
+			return m.path, true
+		}
+	}
+	return "", false
+}
+
+// MountPaths returns where every mount that isn't hidden appears in the
+// workspace
+func (w *Workspace) MountPaths() []string {
+	var paths []string
+	for _, m := range w.mounts {
+		if !m.hidden {
+			paths = append(paths, m.path)
+		}
+	}
+	return paths
+}
+
+// mountFor returns the mount a path is at or below, if any
+func (w *Workspace) mountFor(path string) *mount {
+	for _, m := range w.mounts {
+		if path == m.path || strings.HasPrefix(path, m.path+string(filepath.Separator)) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Root returns the workspace path
+func (w *Workspace) Root() string {
+	return w.root
+}
+
+// OnDisk reports whether the workspace is on the operating system's
+// filesystem, where git, commands and native change notification work
+func (w *Workspace) OnDisk() bool {
+	return w.onDisk
+}
+
+// Overlay returns the overlay the workspace is served from, if it is one
+func (w *Workspace) Overlay() (*Overlay, bool) {
+	overlay, ok := w.fsys.(*Overlay)
+	return overlay, ok
+}
+
+// Writable reports whether files in the workspace can be changed; mounts
+// can differ
+func (w *Workspace) Writable() bool {
+	_, ok := w.fsys.(WriteFS)
+	return ok && !w.readOnly
+}
+
+// Name returns the io/fs name of an absolute path in the workspace
+func (w *Workspace) Name(path string) (string, error) {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Close releases the filesystem and those of the mounts, such as
+// connections to remote ones
+func (w *Workspace) Close() error {
+	var errs []error
+	for _, m := range w.mounts {
+		errs = append(errs, m.ws.Close())
+	}
+	if closer, ok := w.fsys.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Open opens a file for reading
+func (w *Workspace) Open(path string) (fs.File, error) {
+	if err := w.check("open", path, false); err != nil {
+		return nil, err
+	}
+	if m := w.mountFor(path); m != nil {
+		result, err := m.ws.Open(m.inner(path))
+		return result, m.pathError(err)
+	}
+	if w.onDisk {
+		return os.Open(pathnorm.OnDisk(path))
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, err
+	}
+	return w.fsys.Open(name)
+}
+
+// Stat describes a file, following symbolic links
+func (w *Workspace) Stat(path string) (fs.FileInfo, error) {
+	if m := w.mountFor(path); m != nil {
+		result, err := m.ws.Stat(m.inner(path))
+		return result, m.pathError(err)
+	}
+	if w.onDisk {
+		return os.Stat(pathnorm.OnDisk(path))
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, err
+	}
+	return w.fsys.Stat(name)
+}
+
+// Lstat describes a file without following a final symbolic link
+func (w *Workspace) Lstat(path string) (fs.FileInfo, error) {
+	if m := w.mountFor(path); m != nil {
+		result, err := m.ws.Lstat(m.inner(path))
+		return result, m.pathError(err)
+	}
+	if w.onDisk {
+		return os.Lstat(pathnorm.OnDisk(path))
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, err
+	}
+	if lstat, ok := w.fsys.(LstatFS); ok {
+		return lstat.Lstat(name)
+	}
+	return w.fsys.Stat(name)
+}
+
+// ReadFile reads a whole file
+func (w *Workspace) ReadFile(path string) ([]byte, error) {
+	if err := w.check("open", path, false); err != nil {
+		return nil, err
+	}
+	if m := w.mountFor(path); m != nil {
+		result, err := m.ws.ReadFile(m.inner(path))
+		return result, m.pathError(err)
+	}
+	if w.onDisk {
+		return os.ReadFile(pathnorm.OnDisk(path))
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, err
+	}
+	return w.fsys.ReadFile(name)
+}
+
+// ReadDir lists a directory sorted by name
+func (w *Workspace) ReadDir(path string) ([]fs.DirEntry, error) {
+	if m := w.mountFor(path); m != nil {
+		if m.hidden && path == m.path {
+			return nil, &fs.PathError{Op: "readdir", Path: path, Err: fs.ErrPermission}
+		}
+		result, err := m.ws.ReadDir(m.inner(path))
+		return result, m.pathError(err)
+	}
+	entries, err := w.readDir(path)
+	if err != nil || path != w.root || len(w.mounts) == 0 {
+		return entries, err
+	}
+
+	// Mounts replace entries of the same name at the top level
+	entries = slices.DeleteFunc(entries, func(entry fs.DirEntry) bool {
+		return w.mountFor(filepath.Join(w.root, entry.Name())) != nil
+	})
+	for _, m := range w.mounts {
+		if !m.hidden {
+			entries = append(entries, fs.FileInfoToDirEntry(m.info()))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// readDir lists a directory of the workspace's own filesystem
+func (w *Workspace) readDir(path string) ([]fs.DirEntry, error) {
+	if w.onDisk {
+		return os.ReadDir(pathnorm.OnDisk(path))
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, err
+	}
+	return w.fsys.ReadDir(name)
+}
+
+// Walk walks the tree at path like filepath.Walk, without following
+// symbolic links. Walking the root walks the mounts after the workspace's
+// own files.
+func (w *Workspace) Walk(path string, fn filepath.WalkFunc) error {
+	if m := w.mountFor(path); m != nil {
+		if m.hidden && path == m.path {
+			return fn(path, nil, &fs.PathError{Op: "readdir", Path: path, Err: fs.ErrPermission})
+		}
+		return m.walk(m.inner(path), fn)
+	}
+	if path != w.root || len(w.mounts) == 0 {
+		return w.walk(path, fn)
+	}
+
+	err := w.walk(path, func(path string, info fs.FileInfo, err error) error {
+		if m := w.mountFor(path); m != nil {
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(path, info, err)
+	})
+	if err != nil {
+		return err
+	}
+	for _, m := range w.mounts {
+		if m.hidden {
+			continue
+		}
+		if err := m.walk(m.ws.root, fn); err != nil {
+			if err == filepath.SkipAll {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// walk walks the mounted workspace, reporting paths below the mount point
+func (m *mount) walk(path string, fn filepath.WalkFunc) error {
+	return m.ws.Walk(path, func(path string, info fs.FileInfo, err error) error {
+		if path == m.ws.root && info != nil {
+			info = m.renamed(info)
+		}
+		return fn(m.outer(path), info, m.pathError(err))
+	})
+}
+
+// walk walks the workspace's own filesystem
+func (w *Workspace) walk(path string, fn filepath.WalkFunc) error {
+	if w.onDisk {
+		return filepath.Walk(path, fn)
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return fn(path, nil, err)
+	}
+	return fs.WalkDir(w.fsys, name, func(name string, entry fs.DirEntry, err error) error {
+		path := filepath.Join(w.root, filepath.FromSlash(name))
+		if err != nil {
+			return fn(path, nil, err)
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return fn(path, nil, err)
+		}
+		return fn(path, info, nil)
+	})
+}
+
+// WriteFile writes a whole file, creating it with perm if needed
+func (w *Workspace) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if err := w.check("write", path, true); err != nil {
+		return err
+	}
+	if m := w.mountFor(path); m != nil {
+		return m.pathError(m.ws.WriteFile(m.inner(path), data, perm))
+	}
+	if w.readOnly {
+		return &fs.PathError{Op: "write", Path: path, Err: ErrReadOnly}
+	}
+	if w.onDisk {
+		return os.WriteFile(path, data, perm)
+	}
+	fsys, name, err := w.writable("write", path)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(name, data, perm)
+}
+
+// MkdirAll creates a directory and any missing parents
+func (w *Workspace) MkdirAll(path string, perm fs.FileMode) error {
+	if m := w.mountFor(path); m != nil {
+		return m.pathError(m.ws.MkdirAll(m.inner(path), perm))
+	}
+	if path == w.root {
+		return nil
+	}
+	if w.readOnly {
+		return &fs.PathError{Op: "mkdir", Path: path, Err: ErrReadOnly}
+	}
+	if w.onDisk {
+		return os.MkdirAll(path, perm)
+	}
+	fsys, name, err := w.writable("mkdir", path)
+	if err != nil {
+		return err
+	}
+	return fsys.MkdirAll(name, perm)
+}
+
+// Remove deletes a file or empty directory
+func (w *Workspace) Remove(path string) error {
+	if err := w.check("remove", path, true); err != nil {
+		return err
+	}
+	if m := w.mountFor(path); m != nil {
+		if path == m.path {
+			return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrPermission}
+		}
+		return m.pathError(m.ws.Remove(m.inner(path)))
+	}
+	if w.readOnly {
+		return &fs.PathError{Op: "remove", Path: path, Err: ErrReadOnly}
+	}
+	if w.onDisk {
+		return os.Remove(path)
+	}
+	fsys, name, err := w.writable("remove", path)
+	if err != nil {
+		return err
+	}
+	return fsys.Remove(name)
+}
+
+// Rename moves a file or directory
+func (w *Workspace) Rename(oldpath, newpath string) error {
+	if err := w.check("rename", oldpath, true); err != nil {
+		return err
+	}
+	if err := w.check("rename", newpath, true); err != nil {
+		return err
+	}
+	oldMount, newMount := w.mountFor(oldpath), w.mountFor(newpath)
+	if oldMount != newMount {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: ErrCrossMount}
+	}
+	if oldMount != nil {
+		if oldpath == oldMount.path {
+			return &fs.PathError{Op: "rename", Path: oldpath, Err: fs.ErrPermission}
+		}
+		return oldMount.pathError(oldMount.ws.Rename(oldMount.inner(oldpath), oldMount.inner(newpath)))
+	}
+	if w.readOnly {
+		return &fs.PathError{Op: "rename", Path: oldpath, Err: ErrReadOnly}
+	}
+	if w.onDisk {
+		return os.Rename(oldpath, newpath)
+	}
+	fsys, oldname, err := w.writable("rename", oldpath)
+	if err != nil {
+		return err
+	}
+	newname, err := w.Name(newpath)
+	if err != nil {
+		return err
+	}
+	return fsys.Rename(oldname, newname)
+}
+
+// writable returns the filesystem to change and the name of path in it
+func (w *Workspace) writable(op, path string) (WriteFS, string, error) {
+	fsys, ok := w.fsys.(WriteFS)
+	if !ok || w.readOnly {
+		return nil, "", &fs.PathError{Op: op, Path: path, Err: ErrReadOnly}
+	}
+	name, err := w.Name(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return fsys, name, nil
+}
+
+// info describes the mount point as a directory, even while the mounted
+// filesystem can't be reached
+func (m *mount) info() fs.FileInfo {
+	info, err := m.ws.Stat(m.ws.root)
+	if err != nil || !info.IsDir() {
+		return mountEntry{name: m.name}
+	}
+	return m.renamed(info)
+}
+
+// renamed describes the root of the mounted workspace under the mount's name
+func (m *mount) renamed(info fs.FileInfo) fs.FileInfo {
+	return mountEntry{FileInfo: info, name: m.name}
+}
+
+// mountEntry is a mount point in its parent's listing
+type mountEntry struct {
+	fs.FileInfo // nil when the mounted filesystem can't be reached
+	name        string
+}
+
+func (e mountEntry) Name() string { return e.name }
+func (e mountEntry) IsDir() bool  { return true }
+func (e mountEntry) Mode() fs.FileMode {
+	if e.FileInfo == nil {
+		return fs.ModeDir | 0555
+	}
+	return e.FileInfo.Mode()
+}
+func (e mountEntry) Size() int64 {
+	if e.FileInfo == nil {
+		return 0
+	}
+	return e.FileInfo.Size()
+}
+func (e mountEntry) ModTime() time.Time {
+	if e.FileInfo == nil {
+		return time.Time{}
+	}
+	return e.FileInfo.ModTime()
+}
+func (e mountEntry) Sys() any { return nil }
@@ -0,0 +1,240 @@
+package fsys
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mem is a writable filesystem held in memory
+type Mem struct {
+	nodes map[string]*memNode // by name; "." is the root directory
+	mu    sync.RWMutex
+}
+
+// memNode is a file or directory in a Mem
+type memNode struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMem creates an empty in-memory filesystem
+func NewMem() *Mem {
+	return &Mem{nodes: map[string]*memNode{".": {mode: fs.ModeDir | 0755, modTime: time.Now()}}}
+}
+
+// lookup returns the node of a valid name; the caller holds the lock
+func (m *Mem) lookup(op, name string) (*memNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return node, nil
+}
+
+// parentDir checks that the directory holding name exists; the caller holds the lock
+func (m *Mem) parentDir(op, name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	parent, ok := m.nodes[path.Dir(name)]
+	if !ok || !parent.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return nil
+}
+
+// children returns the names directly inside a directory, sorted; the caller holds the lock
+func (m *Mem) children(dir string) []string {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	var names []string
+	for name := range m.nodes {
+		if name != "." && strings.HasPrefix(name, prefix) && !strings.Contains(name[len(prefix):], "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open implements fs.FS
+func (m *Mem) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, err := m.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info := memInfo{name: path.Base(name), node: *node}
+	if node.mode.IsDir() {
+		var entries []fs.DirEntry
+		for _, child := range m.children(name) {
+			entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: path.Base(child), node: *m.nodes[child]}))
+		}
+		return DirFile(info, entries), nil
+	}
+	return &memFile{info: info, Reader: bytes.NewReader(node.data)}, nil
+}
+
+// Stat implements fs.StatFS
+func (m *Mem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return memInfo{name: path.Base(name), node: *node}, nil
+}
+
+// ReadFile implements fs.ReadFileFS
+func (m *Mem) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, err := m.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	return bytes.Clone(node.data), nil
+}
+
+// ReadDir implements fs.ReadDirFS
+func (m *Mem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	node, err := m.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var entries []fs.DirEntry
+	for _, child := range m.children(name) {
+		entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: path.Base(child), node: *m.nodes[child]}))
+	}
+	return entries, nil
+}
+
+// WriteFile implements WriteFS; the directory holding the file must exist
+func (m *Mem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.parentDir("write", name); err != nil {
+		return err
+	}
+	if node, ok := m.nodes[name]; ok {
+		if node.mode.IsDir() {
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+		}
+		perm = node.mode.Perm()
+	}
+	m.nodes[name] = &memNode{data: bytes.Clone(data), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+// MkdirAll implements WriteFS
+func (m *Mem) MkdirAll(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if node, ok := m.nodes[dir]; ok {
+			if !node.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: fs.ErrExist}
+			}
+			break
+		}
+	}
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.nodes[dir]; ok {
+			break
+		}
+		m.nodes[dir] = &memNode{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+// Remove implements WriteFS; directories must be empty
+func (m *Mem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, err := m.lookup("remove", name)
+	if err != nil {
+		return err
+	}
+	if name == "." || node.mode.IsDir() && len(m.children(name)) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+// Rename implements WriteFS, moving a directory with everything below it
+func (m *Mem) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.lookup("rename", oldname); err != nil {
+		return err
+	}
+	if err := m.parentDir("rename", newname); err != nil {
+		return err
+	}
+	if oldname == "." || strings.HasPrefix(newname, oldname+"/") {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	if target, ok := m.nodes[newname]; ok && target.mode.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrExist}
+	}
+	for name, node := range m.nodes {
+		if name == oldname {
+			m.nodes[newname] = node
+			delete(m.nodes, name)
+		} else if rest, ok := strings.CutPrefix(name, oldname+"/"); ok {
+			m.nodes[newname+"/"+rest] = node
+			delete(m.nodes, name)
+		}
+	}
+	return nil
+}
+
+// memInfo describes a node
+type memInfo struct {
+	name string
+	node memNode
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memInfo) Mode() fs.FileMode  { return i.node.mode }
+func (i memInfo) ModTime() time.Time { return i.node.modTime }
+func (i memInfo) IsDir() bool        { return i.node.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memFile is an open file
+type memFile struct {
+	*bytes.Reader
+	info memInfo
+}
+
+// Stat implements fs.File
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Close implements fs.File
+func (f *memFile) Close() error { return nil }
@@ -0,0 +1,112 @@
+package fsys
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// osFS is the operating system's filesystem below a directory
+type osFS struct {
+	root string
+}
+
+// Dir returns the operating system's filesystem below root
+func Dir(root string) WriteFS {
+	return osFS{root: root}
+}
+
+// path returns the on-disk path of a name
+func (o osFS) path(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return pathnorm.OnDisk(filepath.Join(o.root, filepath.FromSlash(name))), nil
+}
+
+// Open implements fs.FS
+func (o osFS) Open(name string) (fs.File, error) {
+	path, err := o.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Stat implements fs.StatFS
+func (o osFS) Stat(name string) (fs.FileInfo, error) {
+	path, err := o.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+// Lstat implements LstatFS
+func (o osFS) Lstat(name string) (fs.FileInfo, error) {
+	path, err := o.path("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(path)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (o osFS) ReadFile(name string) ([]byte, error) {
+	path, err := o.path("read", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (o osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	path, err := o.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(path)
+}
+
+// WriteFile implements WriteFS
+func (o osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	path, err := o.path("write", name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// MkdirAll implements WriteFS
+func (o osFS) MkdirAll(name string, perm fs.FileMode) error {
+	path, err := o.path("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// Remove implements WriteFS
+func (o osFS) Remove(name string) error {
+	path, err := o.path("remove", name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Rename implements WriteFS
+func (o osFS) Rename(oldname, newname string) error {
+	oldpath, err := o.path("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newpath, err := o.path("rename", newname)
+	if err != nil {
+		return err
+	}
+	return os.Rename(oldpath, newpath)
+}
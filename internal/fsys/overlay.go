@@ -0,0 +1,564 @@
+package fsys
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Overlay is a copy-on-write view of a filesystem. Changes go to a layer
+// held in memory and the base is only written when they are applied.
+type Overlay struct {
+	base    WriteFS
+	upper   *Mem
+	deleted map[string]bool // base names removed in the overlay; nothing below them shows through
+	mu      sync.RWMutex
+}
+
+// Change is a file that differs between an overlay and its base
+type Change struct {
+	Name string // slash-separated name of the file
+	Kind string // "added", "modified" or "deleted"
+}
+
+// Kinds of Change
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+)
+
+// NewOverlay returns an overlay with no changes over base
+func NewOverlay(base WriteFS) *Overlay {
+	return &Overlay{base: base, upper: NewMem(), deleted: make(map[string]bool)}
+}
+
+// hidden reports whether a base name was removed in the overlay; the
+// caller holds the lock
+func (o *Overlay) hidden(name string) bool {
+	for ; name != "."; name = path.Dir(name) {
+		if o.deleted[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// stat describes a name in the merged view; the caller holds the lock
+func (o *Overlay) stat(name string) (fs.FileInfo, error) {
+	if info, err := o.upper.Stat(name); err == nil {
+		return info, nil
+	}
+	if o.hidden(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return o.base.Stat(name)
+}
+
+// readDir lists a directory in the merged view; the caller holds the lock
+func (o *Overlay) readDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := o.upper.ReadDir(name)
+	if o.hidden(name) {
+		if upperErr != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+		return upper, nil
+	}
+	base, err := o.base.ReadDir(name)
+	if err != nil {
+		if upperErr == nil {
+			return upper, nil
+		}
+		return nil, err
+	}
+
+	entries := upper
+	inUpper := make(map[string]bool, len(upper))
+	for _, entry := range upper {
+		inUpper[entry.Name()] = true
+	}
+	for _, entry := range base {
+		child := path.Join(name, entry.Name())
+		if !inUpper[entry.Name()] && !o.deleted[child] {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Open implements fs.FS
+func (o *Overlay) Open(name string) (fs.File, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	info, err := o.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := o.readDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return DirFile(info, entries), nil
+	}
+	if _, err := o.upper.Stat(name); err == nil {
+		return o.upper.Open(name)
+	}
+	return o.base.Open(name)
+}
+
+// Stat implements fs.StatFS
+func (o *Overlay) Stat(name string) (fs.FileInfo, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.stat(name)
+}
+
+// Lstat implements LstatFS; only the base has symbolic links
+func (o *Overlay) Lstat(name string) (fs.FileInfo, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if info, err := o.upper.Stat(name); err == nil {
+		return info, nil
+	}
+	if o.hidden(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	if lstat, ok := o.base.(LstatFS); ok {
+		return lstat.Lstat(name)
+	}
+	return o.base.Stat(name)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (o *Overlay) ReadFile(name string) ([]byte, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if _, err := o.upper.Stat(name); err == nil {
+		return o.upper.ReadFile(name)
+	}
+	if o.hidden(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return o.base.ReadFile(name)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (o *Overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.readDir(name)
+}
+
+// WriteFile implements WriteFS; the directory holding the file must exist
+func (o *Overlay) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	if parent, err := o.stat(path.Dir(name)); err != nil || !parent.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+	}
+	if info, err := o.stat(name); err == nil {
+		if info.IsDir() {
+			return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+		}
+		perm = info.Mode().Perm()
+	}
+	if err := o.upper.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	return o.upper.WriteFile(name, data, perm)
+}
+
+// MkdirAll implements WriteFS
+func (o *Overlay) MkdirAll(name string, perm fs.FileMode) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if info, err := o.stat(dir); err == nil {
+			if !info.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: dir, Err: fs.ErrExist}
+			}
+			break
+		}
+	}
+	return o.upper.MkdirAll(name, perm)
+}
+
+// Remove implements WriteFS; directories must be empty
+func (o *Overlay) Remove(name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.remove(name)
+}
+
+// remove deletes a name from the merged view; the caller holds the lock
+func (o *Overlay) remove(name string) error {
+	if name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	info, err := o.stat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if entries, err := o.readDir(name); err != nil || len(entries) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+		}
+	}
+	if _, err := o.upper.Stat(name); err == nil {
+		if err := o.upper.Remove(name); err != nil {
+			return err
+		}
+	}
+	if !o.hidden(name) {
+		if _, err := o.base.Stat(name); err == nil {
+			o.deleted[name] = true
+		}
+	}
+	return nil
+}
+
+// Rename implements WriteFS, moving a directory with everything below it
+func (o *Overlay) Rename(oldname, newname string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	info, err := o.stat(oldname)
+	if err != nil {
+		return err
+	}
+	if oldname == "." || strings.HasPrefix(newname, oldname+"/") {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	if parent, err := o.stat(path.Dir(newname)); err != nil || !parent.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrNotExist}
+	}
+	if !info.IsDir() {
+		return o.move(oldname, newname, info)
+	}
+
+	// Copy the tree, then remove it deepest first
+	var moved []string
+	err = fs.WalkDir(overlayView{o}, oldname, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := newname + strings.TrimPrefix(name, oldname)
+		if entry.IsDir() {
+			moved = append(moved, name)
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			return o.upper.MkdirAll(target, info.Mode().Perm())
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return o.move(name, target, info)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(moved) - 1; i >= 0; i-- {
+		if err := o.remove(moved[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// move copies a file to a new name and removes it; the caller holds the lock
+func (o *Overlay) move(oldname, newname string, info fs.FileInfo) error {
+	var data []byte
+	var err error
+	if _, statErr := o.upper.Stat(oldname); statErr == nil {
+		data, err = o.upper.ReadFile(oldname)
+	} else {
+		data, err = o.base.ReadFile(oldname)
+	}
+	if err != nil {
+		return err
+	}
+	if err := o.upper.MkdirAll(path.Dir(newname), 0755); err != nil {
+		return err
+	}
+	if existing, err := o.upper.Stat(newname); err == nil && !existing.IsDir() {
+		if err := o.upper.Remove(newname); err != nil {
+			return err
+		}
+	}
+	if err := o.upper.WriteFile(newname, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return o.remove(oldname)
+}
+
+// overlayView reads the merged view while the overlay's lock is held
+type overlayView struct {
+	o *Overlay
+}
+
+func (v overlayView) Open(name string) (fs.File, error) {
+	info, err := v.o.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := v.o.readDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return DirFile(info, entries), nil
+}
+
+func (v overlayView) ReadDir(name string) ([]fs.DirEntry, error) {
+	return v.o.readDir(name)
+}
+
+// Changes lists the files that differ from the base, sorted by name
+func (o *Overlay) Changes() ([]Change, error) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.changes()
+}
+
+// changes lists the changed files; the caller holds the lock
+func (o *Overlay) changes() ([]Change, error) {
+	var changes []Change
+	inUpper := make(map[string]bool)
+	err := fs.WalkDir(o.upper, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		inUpper[name] = true
+		if !o.isBaseFile(name) {
+			changes = append(changes, Change{Name: name, Kind: ChangeAdded})
+			return nil
+		}
+		base, err := o.base.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		data, err := o.upper.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, base) {
+			changes = append(changes, Change{Name: name, Kind: ChangeModified})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for deleted := range o.deleted {
+		// Removals below a removed directory are listed with it
+		if o.hidden(path.Dir(deleted)) {
+			continue
+		}
+		err := fs.WalkDir(o.base, deleted, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !entry.IsDir() && !inUpper[name] {
+				changes = append(changes, Change{Name: name, Kind: ChangeDeleted})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes, nil
+}
+
+// isBaseFile reports whether the base has a regular file with a name
+func (o *Overlay) isBaseFile(name string) bool {
+	info, err := o.base.Stat(name)
+	return err == nil && !info.IsDir()
+}
+
+// Base returns the content of a file in the base, for comparing with the
+// overlay's
+func (o *Overlay) Base(name string) ([]byte, error) {
+	return o.base.ReadFile(name)
+}
+
+// Selected reports whether a name is one of names or below one of them;
+// no names selects everything
+func Selected(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == "." || name == n || strings.HasPrefix(name, n+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply writes the changes to the files at or below names to the base and
+// drops them from the overlay; no names applies every change. It returns
+// the changes applied.
+func (o *Overlay) Apply(names []string) ([]Change, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Change
+	for _, change := range changes {
+		if !Selected(change.Name, names) {
+			continue
+		}
+		switch change.Kind {
+		case ChangeDeleted:
+			if err := o.base.Remove(change.Name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return applied, err
+			}
+		default:
+			data, err := o.upper.ReadFile(change.Name)
+			if err != nil {
+				return applied, err
+			}
+			info, err := o.upper.Stat(change.Name)
+			if err != nil {
+				return applied, err
+			}
+			if err := o.base.MkdirAll(path.Dir(change.Name), 0755); err != nil {
+				return applied, err
+			}
+			if err := o.base.WriteFile(change.Name, data, info.Mode().Perm()); err != nil {
+				return applied, err
+			}
+		}
+		applied = append(applied, change)
+	}
+
+	// Directories removed in the overlay go once they are empty
+	for deleted := range o.deleted {
+		if Selected(deleted, names) {
+			o.removeEmptyDirs(deleted)
+		}
+	}
+	o.discard(names)
+	return applied, nil
+}
+
+// removeEmptyDirs removes a base directory tree that has no files left,
+// deepest first; the caller holds the lock
+func (o *Overlay) removeEmptyDirs(name string) {
+	var dirs []string
+	fs.WalkDir(o.base, name, func(name string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			dirs = append(dirs, name)
+		}
+		return nil
+	})
+	for i := len(dirs) - 1; i >= 0; i-- {
+		o.base.Remove(dirs[i])
+	}
+}
+
+// Discard drops the changes to the files at or below names, so they read
+// from the base again; no names discards every change
+func (o *Overlay) Discard(names []string) ([]Change, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	changes, err := o.changes()
+	if err != nil {
+		return nil, err
+	}
+	var discarded []Change
+	for _, change := range changes {
+		if Selected(change.Name, names) {
+			discarded = append(discarded, change)
+		}
+	}
+	o.discard(names)
+	return discarded, nil
+}
+
+// discard drops the overlay's files and removals at or below names; the
+// caller holds the lock
+func (o *Overlay) discard(names []string) {
+	if len(names) == 0 {
+		o.upper = NewMem()
+		o.deleted = make(map[string]bool)
+		return
+	}
+
+	for _, name := range names {
+		// A removed directory above the name is narrowed to its other
+		// entries, so the name shows through again
+		for _, dir := range ancestors(name) {
+			if o.deleted[dir] {
+				delete(o.deleted, dir)
+				entries, _ := o.base.ReadDir(dir)
+				for _, entry := range entries {
+					o.deleted[path.Join(dir, entry.Name())] = true
+				}
+			}
+		}
+		for deleted := range o.deleted {
+			if Selected(deleted, []string{name}) {
+				delete(o.deleted, deleted)
+			}
+		}
+		o.removeUpper(name)
+	}
+}
+
+// removeUpper removes a name and everything below it from the upper layer,
+// then the directories above it that are left empty; the caller holds the
+// lock
+func (o *Overlay) removeUpper(name string) {
+	if name == "." {
+		o.upper = NewMem()
+		return
+	}
+	var names []string
+	fs.WalkDir(o.upper, name, func(name string, entry fs.DirEntry, err error) error {
+		if err == nil {
+			names = append(names, name)
+		}
+		return nil
+	})
+	for i := len(names) - 1; i >= 0; i-- {
+		o.upper.Remove(names[i])
+	}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if entries, err := o.upper.ReadDir(dir); err != nil || len(entries) > 0 || o.upper.Remove(dir) != nil {
+			break
+		}
+	}
+}
+
+// ancestors returns the directories above a name, outermost first
+func ancestors(name string) []string {
+	var dirs []string
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	return dirs
+}
@@ -0,0 +1,29 @@
+package fsys
+
+import "io/fs"
+
+// readOnly adapts any io/fs filesystem to FS
+type readOnly struct {
+	fs.FS
+}
+
+// ReadOnly serves any io/fs filesystem, such as an embed.FS or a zip
+// archive, as an FS that can't be changed
+func ReadOnly(fsys fs.FS) FS {
+	return readOnly{FS: fsys}
+}
+
+// Stat implements fs.StatFS
+func (r readOnly) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(r.FS, name)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (r readOnly) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(r.FS, name)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (r readOnly) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.FS, name)
+}
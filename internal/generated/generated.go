@@ -0,0 +1,122 @@
+package generated
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// headerSize is how much of a file is read to look for generator markers
+const headerSize = 4096
+
+// minifiedLineLength is the line length above which a script or stylesheet
+// is treated as minified
+const minifiedLineLength = 1000
+
+// lockfiles are dependency lock files written by package managers
+var lockfiles = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"go.work.sum":         true,
+	"Cargo.lock":          true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"composer.lock":       true,
+	"Gemfile.lock":        true,
+	"flake.lock":          true,
+	"mix.lock":            true,
+	"pubspec.lock":        true,
+	"Podfile.lock":        true,
+}
+
+// suffixes are file name endings used by code generators and bundlers
+var suffixes = []struct {
+	suffix string
+	reason string
+}{
+	{".min.js", "minified"},
+	{".min.css", "minified"},
+	{".js.map", "source map"},
+	{".css.map", "source map"},
+	{".pb.go", "protobuf"},
+	{"_grpc.pb.go", "protobuf"},
+	{".pb.gw.go", "protobuf"},
+	{"_pb2.py", "protobuf"},
+	{"_pb2_grpc.py", "protobuf"},
+	{".pb.h", "protobuf"},
+	{".pb.cc", "protobuf"},
+	{"_pb.js", "protobuf"},
+	{"_pb.d.ts", "protobuf"},
+	{".g.dart", "code generator"},
+	{".freezed.dart", "code generator"},
+	{".designer.cs", "code generator"},
+}
+
+// headerMarkers match the comments generators put at the top of their output
+var headerMarkers = regexp.MustCompile(`(?m)^\s*(//|#|/\*|\*|--|;|<!--)?\s*(Code generated .* DO NOT EDIT|@generated|<auto-generated|This file (is|was) (automatically|auto-)generated|AUTO-GENERATED FILE|DO NOT EDIT: generated)`)
+
+// Detect reports why a file looks generated, such as "lockfile" or
+// "minified", or returns "" for files that look hand-written. The file's
+// header is read through open.
+func Detect(path string, open func(string) (fs.File, error)) string {
+	name := filepath.Base(path)
+	if lockfiles[name] {
+		return "lockfile"
+	}
+
+	lower := strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(lower, s.suffix) {
+			return s.reason
+		}
+	}
+	header, err := readHeader(path, open)
+	if err != nil {
+		return ""
+	}
+	if headerMarkers.Match(header) {
+		return "generated header"
+	}
+	switch filepath.Ext(lower) {
+	case ".js", ".mjs", ".cjs", ".css":
+		if isMinified(header) {
+			return "minified"
+		}
+	}
+
+	return ""
+}
+
+// readHeader returns the first bytes of a file
+func readHeader(path string, open func(string) (fs.File, error)) ([]byte, error) {
+	file, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+// isMinified reports whether a file's first line is too long to be written
+// by hand
+func isMinified(header []byte) bool {
+	line := header
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		line = header[:i]
+	}
+	return len(line) > minifiedLineLength
+}
@@ -0,0 +1,264 @@
+package gitignore
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// Reasons a path is excluded from the workspace
+const (
+	ReasonOutside   = "outside-workspace"
+	ReasonDotfile   = "dotfile"
+	ReasonDefault   = "default-ignore"
+	ReasonEditor    = "editor-artifact"
+	ReasonConfig    = "config-ignore"
+	ReasonSensitive = "sensitive"
+	ReasonSubmodule = "submodule"
+	ReasonGitignore = "gitignore"
+	ReasonSparse    = "sparse-checkout"
+)
+
+// Explanation tells why a path is or isn't excluded from the workspace
+type Explanation struct {
+	// Path is the workspace-relative path explained
+	Path     string `json:"path"`
+	Excluded bool   `json:"excluded"`
+	// Reason is one of the Reason constants. It is also set for a path that
+	// isn't excluded because a negated .gitignore pattern re-includes it.
+	Reason string `json:"reason,omitempty"`
+	// Directory is set when the path is excluded because a directory above
+	// it is
+	Directory string `json:"directory,omitempty"`
+	// Source is where the deciding rule comes from, such as a .gitignore
+	// relative to the workspace, and Line its line in a .gitignore
+	Source  string `json:"source,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// String describes the explanation in a sentence
+func (e Explanation) String() string {
+	if !e.Excluded {
+		if e.Pattern != "" {
+			return fmt.Sprintf("%s is included: re-included by %q in %s:%d", e.Path, e.Pattern, e.Source, e.Line)
+		}
+		return fmt.Sprintf("%s is included", e.Path)
+	}
+
+	subject := e.Path
+	if e.Directory != "" {
+		subject = fmt.Sprintf("%s is excluded because %s/", e.Path, e.Directory)
+	}
+	switch e.Reason {
+	case ReasonOutside:
+		return fmt.Sprintf("%s is outside the workspace", e.Path)
+	case ReasonDotfile:
+		return fmt.Sprintf("%s is excluded: names starting with a dot are skipped", subject)
+	case ReasonSubmodule:
+		return fmt.Sprintf("%s is a submodule skipped by git.submodules", subject)
+	}
+	rule := e.Source
+	if e.Line > 0 {
+		rule = fmt.Sprintf("%s:%d", e.Source, e.Line)
+	}
+	if e.Pattern != "" {
+		rule = fmt.Sprintf("%q in %s", e.Pattern, rule)
+	}
+	if e.Directory != "" {
+		return fmt.Sprintf("%s is matched by %s", subject, rule)
+	}
+	return fmt.Sprintf("%s is excluded by %s", subject, rule)
+}
+
+// Explain tells which rule excludes a file named explicitly, as Excluded
+// decides it: the rule excluding the file itself or the outermost excluded
+// directory above it. For a path that isn't excluded, it reports any
+// negated pattern that re-included it.
+func (m *Matcher) Explain(path string) Explanation {
+	return m.explainPath(path, false)
+}
+
+// ExplainDir is Explain for a directory, which patterns ending in a slash
+// also match
+func (m *Matcher) ExplainDir(path string) Explanation {
+	if path == m.workspacePath {
+		return Explanation{Path: "."}
+	}
+	return m.explainPath(path, true)
+}
+
+// explainPath explains a file or directory and the directories above it
+func (m *Matcher) explainPath(path string, isDir bool) Explanation {
+	rel := relSlash(m.workspacePath, path)
+	if !isWithin(m.workspacePath, path) {
+		return Explanation{Path: rel, Excluded: true, Reason: ReasonOutside}
+	}
+
+	var dirs []string
+	for dir := filepath.Dir(path); dir != m.workspacePath && isWithin(m.workspacePath, dir); dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if e := m.explain(dirs[i], true); e.Excluded {
+			if e.Directory == "" {
+				e.Directory = e.Path
+			}
+			e.Path = rel
+			return e
+		}
+	}
+	return m.explain(path, isDir)
+}
+
+// explain applies the default ignores, editor artifacts, the config's
+// ignore patterns, hidden sensitive files, skipped submodules and the rules
+// of the repository containing path
+func (m *Matcher) explain(path string, isDir bool) Explanation {
+	relPath, err := filepath.Rel(m.workspacePath, path)
+	if err != nil {
+		// If we can't get relative path, don't ignore
+		return Explanation{Path: path}
+	}
+
+	// Convert to forward slashes for consistency with .gitignore patterns
+	relPath = pathnorm.NFC(filepath.ToSlash(relPath))
+	e := Explanation{Path: relPath}
+
+	// .env files are served although they are dotfiles, with their values
+	// masked unless the config says otherwise, so agents can see which
+	// settings a project needs; so are templates such as .env.example
+	envFile := !isDir && (dotenv.Is(path) || dotenv.IsExample(path))
+
+	// Skip dot files
+	if filepath.Base(path)[0] == '.' && !envFile {
+		e.Excluded, e.Reason = true, ReasonDotfile
+		return e
+	}
+
+	// Check default ignores first; like an excluded parent directory in
+	// git, they can't be undone by negated .gitignore patterns
+	if r, matched := m.defaultIgnores.decide(relPath, isDir); r != nil && !r.negate {
+		e = excludedBy(e, ReasonDefault, "the built-in default ignores", r, matched)
+		e.Line = 0
+		return e
+	}
+
+	// Editor swap and backup files would otherwise come and go as files
+	// are saved
+	if !isDir {
+		if r, matched := m.editorArtifacts.decide(relPath, false); r != nil && !r.negate {
+			e = excludedBy(e, ReasonEditor, "the editor artifact filters (watch.editorArtifacts)", r, matched)
+			e.Line = 0
+			return e
+		}
+	}
+
+	// The config's own patterns, including those of its profile
+	if r, matched := m.configIgnores.decide(relPath, isDir); r != nil && !r.negate {
+		e = excludedBy(e, ReasonConfig, "the config's ignore patterns", r, matched)
+		e.Line = 0
+		return e
+	}
+
+	// Hidden sensitive files are left out like ignored ones; the first
+	// class matching a path decides its policy
+	if path != m.workspacePath {
+		for _, class := range m.sensitive {
+			r, matched := class.rules.decide(relPath, isDir)
+			if r == nil || r.negate {
+				continue
+			}
+			if class.Policy == config.PolicyHide {
+				e = excludedBy(e, ReasonSensitive, fmt.Sprintf("sensitive class %q", class.Name), r, matched)
+				e.Line = 0
+				return e
+			}
+			break
+		}
+	}
+
+	for _, submodule := range m.submodules {
+		if isWithin(submodule, path) {
+			e.Excluded, e.Reason = true, ReasonSubmodule
+			if submodule != path {
+				e.Directory = relSlash(m.workspacePath, submodule)
+			}
+			return e
+		}
+	}
+
+	// They are almost always in .gitignore, which only applies to them if
+	// the config doesn't say otherwise; the directories above them are
+	// checked on their own
+	if envFile && m.envIgnored {
+		return e
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.repos {
+		// A repository root is matched by the repository containing it
+		if path != r.root && isWithin(r.root, path) {
+			return r.explain(m.workspacePath, e, path, isDir)
+		}
+	}
+
+	return e
+}
+
+// explain checks a path against a repository's .gitignore and sparse
+// checkout, filling in e
+func (r *repo) explain(workspacePath string, e Explanation, path string, isDir bool) Explanation {
+	if r.strict != nil {
+		if m, ok := r.strict.match(path); ok {
+			if e = r.explainStrict(workspacePath, e, m); e.Excluded {
+				return e
+			}
+			return r.explainSparse(e, path, isDir)
+		}
+	}
+
+	if rule, matched, dir := r.decide(relSlash(r.root, path), isDir); rule != nil {
+		// Rules are relative to the repository, explanations to the workspace
+		prefix := ""
+		if r.root != workspacePath {
+			prefix = relSlash(workspacePath, r.root) + "/"
+		}
+		source := prefix + ".gitignore"
+		if dir != "" {
+			source = prefix + dir + "/.gitignore"
+		}
+		if !rule.negate {
+			return excludedBy(e, ReasonGitignore, source, rule, prefix+matched)
+		}
+		e.Reason, e.Source, e.Line, e.Pattern = ReasonGitignore, source, rule.line, rule.pattern
+	}
+
+	return r.explainSparse(e, path, isDir)
+}
+
+// explainSparse excludes a path its repository's sparse checkout leaves out
+func (r *repo) explainSparse(e Explanation, path string, isDir bool) Explanation {
+	if r.sparse != nil && !r.sparse.includes(relSlash(r.gitRoot, path), isDir) {
+		return Explanation{Path: e.Path, Excluded: true, Reason: ReasonSparse, Source: "sparse checkout of " + r.gitRoot}
+	}
+	return e
+}
+
+// excludedBy fills in an explanation for a path excluded by rule, which
+// matched the workspace-relative path matched
+func excludedBy(e Explanation, reason, source string, r *rule, matched string) Explanation {
+	e.Excluded, e.Reason, e.Source = true, reason, source
+	if r != nil {
+		e.Line, e.Pattern = r.line, r.pattern
+	}
+	if matched != e.Path && matched != "" {
+		e.Directory = matched
+	}
+	return e
+}
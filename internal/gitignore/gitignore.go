@@ -1,84 +1,272 @@
 package gitignore
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
-	"github.com/sabhiram/go-gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
 )
 
 // Matcher provides functionality to check if files should be ignored
 type Matcher struct {
-	ignore         *ignore.GitIgnore
-	workspacePath  string
-	hasGitIgnore   bool
-	defaultIgnores []string
+	workspacePath   string
+	defaultPatterns []string
+	defaultIgnores  rules
+	editorArtifacts rules // temporary files editors create, never directories
+	configIgnores   rules // the ignore patterns of the config
+	// repos are the workspace and the repositories found inside it, innermost
+	// first, so a path is matched against the rules of the repository that
+	// contains it
+	repos      []*repo
+	submodules []string         // skipped submodule roots
+	sensitive  []sensitiveClass // first matching class decides a file's policy
+	virtual    bool             // the workspace isn't on disk, so it has no repositories
+	strict     bool             // git check-ignore decides what repositories ignore
+	envIgnored bool             // .env files are served even where .gitignore excludes them
+	mu         sync.RWMutex
 }
 
-// NewMatcher creates a new gitignore matcher for the given workspace
-func NewMatcher(workspacePath string) (*Matcher, error) {
-	// Default ignores - common patterns to ignore
-	defaultIgnores := []string{
-		".git/",
-		".DS_Store",
-		"node_modules/",
-	}
+// Repository is a git repository within the workspace
+type Repository struct {
+	// Path is the directory the repository covers in the workspace
+	Path string
+	// GitRoot is the repository's top-level directory. It is above Path when
+	// the workspace is a subdirectory of a repository.
+	GitRoot string
+	// Worktree is set for linked worktrees created with "git worktree add"
+	Worktree bool
+	// Sparse is set when a sparse checkout limits the paths exposed
+	Sparse bool
+}
 
+// defaultIgnores are common patterns ignored in every workspace
+var defaultIgnores = []string{
+	".git/",
+	".DS_Store",
+	"node_modules/",
+}
+
+// NewMatcher creates a gitignore matcher for the given workspace
+func NewMatcher(workspacePath string, cfg *config.Config) (*Matcher, error) {
 	matcher := &Matcher{
-		workspacePath:  workspacePath,
-		defaultIgnores: defaultIgnores,
+		workspacePath:   workspacePath,
+		defaultPatterns: defaultIgnores,
+		defaultIgnores:  compileRules(defaultIgnores),
+		editorArtifacts: compileRules(cfg.Watch.EditorArtifacts),
+		configIgnores:   compileRules(cfg.Ignore),
+		sensitive:       compileSensitive(cfg.Sensitive),
+		strict:          cfg.Git.StrictIgnore,
+		envIgnored:      cfg.DotEnvIgnored,
+	}
+
+	// The workspace may be a subdirectory of a repository; sparse checkout
+	// patterns are relative to the repository root
+	gitRoot := findRepoRoot(workspacePath)
+	workspaceRepo, err := loadRepo(workspacePath, gitRoot, matcher.strict)
+	if err != nil {
+		return nil, err
+	}
+	matcher.repos = append(matcher.repos, workspaceRepo)
+
+	// Submodules have their own ignore rules, which replace the parent's
+	if gitRoot != "" {
+		for _, path := range submodulePaths(gitRoot) {
+			if path != workspacePath && !strings.HasPrefix(path, workspacePath+string(filepath.Separator)) {
+				continue
+			}
+			if cfg.Git.Submodules == config.SubmodulesSkip {
+				matcher.submodules = append(matcher.submodules, path)
+				continue
+			}
+			if findGitDir(path) == "" {
+				// Not initialized; there is nothing on disk to walk
+				continue
+			}
+			submodule, err := loadRepo(path, path, matcher.strict)
+			if err != nil {
+				return nil, err
+			}
+			matcher.repos = append(matcher.repos, submodule)
+		}
 	}
+	matcher.sortRepos()
 
-	// Check if .gitignore exists
-	gitignorePath := filepath.Join(workspacePath, ".gitignore")
-	if _, err := os.Stat(gitignorePath); err == nil {
-		// Read .gitignore file
-		data, err := os.ReadFile(gitignorePath)
-		if err != nil {
-			return nil, err
+	// Find other repositories and worktrees nested in the workspace; ones
+	// created later are picked up as their directories are walked
+	_ = filepath.WalkDir(workspacePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if matcher.ShouldIgnoreDir(path) {
+			return filepath.SkipDir
 		}
+		return nil
+	})
 
-		// Parse .gitignore content
-		ignoreObj := ignore.CompileIgnoreLines(strings.Split(string(data), "\n")...)
+	return matcher, nil
+}
 
-		matcher.ignore = ignoreObj
-		matcher.hasGitIgnore = true
+// NewMatcherFS creates a gitignore matcher for a workspace that may not be
+// on disk. Workspaces on disk are matched as NewMatcher does; others have
+// no git repositories, only the .gitignore at their root.
+func NewMatcherFS(files *fsys.Workspace, cfg *config.Config) (*Matcher, error) {
+	if files.OnDisk() {
+		return NewMatcher(files.Root(), cfg)
 	}
 
+	matcher := &Matcher{
+		workspacePath:   files.Root(),
+		defaultPatterns: defaultIgnores,
+		defaultIgnores:  compileRules(defaultIgnores),
+		editorArtifacts: compileRules(cfg.Watch.EditorArtifacts),
+		configIgnores:   compileRules(cfg.Ignore),
+		sensitive:       compileSensitive(cfg.Sensitive),
+		virtual:         true,
+		envIgnored:      cfg.DotEnvIgnored,
+	}
+
+	r := &repo{root: files.Root()}
+	data, err := files.ReadFile(filepath.Join(files.Root(), ".gitignore"))
+	if err == nil {
+		r.ignore = compileRules(strings.Split(pathnorm.NFC(string(data)), "\n"))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	matcher.repos = []*repo{r}
 	return matcher, nil
 }
 
-// ShouldIgnore checks if a file should be ignored based on .gitignore rules
-func (m *Matcher) ShouldIgnore(path string) bool {
-	// Skip dot files
-	if filepath.Base(path)[0] == '.' {
-		return true
+// Repositories returns the git repositories found in the workspace, sorted by path
+func (m *Matcher) Repositories() []Repository {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var repos []Repository
+	for _, r := range m.repos {
+		if r.gitRoot != "" {
+			repos = append(repos, r.repository())
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Path < repos[j].Path
+	})
+	return repos
+}
+
+// DefaultIgnores returns the patterns ignored in every workspace, which
+// .gitignore files can't re-include
+func (m *Matcher) DefaultIgnores() []string {
+	return append([]string(nil), m.defaultPatterns...)
+}
+
+// SkippedSubmodules returns the roots of submodules left out of the workspace
+func (m *Matcher) SkippedSubmodules() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]string(nil), m.submodules...)
+}
+
+// RepositoryFor returns the innermost git repository containing path
+func (m *Matcher) RepositoryFor(path string) (Repository, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.repos {
+		if path == r.root || isWithin(r.root, path) {
+			if r.gitRoot == "" {
+				break
+			}
+			return r.repository(), true
+		}
+	}
+	return Repository{}, false
+}
+
+// repository describes a repo for callers outside the package
+func (r *repo) repository() Repository {
+	return Repository{Path: r.root, GitRoot: r.gitRoot, Worktree: isWorktree(r.gitRoot), Sparse: r.sparse != nil}
+}
+
+// discoverRepo registers a repository rooted at dir if it hasn't been seen
+func (m *Matcher) discoverRepo(dir string) {
+	if m.virtual || findGitDir(dir) == "" {
+		return
 	}
 
-	// Check against default ignores
-	relPath, err := filepath.Rel(m.workspacePath, path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.repos {
+		if r.root == dir {
+			return
+		}
+	}
+	r, err := loadRepo(dir, dir, m.strict)
 	if err != nil {
-		// If we can't get relative path, don't ignore
-		return false
+		return
+	}
+	m.repos = append(m.repos, r)
+	m.sortRepos()
+}
+
+// discoverIgnoreFile applies the .gitignore of a directory below a
+// repository root, read as the directory is walked like the repositories
+// in the workspace. Workspaces not on disk only have the root one.
+func (m *Matcher) discoverIgnoreFile(dir string) {
+	if m.virtual {
+		return
 	}
 
-	// Convert to forward slashes for consistency (go-gitignore expects this)
-	relPath = filepath.ToSlash(relPath)
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Check default ignores first
-	for _, pattern := range m.defaultIgnores {
-		if strings.HasPrefix(relPath, pattern) || relPath == pattern {
-			return true
+	for _, r := range m.repos {
+		if r.root == dir {
+			return
+		}
+		if isWithin(r.root, dir) {
+			r.loadNested(dir)
+			return
 		}
 	}
+}
+
+// IgnoreFiles returns the .gitignore files applied, sorted
+func (m *Matcher) IgnoreFiles() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	// Check .gitignore rules if available
-	if m.hasGitIgnore {
-		return m.ignore.MatchesPath(relPath)
+	var files []string
+	for _, r := range m.repos {
+		if _, err := os.Stat(filepath.Join(r.root, ".gitignore")); err == nil {
+			files = append(files, filepath.Join(r.root, ".gitignore"))
+		}
+		for dir := range r.nested {
+			files = append(files, filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore"))
+		}
 	}
+	sort.Strings(files)
+	return files
+}
 
-	return false
+// sortRepos orders repositories innermost first
+func (m *Matcher) sortRepos() {
+	sort.SliceStable(m.repos, func(i, j int) bool {
+		return len(m.repos[i].root) > len(m.repos[j].root)
+	})
+}
+
+// ShouldIgnore checks if a file should be ignored based on .gitignore rules
+func (m *Matcher) ShouldIgnore(path string) bool {
+	return m.shouldIgnore(path, false)
 }
 
 // ShouldIgnoreDir checks if a directory should be ignored
@@ -87,6 +275,40 @@ func (m *Matcher) ShouldIgnoreDir(path string) bool {
 	if path == m.workspacePath {
 		return false
 	}
-	
-	return m.ShouldIgnore(path)
+
+	if m.shouldIgnore(path, true) {
+		return true
+	}
+
+	// A repository's own rules apply below it, but whether the repository
+	// itself is ignored is up to the one containing it
+	m.discoverRepo(path)
+	m.discoverIgnoreFile(path)
+	return false
+}
+
+// Excluded reports whether a file named explicitly, rather than found by
+// walking the workspace, is left out: the file itself or any directory
+// above it in the workspace is ignored or hidden
+func (m *Matcher) Excluded(path string) bool {
+	return m.Explain(path).Excluded
+}
+
+// shouldIgnore reports whether a path is left out by the rules explain applies
+func (m *Matcher) shouldIgnore(path string, isDir bool) bool {
+	return m.explain(path, isDir).Excluded
+}
+
+// isWithin reports whether path is root or inside it
+func isWithin(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// relSlash returns path relative to root with forward slashes, in NFC
+func relSlash(root, path string) string {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return pathnorm.NFC(filepath.ToSlash(relPath))
 }
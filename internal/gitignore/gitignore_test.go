@@ -0,0 +1,191 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+)
+
+// workspace creates files in a new directory, with directories for names
+// ending in a slash, and returns it
+func workspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestMatcherPrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// excluded maps paths to whether they are left out; names ending in
+		// a slash are directories
+		excluded map[string]bool
+	}{
+		{
+			name: "negation after glob",
+			files: map[string]string{
+				".gitignore":         "*.log\n!important.log\n",
+				"debug.log":          "",
+				"important.log":      "",
+				"logs/important.log": "",
+				"logs/trace.log":     "",
+			},
+			excluded: map[string]bool{
+				"debug.log":          true,
+				"important.log":      false,
+				"logs/important.log": false,
+				"logs/trace.log":     true,
+			},
+		},
+		{
+			name: "negation under the default node_modules ignore",
+			files: map[string]string{
+				".gitignore":                "!node_modules/\n!node_modules/pkg/keep.js\n",
+				"node_modules/pkg/keep.js":  "",
+				"node_modules/pkg/index.js": "",
+			},
+			excluded: map[string]bool{
+				"node_modules/":             true,
+				"node_modules/pkg/keep.js":  true,
+				"node_modules/pkg/index.js": true,
+			},
+		},
+		{
+			name: "negation in an excluded directory",
+			files: map[string]string{
+				".gitignore":     "build/\n!build/keep.txt\n",
+				"build/keep.txt": "",
+				"build/out.o":    "",
+				"src/build":      "",
+			},
+			excluded: map[string]bool{
+				"build/":         true,
+				"build/keep.txt": true,
+				"build/out.o":    true,
+				"src/build":      false,
+			},
+		},
+		{
+			name: "anchored and unanchored patterns",
+			files: map[string]string{
+				".gitignore":        "/root.txt\ndocs/*.md\ntmp\n",
+				"root.txt":          "",
+				"sub/root.txt":      "",
+				"docs/a.md":         "",
+				"sub/docs/a.md":     "",
+				"docs/api/b.md":     "",
+				"tmp/x":             "",
+				"sub/deeper/tmp":    "",
+				"sub/deeper/tmp.go": "",
+			},
+			excluded: map[string]bool{
+				"root.txt":          true,
+				"sub/root.txt":      false,
+				"docs/a.md":         true,
+				"sub/docs/a.md":     false,
+				"docs/api/b.md":     false,
+				"tmp/":              true,
+				"tmp/x":             true,
+				"sub/deeper/tmp":    true,
+				"sub/deeper/tmp.go": false,
+			},
+		},
+		{
+			name: "nested .gitignore overrides",
+			files: map[string]string{
+				".gitignore":            "*.log\nbuild/\n",
+				"sub/.gitignore":        "!keep.log\nsecret.txt\n/local.txt\n",
+				"keep.log":              "",
+				"sub/keep.log":          "",
+				"sub/deeper/keep.log":   "",
+				"sub/other.log":         "",
+				"secret.txt":            "",
+				"sub/secret.txt":        "",
+				"sub/deeper/secret.txt": "",
+				"sub/local.txt":         "",
+				"sub/deeper/local.txt":  "",
+				"sub/build/.gitignore":  "!*\n",
+				"sub/build/out.o":       "",
+			},
+			excluded: map[string]bool{
+				"keep.log":              true,
+				"sub/keep.log":          false,
+				"sub/deeper/keep.log":   false,
+				"sub/other.log":         true,
+				"secret.txt":            false,
+				"sub/secret.txt":        true,
+				"sub/deeper/secret.txt": true,
+				"sub/local.txt":         true,
+				"sub/deeper/local.txt":  false,
+				"sub/build/out.o":       true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := workspace(t, tt.files)
+			m, err := NewMatcher(root, config.Default())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tt.excluded {
+				path := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(name, "/")))
+				var e Explanation
+				if strings.HasSuffix(name, "/") {
+					e = m.ExplainDir(path)
+				} else {
+					e = m.Explain(path)
+				}
+				if e.Excluded != want {
+					t.Errorf("%s: excluded = %v, want %v (%+v)", name, e.Excluded, want, e)
+				}
+			}
+		})
+	}
+}
+
+func TestNestedGitignoreExplained(t *testing.T) {
+	root := workspace(t, map[string]string{
+		".gitignore":     "*.log\n",
+		"sub/.gitignore": "# comment\n!keep.log\nsecret.txt\n",
+		"sub/keep.log":   "",
+		"sub/secret.txt": "",
+	})
+	m, err := NewMatcher(root, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := m.Explain(filepath.Join(root, "sub", "secret.txt"))
+	if !e.Excluded || e.Source != "sub/.gitignore" || e.Line != 3 || e.Pattern != "secret.txt" {
+		t.Errorf("sub/secret.txt: %+v, want excluded by sub/.gitignore:3", e)
+	}
+	e = m.Explain(filepath.Join(root, "sub", "keep.log"))
+	if e.Excluded || e.Source != "sub/.gitignore" || e.Pattern != "!keep.log" {
+		t.Errorf("sub/keep.log: %+v, want re-included by sub/.gitignore", e)
+	}
+
+	want := []string{filepath.Join(root, ".gitignore"), filepath.Join(root, "sub", ".gitignore")}
+	if got := m.IgnoreFiles(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("IgnoreFiles() = %v, want %v", got, want)
+	}
+}
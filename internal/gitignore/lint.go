@@ -0,0 +1,71 @@
+package gitignore
+
+import (
+	"strings"
+)
+
+// Issue is a problem found in an ignore file
+type Issue struct {
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+	Message string `json:"message"`
+}
+
+// Lint reports .gitignore patterns that are likely mistakes, so ignore rules
+// that silently don't apply are visible
+func Lint(content string) []Issue {
+	var issues []Issue
+	var excludedDirs []string
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		pattern := strings.TrimSpace(line)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		add := func(message string) {
+			issues = append(issues, Issue{Line: i + 1, Pattern: line, Message: message})
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			add("leading whitespace is part of the pattern")
+		}
+		if open := strings.LastIndex(pattern, "["); open >= 0 && !strings.Contains(pattern[open:], "]") {
+			add("unterminated character class; the pattern never matches")
+		}
+		if hasBackslashSeparator(pattern) {
+			add("'\\' escapes the next character; use '/' as the path separator")
+		}
+
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			negated = strings.TrimPrefix(negated, "/")
+			for _, dir := range excludedDirs {
+				if strings.HasPrefix(negated, dir) && negated != dir {
+					add("git can't re-include a path inside an excluded directory (" + dir + ")")
+					break
+				}
+			}
+		} else if strings.HasSuffix(pattern, "/") && !strings.ContainsAny(pattern, "*?[") {
+			excludedDirs = append(excludedDirs, strings.TrimPrefix(pattern, "/"))
+		}
+	}
+
+	return issues
+}
+
+// hasBackslashSeparator reports whether a pattern uses '\' before a name
+// character, which is almost always a Windows path separator rather than an escape
+func hasBackslashSeparator(pattern string) bool {
+	for i := 0; i+1 < len(pattern); i++ {
+		if pattern[i] != '\\' {
+			continue
+		}
+		next := pattern[i+1]
+		if next >= 'a' && next <= 'z' || next >= 'A' && next <= 'Z' || next >= '0' && next <= '9' {
+			return true
+		}
+		i++
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package gitignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// rule is a single .gitignore pattern. It matches a path itself; whether the
+// path's parent directories are matched is decided by rules.matches.
+type rule struct {
+	expr    *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	pattern string // the line as written, to explain matches
+	line    int    // 1-based line number in its file
+}
+
+// rules are the patterns of an ignore file in the order they appear
+type rules []rule
+
+// compileRules parses the lines of a .gitignore file, skipping blank lines,
+// comments and patterns that can't match anything
+func compileRules(lines []string) rules {
+	var compiled rules
+	for i, line := range lines {
+		if r, ok := compileRule(line); ok {
+			r.pattern = strings.TrimSpace(line)
+			r.line = i + 1
+			compiled = append(compiled, r)
+		}
+	}
+	return compiled
+}
+
+// matches reports whether a slash-separated relative path is matched the way
+// git decides a path is ignored: the last pattern matching the path wins,
+// except that nothing inside a matched directory can be re-included
+func (rs rules) matches(relPath string, isDir bool) bool {
+	r, _ := rs.decide(relPath, isDir)
+	return r != nil && !r.negate
+}
+
+// decide returns the rule deciding whether a path is ignored and the path
+// it matched: a directory above relPath that is ignored, or else relPath
+// itself, ignored unless the rule is negated. It returns nil if no rule
+// matches.
+func (rs rules) decide(relPath string, isDir bool) (*rule, string) {
+	for i := 0; i < len(relPath); i++ {
+		if relPath[i] != '/' {
+			continue
+		}
+		if r := rs.last(relPath[:i], true); r != nil && !r.negate {
+			return r, relPath[:i]
+		}
+	}
+	return rs.last(relPath, isDir), relPath
+}
+
+// last returns the last pattern matching the path itself, or nil
+func (rs rules) last(relPath string, isDir bool) *rule {
+	var matched *rule
+	for i, r := range rs {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.expr.MatchString(relPath) {
+			matched = &rs[i]
+		}
+	}
+	return matched
+}
+
+// compileRule translates a .gitignore line into a regular expression
+func compileRule(line string) (rule, bool) {
+	var r rule
+
+	line = strings.TrimRight(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || line[0] == '#' {
+		return r, false
+	}
+
+	if line[0] == '!' {
+		r.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// directory of the .gitignore; otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	body, ok := globToRegexp(line)
+	if !ok {
+		return r, false
+	}
+	if anchored {
+		body = "^" + body + "$"
+	} else {
+		body = "^(?:.*/)?" + body + "$"
+	}
+
+	expr, err := regexp.Compile(body)
+	if err != nil {
+		return r, false
+	}
+	r.expr = expr
+	return r, true
+}
+
+// trimTrailingSpaces removes trailing spaces unless they are escaped with a
+// backslash
+func trimTrailingSpaces(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		if end > 1 && line[end-2] == '\\' {
+			break
+		}
+		end--
+	}
+	return line[:end]
+}
+
+// globToRegexp translates gitignore glob syntax. It returns false for
+// patterns git would never match, such as an unterminated character class.
+func globToRegexp(glob string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				leading := i == 0 || glob[i-1] == '/'
+				trailing := i+2 == len(glob) || glob[i+2] == '/'
+				if leading && trailing {
+					i++
+					switch {
+					case i+1 == len(glob):
+						// "dir/**" matches everything inside dir
+						b.WriteString(".*")
+					default:
+						// "**/" matches zero or more directories
+						i++
+						b.WriteString("(?:.*/)?")
+					}
+					continue
+				}
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			class, n, ok := characterClass(glob[i:])
+			if !ok {
+				return "", false
+			}
+			b.WriteString(class)
+			i += n - 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String(), true
+}
+
+// characterClass translates a bracket expression at the start of glob and
+// returns it with the number of bytes consumed
+func characterClass(glob string) (string, int, bool) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		b.WriteString("^")
+		i++
+	}
+	// A "]" right after the opening bracket is part of the class
+	if i < len(glob) && glob[i] == ']' {
+		b.WriteString(`\]`)
+		i++
+	}
+	for ; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case ']':
+			b.WriteString("]")
+			return b.String(), i + 1, true
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[', '^':
+			b.WriteString(`\` + string(c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// CompileGlob compiles a glob matched against slash-separated paths relative
+// to the workspace, using .gitignore syntax: "*" and "?" don't match "/", and
+// "**" matches any number of directories
+func CompileGlob(glob string) (*regexp.Regexp, error) {
+	body, ok := globToRegexp(strings.TrimPrefix(glob, "/"))
+	if !ok {
+		return nil, fmt.Errorf("invalid glob %q: unterminated character class", glob)
+	}
+	return regexp.Compile("^" + body + "$")
+}
@@ -0,0 +1,47 @@
+package gitignore
+
+import "testing"
+
+func TestRulesMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		// The last matching pattern wins
+		{"glob", []string{"*.log"}, "debug.log", false, true},
+		{"glob in subdirectory", []string{"*.log"}, "logs/debug.log", false, true},
+		{"negated after glob", []string{"*.log", "!important.log"}, "important.log", false, false},
+		{"negated in subdirectory", []string{"*.log", "!important.log"}, "logs/important.log", false, false},
+		{"other files still ignored", []string{"*.log", "!important.log"}, "debug.log", false, true},
+		{"glob after negation wins", []string{"!important.log", "*.log"}, "important.log", false, true},
+
+		// Nothing in an excluded directory can be re-included
+		{"excluded directory", []string{"build/", "!build/keep.txt"}, "build", true, true},
+		{"file in excluded directory", []string{"build/", "!build/keep.txt"}, "build/keep.txt", false, true},
+		{"directory pattern skips files", []string{"build/"}, "build", false, false},
+		{"directory contents re-included", []string{"build/*", "!build/keep.txt"}, "build/keep.txt", false, false},
+		{"other directory contents", []string{"build/*", "!build/keep.txt"}, "build/out.o", false, true},
+
+		// A slash at the start or in the middle anchors a pattern
+		{"leading slash at root", []string{"/root.txt"}, "root.txt", false, true},
+		{"leading slash below root", []string{"/root.txt"}, "sub/root.txt", false, false},
+		{"middle slash at root", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"middle slash below root", []string{"docs/*.md"}, "sub/docs/a.md", false, false},
+		{"middle slash doesn't cross directories", []string{"docs/*.md"}, "docs/api/a.md", false, false},
+		{"unanchored at root", []string{"tmp"}, "tmp", true, true},
+		{"unanchored below root", []string{"tmp"}, "a/b/tmp", false, true},
+		{"unanchored directory below root", []string{"tmp/"}, "a/tmp/x.txt", false, true},
+		{"double star", []string{"**/cache/*.bin"}, "a/b/cache/x.bin", false, true},
+		{"double star at root", []string{"**/cache/*.bin"}, "cache/x.bin", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compileRules(tt.patterns).matches(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("%q matching %q (dir %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
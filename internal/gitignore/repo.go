@@ -0,0 +1,309 @@
+package gitignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// repo holds the ignore rules of one git repository in the workspace
+type repo struct {
+	root    string
+	ignore  rules            // patterns of the repository's root .gitignore
+	nested  map[string]rules // .gitignore files below root, by slash-separated directory
+	sparse  *sparseCheckout  // nil unless sparse checkout is enabled
+	gitRoot string           // top-level directory, "" outside a repository
+	strict  *checkIgnore     // asks git instead of ignore, with git.strictIgnore
+}
+
+// sparseCheckout describes which paths a sparse checkout materializes
+type sparseCheckout struct {
+	cone bool
+	// Cone mode: directories included with everything below them, and
+	// directories whose direct files only are included ("" is the root)
+	recursive []string
+	parents   map[string]bool
+	// Non-cone mode: gitignore-style patterns selecting included files
+	patterns rules
+}
+
+// loadRepo reads the .gitignore in root and the sparse-checkout settings of
+// the repository at gitRoot, which is "" if root isn't in a repository.
+// With strict, git decides what the repository ignores.
+func loadRepo(root, gitRoot string, strict bool) (*repo, error) {
+	r := &repo{root: root, gitRoot: gitRoot}
+
+	data, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if err == nil {
+		// Parse .gitignore content, normalized like the paths it is matched against
+		r.ignore = compileRules(strings.Split(pathnorm.NFC(string(data)), "\n"))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if gitRoot != "" {
+		r.sparse = loadSparseCheckout(findGitDir(gitRoot))
+		if strict {
+			r.strict = newCheckIgnore(gitRoot)
+		}
+	}
+
+	return r, nil
+}
+
+// decide returns the rule deciding whether a path relative to the
+// repository is ignored, the path it matched and the directory of the
+// .gitignore it is in, as rules.decide does for a single file: a
+// .gitignore's patterns are relative to its directory, and those of a
+// deeper one take precedence
+func (r *repo) decide(relPath string, isDir bool) (*rule, string, string) {
+	for i := 0; i < len(relPath); i++ {
+		if relPath[i] != '/' {
+			continue
+		}
+		if rule, dir := r.last(relPath[:i], true); rule != nil && !rule.negate {
+			return rule, relPath[:i], dir
+		}
+	}
+	rule, dir := r.last(relPath, isDir)
+	return rule, relPath, dir
+}
+
+// last returns the last pattern matching the path itself in the deepest
+// .gitignore with one, and that file's directory
+func (r *repo) last(relPath string, isDir bool) (*rule, string) {
+	for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+		if rule := r.nested[dir].last(relPath[len(dir)+1:], isDir); rule != nil {
+			return rule, dir
+		}
+	}
+	return r.ignore.last(relPath, isDir), ""
+}
+
+// loadNested reads the .gitignore of a directory below the repository
+// root, if there is one
+func (r *repo) loadNested(dir string) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	if r.nested == nil {
+		r.nested = make(map[string]rules)
+	}
+	r.nested[relSlash(r.root, dir)] = compileRules(strings.Split(pathnorm.NFC(string(data)), "\n"))
+}
+
+// findGitDir returns the git directory for a repository root, following the
+// "gitdir:" file used by worktrees and submodules. It returns "" if root is
+// not a repository root.
+func findGitDir(root string) string {
+	dotGit := filepath.Join(root, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+	return filepath.Clean(gitDir)
+}
+
+// isWorktree reports whether root is a linked worktree, whose git directory
+// points back to the main repository's through a commondir file
+func isWorktree(root string) bool {
+	gitDir := findGitDir(root)
+	if gitDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(gitDir, "commondir"))
+	return err == nil
+}
+
+// findRepoRoot returns the innermost directory at or above dir that is a
+// repository root, or "" if there is none
+func findRepoRoot(dir string) string {
+	for {
+		if findGitDir(dir) != "" {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// gitConfigBool reads a boolean "section.key" from the repository's config
+// files. Worktrees keep settings in config.worktree and the shared config
+// in the common directory.
+func gitConfigBool(gitDir, section, key string) bool {
+	files := []string{
+		filepath.Join(gitDir, "config.worktree"),
+		filepath.Join(gitDir, "config"),
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		files = append(files, filepath.Join(common, "config"))
+	}
+
+	for _, file := range files {
+		if value, ok := readGitConfig(file, section, key); ok {
+			return value == "true" || value == "yes" || value == "on" || value == "1"
+		}
+	}
+	return false
+}
+
+// readGitConfig finds a key in a git config file. Section and key names
+// are case-insensitive.
+func readGitConfig(path, section, key string) (string, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	current := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if current != strings.ToLower(section) || !strings.EqualFold(strings.TrimSpace(name), key) {
+			continue
+		}
+		if !found {
+			// A key without a value is true
+			return "true", true
+		}
+		return strings.ToLower(strings.TrimSpace(value)), true
+	}
+	return "", false
+}
+
+// loadSparseCheckout reads the sparse-checkout patterns of a repository, or
+// returns nil if sparse checkout isn't enabled
+func loadSparseCheckout(gitDir string) *sparseCheckout {
+	if !gitConfigBool(gitDir, "core", "sparseCheckout") {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "info", "sparse-checkout"))
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(pathnorm.NFC(string(data)), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	sparse := &sparseCheckout{cone: gitConfigBool(gitDir, "core", "sparseCheckoutCone")}
+	if !sparse.cone {
+		sparse.patterns = compileRules(lines)
+		return sparse
+	}
+
+	// Cone mode lists "/dir/" for included directories and "!/dir/*/" for
+	// directories that only contribute their direct files
+	sparse.parents = make(map[string]bool)
+	included := make(map[string]bool)
+	for _, line := range lines {
+		switch {
+		case line == "/*":
+			sparse.parents[""] = true
+		case strings.HasPrefix(line, "!/") && strings.HasSuffix(line, "/*/"):
+			sparse.parents[strings.TrimSuffix(line[2:], "/*/")] = true
+		case strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/"):
+			included[strings.Trim(line, "/")] = true
+		}
+	}
+	for dir := range included {
+		if !sparse.parents[dir] {
+			sparse.recursive = append(sparse.recursive, dir)
+		}
+	}
+	return sparse
+}
+
+// includes reports whether a repository-relative path is materialized
+func (s *sparseCheckout) includes(relPath string, isDir bool) bool {
+	if !s.cone {
+		// Directories can't be judged by file patterns; their files are
+		return isDir || s.patterns.matches(relPath, false)
+	}
+
+	for _, dir := range s.recursive {
+		if relPath == dir || strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+		// Ancestors of included directories must be walked to reach them
+		if isDir && strings.HasPrefix(dir, relPath+"/") {
+			return true
+		}
+	}
+
+	if isDir {
+		for dir := range s.parents {
+			if dir == relPath || strings.HasPrefix(dir, relPath+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	parent := ""
+	if i := strings.LastIndex(relPath, "/"); i >= 0 {
+		parent = relPath[:i]
+	}
+	return s.parents[parent]
+}
+
+// submodulePaths returns the absolute paths of submodules declared in a
+// repository's .gitmodules
+func submodulePaths(root string) []string {
+	file, err := os.Open(filepath.Join(root, ".gitmodules"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if found && strings.TrimSpace(name) == "path" {
+			paths = append(paths, filepath.Join(root, filepath.FromSlash(strings.TrimSpace(value))))
+		}
+	}
+	return paths
+}
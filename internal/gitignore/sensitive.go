@@ -0,0 +1,112 @@
+package gitignore
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// sensitiveClass is a configured class of sensitive files
+type sensitiveClass struct {
+	config.SensitiveClass
+	rules rules
+}
+
+// SensitiveError is an access refused by the policy of a sensitive file
+// class. It matches fs.ErrPermission.
+type SensitiveError struct {
+	Class  string
+	Policy string
+}
+
+func (e *SensitiveError) Error() string {
+	switch e.Policy {
+	case config.PolicyHide:
+		return fmt.Sprintf("hidden by sensitive file class %q", e.Class)
+	case config.PolicyRedact:
+		return fmt.Sprintf("content withheld by sensitive file class %q", e.Class)
+	case config.PolicyReadOnly:
+		return fmt.Sprintf("read-only by sensitive file class %q", e.Class)
+	default:
+		// MCP elicitation is newer than the protocol version this server
+		// speaks, so it has no way to ask the user
+		return fmt.Sprintf("sensitive file class %q needs the user's approval, which this server can't ask for; ask the user to share the file", e.Class)
+	}
+}
+
+// ErrorCode reports hidden files as missing and read-only files as such
+func (e *SensitiveError) ErrorCode() errcode.Code {
+	switch e.Policy {
+	case config.PolicyHide:
+		return errcode.NotFound
+	case config.PolicyReadOnly:
+		return errcode.ReadOnly
+	}
+	return ""
+}
+
+// Is makes errors.Is(err, fs.ErrPermission) true
+func (e *SensitiveError) Is(target error) bool {
+	return target == fs.ErrPermission
+}
+
+// compileSensitive compiles the configured sensitive file classes
+func compileSensitive(classes []config.SensitiveClass) []sensitiveClass {
+	var compiled []sensitiveClass
+	for _, class := range classes {
+		compiled = append(compiled, sensitiveClass{SensitiveClass: class, rules: compileRules(class.Patterns)})
+	}
+	return compiled
+}
+
+// Sensitive returns the first configured class matching path
+func (m *Matcher) Sensitive(path string) (config.SensitiveClass, bool) {
+	return m.sensitiveClass(path, false)
+}
+
+// sensitiveClass matches a file or directory against the sensitive file
+// classes. A class matching a directory covers everything inside it.
+func (m *Matcher) sensitiveClass(path string, isDir bool) (config.SensitiveClass, bool) {
+	if len(m.sensitive) == 0 || !isWithin(m.workspacePath, path) || path == m.workspacePath {
+		return config.SensitiveClass{}, false
+	}
+	relPath := relSlash(m.workspacePath, path)
+	for _, class := range m.sensitive {
+		if class.rules.matches(relPath, isDir) {
+			return class.SensitiveClass, true
+		}
+	}
+	return config.SensitiveClass{}, false
+}
+
+// CanStat refuses describing hidden files
+func (m *Matcher) CanStat(path string) error {
+	class, ok := m.Sensitive(filepath.Clean(path))
+	if !ok || class.Policy != config.PolicyHide {
+		return nil
+	}
+	return &SensitiveError{Class: class.Name, Policy: class.Policy}
+}
+
+// CanRead refuses reading the content of files that are hidden, redacted or
+// need approval
+func (m *Matcher) CanRead(path string) error {
+	class, ok := m.Sensitive(filepath.Clean(path))
+	if !ok || class.Policy == config.PolicyReadOnly {
+		return nil
+	}
+	return &SensitiveError{Class: class.Name, Policy: class.Policy}
+}
+
+// CanWrite refuses changing files that are hidden, read-only or need
+// approval
+func (m *Matcher) CanWrite(path string) error {
+	class, ok := m.Sensitive(filepath.Clean(path))
+	if !ok || class.Policy == config.PolicyRedact {
+		return nil
+	}
+	return &SensitiveError{Class: class.Name, Policy: class.Policy}
+}
@@ -0,0 +1,142 @@
+package gitignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// checkIgnore asks git which pattern decides whether a path is ignored,
+// for git.strictIgnore. One "git check-ignore --stdin" runs per repository
+// for as long as the server, so each path costs a round trip rather than a
+// process. It sees everything git does: nested .gitignore files,
+// .git/info/exclude and core.excludesFile.
+type checkIgnore struct {
+	gitRoot string
+	in      io.WriteCloser
+	out     *bufio.Reader
+	started bool
+	failed  bool
+	mu      sync.Mutex
+}
+
+// gitMatch is the pattern git check-ignore reports for a path. Pattern is
+// "" if none matches and starts with "!" if it re-includes the path.
+type gitMatch struct {
+	source  string
+	line    int
+	pattern string
+}
+
+// newCheckIgnore creates a checker for the repository at gitRoot; git is
+// started by the first check
+func newCheckIgnore(gitRoot string) *checkIgnore {
+	return &checkIgnore{gitRoot: gitRoot}
+}
+
+// start runs git check-ignore reading NUL-separated paths and answering
+// each with the deciding pattern, matched or not. --no-index matches
+// tracked files by their patterns too, as the built-in rules do.
+func (c *checkIgnore) start() error {
+	cmd := exec.Command("git", "-C", c.gitRoot, "check-ignore", "--stdin", "-z", "--verbose", "--non-matching", "--no-index")
+	// git only flushes each answer when asked to
+	cmd.Env = append(os.Environ(), "GIT_FLUSH=1")
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// git exits when the server does and its stdin closes
+	go func() { _ = cmd.Wait() }()
+	c.in, c.out = in, bufio.NewReader(out)
+	return nil
+}
+
+// match returns the pattern git applies to path, or false if git can't be
+// asked, in which case the built-in rules decide
+func (c *checkIgnore) match(path string) (gitMatch, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed {
+		return gitMatch{}, false
+	}
+	rel, err := filepath.Rel(c.gitRoot, pathnorm.OnDisk(path))
+	if err != nil || strings.IndexByte(rel, 0) >= 0 {
+		return gitMatch{}, false
+	}
+
+	m, err := c.ask(filepath.ToSlash(rel))
+	if err != nil {
+		c.failed = true
+		if c.in != nil {
+			c.in.Close()
+		}
+		log.Printf("Warning: git check-ignore failed in %s; matching .gitignore patterns without git: %v", c.gitRoot, err)
+		return gitMatch{}, false
+	}
+	return m, true
+}
+
+// ask sends one path to git and reads its answer: source, line, pattern and
+// path, each terminated by a NUL
+func (c *checkIgnore) ask(rel string) (gitMatch, error) {
+	if !c.started {
+		c.started = true
+		if err := c.start(); err != nil {
+			return gitMatch{}, err
+		}
+	}
+	if _, err := io.WriteString(c.in, rel+"\x00"); err != nil {
+		return gitMatch{}, err
+	}
+	var fields [4]string
+	for i := range fields {
+		field, err := c.out.ReadString(0)
+		if err != nil {
+			return gitMatch{}, err
+		}
+		fields[i] = strings.TrimSuffix(field, "\x00")
+	}
+	if fields[3] != rel {
+		return gitMatch{}, fmt.Errorf("asked about %q, answered about %q", rel, fields[3])
+	}
+	m := gitMatch{source: fields[0], pattern: pathnorm.NFC(fields[2])}
+	if fields[1] != "" {
+		m.line, _ = strconv.Atoi(fields[1])
+	}
+	return m, nil
+}
+
+// explainStrict fills in e from the pattern git applies to path, as
+// repo.explain does from the built-in rules
+func (r *repo) explainStrict(workspacePath string, e Explanation, m gitMatch) Explanation {
+	if m.pattern == "" {
+		return e
+	}
+	// Sources are relative to the repository unless they are global
+	source := m.source
+	if !filepath.IsAbs(source) {
+		source = relSlash(workspacePath, filepath.Join(r.gitRoot, filepath.FromSlash(source)))
+	}
+	if pattern, negated := strings.CutPrefix(m.pattern, "!"); negated && pattern != "" {
+		e.Reason, e.Source, e.Line, e.Pattern = ReasonGitignore, source, m.line, m.pattern
+		return e
+	}
+	e.Excluded, e.Reason, e.Source, e.Line, e.Pattern = true, ReasonGitignore, source, m.line, m.pattern
+	return e
+}
@@ -0,0 +1,546 @@
+package index
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"regexp/syntax"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/docs"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/iosched"
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// Index is a trigram index of the text files in a workspace, with the
+// symbols defined in each and the metadata of Markdown documents. It narrows a search to the files that contain
+// every trigram of a literal; callers still confirm matches by reading the
+// candidates. Files are indexed by a background worker from a priority
+// queue, so indexing never blocks reads or tool calls.
+type Index struct {
+	workspacePath string
+	workspace     *fsys.Workspace
+	cachePath     string // where the index is persisted; "" to keep it in memory
+	maxFileSize   int64
+	scheduler     *iosched.Scheduler // paces the worker's reads
+	debug         bool
+
+	files    []*entry         // by id; nil once removed
+	ids      map[string]int32 // path to id of its current entry
+	postings map[uint32][]int32
+	stale    int             // removed entries still referenced by postings
+	dirty    map[string]bool // files changed since their entry was made
+	ready    bool            // every file is indexed or marked dirty
+	queue    *queue
+	wake     chan struct{}
+	idleSave bool // save once the queue drains
+	disabled bool // dropped to save memory; searches read every file
+	mu       sync.RWMutex
+	saveMu   sync.Mutex // one save at a time, so stopping waits for a background save
+}
+
+// entry is an indexed file
+type entry struct {
+	path    string
+	size    int64
+	modTime int64
+	symbols []symbols.Symbol
+	doc     *docs.Document // nil unless the file is a Markdown document
+	used    atomic.Int64   // when a search or lookup last used the entry
+}
+
+// Status is the progress of the index
+type Status struct {
+	// Ready is set once every workspace file is indexed or queued
+	Ready   bool `json:"ready"`
+	Indexed int  `json:"indexedFiles"`
+	Queued  int  `json:"queuedFiles"`
+	Symbols int  `json:"symbols"`
+	// CachePath is where the index is persisted, if it is
+	CachePath string `json:"cachePath,omitempty"`
+	// Disabled is set once the index was dropped to save memory
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// New creates an empty index; files larger than maxFileSize aren't indexed
+// but are always searched. The worker reads files within the scheduler's budgets.
+func New(files *fsys.Workspace, cachePath string, maxFileSize int64, scheduler *iosched.Scheduler, debug bool) *Index {
+	idx := &Index{
+		workspacePath: files.Root(),
+		workspace:     files,
+		cachePath:     cachePath,
+		maxFileSize:   maxFileSize,
+		scheduler:     scheduler,
+		debug:         debug,
+		ids:           make(map[string]int32),
+		postings:      make(map[uint32][]int32),
+		dirty:         make(map[string]bool),
+		queue:         newQueue(),
+		wake:          make(chan struct{}, 1),
+	}
+	go idx.work()
+	return idx
+}
+
+// Revalidate brings the index up to date with files in the background:
+// entries whose size or modification time changed are queued for
+// rebuilding, new files are queued and missing ones dropped. Searches use
+// the index as soon as the changed files are known, before they are
+// rebuilt, and it is saved once the queue drains.
+func (idx *Index) Revalidate(files []string) {
+	go func() {
+		infos := make([]fs.FileInfo, len(files))
+		for i, path := range files {
+			infos[i], _ = idx.workspace.Stat(path)
+		}
+
+		present := make(map[string]bool, len(files))
+		changed := 0
+		idx.mu.Lock()
+		if idx.disabled {
+			idx.mu.Unlock()
+			return
+		}
+		for i, path := range files {
+			present[path] = true
+			if !idx.matches(path, infos[i]) {
+				idx.dirty[path] = true
+				idx.queue.push(path, priorityBackground)
+				changed++
+			}
+		}
+		for path, id := range idx.ids {
+			if !present[path] {
+				idx.removeLocked(path, id)
+			}
+		}
+		// Dirty files are searched directly until they are reindexed
+		idx.ready = true
+		idx.idleSave = true
+		idx.mu.Unlock()
+		idx.signal()
+
+		if idx.debug {
+			log.Printf("Search index revalidated: %d files, %d queued", len(files), changed)
+		}
+	}()
+}
+
+// matches reports whether a file's entry is up to date with its stat
+// result; the caller holds the lock
+func (idx *Index) matches(path string, info fs.FileInfo) bool {
+	id, ok := idx.ids[path]
+	if !ok || info == nil {
+		return false
+	}
+	e := idx.files[id]
+	return e.size == info.Size() && e.modTime == info.ModTime().UnixNano()
+}
+
+// Update queues a file that was created or modified, ahead of files that
+// changed longer ago; it is searched directly until it is indexed
+func (idx *Index) Update(path string) {
+	idx.mu.Lock()
+	if idx.disabled {
+		idx.mu.Unlock()
+		return
+	}
+	idx.dirty[path] = true
+	idx.queue.push(path, priorityNow())
+	idx.mu.Unlock()
+	idx.signal()
+}
+
+// Prioritize moves a queued file to the front of the queue because a
+// client is reading it
+func (idx *Index) Prioritize(path string) {
+	idx.mu.Lock()
+	idx.queue.raise(path, priorityNow())
+	idx.mu.Unlock()
+}
+
+// signal wakes the worker
+func (idx *Index) signal() {
+	select {
+	case idx.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work indexes queued files, most urgent first, and saves the index when
+// the queue drains after a revalidation
+func (idx *Index) work() {
+	for {
+		idx.mu.Lock()
+		path, ok := idx.queue.pop()
+		save := !ok && idx.idleSave
+		if save {
+			idx.idleSave = false
+		}
+		idx.mu.Unlock()
+
+		switch {
+		case ok:
+			idx.indexFile(path)
+		case save:
+			if idx.debug {
+				log.Printf("Search index up to date")
+			}
+			if err := idx.Save(); err != nil {
+				log.Printf("Warning: failed to save search index: %v", err)
+			}
+		default:
+			<-idx.wake
+		}
+	}
+}
+
+// indexFile reads a file and records its trigrams, symbols and document
+// metadata
+func (idx *Index) indexFile(path string) {
+	idx.scheduler.Op()
+	info, err := idx.workspace.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		idx.Remove(path)
+		return
+	}
+
+	var grams []uint32
+	var defined []symbols.Symbol
+	var doc *docs.Document
+	if info.Size() <= idx.maxFileSize {
+		idx.scheduler.Read(info.Size())
+		data, err := idx.readText(path)
+		if err != nil {
+			idx.Remove(path)
+			return
+		}
+		grams = trigrams(data)
+		if data != nil && symbols.Supported(path) {
+			defined = symbols.Parse(path, data)
+		}
+		if data != nil && docs.Supported(path) {
+			doc = docs.Parse(data)
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.disabled {
+		return
+	}
+	if id, ok := idx.ids[path]; ok {
+		idx.removeLocked(path, id)
+	}
+	// Files too large to index stay dirty so they are always searched, as
+	// do files that changed again while they were being read
+	if info.Size() > idx.maxFileSize || idx.queue.has(path) {
+		idx.dirty[path] = true
+		return
+	}
+	delete(idx.dirty, path)
+	idx.add(&entry{path: path, size: info.Size(), modTime: info.ModTime().UnixNano(), symbols: defined, doc: doc}, grams)
+}
+
+// Symbols returns the symbols defined in an indexed file, and false when
+// the file isn't indexed or changed since it was
+func (idx *Index) Symbols(path string) ([]symbols.Symbol, bool) {
+	info, err := idx.workspace.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.dirty[path] || !idx.matches(path, info) {
+		return nil, false
+	}
+	e := idx.files[idx.ids[path]]
+	e.used.Store(time.Now().UnixNano())
+	return e.symbols, true
+}
+
+// Document returns the metadata of an indexed Markdown document, and false
+// when the file isn't indexed or changed since it was
+func (idx *Index) Document(path string) (*docs.Document, bool) {
+	info, err := idx.workspace.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if idx.dirty[path] || !idx.matches(path, info) {
+		return nil, false
+	}
+	e := idx.files[idx.ids[path]]
+	e.used.Store(time.Now().UnixNano())
+	return e.doc, e.doc != nil
+}
+
+// Status reports how far indexing has got
+func (idx *Index) Status() Status {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	status := Status{
+		Ready:     idx.ready,
+		Indexed:   len(idx.ids),
+		Queued:    idx.queue.Len(),
+		CachePath: idx.cachePath,
+		Disabled:  idx.disabled,
+	}
+	for _, id := range idx.ids {
+		status.Symbols += len(idx.files[id].symbols)
+	}
+	return status
+}
+
+// add records an entry and its trigrams; the caller holds the lock
+func (idx *Index) add(e *entry, grams []uint32) {
+	e.used.Store(time.Now().UnixNano())
+	id := int32(len(idx.files))
+	idx.files = append(idx.files, e)
+	idx.ids[e.path] = id
+	for _, gram := range grams {
+		idx.postings[gram] = append(idx.postings[gram], id)
+	}
+}
+
+// Remove drops a deleted file from the index
+func (idx *Index) Remove(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.dirty, path)
+	if id, ok := idx.ids[path]; ok {
+		idx.removeLocked(path, id)
+	}
+}
+
+// removeLocked drops an entry, compacting the postings once most of what
+// they reference is gone; the caller holds the lock
+func (idx *Index) removeLocked(path string, id int32) {
+	delete(idx.ids, path)
+	idx.files[id] = nil
+	idx.stale++
+	if idx.stale > 1024 && idx.stale > len(idx.ids) {
+		idx.compact()
+	}
+}
+
+// Evict drops the given fraction of entries, least recently used first, to
+// save memory. Evicted files are searched directly, like changed ones, until
+// they change and are indexed again. It returns how many entries it dropped.
+func (idx *Index) Evict(fraction float64) int {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	live := make([]*entry, 0, len(idx.ids))
+	for _, id := range idx.ids {
+		live = append(live, idx.files[id])
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].used.Load() < live[j].used.Load() })
+	evicted := live[:int(float64(len(live))*fraction)]
+	for _, e := range evicted {
+		idx.removeLocked(e.path, idx.ids[e.path])
+		idx.dirty[e.path] = true
+	}
+	if idx.stale > 0 {
+		idx.compact()
+	}
+	return len(evicted)
+}
+
+// Disable drops the whole index to save memory; searches read every file
+// from then on and the persisted index is left as it was. It returns false
+// if the index was already disabled.
+func (idx *Index) Disable() bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.disabled {
+		return false
+	}
+	idx.disabled = true
+	idx.ready = false
+	idx.files = nil
+	idx.ids = make(map[string]int32)
+	idx.postings = make(map[uint32][]int32)
+	idx.dirty = make(map[string]bool)
+	idx.queue = newQueue()
+	idx.idleSave = false
+	idx.stale = 0
+	return true
+}
+
+// compact renumbers the live entries and rewrites the postings without the
+// removed ones; the caller holds the lock
+func (idx *Index) compact() {
+	renumber := make([]int32, len(idx.files))
+	var files []*entry
+	for id, e := range idx.files {
+		renumber[id] = -1
+		if e != nil {
+			renumber[id] = int32(len(files))
+			idx.ids[e.path] = int32(len(files))
+			files = append(files, e)
+		}
+	}
+	for gram, list := range idx.postings {
+		kept := list[:0]
+		for _, id := range list {
+			if renumber[id] >= 0 {
+				kept = append(kept, renumber[id])
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.postings, gram)
+		} else {
+			idx.postings[gram] = kept
+		}
+	}
+	idx.files = files
+	idx.stale = 0
+}
+
+// Candidates returns the files that may contain literal, matched without
+// regard to ASCII case, and false when the index can't narrow the search:
+// the literal is shorter than a trigram or the index isn't built yet.
+func (idx *Index) Candidates(literal string) ([]string, bool) {
+	grams := trigrams([]byte(literal))
+	if len(grams) == 0 {
+		return nil, false
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	if !idx.ready {
+		return nil, false
+	}
+
+	// Intersect the shortest posting lists first
+	lists := make([][]int32, len(grams))
+	for i, gram := range grams {
+		lists[i] = idx.postings[gram]
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	matched := lists[0]
+	for _, list := range lists[1:] {
+		matched = intersect(matched, list)
+	}
+
+	var paths []string
+	now := time.Now().UnixNano()
+	for _, id := range matched {
+		if e := idx.files[id]; e != nil {
+			e.used.Store(now)
+			paths = append(paths, e.path)
+		}
+	}
+	for path := range idx.dirty {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, true
+}
+
+// intersect returns the ids in both sorted lists
+func intersect(a, b []int32) []int32 {
+	var out []int32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// readText reads a file as UTF-8 text, decoding UTF-16 and Latin-1, and
+// returns nil for binary files
+func (idx *Index) readText(path string) ([]byte, error) {
+	file, err := idx.workspace.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return textio.Decode(data), nil
+}
+
+// trigrams returns the distinct trigrams of text in ascending order, with
+// ASCII letters folded to lower case
+func trigrams(text []byte) []uint32 {
+	if len(text) < 3 {
+		return nil
+	}
+	seen := make(map[uint32]bool)
+	var grams []uint32
+	gram := uint32(fold(text[0]))<<8 | uint32(fold(text[1]))
+	for _, c := range text[2:] {
+		gram = (gram<<8 | uint32(fold(c))) & 0xffffff
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	sort.Slice(grams, func(i, j int) bool { return grams[i] < grams[j] })
+	return grams
+}
+
+// fold lowers an ASCII letter
+func fold(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// RequiredLiteral returns the longest literal every match of a regular
+// expression contains, or "" if there is none the index can use
+func RequiredLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		literal := string(re.Rune)
+		// The index only folds ASCII case
+		if re.Flags&syntax.FoldCase != 0 && !isASCII(literal) {
+			return ""
+		}
+		return literal
+	case syntax.OpCapture, syntax.OpPlus:
+		return RequiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return RequiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		longest := ""
+		for _, sub := range re.Sub {
+			if literal := RequiredLiteral(sub); len(literal) > len(longest) {
+				longest = literal
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// isASCII reports whether s has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
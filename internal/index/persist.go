@@ -0,0 +1,168 @@
+package index
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/isaacphi/mcp-filesystem/internal/docs"
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+)
+
+// formatVersion changes whenever the saved layout or how files are read for
+// it does; older files are ignored
+const formatVersion = 5
+
+// savedIndex is the persisted form of an index
+type savedIndex struct {
+	Version   int
+	Workspace string
+	Files     []savedFile
+}
+
+// savedFile is an indexed file with its symbols and its trigrams, delta-encoded
+type savedFile struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Grams   []uint32
+	Symbols []symbols.Symbol
+	Doc     *docs.Document
+}
+
+// CachePath returns where the index of a workspace is persisted inside
+// cacheDir, or the user cache directory when cacheDir is empty
+func CachePath(workspacePath, cacheDir string) (string, error) {
+	if cacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find cache directory: %v", err)
+		}
+		cacheDir = filepath.Join(dir, "mcp-filesystem")
+	}
+	sum := sha256.Sum256([]byte(workspacePath))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".index"), nil
+}
+
+// Load reads the persisted index of an empty index, reporting whether there
+// was one; call Revalidate afterwards to catch up with changes since it was saved
+func (idx *Index) Load() bool {
+	if idx.cachePath == "" {
+		return false
+	}
+	file, err := os.Open(idx.cachePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	var saved savedIndex
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&saved); err != nil {
+		log.Printf("Warning: ignoring unreadable search index %s: %v", idx.cachePath, err)
+		return false
+	}
+	if saved.Version != formatVersion || saved.Workspace != idx.workspacePath {
+		return false
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if len(idx.files) > 0 {
+		return false
+	}
+	for _, f := range saved.Files {
+		path := filepath.Join(idx.workspacePath, filepath.FromSlash(f.Path))
+		grams := f.Grams
+		for i := 1; i < len(grams); i++ {
+			grams[i] += grams[i-1]
+		}
+		idx.add(&entry{path: path, size: f.Size, modTime: f.ModTime, symbols: f.Symbols, doc: f.Doc}, grams)
+	}
+	if idx.debug {
+		log.Printf("Loaded search index with %d files from %s", len(saved.Files), idx.cachePath)
+	}
+	return true
+}
+
+// Save writes the index to its cache file, replacing it atomically
+func (idx *Index) Save() error {
+	if idx.cachePath == "" {
+		return nil
+	}
+	idx.saveMu.Lock()
+	defer idx.saveMu.Unlock()
+
+	idx.mu.RLock()
+	if idx.disabled {
+		idx.mu.RUnlock()
+		return nil
+	}
+	grams := make(map[int32][]uint32, len(idx.ids))
+	for gram, list := range idx.postings {
+		for _, id := range list {
+			if idx.files[id] != nil {
+				grams[id] = append(grams[id], gram)
+			}
+		}
+	}
+	saved := savedIndex{Version: formatVersion, Workspace: idx.workspacePath}
+	for path, id := range idx.ids {
+		rel, err := filepath.Rel(idx.workspacePath, path)
+		if err != nil {
+			continue
+		}
+		e := idx.files[id]
+		saved.Files = append(saved.Files, savedFile{
+			Path:    filepath.ToSlash(rel),
+			Size:    e.size,
+			ModTime: e.modTime,
+			Grams:   grams[id],
+			Symbols: e.symbols,
+			Doc:     e.doc,
+		})
+	}
+	idx.mu.RUnlock()
+
+	for _, f := range saved.Files {
+		sort.Slice(f.Grams, func(i, j int) bool { return f.Grams[i] < f.Grams[j] })
+		for i := len(f.Grams) - 1; i > 0; i-- {
+			f.Grams[i] -= f.Grams[i-1]
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idx.cachePath), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(idx.cachePath), ".index-*")
+	if err != nil {
+		return fmt.Errorf("failed to create index file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(writer).Encode(saved); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), idx.cachePath); err != nil {
+		return fmt.Errorf("failed to replace index: %v", err)
+	}
+
+	if idx.debug {
+		log.Printf("Saved search index with %d files to %s", len(saved.Files), idx.cachePath)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package index
+
+import (
+	"container/heap"
+	"time"
+)
+
+// priorityBackground is the priority of files found stale at startup; files
+// that were just changed or read are indexed before them, most recent first
+const priorityBackground int64 = 0
+
+// priorityNow ranks work requested now above older requests
+func priorityNow() int64 {
+	return time.Now().UnixNano()
+}
+
+// queue is a priority queue of files waiting to be indexed. A file is
+// queued at most once, with the highest priority it was requested with.
+type queue struct {
+	items  []*queued
+	byPath map[string]*queued
+	seq    int64
+}
+
+// queued is a file waiting in the queue
+type queued struct {
+	path     string
+	priority int64
+	seq      int64 // breaks ties in request order
+	index    int
+}
+
+// newQueue creates an empty queue
+func newQueue() *queue {
+	return &queue{byPath: make(map[string]*queued)}
+}
+
+// push queues a file, or raises the priority of a file already queued
+func (q *queue) push(path string, priority int64) {
+	if item, ok := q.byPath[path]; ok {
+		if priority > item.priority {
+			item.priority = priority
+			heap.Fix(q, item.index)
+		}
+		return
+	}
+	q.seq++
+	item := &queued{path: path, priority: priority, seq: q.seq}
+	q.byPath[path] = item
+	heap.Push(q, item)
+}
+
+// raise raises the priority of a file if it is queued
+func (q *queue) raise(path string, priority int64) {
+	if _, ok := q.byPath[path]; ok {
+		q.push(path, priority)
+	}
+}
+
+// pop removes the most urgent file from the queue
+func (q *queue) pop() (string, bool) {
+	if len(q.items) == 0 {
+		return "", false
+	}
+	item := heap.Pop(q).(*queued)
+	delete(q.byPath, item.path)
+	return item.path, true
+}
+
+// has reports whether a file is waiting in the queue
+func (q *queue) has(path string) bool {
+	_, ok := q.byPath[path]
+	return ok
+}
+
+// Len implements heap.Interface
+func (q *queue) Len() int { return len(q.items) }
+
+// Less implements heap.Interface, ordering higher priorities first
+func (q *queue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+// Swap implements heap.Interface
+func (q *queue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+// Push implements heap.Interface
+func (q *queue) Push(x any) {
+	item := x.(*queued)
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+}
+
+// Pop implements heap.Interface
+func (q *queue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
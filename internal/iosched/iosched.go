@@ -0,0 +1,70 @@
+// Package iosched paces background disk work, such as indexing and polling
+// rescans, with byte and operation budgets, much as ionice does, so the
+// server stays out of the way of builds running on the same disk.
+package iosched
+
+import (
+	"sync"
+	"time"
+)
+
+// Scheduler hands out per-second budgets of bytes read and operations
+// (files opened or directories listed). A nil Scheduler never waits.
+type Scheduler struct {
+	bytes *bucket
+	ops   *bucket
+}
+
+// New creates a scheduler; a budget of 0 is unlimited
+func New(bytesPerSecond int64, opsPerSecond int) *Scheduler {
+	if bytesPerSecond <= 0 && opsPerSecond <= 0 {
+		return nil
+	}
+	return &Scheduler{bytes: newBucket(float64(bytesPerSecond)), ops: newBucket(float64(opsPerSecond))}
+}
+
+// Op waits until an operation fits in the budget
+func (s *Scheduler) Op() {
+	if s != nil {
+		s.ops.take(1)
+	}
+}
+
+// Read charges n bytes to the budget, waiting off any overdraft so a large
+// file delays the work after it rather than being split up
+func (s *Scheduler) Read(n int64) {
+	if s != nil && n > 0 {
+		s.bytes.take(float64(n))
+	}
+}
+
+// bucket is a token bucket holding at most one second of budget
+type bucket struct {
+	rate   float64 // tokens per second; 0 is unlimited
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// newBucket creates a full bucket
+func newBucket(rate float64) *bucket {
+	return &bucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// take removes n tokens, sleeping until the bucket is no longer in debt
+func (b *bucket) take(n float64) {
+	if b.rate <= 0 {
+		return
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,15 @@
+// Package longpath handles Windows paths longer than MAX_PATH.
+//
+// The os package already adds the \\?\ extended-length prefix to long
+// absolute paths, but libraries that call Win32 directly (such as fsnotify)
+// don't, so watching directories deep inside node_modules-style trees fails.
+// Fix adds the prefix for those calls and Strip removes it from paths that
+// come back, so the rest of the server only ever sees ordinary paths.
+// On other platforms both are no-ops.
+package longpath
+
+// prefix marks an extended-length path; uncPrefix is its form for network shares
+const (
+	prefix    = `\\?\`
+	uncPrefix = `\\?\UNC\`
+)
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package longpath
+
+// Fix returns path unchanged; only Windows limits path length this way
+func Fix(path string) string {
+	return path
+}
+
+// Strip returns path unchanged; only Windows uses extended-length prefixes
+func Strip(path string) string {
+	return path
+}
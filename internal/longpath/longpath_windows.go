@@ -0,0 +1,33 @@
+package longpath
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length at which Win32 calls start failing without the
+// prefix; directories need room for an 8.3 file name, hence 248 not 260
+const maxPath = 248
+
+// Fix returns path with the extended-length prefix if it is too long for Win32
+func Fix(path string) string {
+	if len(path) < maxPath || strings.HasPrefix(path, prefix) || !filepath.IsAbs(path) {
+		return path
+	}
+
+	// Extended-length paths are passed to the filesystem as-is, so they
+	// must be clean and use backslashes
+	path = filepath.Clean(path)
+	if strings.HasPrefix(path, `\\`) {
+		return uncPrefix + path[2:]
+	}
+	return prefix + path
+}
+
+// Strip removes an extended-length prefix added by Fix
+func Strip(path string) string {
+	if strings.HasPrefix(path, uncPrefix) {
+		return `\\` + path[len(uncPrefix):]
+	}
+	return strings.TrimPrefix(path, prefix)
+}
@@ -0,0 +1,420 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/mod/modfile"
+)
+
+// Dependency scopes
+const (
+	ScopeRuntime  = "runtime"
+	ScopeDev      = "dev"
+	ScopeBuild    = "build"
+	ScopePeer     = "peer"
+	ScopeOptional = "optional"
+	ScopeIndirect = "indirect"
+)
+
+// scopeOrder controls how dependencies are grouped in parsed output
+var scopeOrder = map[string]int{
+	ScopeRuntime:  0,
+	ScopePeer:     1,
+	ScopeOptional: 2,
+	ScopeDev:      3,
+	ScopeBuild:    4,
+	ScopeIndirect: 5,
+}
+
+// Dependency is a single entry declared in a manifest
+type Dependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Scope   string `json:"scope"`
+}
+
+// Manifest is a parsed project manifest
+type Manifest struct {
+	Path         string       `json:"path"`
+	Ecosystem    string       `json:"ecosystem"`
+	Name         string       `json:"name,omitempty"`
+	Version      string       `json:"version,omitempty"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// kinds maps well-known manifest file names to their ecosystem
+var kinds = map[string]string{
+	"go.mod":           "go",
+	"package.json":     "npm",
+	"pyproject.toml":   "python",
+	"requirements.txt": "python",
+	"Pipfile":          "python",
+	"Cargo.toml":       "cargo",
+	"Gemfile":          "rubygems",
+	"composer.json":    "composer",
+}
+
+var (
+	// PEP 508 requirement: name[extras] version-spec ; markers
+	pep508Pattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:\[[^\]]*\])?\s*(?:\(([^)]*)\)|([^;]*))`)
+	// gem "name", "version", ...
+	gemPattern = regexp.MustCompile(`^\s*gem\s+['"]([^'"]+)['"](?:\s*,\s*['"]([^'"]+)['"])?`)
+	// group :development, :test do
+	gemGroupPattern = regexp.MustCompile(`^\s*group\s+(.+?)\s+do\b`)
+)
+
+// Ecosystem returns the ecosystem of a manifest file, or "" if the path is not a manifest
+func Ecosystem(path string) string {
+	base := filepath.Base(path)
+	if ecosystem, ok := kinds[base]; ok {
+		return ecosystem
+	}
+	// requirements-dev.txt, requirements/base.txt style files
+	if strings.HasPrefix(base, "requirements") && strings.HasSuffix(base, ".txt") {
+		return "python"
+	}
+	return ""
+}
+
+// IsManifest reports whether a path is a recognized project manifest
+func IsManifest(path string) bool {
+	return Ecosystem(path) != ""
+}
+
+// Parse reads and parses a manifest file
+func Parse(path string) (*Manifest, error) {
+	ecosystem := Ecosystem(path)
+	if ecosystem == "" {
+		return nil, fmt.Errorf("not a recognized manifest: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	m := &Manifest{Path: path, Ecosystem: ecosystem}
+
+	base := filepath.Base(path)
+	switch {
+	case base == "go.mod":
+		err = parseGoMod(m, data)
+	case base == "package.json":
+		err = parsePackageJSON(m, data)
+	case base == "composer.json":
+		err = parseComposerJSON(m, data)
+	case base == "pyproject.toml":
+		err = parsePyproject(m, data)
+	case base == "Pipfile":
+		err = parsePipfile(m, data)
+	case base == "Cargo.toml":
+		err = parseCargo(m, data)
+	case base == "Gemfile":
+		parseGemfile(m, data)
+	default:
+		parseRequirements(m, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", base, err)
+	}
+
+	sort.SliceStable(m.Dependencies, func(i, j int) bool {
+		if m.Dependencies[i].Scope != m.Dependencies[j].Scope {
+			return scopeOrder[m.Dependencies[i].Scope] < scopeOrder[m.Dependencies[j].Scope]
+		}
+		return m.Dependencies[i].Name < m.Dependencies[j].Name
+	})
+
+	return m, nil
+}
+
+// parseGoMod parses require directives from go.mod
+func parseGoMod(m *Manifest, data []byte) error {
+	file, err := modfile.ParseLax(m.Path, data, nil)
+	if err != nil {
+		return err
+	}
+
+	if file.Module != nil {
+		m.Name = file.Module.Mod.Path
+	}
+	for _, req := range file.Require {
+		scope := ScopeRuntime
+		if req.Indirect {
+			scope = ScopeIndirect
+		}
+		m.Dependencies = append(m.Dependencies, Dependency{Name: req.Mod.Path, Version: req.Mod.Version, Scope: scope})
+	}
+	for _, tool := range file.Tool {
+		m.Dependencies = append(m.Dependencies, Dependency{Name: tool.Path, Scope: ScopeBuild})
+	}
+	return nil
+}
+
+// parsePackageJSON parses the dependency maps of package.json
+func parsePackageJSON(m *Manifest, data []byte) error {
+	var pkg struct {
+		Name                 string            `json:"name"`
+		Version              string            `json:"version"`
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return err
+	}
+
+	m.Name, m.Version = pkg.Name, pkg.Version
+	addVersionMap(m, pkg.Dependencies, ScopeRuntime)
+	addVersionMap(m, pkg.DevDependencies, ScopeDev)
+	addVersionMap(m, pkg.PeerDependencies, ScopePeer)
+	addVersionMap(m, pkg.OptionalDependencies, ScopeOptional)
+	return nil
+}
+
+// parseComposerJSON parses require and require-dev from composer.json
+func parseComposerJSON(m *Manifest, data []byte) error {
+	var pkg struct {
+		Name       string            `json:"name"`
+		Version    string            `json:"version"`
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return err
+	}
+
+	m.Name, m.Version = pkg.Name, pkg.Version
+	addVersionMap(m, pkg.Require, ScopeRuntime)
+	addVersionMap(m, pkg.RequireDev, ScopeDev)
+	return nil
+}
+
+// parsePyproject parses PEP 621 and Poetry dependency tables
+func parsePyproject(m *Manifest, data []byte) error {
+	var doc struct {
+		Project struct {
+			Name                 string              `toml:"name"`
+			Version              string              `toml:"version"`
+			Dependencies         []string            `toml:"dependencies"`
+			OptionalDependencies map[string][]string `toml:"optional-dependencies"`
+		} `toml:"project"`
+		BuildSystem struct {
+			Requires []string `toml:"requires"`
+		} `toml:"build-system"`
+		DependencyGroups map[string][]any `toml:"dependency-groups"`
+		Tool             struct {
+			Poetry struct {
+				Name            string         `toml:"name"`
+				Version         string         `toml:"version"`
+				Dependencies    map[string]any `toml:"dependencies"`
+				DevDependencies map[string]any `toml:"dev-dependencies"`
+				Group           map[string]struct {
+					Dependencies map[string]any `toml:"dependencies"`
+				} `toml:"group"`
+			} `toml:"poetry"`
+		} `toml:"tool"`
+	}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	m.Name, m.Version = doc.Project.Name, doc.Project.Version
+	if m.Name == "" {
+		m.Name, m.Version = doc.Tool.Poetry.Name, doc.Tool.Poetry.Version
+	}
+
+	for _, req := range doc.Project.Dependencies {
+		addRequirement(m, req, ScopeRuntime)
+	}
+	for _, reqs := range doc.Project.OptionalDependencies {
+		for _, req := range reqs {
+			addRequirement(m, req, ScopeOptional)
+		}
+	}
+	for _, req := range doc.BuildSystem.Requires {
+		addRequirement(m, req, ScopeBuild)
+	}
+	for _, entries := range doc.DependencyGroups {
+		for _, entry := range entries {
+			// Groups may include other groups as tables; only strings are requirements
+			if req, ok := entry.(string); ok {
+				addRequirement(m, req, ScopeDev)
+			}
+		}
+	}
+
+	for name, spec := range doc.Tool.Poetry.Dependencies {
+		// Poetry lists the interpreter constraint alongside packages
+		if name != "python" {
+			addTableDependency(m, name, spec, ScopeRuntime)
+		}
+	}
+	for name, spec := range doc.Tool.Poetry.DevDependencies {
+		addTableDependency(m, name, spec, ScopeDev)
+	}
+	for _, group := range doc.Tool.Poetry.Group {
+		for name, spec := range group.Dependencies {
+			addTableDependency(m, name, spec, ScopeDev)
+		}
+	}
+	return nil
+}
+
+// parsePipfile parses the packages tables of a Pipfile
+func parsePipfile(m *Manifest, data []byte) error {
+	var doc struct {
+		Packages    map[string]any `toml:"packages"`
+		DevPackages map[string]any `toml:"dev-packages"`
+	}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	for name, spec := range doc.Packages {
+		addTableDependency(m, name, spec, ScopeRuntime)
+	}
+	for name, spec := range doc.DevPackages {
+		addTableDependency(m, name, spec, ScopeDev)
+	}
+	return nil
+}
+
+// parseCargo parses the dependency tables of Cargo.toml
+func parseCargo(m *Manifest, data []byte) error {
+	var doc struct {
+		Package struct {
+			Name    string `toml:"name"`
+			Version any    `toml:"version"`
+		} `toml:"package"`
+		Dependencies      map[string]any `toml:"dependencies"`
+		DevDependencies   map[string]any `toml:"dev-dependencies"`
+		BuildDependencies map[string]any `toml:"build-dependencies"`
+		Workspace         struct {
+			Dependencies map[string]any `toml:"dependencies"`
+		} `toml:"workspace"`
+	}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	m.Name = doc.Package.Name
+	// version.workspace = true is a table rather than a string
+	if version, ok := doc.Package.Version.(string); ok {
+		m.Version = version
+	}
+
+	for name, spec := range doc.Dependencies {
+		addTableDependency(m, name, spec, ScopeRuntime)
+	}
+	for name, spec := range doc.Workspace.Dependencies {
+		addTableDependency(m, name, spec, ScopeRuntime)
+	}
+	for name, spec := range doc.DevDependencies {
+		addTableDependency(m, name, spec, ScopeDev)
+	}
+	for name, spec := range doc.BuildDependencies {
+		addTableDependency(m, name, spec, ScopeBuild)
+	}
+	return nil
+}
+
+// parseGemfile extracts gem declarations, treating non-default groups as dev
+func parseGemfile(m *Manifest, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	depth := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := gemGroupPattern.FindStringSubmatch(line); match != nil {
+			depth++
+			continue
+		}
+		if line == "end" && depth > 0 {
+			depth--
+			continue
+		}
+
+		if match := gemPattern.FindStringSubmatch(line); match != nil {
+			scope := ScopeRuntime
+			if depth > 0 {
+				scope = ScopeDev
+			}
+			m.Dependencies = append(m.Dependencies, Dependency{Name: match[1], Version: match[2], Scope: scope})
+		}
+	}
+}
+
+// parseRequirements parses a pip requirements file
+func parseRequirements(m *Manifest, data []byte) {
+	scope := ScopeRuntime
+	if strings.Contains(filepath.Base(m.Path), "dev") || strings.Contains(filepath.Base(m.Path), "test") {
+		scope = ScopeDev
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		// Skip blank lines, pip options (-r, -e, --index-url) and URLs
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") {
+			continue
+		}
+		addRequirement(m, line, scope)
+	}
+}
+
+// addVersionMap adds name -> version entries
+func addVersionMap(m *Manifest, deps map[string]string, scope string) {
+	for name, version := range deps {
+		m.Dependencies = append(m.Dependencies, Dependency{Name: name, Version: version, Scope: scope})
+	}
+}
+
+// addRequirement adds a PEP 508 requirement string
+func addRequirement(m *Manifest, req string, scope string) {
+	match := pep508Pattern.FindStringSubmatch(req)
+	if match == nil {
+		return
+	}
+	version := strings.TrimSpace(match[2] + match[3])
+	m.Dependencies = append(m.Dependencies, Dependency{Name: match[1], Version: version, Scope: scope})
+}
+
+// addTableDependency adds a TOML dependency given as a version string or a table
+func addTableDependency(m *Manifest, name string, spec any, scope string) {
+	dep := Dependency{Name: name, Scope: scope}
+
+	switch value := spec.(type) {
+	case string:
+		dep.Version = value
+	case map[string]any:
+		if version, ok := value["version"].(string); ok {
+			dep.Version = version
+		} else if git, ok := value["git"].(string); ok {
+			dep.Version = git
+		} else if path, ok := value["path"].(string); ok {
+			dep.Version = "path:" + path
+		} else if value["workspace"] == true {
+			dep.Version = "workspace"
+		}
+		if optional, ok := value["optional"].(bool); ok && optional && scope == ScopeRuntime {
+			dep.Scope = ScopeOptional
+		}
+	}
+
+	m.Dependencies = append(m.Dependencies, dep)
+}
@@ -0,0 +1,128 @@
+// Package memory keeps the server under a memory limit by dropping caches,
+// cheapest to lose first, as memory use approaches the limit.
+package memory
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// Pressure thresholds as fractions of the limit: above highWater the next
+// release runs, and once use falls below lowWater releases start over from
+// the first
+const (
+	highWater = 0.8
+	lowWater  = 0.6
+)
+
+// checkInterval is how often memory use is measured
+const checkInterval = time.Second
+
+// Release frees memory and describes what it dropped, or returns "" when
+// it had nothing left to drop
+type Release func() string
+
+// Monitor measures memory use against a limit and runs releases under pressure
+type Monitor struct {
+	limit     int64
+	releases  []Release
+	next      int  // the release to run at the next pressure event
+	exhausted bool // every release has run and use is still high
+	debug     bool
+}
+
+// Usage is the memory the process uses, as the Go runtime counts it
+type Usage struct {
+	InUse int64 `json:"inUseBytes"`
+	// Limit is 0 when memory use is unlimited
+	Limit int64 `json:"limitBytes,omitempty"`
+}
+
+// NewMonitor creates a monitor for limit bytes, 0 meaning unlimited, and
+// makes the garbage collector work harder as the limit is approached
+func NewMonitor(limit int64, debugLog bool) *Monitor {
+	if limit > 0 {
+		debug.SetMemoryLimit(limit)
+	}
+	return &Monitor{limit: limit, debug: debugLog}
+}
+
+// Add appends a release; releases run in the order they were added
+func (m *Monitor) Add(release Release) {
+	m.releases = append(m.releases, release)
+}
+
+// Run checks memory use until ctx is done; it returns right away without a limit
+func (m *Monitor) Run(ctx context.Context) {
+	if m.limit <= 0 {
+		return
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+// Usage reports the current memory use
+func (m *Monitor) Usage() Usage {
+	return Usage{InUse: inUse(), Limit: m.limit}
+}
+
+// check runs the next release when memory use is above the high-water mark
+func (m *Monitor) check() {
+	used := inUse()
+	switch {
+	case float64(used) < lowWater*float64(m.limit):
+		if m.next > 0 && m.debug {
+			log.Printf("Memory pressure relieved: %d MiB of %d MiB in use", used>>20, m.limit>>20)
+		}
+		m.next = 0
+		m.exhausted = false
+		return
+	case float64(used) < highWater*float64(m.limit):
+		return
+	}
+
+	// Skip releases with nothing left to drop; the last one may run again
+	for m.next < len(m.releases) {
+		dropped := m.releases[m.next]()
+		if m.next < len(m.releases)-1 || dropped == "" {
+			m.next++
+		}
+		if dropped != "" {
+			debug.FreeOSMemory()
+			log.Printf("Memory pressure: %d MiB of %d MiB in use; dropped %s (now %d MiB)", used>>20, m.limit>>20, dropped, inUse()>>20)
+			return
+		}
+	}
+	if !m.exhausted {
+		m.exhausted = true
+		log.Printf("Memory pressure: %d MiB of %d MiB in use and no caches left to drop", used>>20, m.limit>>20)
+	}
+}
+
+// inUse returns the memory the runtime holds from the OS, which is what
+// its memory limit applies to
+func inUse() int64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	var values [2]uint64
+	for i, sample := range samples {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			values[i] = sample.Value.Uint64()
+		}
+	}
+	return int64(values[0] - values[1])
+}
@@ -0,0 +1,79 @@
+// Package pathnorm gives file paths a single Unicode form.
+//
+// macOS reports decomposed (NFD) file names while clients, editors and
+// .gitignore files almost always use composed (NFC) strings, so the same
+// accented name can arrive in two byte sequences. Paths are normalized to
+// NFC wherever they are compared, stored or shown, and mapped back to the
+// spelling that exists on disk before filesystem calls.
+package pathnorm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NFC returns s in Unicode normalization form C
+func NFC(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	return norm.NFC.String(s)
+}
+
+// Normalize returns path with the part below root in NFC. The root is kept
+// byte for byte so it still matches the workspace path it came from.
+func Normalize(root, path string) string {
+	if isASCII(path) {
+		return path
+	}
+	if rel, ok := strings.CutPrefix(path, root); ok {
+		return root + NFC(rel)
+	}
+	return NFC(path)
+}
+
+// OnDisk returns the spelling of path that exists on disk. Filesystems that
+// store names byte for byte (most Linux filesystems) may hold a name in a
+// different form than the one it is referred to by, so if path doesn't
+// exist as given each component is matched against its directory's entries
+// by normalized form. Components that don't exist in any form are kept as given.
+func OnDisk(path string) string {
+	if isASCII(path) {
+		return path
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return path
+	}
+
+	dir, base := filepath.Dir(path), filepath.Base(path)
+	if dir == path {
+		return path
+	}
+	dir = OnDisk(dir)
+
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		want := NFC(base)
+		for _, entry := range entries {
+			if NFC(entry.Name()) == want {
+				return filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	return filepath.Join(dir, base)
+}
+
+// isASCII reports whether s has no characters that normalization could change
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
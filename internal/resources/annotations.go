@@ -0,0 +1,117 @@
+package resources
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/manifest"
+)
+
+// Priorities of listed resources; clients rank resources by these
+const (
+	priorityManifest  = 1.0
+	priorityKeyFile   = 0.8
+	DefaultPriority   = 0.5
+	priorityGenerated = 0.0
+)
+
+// Annotations are the MCP annotations of a listed resource. mcp-golang's
+// Annotations type has no lastModified, so the listing is rewritten with this.
+type Annotations struct {
+	Audience     []mcp_golang.Role `json:"audience,omitempty"`
+	Priority     float64           `json:"priority"`
+	LastModified string            `json:"lastModified,omitempty"`
+}
+
+// keyFiles are READMEs, configuration and entry points that orient a reader
+var keyFiles = map[string]bool{
+	"readme":              true,
+	"contributing":        true,
+	"architecture":        true,
+	"makefile":            true,
+	"justfile":            true,
+	"dockerfile":          true,
+	"docker-compose.yml":  true,
+	"docker-compose.yaml": true,
+	"compose.yaml":        true,
+	"tsconfig.json":       true,
+	"setup.cfg":           true,
+	"tox.ini":             true,
+	"main.go":             true,
+	"main.py":             true,
+	"__main__.py":         true,
+	"app.py":              true,
+	"manage.py":           true,
+	"main.rs":             true,
+	"lib.rs":              true,
+	"main.c":              true,
+	"main.cpp":            true,
+	"program.cs":          true,
+	"index.js":            true,
+	"index.ts":            true,
+	"main.js":             true,
+	"main.ts":             true,
+}
+
+// Annotate returns the annotations for a listed resource: its priority,
+// an audience for files people also look at first, and its modification time
+func (rm *ResourceManager) Annotate(uri string) *Annotations {
+	path, ok := rm.PathFromURI(uri)
+	if !ok {
+		return nil
+	}
+
+	annotations := &Annotations{Priority: rm.Priority(uri)}
+	if annotations.Priority > DefaultPriority {
+		annotations.Audience = []mcp_golang.Role{mcp_golang.RoleUser, mcp_golang.RoleAssistant}
+	}
+
+	if info, err := rm.files.Stat(path); err == nil {
+		annotations.LastModified = info.ModTime().UTC().Format(time.RFC3339)
+	}
+
+	return annotations
+}
+
+// Priority ranks a resource: manifests and key files are boosted and
+// generated files demoted
+func (rm *ResourceManager) Priority(uri string) float64 {
+	path, ok := rm.PathFromURI(uri)
+	if !ok {
+		return DefaultPriority
+	}
+
+	switch {
+	case manifest.IsManifest(path):
+		return priorityManifest
+	case rm.IsGenerated(uri):
+		return priorityGenerated
+	case rm.isKeyFile(path):
+		return priorityKeyFile
+	}
+	return DefaultPriority
+}
+
+// isKeyFile reports whether a file is a README, build configuration or
+// program entry point near the top of the workspace
+func (rm *ResourceManager) isKeyFile(path string) bool {
+	relPath, err := filepath.Rel(rm.workspacePath, path)
+	if err != nil {
+		return false
+	}
+	// Entry points deep in the tree are usually examples or fixtures
+	if depth := strings.Count(filepath.ToSlash(relPath), "/"); depth > 2 {
+		return false
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	if keyFiles[name] {
+		return true
+	}
+	// README.md, README.rst, ... and vite.config.ts, jest.config.js, ...
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+	return keyFiles[stem] || strings.HasSuffix(stem, ".config")
+}
@@ -0,0 +1,236 @@
+package resources
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/manifest"
+)
+
+// describeLimit is the largest file whose lines are counted and summarized
+const describeLimit = 1 << 20
+
+// summaryLength caps the heading or doc comment quoted in a description
+const summaryLength = 100
+
+// languages names the language of common source extensions
+var languages = map[string]string{
+	".go": "Go", ".py": "Python", ".js": "JavaScript", ".mjs": "JavaScript",
+	".cjs": "JavaScript", ".jsx": "JavaScript (JSX)", ".ts": "TypeScript",
+	".tsx": "TypeScript (JSX)", ".rs": "Rust", ".java": "Java", ".kt": "Kotlin",
+	".c": "C", ".h": "C header", ".cpp": "C++", ".cc": "C++", ".hpp": "C++ header",
+	".cs": "C#", ".rb": "Ruby", ".php": "PHP", ".swift": "Swift", ".scala": "Scala",
+	".sh": "Shell", ".bash": "Shell", ".zsh": "Shell", ".lua": "Lua",
+	".md": "Markdown", ".rst": "reStructuredText", ".json": "JSON", ".yaml": "YAML",
+	".yml": "YAML", ".toml": "TOML", ".xml": "XML", ".html": "HTML", ".css": "CSS",
+	".scss": "SCSS", ".sql": "SQL", ".proto": "Protocol Buffers", ".txt": "Text",
+}
+
+// mimeLanguages names the language of files recognized by name or "#!" line
+var mimeLanguages = map[string]string{
+	"text/x-makefile": "Makefile", "text/x-dockerfile": "Dockerfile", "text/x-ruby": "Ruby",
+	"text/x-groovy": "Groovy", "text/x-shellscript": "Shell", "text/x-python": "Python",
+	"text/javascript": "JavaScript", "text/x-perl": "Perl", "text/x-php": "PHP",
+	"text/x-lua": "Lua", "text/x-tcl": "Tcl", "text/plain": "Text",
+}
+
+// cFamily are extensions of languages with a C preprocessor
+var cFamily = map[string]bool{".c": true, ".h": true, ".cpp": true, ".cc": true, ".hpp": true, ".cs": true}
+
+// description is a cached description, valid while the file is unchanged
+type description struct {
+	modTime time.Time
+	size    int64
+	text    string
+}
+
+// Describe returns a description of a file resource with its kind, size,
+// line count and first heading or doc comment. Descriptions are built when
+// first listed and cached until the file changes.
+func (rm *ResourceManager) Describe(uri string) (string, bool) {
+	path, ok := rm.PathFromURI(uri)
+	if !ok {
+		return "", false
+	}
+	info, err := rm.files.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+
+	rm.mu.RLock()
+	cached, ok := rm.descriptions[uri]
+	rm.mu.RUnlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.text, true
+	}
+
+	parts := []string{rm.kind(path), formatSize(info.Size())}
+	summary := ""
+	if info.Size() <= describeLimit {
+		if data, err := rm.files.ReadFile(path); err == nil {
+			switch lines := countLines(data); lines {
+			case 0:
+			case 1:
+				parts = append(parts, "1 line")
+			default:
+				parts = append(parts, fmt.Sprintf("%d lines", lines))
+			}
+			summary = summarize(path, data)
+		}
+	}
+	text := strings.Join(parts, ", ")
+	if summary != "" {
+		text += ": " + summary
+	}
+
+	rm.mu.Lock()
+	rm.descriptions[uri] = description{modTime: info.ModTime(), size: info.Size(), text: text}
+	rm.mu.Unlock()
+
+	return text, true
+}
+
+// DropDescriptions empties the description cache to save memory and
+// returns how many descriptions it held; they are rebuilt as needed
+func (rm *ResourceManager) DropDescriptions() int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	dropped := len(rm.descriptions)
+	rm.descriptions = make(map[string]description)
+	return dropped
+}
+
+// kind names what a file is: a manifest, a generated file or its language
+func (rm *ResourceManager) kind(path string) string {
+	rm.mu.RLock()
+	reason, isGenerated := rm.generated[rm.GetFileURI(path)]
+	rm.mu.RUnlock()
+
+	language := languages[strings.ToLower(filepath.Ext(path))]
+	if ecosystem := manifest.Ecosystem(path); ecosystem != "" {
+		return fmt.Sprintf("Project manifest (%s)", ecosystem)
+	}
+	if isGenerated {
+		return fmt.Sprintf("Generated file (%s)", reason)
+	}
+	if language != "" {
+		return language + " file"
+	}
+	mimeType := rm.getFileMIMEType(path)
+	if language, ok := mimeLanguages[mimeType]; ok {
+		return language + " file"
+	}
+	return mimeType + " file"
+}
+
+// formatSize renders a byte count for people
+func formatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d bytes", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	}
+}
+
+// countLines counts lines, including a final line without a newline. Binary
+// content has no lines.
+func countLines(data []byte) int {
+	if len(data) == 0 || bytes.IndexByte(data, 0) >= 0 {
+		return 0
+	}
+	lines := bytes.Count(data, []byte("\n"))
+	if data[len(data)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// summarize returns the first Markdown heading, module docstring or leading
+// comment of a file
+func summarize(path string, data []byte) string {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return ""
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), describeLimit)
+
+	for first := true; scanner.Scan(); first = false {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || first && strings.HasPrefix(line, "#!") {
+			continue
+		}
+
+		switch ext {
+		case ".md", ".markdown":
+			if heading, ok := strings.CutPrefix(line, "#"); ok {
+				return truncate(strings.TrimSpace(strings.TrimLeft(heading, "#")))
+			}
+			continue
+		case ".py":
+			for _, quote := range []string{`"""`, `'''`} {
+				if doc, ok := strings.CutPrefix(line, quote); ok {
+					doc, _, _ = strings.Cut(doc, quote)
+					if doc == "" && scanner.Scan() {
+						doc = scanner.Text()
+					}
+					return truncate(strings.TrimSpace(doc))
+				}
+			}
+		}
+
+		// In C-like languages "#" starts a preprocessor directive
+		if strings.HasPrefix(line, "#") && cFamily[ext] {
+			return ""
+		}
+		if comment, ok := commentText(line); ok {
+			if comment == "" || isBoilerplate(comment) {
+				continue
+			}
+			return truncate(comment)
+		}
+		// Code before any comment; there is no leading doc comment
+		return ""
+	}
+	return ""
+}
+
+// commentText strips a line comment marker, reporting whether the line is a comment
+func commentText(line string) (string, bool) {
+	for _, marker := range []string{"///", "//!", "//", "/**", "/*", "*/", "*", "#", "--", ";"} {
+		if text, ok := strings.CutPrefix(line, marker); ok {
+			text = strings.TrimSuffix(strings.TrimSpace(text), "*/")
+			return strings.TrimSpace(text), true
+		}
+	}
+	return "", false
+}
+
+// isBoilerplate reports whether a comment is a license header, build
+// constraint or tool directive rather than documentation
+func isBoilerplate(comment string) bool {
+	lower := strings.ToLower(comment)
+	for _, prefix := range []string{"copyright", "spdx-license", "license", "go:build", "+build", "eslint", "prettier", "@ts-", "-*-", "vim:", "nolint", "type:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens a summary to summaryLength characters
+func truncate(text string) string {
+	runes := []rune(text)
+	if len(runes) <= summaryLength {
+		return text
+	}
+	return string(runes[:summaryLength-1]) + "…"
+}
@@ -0,0 +1,146 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// directoryListing is the content of a directory resource
+type directoryListing struct {
+	Path    string           `json:"path"`
+	Entries []directoryEntry `json:"entries"`
+}
+
+// directoryEntry describes one child of a directory
+type directoryEntry struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"modTime"`
+	URI     string    `json:"uri"`
+}
+
+// GetDirectoryURI returns the URI for a directory, which ends in "/"
+func (rm *ResourceManager) GetDirectoryURI(path string) string {
+	// The workspace root is "." relative to itself
+	return strings.TrimSuffix(rm.GetFileURI(path), "/.") + "/"
+}
+
+// RegisterDirectoryResource registers a directory as a resource whose
+// content lists its non-ignored children
+func (rm *ResourceManager) RegisterDirectoryResource(server *mcp_golang.Server, path string) error {
+	resourceID := rm.GetResourceIDFromPath(path) + "/"
+	if path == rm.workspacePath {
+		resourceID = "."
+	}
+	uri := rm.GetDirectoryURI(path)
+
+	if rm.debug {
+		log.Printf("Registering directory resource: %s (URI: %s)\n", resourceID, uri)
+	}
+
+	return server.RegisterResource(
+		uri,
+		resourceID,
+		fmt.Sprintf("Directory: %s", resourceID),
+		"application/json",
+		func() (*mcp_golang.ResourceResponse, error) {
+			response, err := rm.getDirectoryResourceHandler(path)()
+			return response, errcode.Tagged(err)
+		},
+	)
+}
+
+// DeregisterDirectoryResource removes a directory resource from the MCP server
+func (rm *ResourceManager) DeregisterDirectoryResource(server *mcp_golang.Server, path string) error {
+	uri := rm.GetDirectoryURI(path)
+
+	if rm.debug {
+		log.Printf("Deregistering directory resource: %s\n", uri)
+	}
+
+	return server.DeregisterResource(uri)
+}
+
+// getDirectoryResourceHandler returns a handler that lists a directory
+func (rm *ResourceManager) getDirectoryResourceHandler(path string) func() (*mcp_golang.ResourceResponse, error) {
+	return func() (*mcp_golang.ResourceResponse, error) {
+		entries, err := rm.files.ReadDir(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, errcode.New(errcode.NotFound, "directory does not exist: %s", path)
+			}
+			return nil, fmt.Errorf("failed to read directory: %v", err)
+		}
+
+		listing := directoryListing{
+			Path:    rm.GetResourceIDFromPath(path),
+			Entries: []directoryEntry{},
+		}
+		for _, entry := range entries {
+			childPath := pathnorm.Normalize(rm.workspacePath, filepath.Join(path, entry.Name()))
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			if info.IsDir() {
+				if rm.matcher.ShouldIgnoreDir(childPath) {
+					continue
+				}
+				listing.Entries = append(listing.Entries, directoryEntry{
+					Name:    filepath.Base(childPath),
+					Type:    "directory",
+					ModTime: info.ModTime(),
+					URI:     rm.GetDirectoryURI(childPath),
+				})
+				continue
+			}
+
+			if rm.matcher.ShouldIgnore(childPath) || diagnostics.SpecialFileType(filepath.Join(path, entry.Name()), info, rm.files.Stat) != "" {
+				continue
+			}
+			// Symlinks to files are listed with the size of their target
+			if target, err := rm.files.Stat(childPath); err == nil {
+				info = target
+			}
+			listing.Entries = append(listing.Entries, directoryEntry{
+				Name:    filepath.Base(childPath),
+				Type:    "file",
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+				URI:     rm.GetFileURI(childPath),
+			})
+		}
+
+		// Directories first, then files, each by name
+		sort.SliceStable(listing.Entries, func(i, j int) bool {
+			a, b := listing.Entries[i], listing.Entries[j]
+			if a.Type != b.Type {
+				return a.Type == "directory"
+			}
+			return a.Name < b.Name
+		})
+
+		data, err := json.MarshalIndent(listing, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode directory listing: %v", err)
+		}
+		return mcp_golang.NewResourceResponse(
+			mcp_golang.NewTextEmbeddedResource(rm.GetDirectoryURI(path), string(data), "application/json"),
+		), nil
+	}
+}
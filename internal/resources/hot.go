@@ -0,0 +1,89 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// URI of the hot files resource
+const hotFilesURI = "workspace://hot-files"
+
+// maxHotFiles caps the files listed by the hot files resource
+const maxHotFiles = 50
+
+// fileReads counts the reads of a file
+type fileReads struct {
+	count int
+	last  time.Time
+}
+
+// hotFile is an entry of the hot files resource
+type hotFile struct {
+	Path     string    `json:"path"`
+	URI      string    `json:"uri"`
+	Reads    int       `json:"reads"`
+	LastRead time.Time `json:"lastRead"`
+}
+
+// RecordRead counts a read of a file by a client, through its resource or
+// a reading tool
+func (rm *ResourceManager) RecordRead(path string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	reads := rm.reads[path]
+	if reads == nil {
+		reads = &fileReads{}
+		rm.reads[path] = reads
+	}
+	reads.count++
+	reads.last = time.Now()
+}
+
+// hotFiles returns the files read most, most recently read first among
+// files read equally often
+func (rm *ResourceManager) hotFiles() []hotFile {
+	rm.mu.RLock()
+	files := make([]hotFile, 0, len(rm.reads))
+	for path, reads := range rm.reads {
+		files = append(files, hotFile{Path: rm.GetResourceIDFromPath(path), URI: rm.GetFileURI(path), Reads: reads.count, LastRead: reads.last})
+	}
+	rm.mu.RUnlock()
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Reads != files[j].Reads {
+			return files[i].Reads > files[j].Reads
+		}
+		return files[i].LastRead.After(files[j].LastRead)
+	})
+	if len(files) > maxHotFiles {
+		files = files[:maxHotFiles]
+	}
+	return files
+}
+
+// RegisterHotFilesResource registers a resource listing the files clients
+// have read most, so an agent can re-orient itself in a long session
+func (rm *ResourceManager) RegisterHotFilesResource(server *mcp_golang.Server) error {
+	return server.RegisterResource(
+		hotFilesURI,
+		"hot-files",
+		"Files read most often through resources and reading tools since the server started, with read counts and the time of the last read",
+		"application/json",
+		func() (*mcp_golang.ResourceResponse, error) {
+			data, err := json.MarshalIndent(struct {
+				Files []hotFile `json:"files"`
+			}{rm.hotFiles()}, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode hot files: %v", err)
+			}
+			return mcp_golang.NewResourceResponse(
+				mcp_golang.NewTextEmbeddedResource(hotFilesURI, string(data), "application/json"),
+			), nil
+		},
+	)
+}
@@ -0,0 +1,94 @@
+package resources
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// preview returns the start of a large file followed by a notice saying how
+// much was left out and how to read the rest
+func (rm *ResourceManager) preview(path string, size int64) (string, error) {
+	file, err := rm.files.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+
+	reader := textio.NewReader(file)
+	head := make([]byte, rm.previewSize)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	head = head[:n]
+
+	// End on a whole line when there is one, and never inside a character
+	rest := head
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	} else {
+		for len(head) > 0 && !utf8.Valid(head) {
+			head = head[:len(head)-1]
+		}
+	}
+	rest = rest[len(head):]
+
+	shownLines := bytes.Count(head, []byte("\n"))
+	totalLines, err := countRemainingLines(rest, reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	totalLines += shownLines
+
+	rel := filepath.ToSlash(rm.GetResourceIDFromPath(path))
+	notice := fmt.Sprintf("[Preview: the first %s of %s", formatSize(int64(len(head))), formatSize(size))
+	if bytes.IndexByte(head, 0) < 0 {
+		notice += fmt.Sprintf(" (lines 1-%d of %d)", shownLines, totalLines)
+	}
+	notice += fmt.Sprintf(". Use the read_file_range tool with path %q to read further lines.]", rel)
+
+	text := string(head)
+	if rm.maskDotEnv && dotenv.Is(path) {
+		text = dotenv.Mask(text)
+	}
+	if rm.lineNumbers {
+		text = textio.NumberLines(text, 1)
+	}
+	return text + "\n" + notice + "\n", nil
+}
+
+// countRemainingLines counts the lines in buffered content followed by the
+// rest of a reader, including a final line without a newline
+func countRemainingLines(buffered []byte, r io.Reader) (int, error) {
+	lines := bytes.Count(buffered, []byte("\n"))
+	last := byte('\n')
+	if len(buffered) > 0 {
+		last = buffered[len(buffered)-1]
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			lines += bytes.Count(buf[:n], []byte("\n"))
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if last != '\n' {
+		lines++
+	}
+	return lines, nil
+}
@@ -1,44 +1,212 @@
 package resources
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"mime"
-	"os"
+	"net/url"
 	"path/filepath"
 	"strings"
-
-	"golang.org/x/text/encoding/unicode"
-	"golang.org/x/text/transform"
+	"sync"
+	"unicode/utf8"
 
 	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/generated"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/manifest"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// URI prefixes for file resources
+const (
+	fileURIPrefix      = "file://"
+	workspaceURIPrefix = "workspace://"
 )
 
-// URI prefix for file resources
-const fileURIPrefix = "file://"
+// URI of the diagnostics resource
+const diagnosticsURI = "workspace://diagnostics"
 
 // ResourceManager manages file resources for the MCP server
 type ResourceManager struct {
-	workspacePath string
-	debug         bool
+	workspacePath   string
+	files           *fsys.Workspace
+	uriScheme       string
+	alias           string
+	matcher         *gitignore.Matcher
+	detectGenerated bool
+	previewAbove    int64 // files larger than this are read as a preview; 0 never
+	previewSize     int64
+	lineNumbers     bool
+	maskDotEnv      bool              // mask the values of .env files
+	generated       map[string]string // URI to the reason the file looks generated
+	descriptions    map[string]description
+	reads           map[string]*fileReads // by path, for the hot files resource
+	mu              sync.RWMutex
+	debug           bool
 }
 
-// NewResourceManager creates a new resource manager
-func NewResourceManager(workspacePath string, debug bool) *ResourceManager {
+// NewResourceManager creates a new resource manager serving the files of a workspace
+func NewResourceManager(files *fsys.Workspace, cfg *config.Config, matcher *gitignore.Matcher, debug bool) *ResourceManager {
+	workspacePath := files.Root()
+	alias := WorkspaceAlias(cfg, workspacePath)
+
 	return &ResourceManager{
-		workspacePath: workspacePath,
-		debug:         debug,
+		workspacePath:   workspacePath,
+		files:           files,
+		uriScheme:       cfg.Resources.URIScheme,
+		alias:           alias,
+		matcher:         matcher,
+		detectGenerated: cfg.DetectGenerated,
+		previewAbove:    cfg.Resources.PreviewThreshold,
+		previewSize:     cfg.Resources.PreviewSize,
+		lineNumbers:     cfg.Resources.LineNumbers,
+		maskDotEnv:      cfg.DotEnv == config.DotEnvMask,
+		generated:       make(map[string]string),
+		descriptions:    make(map[string]description),
+		reads:           make(map[string]*fileReads),
+		debug:           debug,
 	}
 }
 
-// GetFileURI returns the URI for a file path
+// GetFileURI returns the URI for a file path. Files in mounts always have
+// workspace:// URIs named after the mount, since they have no local path.
+// Path segments are percent-encoded, so names with spaces, '#', '%' or
+// non-ASCII characters make valid URIs.
 func (rm *ResourceManager) GetFileURI(path string) string {
 	// Use absolute path
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return fileURIPrefix + path
+		return fileURIPrefix + escapePath(filepath.ToSlash(path))
+	}
+
+	if mount, rel, ok := rm.files.MountPoint(absPath); ok {
+		return workspaceURIPrefix + escapePath(mount+"/"+rel)
+	}
+
+	if rm.uriScheme == config.URISchemeWorkspace {
+		relPath, err := filepath.Rel(rm.workspacePath, absPath)
+		if err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			return workspaceURIPrefix + rm.alias + "/" + escapePath(filepath.ToSlash(relPath))
+		}
+	}
+	return fileURIPrefix + escapePath(filepath.ToSlash(absPath))
+}
+
+// PathFromURI returns the file path of a file:// or workspace:// URI,
+// decoding percent-encoded path segments. URIs that name a path outside the
+// workspace, or outside the mount they start with, aren't file URIs of the
+// workspace, however they get there: "..", encoded separators, backslashes
+// or bytes that aren't UTF-8.
+func (rm *ResourceManager) PathFromURI(uri string) (string, bool) {
+	if path, ok := strings.CutPrefix(uri, fileURIPrefix); ok {
+		path, ok = unescapePath(path)
+		if !ok {
+			return "", false
+		}
+		path = filepath.FromSlash(path)
+		if !filepath.IsAbs(path) {
+			return "", false
+		}
+		return within(rm.workspacePath, filepath.Clean(path))
+	}
+	if relPath, ok := strings.CutPrefix(uri, workspaceURIPrefix+rm.alias+"/"); ok {
+		relPath, ok = unescapePath(relPath)
+		if !ok {
+			return "", false
+		}
+		return within(rm.workspacePath, filepath.Join(rm.workspacePath, filepath.FromSlash(relPath)))
+	}
+	if rest, ok := strings.CutPrefix(uri, workspaceURIPrefix); ok {
+		rest, ok = unescapePath(rest)
+		if !ok {
+			return "", false
+		}
+		mount, relPath, _ := strings.Cut(rest, "/")
+		if mountPath, ok := rm.files.MountPath(mount); ok {
+			return within(mountPath, filepath.Join(mountPath, filepath.FromSlash(relPath)))
+		}
+	}
+	return "", false
+}
+
+// within returns a cleaned path if it is root or below it
+func within(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
 	}
-	return fileURIPrefix + absPath
+	return path, true
+}
+
+// escapePath percent-encodes each segment of a slash-separated path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// unescapePath decodes a percent-encoded path. Clients that send paths
+// unencoded are understood too: a path that isn't valid percent-encoding,
+// such as "100%.txt", is taken as it is. Paths that decode to NUL bytes or
+// to bytes that aren't UTF-8, such as overlong encodings of "." and "/",
+// are rejected.
+func unescapePath(path string) (string, bool) {
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		decoded = path
+	}
+	if strings.IndexByte(decoded, 0) >= 0 || !utf8.ValidString(decoded) {
+		return "", false
+	}
+	return decoded, true
+}
+
+// CanonicalURI returns the URI a file or directory resource is registered
+// under, so clients can refer to it with either scheme
+func (rm *ResourceManager) CanonicalURI(uri string) string {
+	path, ok := rm.PathFromURI(uri)
+	if !ok {
+		return uri
+	}
+	if strings.HasSuffix(uri, "/") {
+		return rm.GetDirectoryURI(path)
+	}
+	return rm.GetFileURI(path)
+}
+
+// WorkspaceAlias returns the name of the workspace in workspace:// URIs
+func WorkspaceAlias(cfg *config.Config, workspacePath string) string {
+	if cfg.Resources.Alias != "" {
+		return cfg.Resources.Alias
+	}
+	return defaultAlias(workspacePath)
+}
+
+// defaultAlias derives a workspace alias from the workspace directory name
+func defaultAlias(workspacePath string) string {
+	alias := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, filepath.Base(workspacePath))
+	alias = strings.TrimLeft(alias, ".-_")
+	if alias == "" || config.Reserved(alias) {
+		return "workspace"
+	}
+	return alias
 }
 
 // GetResourceIDFromPath returns a resource ID from a file path
@@ -56,28 +224,48 @@ func (rm *ResourceManager) GetResourceIDFromPath(path string) string {
 func (rm *ResourceManager) GetFileResourceHandler(path string) func() (*mcp_golang.ResourceResponse, error) {
 	return func() (*mcp_golang.ResourceResponse, error) {
 		// Check if file still exists
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			return nil, fmt.Errorf("file does not exist: %s", path)
+		info, err := rm.files.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errcode.New(errcode.NotFound, "file does not exist: %s", path)
 		}
-
-		// Read file content
-		data, err := os.ReadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read file: %v", err)
+			return nil, fmt.Errorf("failed to stat file: %v", err)
 		}
 
-		// Detect and handle text encodings (UTF-8, UTF-16, etc.)
-		data, err = ensureUTF8(data)
-		if err != nil {
-			return nil, fmt.Errorf("encoding error: %v", err)
+		// Reading a FIFO or device could block forever
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("not a regular file: %s", path)
 		}
 
 		// Get MIME type for the file
-		mimeType := getFileMIMEType(path)
+		mimeType := rm.getFileMIMEType(path)
 		uri := rm.GetFileURI(path)
 
+		// Large files are read as a preview; read_file_range serves the rest
+		if rm.previewAbove > 0 && info.Size() > rm.previewAbove {
+			text, err := rm.preview(path, info.Size())
+			if err != nil {
+				return nil, err
+			}
+			return mcp_golang.NewResourceResponse(
+				mcp_golang.NewTextEmbeddedResource(uri, text, mimeType),
+			), nil
+		}
+
+		// Stream the file through the decoder rather than buffering it twice
+		text, err := rm.readText(path, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		if rm.maskDotEnv && dotenv.Is(path) {
+			text = dotenv.Mask(text)
+		}
+		if rm.lineNumbers {
+			text = textio.NumberLines(text, 1)
+		}
+
 		return mcp_golang.NewResourceResponse(
-			mcp_golang.NewTextEmbeddedResource(uri, string(data), mimeType),
+			mcp_golang.NewTextEmbeddedResource(uri, text, mimeType),
 		), nil
 	}
 }
@@ -86,19 +274,58 @@ func (rm *ResourceManager) GetFileResourceHandler(path string) func() (*mcp_gola
 func (rm *ResourceManager) RegisterFileResource(server *mcp_golang.Server, path string) error {
 	resourceID := rm.GetResourceIDFromPath(path)
 	description := fmt.Sprintf("File: %s", resourceID)
-	mimeType := getFileMIMEType(path)
+	mimeType := rm.getFileMIMEType(path)
 	uri := rm.GetFileURI(path)
 
+	if ecosystem := manifest.Ecosystem(path); ecosystem != "" {
+		description = fmt.Sprintf("Project manifest (%s): %s", ecosystem, resourceID)
+	} else if rm.detectGenerated {
+		if reason := generated.Detect(path, rm.files.Open); reason != "" {
+			description = fmt.Sprintf("Generated file (%s): %s", reason, resourceID)
+			rm.mu.Lock()
+			rm.generated[uri] = reason
+			rm.mu.Unlock()
+		}
+	}
+
 	if rm.debug {
 		log.Printf("Registering resource: %s (URI: %s, MIME: %s)\n", resourceID, uri, mimeType)
 	}
 
+	handler := rm.GetFileResourceHandler(path)
 	return server.RegisterResource(
 		uri,
 		resourceID,
 		description,
 		mimeType,
-		rm.GetFileResourceHandler(path),
+		func() (*mcp_golang.ResourceResponse, error) {
+			response, err := handler()
+			if err != nil {
+				return nil, errcode.Tagged(err)
+			}
+			rm.RecordRead(path)
+			return response, nil
+		},
+	)
+}
+
+// RegisterDiagnosticsResource registers a resource reporting files the server
+// skipped or couldn't access
+func (rm *ResourceManager) RegisterDiagnosticsResource(server *mcp_golang.Server, diag *diagnostics.Diagnostics) error {
+	return server.RegisterResource(
+		diagnosticsURI,
+		"diagnostics",
+		"Server diagnostics: workspace paths that were skipped and why",
+		"application/json",
+		func() (*mcp_golang.ResourceResponse, error) {
+			data, err := json.MarshalIndent(diag.Report(), "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode diagnostics: %v", err)
+			}
+			return mcp_golang.NewResourceResponse(
+				mcp_golang.NewTextEmbeddedResource(diagnosticsURI, string(data), "application/json"),
+			), nil
+		},
 	)
 }
 
@@ -110,11 +337,36 @@ func (rm *ResourceManager) DeregisterFileResource(server *mcp_golang.Server, pat
 		log.Printf("Deregistering resource: %s\n", uri)
 	}
 
+	rm.mu.Lock()
+	delete(rm.generated, uri)
+	delete(rm.descriptions, uri)
+	delete(rm.reads, path)
+	rm.mu.Unlock()
+
 	return server.DeregisterResource(uri)
 }
 
+// ForgetDescription drops the cached description of a file, so it is
+// described afresh even if its size and modification time didn't change
+func (rm *ResourceManager) ForgetDescription(path string) {
+	uri := rm.GetFileURI(path)
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	delete(rm.descriptions, uri)
+}
+
+// IsGenerated reports whether a resource looks generated and should be
+// listed after hand-written files
+func (rm *ResourceManager) IsGenerated(uri string) bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	_, ok := rm.generated[uri]
+	return ok
+}
+
 // getFileMIMEType returns the MIME type for a file
-func getFileMIMEType(path string) string {
+func (rm *ResourceManager) getFileMIMEType(path string) string {
 	// Get MIME type from file extension
 	ext := filepath.Ext(path)
 	mimeType := mime.TypeByExtension(ext)
@@ -125,6 +377,12 @@ func getFileMIMEType(path string) string {
 		if isLikelyTextFile(path) {
 			return "text/plain"
 		}
+		if mimeType := filenameMIMEType(filepath.Base(path)); mimeType != "" {
+			return mimeType
+		}
+		if mimeType := rm.shebangMIMEType(path); mimeType != "" {
+			return mimeType
+		}
 		return "application/octet-stream"
 	}
 
@@ -147,21 +405,97 @@ func isLikelyTextFile(path string) bool {
 	return textExts[ext]
 }
 
-// ensureUTF8 converts text to UTF-8 encoding
-func ensureUTF8(data []byte) ([]byte, error) {
-	// Check for UTF-16 BOM
-	if len(data) >= 2 {
-		if data[0] == 0xFE && data[1] == 0xFF { // UTF-16BE BOM
-			decoder := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
-			result, _, err := transform.Bytes(decoder, data)
-			return result, err
-		} else if data[0] == 0xFF && data[1] == 0xFE { // UTF-16LE BOM
-			decoder := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
-			result, _, err := transform.Bytes(decoder, data)
-			return result, err
+// textFilenames maps well-known extensionless file names to MIME types
+var textFilenames = map[string]string{
+	"Makefile": "text/x-makefile", "makefile": "text/x-makefile", "GNUmakefile": "text/x-makefile",
+	"Dockerfile": "text/x-dockerfile", "Containerfile": "text/x-dockerfile",
+	"Vagrantfile": "text/x-ruby", "Gemfile": "text/x-ruby", "Rakefile": "text/x-ruby",
+	"Podfile": "text/x-ruby", "Brewfile": "text/x-ruby", "Guardfile": "text/x-ruby",
+	"Jenkinsfile": "text/x-groovy", "Justfile": "text/plain", "justfile": "text/plain",
+	"Procfile": "text/plain", "CODEOWNERS": "text/plain", "OWNERS": "text/plain",
+	"LICENSE": "text/plain", "LICENCE": "text/plain", "COPYING": "text/plain",
+	"NOTICE": "text/plain", "AUTHORS": "text/plain", "CONTRIBUTORS": "text/plain",
+	"README": "text/plain", "CHANGELOG": "text/plain", "CHANGES": "text/plain",
+	"INSTALL": "text/plain", "TODO": "text/plain", "VERSION": "text/plain",
+}
+
+// filenameMIMEType returns the MIME type of a well-known file name, such as
+// Makefile or LICENSE, or "" if the name isn't known
+func filenameMIMEType(name string) string {
+	if mimeType, ok := textFilenames[name]; ok {
+		return mimeType
+	}
+	// Variants such as Dockerfile.dev or LICENSE-MIT
+	for _, sep := range []string{".", "-"} {
+		if base, _, found := strings.Cut(name, sep); found {
+			if mimeType, ok := textFilenames[base]; ok {
+				return mimeType
+			}
+		}
+	}
+	return ""
+}
+
+// interpreters maps script interpreters to the MIME type of their scripts
+var interpreters = map[string]string{
+	"sh": "text/x-shellscript", "bash": "text/x-shellscript", "zsh": "text/x-shellscript",
+	"dash": "text/x-shellscript", "ksh": "text/x-shellscript", "fish": "text/x-shellscript",
+	"python": "text/x-python", "node": "text/javascript", "deno": "text/javascript",
+	"bun": "text/javascript", "ruby": "text/x-ruby", "perl": "text/x-perl",
+	"php": "text/x-php", "lua": "text/x-lua", "tclsh": "text/x-tcl",
+}
+
+// shebangMIMEType returns the MIME type of a script from its "#!" line, or
+// "" if the file doesn't start with one
+func (rm *ResourceManager) shebangMIMEType(path string) string {
+	file, err := rm.files.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	head := make([]byte, 256)
+	n, _ := io.ReadFull(file, head)
+	line, ok := strings.CutPrefix(string(head[:n]), "#!")
+	if !ok {
+		return ""
+	}
+	line, _, _ = strings.Cut(line, "\n")
+
+	// "#!/usr/bin/env -S python3 -u" names the interpreter after env's flags
+	fields := strings.Fields(line)
+	if len(fields) > 0 && filepath.Base(fields[0]) == "env" {
+		fields = fields[1:]
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
 		}
 	}
+	if len(fields) == 0 {
+		return "text/plain"
+	}
 
-	// Already UTF-8 or other encoding
-	return data, nil
+	// python3.12 is a python interpreter
+	interpreter := strings.TrimRight(filepath.Base(fields[0]), "0123456789.")
+	if mimeType, ok := interpreters[interpreter]; ok {
+		return mimeType
+	}
+	return "text/plain"
+}
+
+// readText reads a file as UTF-8, decoding UTF-16 with a byte order mark.
+// The content is copied in small chunks straight into a buffer of the
+// file's size, so only one copy of the file is held at a time.
+func (rm *ResourceManager) readText(path string, size int64) (string, error) {
+	file, err := rm.files.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+
+	var text strings.Builder
+	text.Grow(int(size))
+	if _, err := io.Copy(&text, textio.NewReader(file)); err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	return text.String(), nil
 }
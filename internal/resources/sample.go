@@ -0,0 +1,201 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// URI of the workspace sample resource
+const sampleURI = "workspace://sample"
+
+// maxSampledFiles caps the files in the workspace sample
+const maxSampledFiles = 100
+
+// Reasons a file is in the workspace sample
+const (
+	sampleReadme     = "readme"
+	sampleConfig     = "config"
+	sampleEntryPoint = "entry-point"
+	sampleDirectory  = "directory"
+)
+
+// sampleConfigs are manifests and build files that say what a project is
+// and how it is built, wherever they are
+var sampleConfigs = map[string]bool{
+	"go.mod": true, "package.json": true, "pyproject.toml": true, "setup.py": true, "setup.cfg": true,
+	"requirements.txt": true, "Pipfile": true, "Cargo.toml": true, "pom.xml": true, "build.gradle": true,
+	"build.gradle.kts": true, "settings.gradle": true, "Gemfile": true, "composer.json": true, "mix.exs": true,
+	"CMakeLists.txt": true, "Makefile": true, "Dockerfile": true, "docker-compose.yml": true,
+	"docker-compose.yaml": true, "compose.yaml": true, "tsconfig.json": true, "deno.json": true,
+	"WORKSPACE": true, "MODULE.bazel": true, "pnpm-workspace.yaml": true, "lerna.json": true, "nx.json": true,
+	"turbo.json": true, "go.work": true,
+}
+
+// sampleEntryPoints are file names programs and packages usually start in
+var sampleEntryPoints = map[string]bool{
+	"main.go": true, "main.py": true, "__main__.py": true, "app.py": true, "manage.py": true, "wsgi.py": true,
+	"index.js": true, "index.ts": true, "index.tsx": true, "main.js": true, "main.ts": true, "main.tsx": true,
+	"server.js": true, "server.ts": true, "app.js": true, "app.ts": true, "main.rs": true, "lib.rs": true,
+	"Main.java": true, "Application.java": true, "Program.cs": true, "main.c": true, "main.cpp": true,
+	"main.swift": true, "main.kt": true, "index.php": true, "config.ru": true,
+}
+
+// sampleSummaries are file names that usually introduce their directory
+var sampleSummaries = map[string]bool{
+	"doc.go": true, "__init__.py": true, "index.js": true, "index.ts": true, "mod.rs": true, "lib.rs": true,
+	"README.md": true, "package-info.java": true,
+}
+
+// sampledFile is an entry of the workspace sample
+type sampledFile struct {
+	Path   string `json:"path"`
+	URI    string `json:"uri"`
+	Reason string `json:"reason"`
+}
+
+// workspaceSample is the content of the workspace sample resource
+type workspaceSample struct {
+	Files       int           `json:"files"`
+	Directories int           `json:"directories"`
+	Sample      []sampledFile `json:"sample"`
+	Omitted     int           `json:"omittedDirectories,omitempty"` // directories left out by the cap
+}
+
+// sample picks files that give a picture of a workspace too big to list:
+// its READMEs, manifests and build files, the files programs start in and
+// one file from each directory, shallowest directories first
+func (rm *ResourceManager) sample(files []string) workspaceSample {
+	byDir := make(map[string][]string)
+	for _, path := range files {
+		dir := filepath.Dir(path)
+		byDir[dir] = append(byDir[dir], path)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	// Shallow directories first; among equals, the ones with more files
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], string(filepath.Separator)), strings.Count(dirs[j], string(filepath.Separator))
+		if di != dj {
+			return di < dj
+		}
+		if len(byDir[dirs[i]]) != len(byDir[dirs[j]]) {
+			return len(byDir[dirs[i]]) > len(byDir[dirs[j]])
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	result := workspaceSample{Files: len(files), Directories: len(dirs), Sample: []sampledFile{}}
+	picked := make(map[string]bool)
+	covered := make(map[string]bool)
+	pick := func(path, reason string) bool {
+		if picked[path] || len(result.Sample) >= maxSampledFiles {
+			return false
+		}
+		uri := rm.GetFileURI(path)
+		if rm.IsGenerated(uri) {
+			return false
+		}
+		picked[path] = true
+		covered[filepath.Dir(path)] = true
+		result.Sample = append(result.Sample, sampledFile{Path: rm.GetResourceIDFromPath(path), URI: uri, Reason: reason})
+		return true
+	}
+
+	// The root README and configs, then those of projects inside the
+	// workspace, then entry points, each shallowest first; they take at
+	// most half the sample, so that monorepos leave room for directories
+	for _, wanted := range []struct {
+		reason  string
+		matches func(name string) bool
+	}{
+		{sampleReadme, func(name string) bool { return strings.HasPrefix(strings.ToLower(name), "readme") }},
+		{sampleConfig, func(name string) bool { return sampleConfigs[name] }},
+		{sampleEntryPoint, func(name string) bool { return sampleEntryPoints[name] }},
+	} {
+		for _, dir := range dirs {
+			for _, path := range byDir[dir] {
+				if len(result.Sample) < maxSampledFiles/2 && wanted.matches(filepath.Base(path)) {
+					pick(path, wanted.reason)
+				}
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		if covered[dir] {
+			continue
+		}
+		if path := representative(dir, byDir[dir]); !pick(path, sampleDirectory) && len(result.Sample) >= maxSampledFiles {
+			result.Omitted++
+		}
+	}
+	return result
+}
+
+// representative picks the file that best introduces a directory: one
+// named after it or that usually summarizes it, else the first source file
+// that isn't a test, else the first file
+func representative(dir string, files []string) string {
+	sorted := append([]string(nil), files...)
+	sort.Strings(sorted)
+	name := strings.ToLower(filepath.Base(dir))
+	for _, path := range sorted {
+		base := filepath.Base(path)
+		if strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base))) == name || sampleSummaries[base] {
+			return path
+		}
+	}
+	for _, path := range sorted {
+		if isSourceFile(path) && !isTestName(filepath.Base(path)) {
+			return path
+		}
+	}
+	return sorted[0]
+}
+
+// isSourceFile reports whether a file is code rather than data or docs
+func isSourceFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go", ".py", ".js", ".jsx", ".ts", ".tsx", ".mjs", ".rs", ".java", ".kt", ".scala", ".rb", ".php",
+		".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".swift", ".m", ".ex", ".exs", ".erl", ".hs", ".ml",
+		".clj", ".lua", ".dart", ".vue", ".svelte", ".sh":
+		return true
+	}
+	return false
+}
+
+// isTestName reports whether a file name follows a common test naming
+// convention
+func isTestName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "_test.") || strings.Contains(lower, ".test.") || strings.Contains(lower, ".spec.") ||
+		strings.HasPrefix(lower, "test_") || strings.HasSuffix(strings.TrimSuffix(name, filepath.Ext(name)), "Test")
+}
+
+// RegisterSampleResource registers a resource sampling the workspace's
+// files, for a quick picture of a workspace too big to list. Files returns
+// the registered files.
+func (rm *ResourceManager) RegisterSampleResource(server *mcp_golang.Server, files func() []string) error {
+	return server.RegisterResource(
+		sampleURI,
+		"sample",
+		fmt.Sprintf("A sample of up to %d files that give a picture of the workspace: READMEs, manifests and build configs, entry points and one file from each directory, shallowest first", maxSampledFiles),
+		"application/json",
+		func() (*mcp_golang.ResourceResponse, error) {
+			data, err := json.MarshalIndent(rm.sample(files()), "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode workspace sample: %v", err)
+			}
+			return mcp_golang.NewResourceResponse(
+				mcp_golang.NewTextEmbeddedResource(sampleURI, string(data), "application/json"),
+			), nil
+		},
+	)
+}
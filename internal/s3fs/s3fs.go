@@ -0,0 +1,453 @@
+// Package s3fs serves a bucket in S3 or an S3-compatible object store as a
+// read-only filesystem. Object keys are split on "/" into directories, so
+// a key prefix is a directory and listing one follows every page of
+// results.
+//
+// Credentials and the region come from the standard AWS environment
+// variables; without credentials requests are unsigned, for public
+// buckets. AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL points the client at an
+// S3-compatible service such as MinIO, addressed path-style.
+package s3fs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+)
+
+// defaultRegion is used when the environment doesn't set one
+const defaultRegion = "us-east-1"
+
+// Target is a parsed s3:// workspace URL
+type Target struct {
+	Bucket string
+	Prefix string // key prefix without leading or trailing slashes
+}
+
+// IsURL reports whether a workspace argument names a bucket
+func IsURL(workspace string) bool {
+	return strings.HasPrefix(workspace, "s3://")
+}
+
+// Parse parses s3://bucket[/prefix]
+func Parse(workspace string) (Target, error) {
+	u, err := url.Parse(workspace)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid workspace URL: %v", err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return Target{}, fmt.Errorf("invalid workspace URL %q: expected s3://bucket[/prefix]", workspace)
+	}
+	return Target{Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+// FS is a read-only view of the objects below a prefix of a bucket
+type FS struct {
+	client    *http.Client
+	endpoint  *url.URL
+	pathStyle bool
+	bucket    string
+	prefix    string
+	region    string
+	creds     credentials
+}
+
+var _ fsys.FS = (*FS)(nil)
+
+// Dial configures access to the target from the environment and checks
+// that the bucket can be listed
+func Dial(target Target) (*FS, error) {
+	f := &FS{
+		client: &http.Client{Timeout: 60 * time.Second},
+		bucket: target.Bucket,
+		prefix: target.Prefix,
+		region: firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		creds: credentials{
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+	}
+	if f.region == "" {
+		f.region = defaultRegion
+	}
+
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		f.endpoint = u
+		f.pathStyle = true
+	} else {
+		f.endpoint = &url.URL{Scheme: "https", Host: "s3." + f.region + ".amazonaws.com"}
+		// Virtual-hosted names can't carry dots under the wildcard certificate
+		f.pathStyle = strings.Contains(f.bucket, ".")
+	}
+
+	_, err := f.list(f.dirPrefix("."), "/", 1, "")
+	// Buckets outside the configured region name their own
+	var wrongRegion *regionError
+	if errors.As(err, &wrongRegion) && f.endpoint.Host == "s3."+f.region+".amazonaws.com" {
+		f.region = wrongRegion.region
+		f.endpoint.Host = "s3." + f.region + ".amazonaws.com"
+		_, err = f.list(f.dirPrefix("."), "/", 1, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list s3://%s/%s: %v", f.bucket, f.prefix, err)
+	}
+	return f, nil
+}
+
+// Mount opens an s3:// workspace URL as a read-only workspace. The
+// workspace path is /<bucket>/<prefix>; it only names the workspace.
+func Mount(workspace string) (*fsys.Workspace, error) {
+	target, err := Parse(workspace)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Dial(target)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.Mount(path.Join("/", target.Bucket, target.Prefix), f), nil
+}
+
+// firstEnv returns the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// key returns the object key of a name
+func (f *FS) key(name string) string {
+	if name == "." {
+		return f.prefix
+	}
+	if f.prefix == "" {
+		return name
+	}
+	return f.prefix + "/" + name
+}
+
+// dirPrefix returns the key prefix of the objects inside a directory
+func (f *FS) dirPrefix(name string) string {
+	if key := f.key(name); key != "" {
+		return key + "/"
+	}
+	return ""
+}
+
+// request sends a signed GET or HEAD for a key, or for the bucket when key
+// is empty, and returns the response of a successful request
+func (f *FS) request(method, key string, query url.Values) (*http.Response, error) {
+	u := *f.endpoint
+	objectPath := "/" + key
+	if f.pathStyle {
+		objectPath = strings.TrimSuffix(u.Path, "/") + "/" + f.bucket + "/" + key
+	} else {
+		u.Host = f.bucket + "." + u.Host
+	}
+	if key == "" {
+		objectPath = strings.TrimSuffix(objectPath, "/")
+		if objectPath == "" {
+			objectPath = "/"
+		}
+	}
+	u.Path = objectPath
+	u.RawPath = escapePath(objectPath)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	f.creds.sign(req, f.region, time.Now())
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if region := resp.Header.Get("x-amz-bucket-region"); region != "" && region != f.region {
+		return nil, &regionError{region: region}
+	}
+	return nil, responseError(resp)
+}
+
+// regionError is returned for a bucket in another region than the one
+// requests were signed for
+type regionError struct {
+	region string
+}
+
+func (e *regionError) Error() string {
+	return "bucket is in region " + e.region
+}
+
+// responseError converts a failed response to an error
+func responseError(resp *http.Response) error {
+	err := &requestError{status: resp.StatusCode, message: resp.Status}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		err.message = body.Code
+		if body.Message != "" && body.Message != body.Code {
+			err.message += ": " + body.Message
+		}
+	}
+	return err
+}
+
+// requestError is a failed request
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// Unwrap maps missing keys and denied access to the io/fs errors
+func (e *requestError) Unwrap() error {
+	switch e.status {
+	case http.StatusNotFound:
+		return fs.ErrNotExist
+	case http.StatusForbidden:
+		return fs.ErrPermission
+	}
+	return nil
+}
+
+// listPage is one page of a ListObjectsV2 response
+type listPage struct {
+	KeyCount              int    `xml:"KeyCount"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// list requests one page of the keys below a prefix
+func (f *FS) list(prefix, delimiter string, maxKeys int, token string) (*listPage, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	resp, err := f.request(http.MethodGet, "", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var page listPage
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to parse listing: %v", err)
+	}
+	return &page, nil
+}
+
+// Stat implements fs.StatFS. A name is a file if an object has its key,
+// and a directory if any object's key starts with it and a slash.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return fileInfo{name: ".", dir: true}, nil
+	}
+	resp, err := f.request(http.MethodHead, f.key(name), nil)
+	if err == nil {
+		resp.Body.Close()
+		return objectInfo(path.Base(name), resp), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	page, err := f.list(f.dirPrefix(name), "", 1, "")
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if page.KeyCount == 0 && len(page.Contents) == 0 {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// objectInfo describes an object from the headers of its response
+func objectInfo(name string, resp *http.Response) fileInfo {
+	info := fileInfo{name: name, size: resp.ContentLength}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.modTime = modified
+	}
+	return info
+}
+
+// ReadDir implements fs.ReadDirFS, following every page of the listing.
+// Keys that aren't valid names, such as ones with empty segments, are
+// left out, as are the empty marker objects some tools create for
+// directories.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := f.dirPrefix(name)
+	found := name == "."
+	dirs := make(map[string]bool)
+	files := make(map[string]fileInfo)
+	for token := ""; ; {
+		page, err := f.list(prefix, "/", 0, token)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, object := range page.Contents {
+			found = true
+			child := strings.TrimPrefix(object.Key, prefix)
+			if child == "" || !validName(child) {
+				continue
+			}
+			files[child] = fileInfo{name: child, size: object.Size, modTime: object.LastModified}
+		}
+		for _, common := range page.CommonPrefixes {
+			found = true
+			child := strings.TrimSuffix(strings.TrimPrefix(common.Prefix, prefix), "/")
+			if validName(child) {
+				dirs[child] = true
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(dirs)+len(files))
+	for child := range dirs {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: child, dir: true}))
+	}
+	for child, info := range files {
+		// A key that is also a prefix is served as the directory
+		if !dirs[child] {
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// validName reports whether a key segment can be a file name
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// Open implements fs.FS
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name != "." {
+		resp, err := f.request(http.MethodGet, f.key(name), nil)
+		if err == nil {
+			return &file{info: objectInfo(path.Base(name), resp), body: resp.Body}, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	entries, err := f.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.DirFile(fileInfo{name: path.Base(name), dir: true}, entries), nil
+}
+
+// ReadFile implements fs.ReadFileFS
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	resp, err := f.request(http.MethodGet, f.key(name), nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	defer resp.Body.Close()
+	buf := bytes.NewBuffer(make([]byte, 0, max(resp.ContentLength, 0)))
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return buf.Bytes(), nil
+}
+
+// fileInfo describes an object or a key prefix
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
+
+// Mode reports objects as read-only files
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// file is an object being downloaded
+type file struct {
+	info fileInfo
+	body io.ReadCloser
+}
+
+// Stat implements fs.File
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Read implements fs.File
+func (f *file) Read(p []byte) (int, error) { return f.body.Read(p) }
+
+// Close implements fs.File
+func (f *file) Close() error { return f.body.Close() }
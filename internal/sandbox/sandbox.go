@@ -0,0 +1,33 @@
+// Package sandbox confines the server to the files it needs, so a bug in
+// path validation can't reach anything else on the machine.
+//
+// The confinement is entered by re-executing the server under it: on Linux
+// the current thread is restricted with Landlock and the server executed
+// again from it, since Landlock confines the process image started from a
+// confined thread as a whole; on macOS the server is restarted through
+// sandbox-exec with a generated profile. The re-executed server starts over
+// inside the sandbox and finds it is already confined. Commands the server
+// runs are confined as well.
+package sandbox
+
+import "os"
+
+// confinedEnv marks a server re-executed inside the sandbox
+const confinedEnv = "MCP_FILESYSTEM_SANDBOXED"
+
+// Rules are the paths a confined server may use. Paths that don't exist
+// are skipped.
+type Rules struct {
+	// ReadWrite may be read, written, created and removed
+	ReadWrite []string
+	// ReadOnly may be read
+	ReadOnly []string
+	// Exec may be read and executed, for the server itself and the programs
+	// it runs, such as git and formatters
+	Exec []string
+}
+
+// Active reports whether the server runs inside the sandbox
+func Active() bool {
+	return os.Getenv(confinedEnv) != ""
+}
@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// sandboxExec applies a Seatbelt profile to the program it runs
+const sandboxExec = "/usr/bin/sandbox-exec"
+
+// System returns the system paths programs need to run: frameworks,
+// libraries and binaries for git, formatters and allowed commands, and the
+// devices the runtime uses
+func System() Rules {
+	return Rules{
+		ReadWrite: []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom", "/dev/tty", "/dev/fd", "/dev/dtracehelper"},
+		Exec: []string{
+			"/usr", "/bin", "/sbin", "/System", "/Library", "/private/etc", "/private/var/db",
+			"/opt/homebrew", "/Applications/Xcode.app",
+		},
+	}
+}
+
+// Enter restarts the server through sandbox-exec with a profile allowing
+// file access to the paths of rules only; it only returns, with an error,
+// if that fails. Inside the sandbox it returns nil right away.
+func Enter(rules Rules) error {
+	if Active() {
+		return nil
+	}
+	if _, err := os.Stat(sandboxExec); err != nil {
+		return fmt.Errorf("sandbox-exec isn't available: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the server executable: %v", err)
+	}
+
+	args := append([]string{sandboxExec, "-p", profile(rules, exe), exe}, os.Args[1:]...)
+	env := append(os.Environ(), confinedEnv+"=1")
+	return fmt.Errorf("failed to restart inside the sandbox: %v", syscall.Exec(sandboxExec, args, env))
+}
+
+// profile returns a Seatbelt profile that denies reading and writing files
+// outside the rules. Everything else, such as networking and running
+// programs, is left to the system's usual checks.
+func profile(rules Rules, exe string) string {
+	var b strings.Builder
+	b.WriteString("(version 1)\n(allow default)\n(deny file-read* file-write*)\n")
+	// Resolving any path needs the metadata of the directories above it
+	b.WriteString("(allow file-read-metadata)\n")
+	allow := func(ops string, paths []string) {
+		for _, path := range paths {
+			fmt.Fprintf(&b, "(allow %s (subpath %s))\n", ops, quote(realPath(path)))
+		}
+	}
+	allow("file-read*", append(append(rules.ReadOnly, rules.Exec...), exe))
+	allow("file-read* file-write*", rules.ReadWrite)
+	return b.String()
+}
+
+// realPath resolves symbolic links, since profiles match the paths files
+// really have, such as /private/var/folders for the temp directory
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// quote returns path as a profile string literal
+func quote(path string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path) + `"`
+}
@@ -0,0 +1,135 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Landlock access rights granted by rule kind
+const (
+	readAccess = unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	execAccess = readAccess | unix.LANDLOCK_ACCESS_FS_EXECUTE
+	// fileAccess are the rights that apply to a file rather than a directory
+	fileAccess = unix.LANDLOCK_ACCESS_FS_EXECUTE | unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+		unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+// rulesetAttrSize is the size of the ruleset attribute up to its
+// filesystem rights, the only part used
+const rulesetAttrSize = 8
+
+// System returns the system paths programs need to run: libraries and
+// binaries for git, formatters and allowed commands, and the devices and
+// process information the runtime reads
+func System() Rules {
+	return Rules{
+		ReadWrite: []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom", "/dev/tty", "/dev/pts", "/dev/ptmx", "/dev/shm"},
+		ReadOnly:  []string{"/proc", "/sys"},
+		Exec:      []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt", "/nix/store"},
+	}
+}
+
+// Enter confines the server with Landlock to the paths of rules and
+// re-executes it; it only returns, with an error, if that fails. Inside the
+// sandbox it returns nil right away.
+func Enter(rules Rules) error {
+	if Active() {
+		return nil
+	}
+
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("Landlock isn't available; it needs Linux 5.13 or later with Landlock enabled (%v)", errno)
+	}
+	handled := handledAccess(int(abi))
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	ruleset, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), rulesetAttrSize, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create Landlock ruleset: %v", errno)
+	}
+	defer unix.Close(int(ruleset))
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the server executable: %v", err)
+	}
+	for _, path := range rules.ReadWrite {
+		if err := addRule(ruleset, path, handled, handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range rules.ReadOnly {
+		if err := addRule(ruleset, path, readAccess, handled); err != nil {
+			return err
+		}
+	}
+	for _, path := range append(rules.Exec, exe) {
+		if err := addRule(ruleset, path, execAccess, handled); err != nil {
+			return err
+		}
+	}
+
+	// The restriction applies to this thread only; the process started by
+	// exec from it is confined as a whole. The thread is never unlocked.
+	runtime.LockOSThread()
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to drop privilege escalation: %v", err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, ruleset, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enter Landlock sandbox: %v", errno)
+	}
+	env := append(os.Environ(), confinedEnv+"=1")
+	return fmt.Errorf("failed to restart inside the sandbox: %v", unix.Exec(exe, os.Args, env))
+}
+
+// handledAccess returns the filesystem rights a Landlock ABI version can
+// restrict; all of them are denied outside the rules
+func handledAccess(abi int) uint64 {
+	// Version 1 covers executing, reading, writing, removing and creating
+	access := uint64(unix.LANDLOCK_ACCESS_FS_MAKE_SYM<<1 - 1)
+	if abi >= 2 {
+		access |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		access |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	if abi >= 5 {
+		access |= unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	}
+	return access
+}
+
+// addRule grants access to path and everything below it
+func addRule(ruleset uintptr, path string, access, handled uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s for the sandbox: %v", path, err)
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("failed to stat %s for the sandbox: %v", path, err)
+	}
+	access &= handled
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= fileAccess
+	}
+
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, ruleset, unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to add %s to the sandbox: %v", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package sandbox
+
+import "fmt"
+
+// Enter is not supported on this platform
+func Enter(rules Rules) error {
+	return fmt.Errorf("sandboxing isn't supported on this platform")
+}
+
+// System returns no paths on this platform
+func System() Rules {
+	return Rules{}
+}
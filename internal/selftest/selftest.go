@@ -0,0 +1,323 @@
+// Package selftest starts the server against a scratch workspace and drives
+// it through the MCP handshake and basic requests, so users can verify a
+// build and environment before pointing a client at it.
+package selftest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options control a self-test run
+type Options struct {
+	// Executable is the server binary to test
+	Executable string
+	// Args are extra arguments passed to "serve"
+	Args []string
+	// Timeout bounds each request and the wait for change notifications
+	Timeout time.Duration
+	// Keep leaves the scratch workspace in place for inspection
+	Keep bool
+}
+
+// rpcMessage is a JSON-RPC request, response or notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// client is a minimal line-delimited JSON-RPC client
+type client struct {
+	stdin         io.Writer
+	lines         chan []byte
+	nextID        int
+	timeout       time.Duration
+	notifications []string
+}
+
+// call sends a request and waits for its response, recording notifications seen meanwhile
+func (c *client) call(method string, params any, result any) error {
+	c.nextID++
+	id := c.nextID
+	data, err := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+
+	deadline := time.After(c.timeout)
+	for {
+		select {
+		case line, ok := <-c.lines:
+			if !ok {
+				return fmt.Errorf("server exited")
+			}
+			var msg rpcMessage
+			if err := json.Unmarshal(line, &msg); err != nil {
+				return fmt.Errorf("invalid JSON from server: %v: %s", err, line)
+			}
+			if msg.ID == nil {
+				c.notifications = append(c.notifications, msg.Method)
+				continue
+			}
+			if *msg.ID != id {
+				continue
+			}
+			if msg.Error != nil {
+				return fmt.Errorf("%s (code %d)", msg.Error.Message, msg.Error.Code)
+			}
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(msg.Result, result)
+		case <-deadline:
+			return fmt.Errorf("no response to %s within %v", method, c.timeout)
+		}
+	}
+}
+
+// notify sends a notification
+func (c *client) notify(method string) error {
+	data, err := json.Marshal(rpcMessage{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// resourceList is the result of resources/list
+type resourceList struct {
+	Resources []struct {
+		URI  string `json:"uri"`
+		Name string `json:"name"`
+	} `json:"resources"`
+}
+
+// resourceContents is the result of resources/read
+type resourceContents struct {
+	Contents []struct {
+		Text string `json:"text"`
+	} `json:"contents"`
+}
+
+// toolResult is the result of tools/call
+type toolResult struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// Run performs the self-test, printing one line per step to out, and
+// reports whether every step passed
+func Run(opts Options, out io.Writer) bool {
+	workspace, err := os.MkdirTemp("", "mcp-filesystem-selftest-")
+	if err != nil {
+		fmt.Fprintf(out, "FAIL  create workspace: %v\n", err)
+		return false
+	}
+	if opts.Keep {
+		fmt.Fprintf(out, "Workspace: %s\n", workspace)
+	} else {
+		defer os.RemoveAll(workspace)
+	}
+
+	files := map[string]string{
+		"hello.txt": "hello from the self-test\n",
+		"go.mod":    "module example.com/selftest\n\ngo 1.21\n",
+		// The scratch workspace is deleted, so its search index shouldn't outlive it
+		".mcp-filesystem.yaml": "index:\n  persist: false\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+			fmt.Fprintf(out, "FAIL  create workspace: %v\n", err)
+			return false
+		}
+	}
+
+	args := append([]string{"serve", "--workspace", workspace}, opts.Args...)
+	cmd := exec.Command(opts.Executable, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		fmt.Fprintf(out, "FAIL  start server: %v\n", err)
+		return false
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		fmt.Fprintf(out, "FAIL  start server: %v\n", err)
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(out, "FAIL  start server: %v\n", err)
+		return false
+	}
+	defer func() {
+		_ = stdin.Close()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	c := &client{stdin: stdin, lines: make(chan []byte, 64), timeout: opts.Timeout}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+		for scanner.Scan() {
+			c.lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+		close(c.lines)
+	}()
+
+	helloURI := "file://" + filepath.ToSlash(filepath.Join(workspace, "hello.txt"))
+	writtenURI := "file://" + filepath.ToSlash(filepath.Join(workspace, "written.txt"))
+	const writtenContent = "written by the self-test\n"
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"initialize", func() error {
+			var result struct {
+				ServerInfo struct {
+					Name string `json:"name"`
+				} `json:"serverInfo"`
+			}
+			params := map[string]any{
+				"protocolVersion": "2024-11-05",
+				"capabilities":    map[string]any{},
+				"clientInfo":      map[string]any{"name": "mcp-filesystem-selftest", "version": "1.0.0"},
+			}
+			if err := c.call("initialize", params, &result); err != nil {
+				return err
+			}
+			if result.ServerInfo.Name == "" {
+				return fmt.Errorf("response has no serverInfo")
+			}
+			return c.notify("notifications/initialized")
+		}},
+		{"tools/list", func() error {
+			var result struct {
+				Tools []struct {
+					Name string `json:"name"`
+				} `json:"tools"`
+			}
+			if err := c.call("tools/list", map[string]any{}, &result); err != nil {
+				return err
+			}
+			for _, tool := range result.Tools {
+				if tool.Name == "write_file" {
+					return nil
+				}
+			}
+			return fmt.Errorf("write_file is not among %d tools", len(result.Tools))
+		}},
+		{"resources/list", func() error {
+			var result resourceList
+			if err := c.call("resources/list", map[string]any{}, &result); err != nil {
+				return err
+			}
+			if len(result.Resources) == 0 || result.Resources[0].Name != "go.mod" {
+				return fmt.Errorf("expected the go.mod manifest to be listed first")
+			}
+			for _, resource := range result.Resources {
+				if resource.URI == helloURI {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s is not listed", helloURI)
+		}},
+		{"resources/read", func() error {
+			var result resourceContents
+			if err := c.call("resources/read", map[string]any{"uri": helloURI}, &result); err != nil {
+				return err
+			}
+			if len(result.Contents) != 1 || result.Contents[0].Text != files["hello.txt"] {
+				return fmt.Errorf("unexpected contents: %+v", result.Contents)
+			}
+			return nil
+		}},
+		{"tools/call write_file", func() error {
+			var result toolResult
+			args := map[string]any{"name": "write_file", "arguments": map[string]any{"path": "written.txt", "content": writtenContent}}
+			if err := c.call("tools/call", args, &result); err != nil {
+				return err
+			}
+			if result.IsError {
+				return fmt.Errorf("tool failed: %+v", result.Content)
+			}
+			data, err := os.ReadFile(filepath.Join(workspace, "written.txt"))
+			if err != nil {
+				return err
+			}
+			if string(data) != writtenContent {
+				return fmt.Errorf("file on disk has unexpected content %q", data)
+			}
+			return nil
+		}},
+		{"change notification", func() error {
+			// The watcher registers the new file shortly after it is written
+			deadline := time.Now().Add(c.timeout)
+			for time.Now().Before(deadline) {
+				var result resourceList
+				if err := c.call("resources/list", map[string]any{}, &result); err != nil {
+					return err
+				}
+				for _, resource := range result.Resources {
+					if resource.URI == writtenURI {
+						return nil
+					}
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			return fmt.Errorf("%s was not registered within %v", writtenURI, c.timeout)
+		}},
+		{"resources/read written file", func() error {
+			var result resourceContents
+			if err := c.call("resources/read", map[string]any{"uri": writtenURI}, &result); err != nil {
+				return err
+			}
+			if len(result.Contents) != 1 || result.Contents[0].Text != writtenContent {
+				return fmt.Errorf("unexpected contents: %+v", result.Contents)
+			}
+			return nil
+		}},
+	}
+
+	passed := true
+	for _, step := range steps {
+		start := time.Now()
+		if err := step.run(); err != nil {
+			fmt.Fprintf(out, "FAIL  %s: %v\n", step.name, err)
+			passed = false
+			break
+		}
+		fmt.Fprintf(out, "PASS  %s (%v)\n", step.name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if !passed && stderr.Len() > 0 {
+		fmt.Fprintf(out, "\nServer output:\n%s\n", strings.TrimSpace(stderr.String()))
+	}
+	return passed
+}
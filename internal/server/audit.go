@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/isaacphi/mcp-filesystem/internal/tools"
+)
+
+// auditLog keeps the audit records of tools, one JSON object per line, in
+// a file or else in the server log
+type auditLog struct {
+	file *os.File // nil to use the server log
+	mu   sync.Mutex
+}
+
+// newAuditLog opens path for appending, or uses the server log if path is
+// empty
+func newAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		return &auditLog{}, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+// Audit implements tools.Auditor
+func (a *auditLog) Audit(record tools.AuditRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Error encoding audit record: %v", err)
+		return
+	}
+	if a.file == nil {
+		log.Printf("Audit: %s", data)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// Close closes the audit log file
+func (a *auditLog) Close() error {
+	if a.file == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
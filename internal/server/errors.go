@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// JSON-RPC error codes for failed resource reads
+const (
+	resourceNotFoundCode = -32002
+	internalErrorCode    = -32603
+)
+
+// toolErrorPrefix is what mcp-golang puts before the message of an error
+// returned by a tool handler
+const toolErrorPrefix = "handler returned an error: "
+
+// errorData is the structured data of a failed tool call or resource read
+type errorData struct {
+	Code    errcode.Code `json:"code"`
+	Message string       `json:"message"`
+	// Remediation tells the client what to do instead, for clients to
+	// surface or act on
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// rpcError is returned by a result rewriter to answer the request with a
+// JSON-RPC error instead
+type rpcError struct {
+	code    int
+	message string
+	data    any
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+// codeToolError gives failed tool calls their error code: the message keeps
+// it as a prefix, and _meta.error holds the code and message as data
+func codeToolError(params, result json.RawMessage) (json.RawMessage, error) {
+	var response map[string]any
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, err
+	}
+	if isError, _ := response["isError"].(bool); !isError {
+		return result, nil
+	}
+	content, _ := response["content"].([]any)
+	if len(content) != 1 {
+		return result, nil
+	}
+	item, _ := content[0].(map[string]any)
+	text, ok := item["text"].(string)
+	if !ok {
+		return result, nil
+	}
+
+	text = strings.TrimPrefix(text, toolErrorPrefix)
+	item["text"] = text
+	if code, message, remediation := errcode.Parse(text); code != "" {
+		response["_meta"] = map[string]any{"error": errorData{Code: code, Message: message, Remediation: remediation}}
+	}
+	return json.Marshal(response)
+}
+
+// codeResourceError turns a failed resource read, which mcp-golang answers
+// with the error message as the resource's content, into a JSON-RPC error
+// carrying the error code as data
+func codeResourceError(params, result json.RawMessage) (json.RawMessage, error) {
+	// mcp-golang answers reads of unknown resources with a null result
+	if string(result) == "null" {
+		var request struct {
+			URI string `json:"uri"`
+		}
+		json.Unmarshal(params, &request)
+		return nil, resourceError(fmt.Sprintf("%s: resource not found: %s", errcode.NotFound, request.URI))
+	}
+	var response struct {
+		Contents []struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, err
+	}
+	// Every resource the server registers responds with its URI
+	if len(response.Contents) != 1 || response.Contents[0].URI != "" {
+		return result, nil
+	}
+	return nil, resourceError(response.Contents[0].Text)
+}
+
+// resourceError returns the JSON-RPC error for a resource read that failed
+// with message
+func resourceError(message string) *rpcError {
+	code, text, remediation := errcode.Parse(message)
+	if code == "" {
+		return &rpcError{code: internalErrorCode, message: message}
+	}
+	rpcCode := internalErrorCode
+	if code == errcode.NotFound {
+		rpcCode = resourceNotFoundCode
+	}
+	return &rpcError{code: rpcCode, message: fmt.Sprintf("%s: %s", code, text), data: errorData{Code: code, Message: text, Remediation: remediation}}
+}
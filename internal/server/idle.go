@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// activity tracks when clients last sent anything and how many of their
+// requests are still being answered
+type activity struct {
+	last     time.Time
+	inflight int
+	mu       sync.Mutex
+}
+
+// newActivity creates an activity tracker that counts from now
+func newActivity() *activity {
+	return &activity{last: time.Now()}
+}
+
+// request records a request from a client
+func (a *activity) request() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight++
+	a.last = time.Now()
+}
+
+// answered records the response to a request
+func (a *activity) answered() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inflight > 0 {
+		a.inflight--
+	}
+	a.last = time.Now()
+}
+
+// notified records a notification from a client
+func (a *activity) notified() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.last = time.Now()
+}
+
+// idleFor returns how long clients have been quiet; a request still being
+// answered, such as a long command, isn't idle
+func (a *activity) idleFor() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inflight > 0 {
+		return 0
+	}
+	return time.Since(a.last)
+}
+
+// handlePing answers pings, which clients send as keepalives; like any
+// request they count as activity
+func handlePing(ctx context.Context, params json.RawMessage) (any, error) {
+	return struct{}{}, nil
+}
+
+// ShutdownWhenIdle calls shutdown once no client has sent a request,
+// notification or ping for timeout, so servers orphaned by clients that
+// never closed them don't pile up; call it before starting the server
+func (s *MCPServer) ShutdownWhenIdle(timeout time.Duration, shutdown func()) {
+	go func() {
+		for {
+			idle := s.activity.idleFor()
+			if idle >= timeout {
+				log.Printf("No client requests for %v, shutting down", timeout)
+				shutdown()
+				return
+			}
+			select {
+			case <-time.After(timeout - idle):
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
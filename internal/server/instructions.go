@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scanWait is how long the initialize response waits for the first scan
+// of the workspace, so the file counts it describes are complete
+const scanWait = 2 * time.Second
+
+// describedExtensions is how many of the most common file extensions the
+// instructions list
+const describedExtensions = 5
+
+// sampleHint is how many files a workspace has before the instructions
+// point to workspace://sample
+const sampleHint = 1000
+
+// notableTools are pointed out in the instructions when offered, in order
+var notableTools = []struct{ name, use string }{
+	{"workspace_info", "the environment and ignore rules"},
+	{"search", "text and definitions across files"},
+	{"read_file_range", "parts of large files"},
+	{"read_symbol", "a single function or type"},
+	{"find_references", "where an identifier is used"},
+	{"write_file", "creating and changing files"},
+	{"edit_structured", "values in JSON, YAML and TOML files"},
+	{"run_command", "allowed commands"},
+	{"revert_last_operation", "undoing the last change"},
+}
+
+// initializeResult advertises resource subscriptions and adds instructions
+// describing the workspace to the initialize response, so models get their
+// bearings without an extra round trip
+func (s *MCPServer) initializeResult(params, result json.RawMessage) (json.RawMessage, error) {
+	result, err := advertiseSubscribe(params, result)
+	if err != nil {
+		return nil, err
+	}
+	var response map[string]any
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, err
+	}
+	response["instructions"] = s.instructions()
+	return json.Marshal(response)
+}
+
+// instructions describes the workspace: its root, how many files it
+// exposes, whether they can be changed and the tools worth starting with
+func (s *MCPServer) instructions() string {
+	scanned := true
+	select {
+	case <-s.scanned:
+	case <-time.After(scanWait):
+		scanned = false
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This server exposes the workspace at %s (resources under %s).", s.workspacePath, s.resourceManager.GetDirectoryURI(s.workspacePath))
+
+	s.mu.RLock()
+	files, dirs := len(s.registeredFiles), len(s.registeredDirs)
+	extensions := make(map[string]int)
+	for path := range s.registeredFiles {
+		if ext := strings.ToLower(filepath.Ext(path)); ext != "" {
+			extensions[ext]++
+		}
+	}
+	s.mu.RUnlock()
+	if scanned {
+		fmt.Fprintf(&b, " It has %d files in %d directories", files, dirs)
+	} else {
+		fmt.Fprintf(&b, " It has at least %d files in %d directories; the first scan is still running", files, dirs)
+	}
+	if common := commonExtensions(extensions); common != "" {
+		fmt.Fprintf(&b, ", mostly %s", common)
+	}
+	b.WriteString(". Ignored files, such as those matched by .gitignore, aren't exposed.")
+	if files > sampleHint {
+		b.WriteString(" For an overview of a workspace this size, read workspace://sample: its READMEs, build configs, entry points and a file from each directory.")
+	}
+
+	if mounts := s.files.MountPaths(); len(mounts) > 0 {
+		fmt.Fprintf(&b, " Also mounted: %s.", strings.Join(mounts, ", "))
+	}
+
+	switch _, overlay := s.files.Overlay(); {
+	case !s.files.Writable():
+		b.WriteString(" The workspace is read-only: changes are refused.")
+	case overlay:
+		b.WriteString(" Changes are held in memory and don't reach the disk until applied.")
+	default:
+		b.WriteString(" Tools can change files.")
+	}
+	if s.config.Profile != "" {
+		fmt.Fprintf(&b, " Config profile: %s.", s.config.Profile)
+	}
+
+	offered := make(map[string]bool)
+	for _, name := range s.toolManager.Registered() {
+		offered[name] = true
+	}
+	var notable []string
+	for _, tool := range notableTools {
+		if offered[tool.name] {
+			notable = append(notable, fmt.Sprintf("%s (%s)", tool.name, tool.use))
+		}
+	}
+	if len(notable) > 0 {
+		fmt.Fprintf(&b, " Useful tools: %s.", strings.Join(notable, ", "))
+	}
+	return b.String()
+}
+
+// commonExtensions lists the most common file extensions with their counts,
+// such as ".go (120), .md (14)"
+func commonExtensions(counts map[string]int) string {
+	extensions := make([]string, 0, len(counts))
+	for ext := range counts {
+		extensions = append(extensions, ext)
+	}
+	sort.Slice(extensions, func(i, j int) bool {
+		if counts[extensions[i]] != counts[extensions[j]] {
+			return counts[extensions[i]] > counts[extensions[j]]
+		}
+		return extensions[i] < extensions[j]
+	})
+	if len(extensions) > describedExtensions {
+		extensions = extensions[:describedExtensions]
+	}
+	for i, ext := range extensions {
+		extensions[i] = fmt.Sprintf("%s (%d)", ext, counts[ext])
+	}
+	return strings.Join(extensions, ", ")
+}
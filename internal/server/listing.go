@@ -0,0 +1,181 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/resources"
+)
+
+// annotatedResource is a listed resource with annotations mcp-golang can't express
+type annotatedResource struct {
+	*mcp_golang.ResourceSchema
+	Annotations *resources.Annotations `json:"annotations,omitempty"`
+}
+
+// annotatedResourceList is a resources/list result with annotated resources
+type annotatedResourceList struct {
+	Resources  []annotatedResource `json:"resources"`
+	NextCursor *string             `json:"nextCursor,omitempty"`
+}
+
+// Groups of listed resources, listed in this order within a priority
+const (
+	groupDirectory = iota // with resources.directoriesFirst
+	groupFile
+	groupOther // resources that aren't workspace files, such as diagnostics
+)
+
+// listCursor is the position of the last resource of a page in listing
+// order. It holds the resource's ordering key rather than an index, so
+// following it stays stable when resources are added or removed between
+// pages.
+type listCursor struct {
+	Priority float64 `json:"p"`
+	Group    int     `json:"g"`
+	Path     string  `json:"k"`
+	URI      string  `json:"u"`
+}
+
+// listedResource is a resource with the key it is ordered by
+type listedResource struct {
+	schema *mcp_golang.ResourceSchema
+	key    listCursor
+}
+
+// before reports whether a resource is listed before the cursor position
+func (c listCursor) before(r listedResource) bool {
+	k := r.key
+	switch {
+	case c.Priority != k.Priority:
+		return c.Priority > k.Priority
+	case c.Group != k.Group:
+		return c.Group < k.Group
+	case c.Path != k.Path:
+		return c.Path < k.Path
+	}
+	return c.URI < k.URI
+}
+
+// listingKey returns the key a resource is listed by: its priority, then
+// its workspace-relative path, so the order doesn't depend on the URI scheme
+// or on percent-encoding
+func (s *MCPServer) listingKey(uri string) listCursor {
+	key := listCursor{Priority: s.resourceManager.Priority(uri), Group: groupOther, Path: uri, URI: uri}
+	path, ok := s.resourceManager.PathFromURI(uri)
+	if !ok {
+		return key
+	}
+	rel, err := filepath.Rel(s.workspacePath, path)
+	if err != nil {
+		return key
+	}
+	key.Group = groupFile
+	if rel != "." {
+		key.Path = filepath.ToSlash(rel)
+	} else {
+		key.Path = ""
+	}
+	if strings.HasSuffix(uri, "/") && s.config.Resources.DirectoriesFirst {
+		key.Group = groupDirectory
+	}
+	return key
+}
+
+// annotateResourceList orders a resources/list result by priority, so
+// project manifests come first and generated files last, and then by path,
+// returns the page
+// after the request's cursor, and adds descriptions and priority, audience
+// and last-modified annotations to it
+func (s *MCPServer) annotateResourceList(params, result json.RawMessage) (json.RawMessage, error) {
+	var list mcp_golang.ListResourcesResponse
+	if err := json.Unmarshal(result, &list); err != nil {
+		return nil, err
+	}
+
+	listed := make([]listedResource, len(list.Resources))
+	for i, resource := range list.Resources {
+		listed[i] = listedResource{schema: resource, key: s.listingKey(resource.Uri)}
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].key.before(listed[j])
+	})
+
+	// The page starts after the cursor; a cursor that can't be decoded ends
+	// the listing rather than restarting it
+	start := 0
+	var request struct {
+		Cursor *string `json:"cursor"`
+	}
+	if len(params) > 0 {
+		_ = json.Unmarshal(params, &request)
+	}
+	if request.Cursor != nil {
+		cursor, ok := decodeCursor(*request.Cursor)
+		if !ok {
+			start = len(listed)
+		} else {
+			start = sort.Search(len(listed), func(i int) bool { return cursor.before(listed[i]) })
+		}
+	}
+	end := len(listed)
+	if size := s.config.PageSize; size > 0 && start+size < end {
+		end = start + size
+	}
+
+	annotated := annotatedResourceList{Resources: make([]annotatedResource, 0, end-start)}
+	for _, entry := range listed[start:end] {
+		resource := entry.schema
+		if description, ok := s.resourceManager.Describe(resource.Uri); ok {
+			resource.Description = &description
+		}
+		annotated.Resources = append(annotated.Resources, annotatedResource{
+			ResourceSchema: resource,
+			Annotations:    s.resourceManager.Annotate(resource.Uri),
+		})
+	}
+	if end < len(listed) {
+		cursor := encodeCursor(listed[end-1].key)
+		annotated.NextCursor = &cursor
+	}
+
+	return json.Marshal(annotated)
+}
+
+// stripCursor removes the cursor from resources/list params; mcp-golang
+// would otherwise apply it to its own URI ordering
+func stripCursor(params json.RawMessage) (json.RawMessage, error) {
+	if len(params) == 0 {
+		return params, nil
+	}
+	var request map[string]any
+	if err := json.Unmarshal(params, &request); err != nil {
+		return nil, err
+	}
+	if _, ok := request["cursor"]; !ok {
+		return params, nil
+	}
+	delete(request, "cursor")
+	return json.Marshal(request)
+}
+
+// encodeCursor returns an opaque cursor string
+func encodeCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor returned by encodeCursor
+func decodeCursor(s string) (listCursor, bool) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &cursor) != nil {
+		return cursor, false
+	}
+	return cursor, true
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+	"github.com/metoro-io/mcp-golang/transport/stdio"
+
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+)
+
+// muxTransport serves every connection accepted on a listener through one
+// MCP server. mcp-golang's server keeps no per-session state, so requests
+// from all connections can share it: request ids are remapped to be unique,
+// responses are routed back to the connection that sent the request, and
+// server notifications are sent to every connection. Each connection is a
+// session, whose ID is in the context of its requests. The process's own
+// stdio client can be served alongside them.
+type muxTransport struct {
+	listener     net.Listener
+	recorder     *sessionRecorder
+	debug        bool
+	stdio        *muxStdio // nil unless the stdio client is served too
+	onMessage    func(ctx context.Context, message *transport.BaseJsonRpcMessage)
+	onClose      func()
+	onError      func(error)
+	onDisconnect func(session string)
+	conns        map[*muxConn]bool
+	routes       map[transport.RequestId]muxRoute
+	nextID       transport.RequestId
+	connCount    int
+	closed       bool
+	mu           sync.Mutex
+}
+
+// muxConn is a single client connection
+type muxConn struct {
+	name      string // for logs
+	closer    io.Closer
+	transport *stdio.StdioServerTransport
+	session   string
+}
+
+// muxStdio is the process's own stdio client, served with the connections
+type muxStdio struct {
+	in   io.Reader
+	out  io.Writer
+	conn *muxConn
+	gone bool
+	// onLast is called once the stdio client and every connection are gone
+	onLast func()
+}
+
+// muxRoute records where a remapped request came from
+type muxRoute struct {
+	conn *muxConn
+	id   transport.RequestId
+}
+
+// newMuxTransport creates a transport that accepts clients on listener,
+// recording their traffic if recorder is not nil
+func newMuxTransport(listener net.Listener, recorder *sessionRecorder, debug bool) *muxTransport {
+	return &muxTransport{
+		listener: listener,
+		recorder: recorder,
+		debug:    debug,
+		conns:    make(map[*muxConn]bool),
+		routes:   make(map[transport.RequestId]muxRoute),
+	}
+}
+
+// serveStdio serves the process's own stdio client along with the
+// connections; onLast is called once it and every connection are gone.
+// Call it before Start.
+func (m *muxTransport) serveStdio(in io.Reader, out io.Writer, onLast func()) {
+	m.stdio = &muxStdio{in: in, out: out, onLast: onLast}
+}
+
+// Start begins accepting connections
+func (m *muxTransport) Start(ctx context.Context) error {
+	if m.stdio != nil {
+		in, out := m.stdio.in, m.stdio.out
+		if m.recorder != nil {
+			in = m.recorder.Reader("stdio", in)
+			out = m.recorder.Writer("stdio", out)
+		}
+		c := m.add("stdio", in, out, io.NopCloser(in))
+		m.mu.Lock()
+		m.stdio.conn = c
+		m.mu.Unlock()
+		m.start(ctx, c)
+	}
+	go m.acceptLoop(ctx)
+	return nil
+}
+
+// acceptLoop serves each new connection with its own stdio transport
+func (m *muxTransport) acceptLoop(ctx context.Context) {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			m.mu.Lock()
+			closed := m.closed
+			m.mu.Unlock()
+			if !closed {
+				m.handleError(err)
+			}
+			return
+		}
+
+		var in io.Reader = conn
+		var out io.Writer = conn
+		if m.recorder != nil {
+			client := fmt.Sprintf("conn-%d", m.nextConn())
+			in = m.recorder.Reader(client, in)
+			out = m.recorder.Writer(client, out)
+		}
+		m.start(ctx, m.add(conn.RemoteAddr().String(), in, out, conn))
+	}
+}
+
+// add adds a client served with its own stdio transport
+func (m *muxTransport) add(name string, in io.Reader, out io.Writer, closer io.Closer) *muxConn {
+	c := &muxConn{name: name, closer: closer, session: session.NewID()}
+	c.transport = stdio.NewStdioServerTransportWithIO(&eofReader{
+		Reader: in,
+		onEOF:  func() { m.drop(c) },
+	}, out)
+	c.transport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		m.receive(ctx, c, message)
+	})
+
+	m.mu.Lock()
+	m.conns[c] = true
+	m.mu.Unlock()
+
+	if m.debug {
+		log.Printf("Client connected: %s", name)
+	}
+	return c
+}
+
+// start starts reading a client's requests
+func (m *muxTransport) start(ctx context.Context, c *muxConn) {
+	if err := c.transport.Start(ctx); err != nil {
+		m.handleError(err)
+		m.drop(c)
+	}
+}
+
+// nextConn numbers connections so recordings can tell clients apart; unix
+// socket clients have no remote address
+func (m *muxTransport) nextConn() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connCount++
+	return m.connCount
+}
+
+// receive remaps request ids before passing messages to the server
+func (m *muxTransport) receive(ctx context.Context, c *muxConn, message *transport.BaseJsonRpcMessage) {
+	m.mu.Lock()
+	if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+		m.nextID++
+		m.routes[m.nextID] = muxRoute{conn: c, id: message.JsonRpcRequest.Id}
+		message.JsonRpcRequest.Id = m.nextID
+	}
+	handler := m.onMessage
+	m.mu.Unlock()
+
+	if handler != nil {
+		handler(session.With(ctx, c.session), message)
+	}
+}
+
+// drop forgets a disconnected client and any requests it still has in flight
+func (m *muxTransport) drop(c *muxConn) {
+	m.mu.Lock()
+	if !m.conns[c] {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.conns, c)
+	for id, route := range m.routes {
+		if route.conn == c {
+			delete(m.routes, id)
+		}
+	}
+	var onLast func()
+	if m.stdio != nil {
+		if c == m.stdio.conn {
+			m.stdio.gone = true
+		}
+		if m.stdio.gone && len(m.conns) == 0 && !m.closed {
+			onLast = m.stdio.onLast
+		}
+	}
+	m.mu.Unlock()
+
+	_ = c.transport.Close()
+	_ = c.closer.Close()
+	if m.onDisconnect != nil {
+		m.onDisconnect(c.session)
+	}
+
+	if m.debug {
+		log.Printf("Client disconnected: %s", c.name)
+	}
+	if onLast != nil {
+		onLast()
+	}
+}
+
+// dropStdio disconnects the stdio client, leaving the connections served
+func (m *muxTransport) dropStdio() {
+	m.mu.Lock()
+	var c *muxConn
+	if m.stdio != nil {
+		c = m.stdio.conn
+	}
+	m.mu.Unlock()
+	if c != nil {
+		m.drop(c)
+	}
+}
+
+// Send routes responses to the requesting connection and broadcasts everything else
+func (m *muxTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	var id *transport.RequestId
+	switch message.Type {
+	case transport.BaseMessageTypeJSONRPCResponseType:
+		id = &message.JsonRpcResponse.Id
+	case transport.BaseMessageTypeJSONRPCErrorType:
+		id = &message.JsonRpcError.Id
+	}
+
+	if id != nil {
+		m.mu.Lock()
+		route, ok := m.routes[*id]
+		delete(m.routes, *id)
+		m.mu.Unlock()
+
+		// The client disconnected before the response was ready
+		if !ok {
+			return nil
+		}
+		*id = route.id
+		return route.conn.transport.Send(ctx, message)
+	}
+
+	m.mu.Lock()
+	conns := make([]*muxConn, 0, len(m.conns))
+	for c := range m.conns {
+		conns = append(conns, c)
+	}
+	m.mu.Unlock()
+
+	for _, c := range conns {
+		if err := c.transport.Send(ctx, message); err != nil && m.debug {
+			log.Printf("Error sending to %s: %v", c.name, err)
+		}
+	}
+	return nil
+}
+
+// Close stops accepting connections and disconnects every client
+func (m *muxTransport) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	conns := make([]*muxConn, 0, len(m.conns))
+	for c := range m.conns {
+		conns = append(conns, c)
+	}
+	onClose := m.onClose
+	m.mu.Unlock()
+
+	err := m.listener.Close()
+	for _, c := range conns {
+		m.drop(c)
+	}
+	if onClose != nil {
+		onClose()
+	}
+	return err
+}
+
+// SetCloseHandler sets the handler for close events
+func (m *muxTransport) SetCloseHandler(handler func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onClose = handler
+}
+
+// SetErrorHandler sets the handler for error events
+func (m *muxTransport) SetErrorHandler(handler func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onError = handler
+}
+
+// SetMessageHandler sets the handler for incoming messages from any connection
+func (m *muxTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onMessage = handler
+}
+
+// handleError reports an error to the error handler or the log
+func (m *muxTransport) handleError(err error) {
+	m.mu.Lock()
+	handler := m.onError
+	m.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	} else {
+		log.Printf("Transport error: %v", err)
+	}
+}
+
+// eofReader calls onEOF once when the underlying reader fails, since the
+// stdio transport stops reading silently when a client disconnects
+type eofReader struct {
+	io.Reader
+	onEOF func()
+	once  sync.Once
+}
+
+// Read reads from the connection and reports when it ends
+func (r *eofReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil {
+		r.once.Do(r.onEOF)
+	}
+	return n, err
+}
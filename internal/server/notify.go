@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/metoro-io/mcp-golang/transport"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+)
+
+// Notification methods the server sends
+const (
+	methodListChanged = "notifications/resources/list_changed"
+	methodUpdated     = "notifications/resources/updated"
+)
+
+// notifier batches resource notifications so bursts of changes, such as an
+// npm install, don't flood clients. Changes collected during the batch
+// window are sent together, each URI at most once, and list_changed at most
+// once. When a batch needs more notifications than the rate cap allows, the
+// updated URIs are summarized as one notification for their common directory.
+type notifier struct {
+	send   func(method string, params any)
+	dirURI func(path string) string
+	window time.Duration
+	rate   float64 // notifications per second; 0 is unlimited
+
+	tokens      float64
+	refilled    time.Time
+	updated     []pendingUpdate
+	seen        map[string]bool
+	listChanged bool
+	scheduled   bool
+	mu          sync.Mutex
+}
+
+// pendingUpdate is a changed resource waiting to be notified
+type pendingUpdate struct {
+	uri string
+	dir string // directory the resource is in, or the directory itself
+}
+
+// updatedParams are the params of notifications/resources/updated. Changed
+// is set on summaries and counts the resources below the URI that changed.
+type updatedParams struct {
+	URI     string `json:"uri"`
+	Changed int    `json:"changed,omitempty"`
+}
+
+// newNotifier creates a notifier sending through send
+func newNotifier(cfg config.NotificationsConfig, send func(method string, params any), dirURI func(path string) string) *notifier {
+	return &notifier{
+		send:     send,
+		dirURI:   dirURI,
+		window:   cfg.BatchWindow,
+		rate:     float64(cfg.MaxPerSecond),
+		tokens:   float64(cfg.MaxPerSecond),
+		refilled: time.Now(),
+		seen:     make(map[string]bool),
+	}
+}
+
+// Updated queues notifications/resources/updated for a resource in dir
+func (n *notifier) Updated(uri, dir string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.seen[uri] {
+		n.seen[uri] = true
+		n.updated = append(n.updated, pendingUpdate{uri: uri, dir: dir})
+	}
+	n.schedule(n.window)
+}
+
+// ListChanged queues notifications/resources/list_changed
+func (n *notifier) ListChanged() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listChanged = true
+	n.schedule(n.window)
+}
+
+// schedule arranges a flush after delay unless one is already due; the
+// caller holds the lock
+func (n *notifier) schedule(delay time.Duration) {
+	if n.scheduled {
+		return
+	}
+	n.scheduled = true
+	time.AfterFunc(delay, n.flush)
+}
+
+// flush sends the queued notifications, or waits until the rate cap allows
+// sending at least one
+func (n *notifier) flush() {
+	n.mu.Lock()
+	n.scheduled = false
+
+	if n.rate > 0 {
+		now := time.Now()
+		n.tokens = min(n.rate, n.tokens+now.Sub(n.refilled).Seconds()*n.rate)
+		n.refilled = now
+		if n.tokens < 1 {
+			n.schedule(time.Duration((1 - n.tokens) / n.rate * float64(time.Second)))
+			n.mu.Unlock()
+			return
+		}
+	}
+
+	listChanged := n.listChanged
+	updated := n.updated
+	n.listChanged = false
+	n.updated = nil
+	n.seen = make(map[string]bool)
+
+	count := len(updated)
+	if listChanged {
+		count++
+	}
+	var summary *updatedParams
+	if n.rate > 0 && float64(count) > n.tokens && len(updated) > 1 {
+		summary = &updatedParams{URI: n.dirURI(commonDir(updated)), Changed: len(updated)}
+		count = 1
+		if listChanged {
+			count++
+		}
+	}
+	n.tokens -= float64(count)
+	n.mu.Unlock()
+
+	if listChanged {
+		n.send(methodListChanged, nil)
+	}
+	if summary != nil {
+		n.send(methodUpdated, summary)
+		return
+	}
+	for _, update := range updated {
+		n.send(methodUpdated, updatedParams{URI: update.uri})
+	}
+}
+
+// commonDir returns the deepest directory containing every update
+func commonDir(updated []pendingUpdate) string {
+	common := updated[0].dir
+	for _, update := range updated[1:] {
+		for !within(update.dir, common) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				return common
+			}
+			common = parent
+		}
+	}
+	return common
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// sendNotification sends a server notification to clients
+func (s *MCPServer) sendNotification(method string, params any) {
+	if s.transport == nil {
+		return
+	}
+	var data json.RawMessage
+	if params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			log.Printf("Error encoding %s notification: %v", method, err)
+			return
+		}
+	}
+	err := s.transport.Send(s.ctx, transport.NewBaseMessageNotification(&transport.BaseJSONRPCNotification{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  data,
+	}))
+	if err != nil && s.debug {
+		log.Printf("Error sending %s notification: %v", method, err)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"log"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// profiledDirectories is how many of the largest top-level directories the
+// startup profile lists
+const profiledDirectories = 5
+
+// startupProfile records how long each phase of startup took
+type startupProfile struct {
+	started      time.Time
+	scan         *watcher.ScanProfile
+	registration time.Duration
+	indexLoad    time.Duration
+	watchSetup   time.Duration
+	memory       runtime.MemStats // read right after registration
+}
+
+// ProfileStartup makes the server log a breakdown of its startup; call it
+// before starting the server
+func (s *MCPServer) ProfileStartup() {
+	s.profile = &startupProfile{scan: s.watcher.ProfileScan()}
+}
+
+// logStartupProfile logs where startup time went, to guide tuning ignore
+// patterns in workspaces that are slow to open
+func (s *MCPServer) logStartupProfile() {
+	p := s.profile
+	scan := p.scan
+	log.Printf("Startup profile for %s:", s.workspacePath)
+	log.Printf("  walk:           %v (%d files in %d directories; ignored %d directories and %d files)",
+		scan.Walk.Round(time.Millisecond), scan.Files, scan.Dirs, scan.IgnoredDirs, scan.IgnoredFiles)
+	log.Printf("  gitignore:      %v of the walk", scan.Matching.Round(time.Millisecond))
+	log.Printf("  registration:   %v", p.registration.Round(time.Millisecond))
+	log.Printf("  index load:     %v", p.indexLoad.Round(time.Millisecond))
+	log.Printf("  watch setup:    %v", p.watchSetup.Round(time.Millisecond))
+	log.Printf("  total:          %v", time.Since(p.started).Round(time.Millisecond))
+	log.Printf("  memory after scan: %d MiB heap, %d MiB from the OS", p.memory.HeapAlloc>>20, p.memory.Sys>>20)
+
+	dirs := make([]string, 0, len(scan.TopLevel))
+	for dir := range scan.TopLevel {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if scan.TopLevel[dirs[i]] != scan.TopLevel[dirs[j]] {
+			return scan.TopLevel[dirs[i]] > scan.TopLevel[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > profiledDirectories {
+		dirs = dirs[:profiledDirectories]
+	}
+	if len(dirs) > 0 {
+		log.Printf("  largest top-level directories:")
+	}
+	for _, dir := range dirs {
+		log.Printf("  %7d files in %s", scan.TopLevel[dir], dir)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"log"
+	"time"
+)
+
+// settleDelay is how long file events keep being dropped after a bulk
+// operation so the events it caused are not applied one by one
+const settleDelay = 250 * time.Millisecond
+
+// PauseEvents stops applying file events while a tool, such as a branch
+// checkout, rewrites many files at once
+func (s *MCPServer) PauseEvents() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused++
+}
+
+// ResumeEvents applies file events again once the burst caused by the paused
+// operation has settled, and reconciles the registered resources with the
+// files now in the workspace
+func (s *MCPServer) ResumeEvents() {
+	time.AfterFunc(settleDelay, func() {
+		s.mu.Lock()
+		s.paused--
+		resume := s.paused == 0
+		s.mu.Unlock()
+
+		if resume {
+			s.reconcile()
+		}
+	})
+}
+
+// eventsPaused reports whether file events are being dropped
+func (s *MCPServer) eventsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused > 0
+}
+
+// reconcile registers files that appeared and unregisters files that
+// disappeared while events were paused
+func (s *MCPServer) reconcile() {
+	files, err := s.watcher.GetInitialFiles()
+	if err != nil {
+		log.Printf("Warning: failed to rescan workspace: %v", err)
+		return
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+
+	s.mu.RLock()
+	var gone []string
+	for file := range s.registeredFiles {
+		if !present[file] {
+			gone = append(gone, file)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, file := range gone {
+		if err := s.unregisterFile(file); err != nil {
+			log.Printf("Warning: failed to unregister file %s: %v", file, err)
+		}
+	}
+	for _, file := range files {
+		if err := s.registerFile(file); err != nil {
+			log.Printf("Warning: failed to register file %s: %v", file, err)
+		}
+	}
+	s.index.Revalidate(files)
+
+	if s.debug {
+		log.Printf("Reconciled resources: %d files, %d removed", len(files), len(gone))
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Directions of recorded messages
+const (
+	directionReceived = "recv"
+	directionSent     = "send"
+)
+
+// sessionRecorder writes every JSON-RPC line exchanged with clients to a
+// file, one JSON object per line, for debugging protocol mismatches
+type sessionRecorder struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// sessionRecord is a single recorded message
+type sessionRecord struct {
+	Time      time.Time       `json:"time"`
+	Client    string          `json:"client"`
+	Direction string          `json:"direction"`
+	Message   json.RawMessage `json:"message,omitempty"`
+	// Raw holds lines that aren't valid JSON, exactly as they were exchanged
+	Raw string `json:"raw,omitempty"`
+}
+
+// newSessionRecorder creates or truncates the recording file
+func newSessionRecorder(path string) (*sessionRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session recording: %v", err)
+	}
+	return &sessionRecorder{file: file}, nil
+}
+
+// Reader records each line read from in as received from client
+func (r *sessionRecorder) Reader(client string, in io.Reader) io.Reader {
+	return io.TeeReader(in, &lineRecorder{recorder: r, client: client, direction: directionReceived})
+}
+
+// Writer records each line written to out as sent to client
+func (r *sessionRecorder) Writer(client string, out io.Writer) io.Writer {
+	return io.MultiWriter(out, &lineRecorder{recorder: r, client: client, direction: directionSent})
+}
+
+// record appends one message to the recording
+func (r *sessionRecorder) record(client, direction string, line []byte) {
+	entry := sessionRecord{
+		Time:      time.Now(),
+		Client:    client,
+		Direction: direction,
+	}
+	if json.Valid(line) {
+		entry.Message = line
+	} else {
+		entry.Raw = string(line)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = r.file.Write(append(data, '\n'))
+}
+
+// Close closes the recording file
+func (r *sessionRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// lineRecorder splits a byte stream into lines and records each one
+type lineRecorder struct {
+	recorder  *sessionRecorder
+	client    string
+	direction string
+	buf       []byte
+}
+
+// Write buffers partial lines until their newline arrives
+func (l *lineRecorder) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimRight(l.buf[:i], "\r")
+		if len(bytes.TrimSpace(line)) > 0 {
+			l.recorder.record(l.client, l.direction, append([]byte(nil), line...))
+		}
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
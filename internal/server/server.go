@@ -2,77 +2,316 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"sync"
+	"time"
 
 	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/metoro-io/mcp-golang/transport"
 	"github.com/metoro-io/mcp-golang/transport/stdio"
 
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/index"
+	"github.com/isaacphi/mcp-filesystem/internal/iosched"
+	"github.com/isaacphi/mcp-filesystem/internal/memory"
 	"github.com/isaacphi/mcp-filesystem/internal/resources"
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+	"github.com/isaacphi/mcp-filesystem/internal/tools"
 	"github.com/isaacphi/mcp-filesystem/internal/watcher"
 )
 
+// URI of the session changes resource
+const sessionChangesURI = "workspace://session-changes"
+
 // MCPServer represents the MCP server for the filesystem
 type MCPServer struct {
 	workspacePath   string
+	files           *fsys.Workspace
+	config          *config.Config
 	mcpServer       *mcp_golang.Server
+	transport       transport.Transport
+	recorder        *sessionRecorder
+	audit           *auditLog
 	watcher         *watcher.FileWatcher
 	resourceManager *resources.ResourceManager
+	toolManager     *tools.ToolManager
+	index           *index.Index
+	diagnostics     *diagnostics.Diagnostics
 	debug           bool
 	ctx             context.Context
 	cancelFunc      context.CancelFunc
 	registeredFiles map[string]bool
+	registeredDirs  map[string]int // directory to the number of registered files below it
+	paused          int            // file events are dropped while above zero
+	scanned         chan struct{}  // closed once the existing files are registered
+	activity        *activity
+	subscriptions   *subscriptions
+	notifier        *notifier
+	profile         *startupProfile // set when startup is profiled
 	mu              sync.RWMutex
 }
 
-// NewMCPServer creates a new MCP server
-func NewMCPServer(workspacePath string, debug bool) (*MCPServer, error) {
+// NewMCPServer creates a new MCP server for a workspace on disk
+func NewMCPServer(workspacePath string, cfg *config.Config, debug bool) (*MCPServer, error) {
+	return NewMCPServerFS(fsys.OS(workspacePath), cfg, debug)
+}
+
+// NewMCPServerFS creates a new MCP server for a workspace on any
+// filesystem. Workspaces that aren't on disk are polled for changes, and
+// the tools that need git, commands or the OS filesystem aren't offered.
+func NewMCPServerFS(files *fsys.Workspace, cfg *config.Config, debug bool) (*MCPServer, error) {
+	workspacePath := files.Root()
+	if !files.OnDisk() {
+		// Commands, formatters, checkers and git need the files on disk
+		cfg.Exec.Enabled = false
+		cfg.Formatters = nil
+		cfg.Checkers = nil
+		cfg.Git.WriteEnabled = false
+		cfg.Git.Snapshots = config.SnapshotOff
+	}
+	if cfg.ReadOnly {
+		// Commands and git could change files behind the workspace's back
+		files.SetReadOnly()
+		cfg.Exec.Enabled = false
+		cfg.Git.WriteEnabled = false
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 
-	fileWatcher, err := watcher.NewFileWatcher(workspacePath, debug)
+	diag := diagnostics.New(workspacePath)
+
+	// Indexing and poll rescans share one budget for background disk work
+	backgroundBytes, err := config.ParseSize(cfg.Background.BytesPerSecond)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid background bytes per second: %v", err)
+	}
+	scheduler := iosched.New(backgroundBytes, cfg.Background.OpsPerSecond)
+
+	fileWatcher, err := watcher.NewFileWatcher(files, cfg, diag, scheduler, debug)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to create file watcher: %v", err)
 	}
 
-	resourceManager := resources.NewResourceManager(workspacePath, debug)
+	// Sensitive file policies apply to every read and write the server
+	// makes, whichever resource or tool it is for
+	if len(cfg.Sensitive) > 0 {
+		files.SetGuard(fileWatcher.Matcher())
+	}
+
+	resourceManager := resources.NewResourceManager(files, cfg, fileWatcher.Matcher(), debug)
+	toolManager := tools.NewToolManager(files, cfg, fileWatcher.Matcher(), debug)
+	audit, err := newAuditLog(cfg.AuditLog)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	toolManager.SetAuditor(audit)
+	if cfg.Scratch {
+		if resources.WorkspaceAlias(cfg, workspacePath) == tools.ScratchName {
+			cancel()
+			return nil, fmt.Errorf("the workspace alias can't be %s while scratch directories are enabled; set resources.alias", tools.ScratchName)
+		}
+		if err := toolManager.EnableScratch(); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	// The index lives in memory only when it can't be persisted, or when
+	// it indexes files that aren't on disk
+	cachePath := ""
+	if cfg.Index.Persist && files.OnDisk() {
+		if cachePath, err = index.CachePath(workspacePath, cfg.Index.CacheDir); err != nil {
+			log.Printf("Warning: search index won't be persisted: %v", err)
+		}
+	}
+	searchIndex := index.New(files, cachePath, cfg.Index.MaxFileSize, scheduler, debug)
+	toolManager.SetIndex(searchIndex)
 
-	return &MCPServer{
+	s := &MCPServer{
 		workspacePath:   workspacePath,
+		files:           files,
+		config:          cfg,
 		resourceManager: resourceManager,
+		toolManager:     toolManager,
+		audit:           audit,
+		index:           searchIndex,
+		diagnostics:     diag,
 		watcher:         fileWatcher,
 		debug:           debug,
 		ctx:             ctx,
 		cancelFunc:      cancel,
 		registeredFiles: make(map[string]bool),
-	}, nil
+		registeredDirs:  make(map[string]int),
+		scanned:         make(chan struct{}),
+		activity:        newActivity(),
+		subscriptions:   newSubscriptions(),
+	}
+	s.notifier = newNotifier(cfg.Notifications, s.sendNotification, resourceManager.GetDirectoryURI)
+	toolManager.SetEventPauser(s)
+	toolManager.SetReadRecorder(s)
+	toolManager.SetChangeApplier(s)
+	toolManager.SetURIResolver(s.resourceManager)
+
+	// Caches are dropped under memory pressure, the cheapest to rebuild
+	// first; the list of files is kept
+	memoryLimit, err := config.ParseSize(cfg.MemoryLimit)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid memory limit: %v", err)
+	}
+	monitor := memory.NewMonitor(memoryLimit, debug)
+	monitor.Add(func() string {
+		if n := resourceManager.DropDescriptions(); n > 0 {
+			return fmt.Sprintf("%d cached resource descriptions", n)
+		}
+		return ""
+	})
+	monitor.Add(func() string {
+		if n := searchIndex.Evict(0.5); n > 0 {
+			return fmt.Sprintf("the %d least recently used search index entries", n)
+		}
+		return ""
+	})
+	monitor.Add(func() string {
+		if searchIndex.Disable() {
+			return "the search index; search reads every file from now on"
+		}
+		return ""
+	})
+	toolManager.SetMemoryMonitor(monitor)
+	go monitor.Run(ctx)
+
+	return s, nil
+}
+
+// RecordSession tees all JSON-RPC traffic to a file; call it before starting the server
+func (s *MCPServer) RecordSession(path string) error {
+	recorder, err := newSessionRecorder(path)
+	if err != nil {
+		return err
+	}
+	s.recorder = recorder
+	return nil
 }
 
-// Start starts the MCP server
+// Start starts the MCP server on stdio
 func (s *MCPServer) Start() error {
+	if s.recorder != nil {
+		return s.start(stdio.NewStdioServerTransportWithIO(
+			s.recorder.Reader("stdio", os.Stdin),
+			s.recorder.Writer("stdio", os.Stdout),
+		))
+	}
+	return s.start(stdio.NewStdioServerTransport())
+}
+
+// StartOnListener starts the MCP server for every client that connects to listener
+func (s *MCPServer) StartOnListener(listener net.Listener) error {
+	mux := newMuxTransport(listener, s.recorder, s.debug)
+	mux.onDisconnect = s.toolManager.EndSession
+	return s.start(mux)
+}
+
+// StartShared starts the MCP server for the stdio client and for every
+// client that connects to listener, which other server processes launched
+// for the workspace hand their clients off to. lastClient is called once
+// the stdio client and every connected client are gone.
+func (s *MCPServer) StartShared(listener net.Listener, lastClient func()) error {
+	mux := newMuxTransport(listener, s.recorder, s.debug)
+	mux.onDisconnect = s.toolManager.EndSession
+	mux.serveStdio(os.Stdin, os.Stdout, lastClient)
+	return s.start(mux)
+}
+
+// DisconnectStdio ends the stdio client's session of a shared server, which
+// keeps serving the clients handed off to it
+func (s *MCPServer) DisconnectStdio() {
+	if mux, ok := s.transport.(*muxTransport); ok {
+		mux.dropStdio()
+	}
+}
+
+// start starts the MCP server on a transport
+func (s *MCPServer) start(t transport.Transport) error {
+	if s.profile != nil {
+		s.profile.started = time.Now()
+	}
+
+	// Wrap the transport so resource listings can be adjusted
+	s.transport = t
+	intercept := newInterceptTransport(t)
+	intercept.RewriteResult("resources/list", s.annotateResourceList)
+	intercept.RewriteParams("resources/list", stripCursor)
+	intercept.RewriteParams("resources/read", s.canonicalizeResourceURI)
+	intercept.HandleRequest("resources/read", s.readSessionResource)
+	intercept.TrackActivity(s.activity)
+	intercept.HandleRequest("ping", handlePing)
+	intercept.RewriteResult("initialize", s.initializeResult)
+	intercept.RewriteResult("tools/call", codeToolError)
+	intercept.RewriteResult("resources/read", codeResourceError)
+	intercept.HandleRequest("resources/subscribe", s.handleSubscribe)
+	intercept.HandleRequest("resources/unsubscribe", s.handleUnsubscribe)
+	intercept.RedirectNotification(methodListChanged, s.notifier.ListChanged)
+
 	// Create and initialize MCP server
 	s.mcpServer = mcp_golang.NewServer(
-		stdio.NewStdioServerTransport(),
+		intercept,
 		mcp_golang.WithName("MCP Filesystem Server"),
 		mcp_golang.WithVersion("1.0.0"),
 	)
 
+	// Register tools before serving so clients see them on first list
+	if err := s.toolManager.RegisterTools(s.mcpServer); err != nil {
+		return fmt.Errorf("failed to register tools: %v", err)
+	}
+
 	// Start serving MCP requests
 	if err := s.mcpServer.Serve(); err != nil {
 		return fmt.Errorf("failed to start MCP server: %v", err)
 	}
 
+	// Register server resources
+	if err := s.resourceManager.RegisterDiagnosticsResource(s.mcpServer, s.diagnostics); err != nil {
+		return fmt.Errorf("failed to register diagnostics resource: %v", err)
+	}
+	if err := s.resourceManager.RegisterHotFilesResource(s.mcpServer); err != nil {
+		return fmt.Errorf("failed to register hot files resource: %v", err)
+	}
+	if err := s.registerSessionChangesResource(); err != nil {
+		return fmt.Errorf("failed to register session changes resource: %v", err)
+	}
+	if err := s.resourceManager.RegisterSampleResource(s.mcpServer, s.fileList); err != nil {
+		return fmt.Errorf("failed to register sample resource: %v", err)
+	}
+
 	// Register all existing files
 	if err := s.registerExistingFiles(); err != nil {
 		return fmt.Errorf("failed to register existing files: %v", err)
 	}
+	close(s.scanned)
 
 	// Start file watcher
+	watchStart := time.Now()
 	fileEvents, err := s.watcher.Start(s.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to start file watcher: %v", err)
 	}
+	if s.profile != nil {
+		s.profile.watchSetup = time.Since(watchStart)
+		s.logStartupProfile()
+	}
 
 	// Process file events
 	go s.processFileEvents(fileEvents)
@@ -84,6 +323,20 @@ func (s *MCPServer) Start() error {
 func (s *MCPServer) Stop() {
 	s.cancelFunc()
 	s.watcher.Stop()
+	if s.transport != nil {
+		_ = s.transport.Close()
+	}
+	if s.recorder != nil {
+		_ = s.recorder.Close()
+	}
+	_ = s.audit.Close()
+	if err := s.index.Save(); err != nil {
+		log.Printf("Warning: failed to save search index: %v", err)
+	}
+	s.toolManager.CloseScratch()
+	if err := s.files.Close(); err != nil {
+		log.Printf("Warning: failed to close workspace: %v", err)
+	}
 }
 
 // registerExistingFiles registers all existing files in the workspace
@@ -98,11 +351,25 @@ func (s *MCPServer) registerExistingFiles() error {
 	}
 
 	// Register each file
+	registrationStart := time.Now()
 	for _, file := range files {
 		if err := s.registerFile(file); err != nil {
 			log.Printf("Warning: failed to register file %s: %v", file, err)
 		}
 	}
+	if s.profile != nil {
+		s.profile.registration = time.Since(registrationStart)
+		runtime.ReadMemStats(&s.profile.memory)
+	}
+
+	// A saved index makes search fast right away; changes since are queued
+	// and indexed in the background
+	loadStart := time.Now()
+	s.index.Load()
+	s.index.Revalidate(files)
+	if s.profile != nil {
+		s.profile.indexLoad = time.Since(loadStart)
+	}
 
 	return nil
 }
@@ -124,20 +391,36 @@ func (s *MCPServer) processFileEvents(events <-chan watcher.FileEvent) {
 
 // handleFileEvent handles a file event
 func (s *MCPServer) handleFileEvent(event watcher.FileEvent) {
+	// Bulk operations reconcile once they finish instead
+	if s.eventsPaused() {
+		return
+	}
+
+	// The watcher lost events, so the workspace is rescanned instead
+	if event.EventType == watcher.EventRescan {
+		s.reconcile()
+		return
+	}
+
 	var err error
 
 	switch event.EventType {
 	case watcher.EventCreate:
 		err = s.registerFile(event.Path)
+		s.index.Update(event.Path)
 	case watcher.EventModify:
 		err = s.updateFile(event.Path)
+		s.index.Update(event.Path)
 	case watcher.EventDelete:
 		err = s.unregisterFile(event.Path)
+		s.index.Remove(event.Path)
 	}
 
 	if err != nil {
 		log.Printf("Error handling event for %s: %v", event.Path, err)
 	}
+
+	s.notifySubscribers(event)
 }
 
 // registerFile registers a file as a resource
@@ -161,6 +444,19 @@ func (s *MCPServer) registerFile(path string) error {
 		log.Printf("Registered file: %s", path)
 	}
 
+	// Directories are listed while they contain registered files
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		s.registeredDirs[dir]++
+		if s.registeredDirs[dir] == 1 {
+			if err := s.resourceManager.RegisterDirectoryResource(s.mcpServer, dir); err != nil {
+				log.Printf("Warning: failed to register directory %s: %v", dir, err)
+			}
+		}
+		if dir == s.workspacePath || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -206,5 +502,159 @@ func (s *MCPServer) unregisterFile(path string) error {
 		log.Printf("Unregistered file: %s", path)
 	}
 
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		s.registeredDirs[dir]--
+		if s.registeredDirs[dir] <= 0 {
+			delete(s.registeredDirs, dir)
+			if err := s.resourceManager.DeregisterDirectoryResource(s.mcpServer, dir); err != nil {
+				log.Printf("Warning: failed to deregister directory %s: %v", dir, err)
+			}
+		}
+		if dir == s.workspacePath || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
 	return nil
 }
+
+// canonicalizeResourceURI lets clients read a file with either its file:// or
+// workspace:// URI, whichever scheme resources are listed with
+func (s *MCPServer) canonicalizeResourceURI(params json.RawMessage) (json.RawMessage, error) {
+	var request map[string]any
+	if err := json.Unmarshal(params, &request); err != nil {
+		return nil, err
+	}
+	uri, ok := request["uri"].(string)
+	if !ok {
+		return params, nil
+	}
+	request["uri"] = s.resourceManager.CanonicalURI(uri)
+
+	// Files clients read are indexed before the rest of the queue, and
+	// checked first if their subtree isn't watched
+	if path, ok := s.resourceManager.PathFromURI(uri); ok {
+		s.index.Prioritize(path)
+		s.watcher.Access(path)
+	}
+	return json.Marshal(request)
+}
+
+// fileList returns the registered files, sorted
+func (s *MCPServer) fileList() []string {
+	s.mu.RLock()
+	files := make([]string, 0, len(s.registeredFiles))
+	for path := range s.registeredFiles {
+		files = append(files, path)
+	}
+	s.mu.RUnlock()
+	sort.Strings(files)
+	return files
+}
+
+// registerSessionChangesResource registers the changelog of files changed
+// by tool calls. Reads are answered by readSessionResource with the
+// changelog of the reading session; this handler serves stdio's.
+func (s *MCPServer) registerSessionChangesResource() error {
+	return s.mcpServer.RegisterResource(
+		sessionChangesURI,
+		"session-changes",
+		"Files created, modified and deleted by this session's tool calls, in order, with diffs, to review the session's work",
+		"application/json",
+		func() (*mcp_golang.ResourceResponse, error) {
+			return s.sessionChanges("")
+		},
+	)
+}
+
+// sessionChanges returns the session changes resource of a session
+func (s *MCPServer) sessionChanges(id string) (*mcp_golang.ResourceResponse, error) {
+	text, err := s.toolManager.SessionChanges(id)
+	if err != nil {
+		return nil, err
+	}
+	return mcp_golang.NewResourceResponse(
+		mcp_golang.NewTextEmbeddedResource(sessionChangesURI, text, "application/json"),
+	), nil
+}
+
+// readSessionResource reads the resources that aren't registered: the
+// session changes resource, which differs between sessions, files in
+// session scratch areas, which aren't registered so that other sessions
+// can't find them, and files outside the watched subtrees or in cold ones,
+// which are read on demand
+func (s *MCPServer) readSessionResource(ctx context.Context, params json.RawMessage) (any, error) {
+	var request struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(params, &request); err != nil {
+		return nil, errNotHandled
+	}
+	if request.URI == sessionChangesURI {
+		id, _ := session.From(ctx)
+		return s.sessionChanges(id)
+	}
+	path, ok := s.resourceManager.PathFromURI(request.URI)
+	if !ok {
+		return nil, errNotHandled
+	}
+	if !s.watcher.Watched(path) {
+		return s.readUnwatched(path)
+	}
+	// The file may not be registered yet when its subtree is checked on access
+	if s.watcher.Cold(path) {
+		s.watcher.Access(path)
+		return s.readUnwatched(path)
+	}
+	if !s.config.Scratch {
+		return nil, errNotHandled
+	}
+	if mount, _, ok := s.files.MountPoint(path); !ok || mount != tools.ScratchName {
+		return nil, errNotHandled
+	}
+	return s.resourceManager.GetFileResourceHandler(path)()
+}
+
+// RecordRead counts a file read by a tool for the hot files resource and
+// for tiered watching
+func (s *MCPServer) RecordRead(path string) {
+	s.resourceManager.RecordRead(path)
+	s.watcher.Access(path)
+}
+
+// FileChanged applies a change a tool made to a file right away, so the
+// session's next resource read, listing or search sees it without waiting
+// for the watcher, whose event for the change then finds nothing to do
+func (s *MCPServer) FileChanged(path string) {
+	info, err := s.files.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		if err := s.unregisterFile(path); err != nil {
+			log.Printf("Error applying change to %s: %v", path, err)
+		}
+		s.index.Remove(path)
+		return
+	}
+	if !s.watcher.Watched(path) || s.watcher.Matcher().ShouldIgnore(path) {
+		return
+	}
+	s.resourceManager.ForgetDescription(path)
+	if err := s.updateFile(path); err != nil {
+		log.Printf("Error applying change to %s: %v", path, err)
+	}
+	s.index.Update(path)
+}
+
+// readUnwatched reads a file outside the watched subtrees, which isn't
+// listed but can be read like any other file that isn't ignored
+func (s *MCPServer) readUnwatched(path string) (any, error) {
+	path = filepath.Clean(path)
+	if s.watcher.Matcher().Excluded(path) {
+		return nil, errcode.New(errcode.NotFound, "file does not exist: %s", path)
+	}
+	response, err := s.resourceManager.GetFileResourceHandler(path)()
+	if err != nil {
+		return nil, err
+	}
+	s.resourceManager.RecordRead(path)
+	return response, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// subscriptions are the resources clients asked to be told about: exact
+// URIs, and glob patterns matched against workspace-relative paths.
+// Subscriptions are shared by every client of a daemon.
+type subscriptions struct {
+	uris  map[string]bool
+	globs map[string]*regexp.Regexp
+	mu    sync.RWMutex
+}
+
+// subscribeParams are the params of resources/subscribe and resources/unsubscribe
+type subscribeParams struct {
+	URI string `json:"uri"`
+}
+
+// newSubscriptions creates an empty subscription set
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		uris:  make(map[string]bool),
+		globs: make(map[string]*regexp.Regexp),
+	}
+}
+
+// isGlob reports whether a subscribed URI is a pattern rather than a resource
+func isGlob(uri string) bool {
+	return strings.ContainsAny(uri, "*?[")
+}
+
+// handleSubscribe subscribes to a resource URI or, when the URI contains
+// glob characters, to every file matching it. Patterns may be written as
+// file:// or workspace:// URIs or as workspace-relative paths.
+func (s *MCPServer) handleSubscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	var request subscribeParams
+	if err := json.Unmarshal(params, &request); err != nil || request.URI == "" {
+		return nil, fmt.Errorf("uri is required")
+	}
+
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+
+	if !isGlob(request.URI) {
+		s.subscriptions.uris[s.resourceManager.CanonicalURI(request.URI)] = true
+		return struct{}{}, nil
+	}
+
+	pattern := request.URI
+	if path, ok := s.resourceManager.PathFromURI(pattern); ok {
+		rel, err := filepath.Rel(s.workspacePath, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("pattern is outside the workspace: %s", request.URI)
+		}
+		pattern = filepath.ToSlash(rel)
+	}
+	expr, err := gitignore.CompileGlob(strings.TrimPrefix(pattern, "/"))
+	if err != nil {
+		return nil, err
+	}
+	s.subscriptions.globs[request.URI] = expr
+
+	if s.debug {
+		log.Printf("Subscribed to pattern: %s", pattern)
+	}
+	return struct{}{}, nil
+}
+
+// handleUnsubscribe removes a subscription made with the same URI or pattern
+func (s *MCPServer) handleUnsubscribe(ctx context.Context, params json.RawMessage) (any, error) {
+	var request subscribeParams
+	if err := json.Unmarshal(params, &request); err != nil || request.URI == "" {
+		return nil, fmt.Errorf("uri is required")
+	}
+
+	s.subscriptions.mu.Lock()
+	defer s.subscriptions.mu.Unlock()
+	delete(s.subscriptions.uris, s.resourceManager.CanonicalURI(request.URI))
+	delete(s.subscriptions.globs, request.URI)
+	return struct{}{}, nil
+}
+
+// advertiseSubscribe adds resource subscription support to the server
+// capabilities returned by initialize
+func advertiseSubscribe(params, result json.RawMessage) (json.RawMessage, error) {
+	var response map[string]any
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, err
+	}
+	capabilities, _ := response["capabilities"].(map[string]any)
+	if capabilities == nil {
+		return result, nil
+	}
+	resources, _ := capabilities["resources"].(map[string]any)
+	if resources == nil {
+		resources = make(map[string]any)
+		capabilities["resources"] = resources
+	}
+	resources["subscribe"] = true
+	return json.Marshal(response)
+}
+
+// notifySubscribers queues notifications/resources/updated for a changed
+// file when it, or a pattern matching it, is subscribed to. Creations and
+// deletions also update the file's directory listing.
+func (s *MCPServer) notifySubscribers(event watcher.FileEvent) {
+	uri := s.resourceManager.GetFileURI(event.Path)
+	var updated []pendingUpdate
+
+	s.subscriptions.mu.RLock()
+	matched := s.subscriptions.uris[uri]
+	if !matched && len(s.subscriptions.globs) > 0 {
+		if rel, err := filepath.Rel(s.workspacePath, event.Path); err == nil {
+			rel = filepath.ToSlash(rel)
+			for _, expr := range s.subscriptions.globs {
+				if expr.MatchString(rel) {
+					matched = true
+					break
+				}
+			}
+		}
+	}
+	dir := filepath.Dir(event.Path)
+	if matched {
+		updated = append(updated, pendingUpdate{uri: uri, dir: dir})
+	}
+	if event.EventType != watcher.EventModify {
+		dirURI := s.resourceManager.GetDirectoryURI(dir)
+		if s.subscriptions.uris[dirURI] {
+			updated = append(updated, pendingUpdate{uri: dirURI, dir: dir})
+		}
+	}
+	s.subscriptions.mu.RUnlock()
+
+	for _, update := range updated {
+		s.notifier.Updated(update.uri, update.dir)
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/metoro-io/mcp-golang/transport"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// invalidParamsCode is the JSON-RPC error code for requests the server rejects
+const invalidParamsCode = -32602
+
+// resultRewriter adjusts the result of a response before it is sent, given
+// the params of the request it answers
+type resultRewriter func(params, result json.RawMessage) (json.RawMessage, error)
+
+// paramsRewriter adjusts the params of a request before it is handled
+type paramsRewriter func(params json.RawMessage) (json.RawMessage, error)
+
+// requestHandler answers a request mcp-golang doesn't implement, or one it
+// only implements in part by returning errNotHandled for the rest
+type requestHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// errNotHandled passes a request on to mcp-golang
+var errNotHandled = errors.New("request not handled")
+
+// interceptTransport wraps a transport so the server can adjust protocol
+// messages that mcp-golang doesn't expose hooks for
+type interceptTransport struct {
+	transport.Transport
+	rewriters map[string]resultRewriter
+	params    map[string]paramsRewriter
+	handlers  map[string]requestHandler
+	redirects map[string]func()
+	pending   map[transport.RequestId]pendingRequest
+	activity  *activity // nil unless activity is tracked
+	mu        sync.Mutex
+}
+
+// pendingRequest is a request whose response will be rewritten
+type pendingRequest struct {
+	method string
+	params json.RawMessage
+}
+
+// newInterceptTransport wraps an existing transport
+func newInterceptTransport(t transport.Transport) *interceptTransport {
+	return &interceptTransport{
+		Transport: t,
+		rewriters: make(map[string]resultRewriter),
+		params:    make(map[string]paramsRewriter),
+		handlers:  make(map[string]requestHandler),
+		redirects: make(map[string]func()),
+		pending:   make(map[transport.RequestId]pendingRequest),
+	}
+}
+
+// RewriteResult registers a rewriter for responses to the given method
+func (t *interceptTransport) RewriteResult(method string, rewriter resultRewriter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rewriters[method] = rewriter
+}
+
+// RewriteParams registers a rewriter for the params of requests to the given method
+func (t *interceptTransport) RewriteParams(method string, rewriter paramsRewriter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.params[method] = rewriter
+}
+
+// HandleRequest answers requests to the given method instead of passing
+// them to the server
+func (t *interceptTransport) HandleRequest(method string, handler requestHandler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[method] = handler
+}
+
+// RedirectNotification calls redirect instead of sending server
+// notifications with the given method, so they can be batched
+func (t *interceptTransport) RedirectNotification(method string, redirect func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.redirects[method] = redirect
+}
+
+// TrackActivity records every message from clients, and every response to
+// them, so idle servers can be told apart from busy ones
+func (t *interceptTransport) TrackActivity(a *activity) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.activity = a
+}
+
+// SetMessageHandler records the method of incoming requests and applies any
+// params rewriter before passing them on
+func (t *interceptTransport) SetMessageHandler(handler func(ctx context.Context, message *transport.BaseJsonRpcMessage)) {
+	t.Transport.SetMessageHandler(func(ctx context.Context, message *transport.BaseJsonRpcMessage) {
+		if message.Type == transport.BaseMessageTypeJSONRPCRequestType {
+			// Result rewriters see the params as the client sent them
+			request := message.JsonRpcRequest
+			t.mu.Lock()
+			if _, ok := t.rewriters[request.Method]; ok {
+				t.pending[request.Id] = pendingRequest{method: request.Method, params: request.Params}
+			}
+			rewriter := t.params[request.Method]
+			own := t.handlers[request.Method]
+			activity := t.activity
+			t.mu.Unlock()
+			if activity != nil {
+				activity.request()
+			}
+
+			if own != nil && t.respond(ctx, request, own) {
+				t.mu.Lock()
+				delete(t.pending, request.Id)
+				t.mu.Unlock()
+				if activity != nil {
+					activity.answered()
+				}
+				return
+			}
+
+			if rewriter != nil {
+				params, err := rewriter(request.Params)
+				if err != nil {
+					log.Printf("Error rewriting %s request: %v", request.Method, err)
+				} else {
+					request.Params = params
+				}
+			}
+		} else if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+			t.mu.Lock()
+			activity := t.activity
+			t.mu.Unlock()
+			if activity != nil {
+				activity.notified()
+			}
+		}
+		handler(ctx, message)
+	})
+}
+
+// respond answers a request with a handler registered through HandleRequest,
+// reporting false if the handler passed it on
+func (t *interceptTransport) respond(ctx context.Context, request *transport.BaseJSONRPCRequest, handler requestHandler) bool {
+	var message *transport.BaseJsonRpcMessage
+	result, err := handler(ctx, request.Params)
+	if err == errNotHandled {
+		return false
+	}
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(result)
+		message = transport.NewBaseMessageResponse(&transport.BaseJSONRPCResponse{
+			Jsonrpc: "2.0",
+			Id:      request.Id,
+			Result:  data,
+		})
+	}
+	if err != nil {
+		inner := transport.BaseJSONRPCErrorInner{Code: invalidParamsCode, Message: errcode.Tagged(err).Error()}
+		if code, text, remediation := errcode.Parse(inner.Message); code != "" {
+			inner.Data = errorData{Code: code, Message: text, Remediation: remediation}
+		}
+		// Failed reads are answered as they are for registered resources
+		if request.Method == "resources/read" {
+			failed := resourceError(inner.Message)
+			inner = transport.BaseJSONRPCErrorInner{Code: failed.code, Message: failed.message, Data: failed.data}
+		}
+		message = transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+			Jsonrpc: "2.0",
+			Id:      request.Id,
+			Error:   inner,
+		})
+	}
+	if err := t.Transport.Send(ctx, message); err != nil {
+		log.Printf("Error answering %s request: %v", request.Method, err)
+	}
+	return true
+}
+
+// Send applies any registered rewriter to responses before sending them
+func (t *interceptTransport) Send(ctx context.Context, message *transport.BaseJsonRpcMessage) error {
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType || message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		t.mu.Lock()
+		activity := t.activity
+		t.mu.Unlock()
+		if activity != nil {
+			activity.answered()
+		}
+	}
+	if message.Type == transport.BaseMessageTypeJSONRPCResponseType {
+		t.mu.Lock()
+		request, ok := t.pending[message.JsonRpcResponse.Id]
+		delete(t.pending, message.JsonRpcResponse.Id)
+		rewriter := t.rewriters[request.method]
+		t.mu.Unlock()
+
+		if ok && rewriter != nil {
+			result, err := rewriter(request.params, message.JsonRpcResponse.Result)
+			var failed *rpcError
+			switch {
+			case errors.As(err, &failed):
+				message = transport.NewBaseMessageError(&transport.BaseJSONRPCError{
+					Jsonrpc: "2.0",
+					Id:      message.JsonRpcResponse.Id,
+					Error:   transport.BaseJSONRPCErrorInner{Code: failed.code, Message: failed.message, Data: failed.data},
+				})
+			case err != nil:
+				log.Printf("Error rewriting %s response: %v", request.method, err)
+			default:
+				message.JsonRpcResponse.Result = result
+			}
+		}
+	} else if message.Type == transport.BaseMessageTypeJSONRPCErrorType {
+		t.mu.Lock()
+		delete(t.pending, message.JsonRpcError.Id)
+		t.mu.Unlock()
+	} else if message.Type == transport.BaseMessageTypeJSONRPCNotificationType {
+		t.mu.Lock()
+		redirect := t.redirects[message.JsonRpcNotification.Method]
+		t.mu.Unlock()
+		if redirect != nil {
+			redirect()
+			return nil
+		}
+	}
+
+	return t.Transport.Send(ctx, message)
+}
@@ -0,0 +1,36 @@
+// Package session identifies the client a request came from.
+//
+// A daemon serves many clients through one MCP server, whose handlers only
+// see the request's context. The transport gives each connection a random
+// ID and puts it in the context of the connection's requests, so handlers
+// can keep state per client. Stdio serves a single client, whose requests
+// carry no ID.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// key is the context key of a session ID
+type key struct{}
+
+// NewID returns a random session ID that can't be guessed, so it can name
+// the session's private files
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// With returns a context for requests from the session id
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the session a request's context belongs to
+func From(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(key{}).(string)
+	return id, ok
+}
@@ -0,0 +1,357 @@
+package sftpfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Packet types of SFTP version 3 (draft-ietf-secsh-filexfer-02)
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpRead     = 5
+	fxpWrite    = 6
+	fxpLstat    = 7
+	fxpOpendir  = 11
+	fxpReaddir  = 12
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpRmdir    = 15
+	fxpRealpath = 16
+	fxpStat     = 17
+	fxpRename   = 18
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpData     = 103
+	fxpName     = 104
+	fxpAttrs    = 105
+	fxpExtended = 200
+)
+
+// Status codes
+const (
+	fxOK               = 0
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+)
+
+// Open flags
+const (
+	fxfRead  = 0x01
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+)
+
+// Attribute flags
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+	attrExtended    = 0x80000000
+)
+
+// maxPacket bounds the packets accepted from the server
+const maxPacket = 1 << 20
+
+// errConnectionLost is returned for requests the server never answered
+var errConnectionLost = errors.New("sftp connection lost")
+
+// statusError is a failure reported by the server
+type statusError struct {
+	code    uint32
+	message string
+}
+
+func (e *statusError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return fmt.Sprintf("sftp status %d", e.code)
+}
+
+// Unwrap maps status codes to the io/fs errors callers check for
+func (e *statusError) Unwrap() error {
+	switch e.code {
+	case fxNoSuchFile:
+		return fs.ErrNotExist
+	case fxPermissionDenied:
+		return fs.ErrPermission
+	}
+	return nil
+}
+
+// client speaks SFTP over a connection to the server's subsystem,
+// matching responses to requests by id so requests can run concurrently
+type client struct {
+	w          io.WriteCloser
+	writeMu    sync.Mutex
+	pending    map[uint32]chan packet
+	nextID     uint32
+	extensions map[string]string
+	err        error // set once the connection is lost
+	mu         sync.Mutex
+}
+
+// packet is a response: its type and the payload after the request id
+type packet struct {
+	kind byte
+	data []byte
+}
+
+// newClient runs the version handshake and starts reading responses
+func newClient(r io.Reader, w io.WriteCloser) (*client, error) {
+	c := &client{w: w, pending: make(map[uint32]chan packet), extensions: make(map[string]string)}
+	reader := bufio.NewReaderSize(r, 64<<10)
+
+	var init buffer
+	init.uint32(3)
+	if err := c.send(fxpInit, init); err != nil {
+		return nil, err
+	}
+	kind, data, err := readPacket(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp session: %v", err)
+	}
+	if kind != fxpVersion {
+		return nil, fmt.Errorf("failed to start sftp session: unexpected packet %d", kind)
+	}
+	version := parser{data: data}
+	if v := version.uint32(); v < 3 {
+		return nil, fmt.Errorf("unsupported sftp version %d", v)
+	}
+	for version.remaining() > 0 {
+		name, value := version.string(), version.string()
+		if version.err != nil {
+			break
+		}
+		c.extensions[name] = value
+	}
+
+	go c.readLoop(reader)
+	return c, nil
+}
+
+// send writes one packet
+func (c *client) send(kind byte, payload buffer) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header, uint32(len(payload.b)+1))
+	header[4] = kind
+	if _, err := c.w.Write(append(header, payload.b...)); err != nil {
+		return errConnectionLost
+	}
+	return nil
+}
+
+// readPacket reads one packet
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("bad sftp packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// readLoop delivers responses until the connection closes, then fails
+// every request still waiting
+func (c *client) readLoop(r io.Reader) {
+	for {
+		kind, data, err := readPacket(r)
+		if err != nil || len(data) < 4 {
+			break
+		}
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- packet{kind: kind, data: data[4:]}
+		}
+	}
+
+	c.mu.Lock()
+	c.err = errConnectionLost
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+}
+
+// request sends a request and waits for its response
+func (c *client) request(kind byte, build func(*buffer)) (packet, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return packet{}, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan packet, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	var payload buffer
+	payload.uint32(id)
+	build(&payload)
+	if err := c.send(kind, payload); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return packet{}, err
+	}
+	response, ok := <-ch
+	if !ok {
+		return packet{}, errConnectionLost
+	}
+	return response, nil
+}
+
+// status returns the error carried by a status response, or an error for
+// a response of an unexpected kind
+func status(response packet, want byte) error {
+	if response.kind == want {
+		return nil
+	}
+	if response.kind != fxpStatus {
+		return fmt.Errorf("unexpected sftp response %d", response.kind)
+	}
+	p := parser{data: response.data}
+	code := p.uint32()
+	message := p.string()
+	if code == fxOK {
+		if want == fxpStatus {
+			return nil
+		}
+		return fmt.Errorf("unexpected sftp status")
+	}
+	return &statusError{code: code, message: message}
+}
+
+// isEOF reports whether an error is the server's end-of-file status
+func isEOF(err error) bool {
+	var status *statusError
+	return errors.As(err, &status) && status.code == fxEOF
+}
+
+// attrs are the attributes of a file
+type attrs struct {
+	size    int64
+	mode    uint32 // POSIX st_mode
+	modTime time.Time
+}
+
+// attrs parses an ATTRS structure
+func (p *parser) attrs() attrs {
+	var a attrs
+	flags := p.uint32()
+	if flags&attrSize != 0 {
+		a.size = int64(p.uint64())
+	}
+	if flags&attrUIDGID != 0 {
+		p.uint32()
+		p.uint32()
+	}
+	if flags&attrPermissions != 0 {
+		a.mode = p.uint32()
+	}
+	if flags&attrACModTime != 0 {
+		p.uint32()
+		a.modTime = time.Unix(int64(p.uint32()), 0)
+	}
+	if flags&attrExtended != 0 {
+		for n := p.uint32(); n > 0 && p.err == nil; n-- {
+			p.string()
+			p.string()
+		}
+	}
+	return a
+}
+
+// fileMode converts a POSIX st_mode to an fs.FileMode
+func (a attrs) fileMode() fs.FileMode {
+	mode := fs.FileMode(a.mode & 0777)
+	switch a.mode & 0170000 {
+	case 0040000:
+		mode |= fs.ModeDir
+	case 0120000:
+		mode |= fs.ModeSymlink
+	case 0010000:
+		mode |= fs.ModeNamedPipe
+	case 0140000:
+		mode |= fs.ModeSocket
+	case 0020000:
+		mode |= fs.ModeDevice | fs.ModeCharDevice
+	case 0060000:
+		mode |= fs.ModeDevice
+	}
+	return mode
+}
+
+// buffer builds a request payload
+type buffer struct {
+	b []byte
+}
+
+func (b *buffer) uint32(v uint32) { b.b = binary.BigEndian.AppendUint32(b.b, v) }
+func (b *buffer) uint64(v uint64) { b.b = binary.BigEndian.AppendUint64(b.b, v) }
+func (b *buffer) string(s string) { b.uint32(uint32(len(s))); b.b = append(b.b, s...) }
+func (b *buffer) bytes(s []byte)  { b.uint32(uint32(len(s))); b.b = append(b.b, s...) }
+
+// parser reads a response payload; the first malformed field sets err and
+// every later read returns zero values
+type parser struct {
+	data []byte
+	err  error
+}
+
+func (p *parser) remaining() int { return len(p.data) }
+
+func (p *parser) take(n int) []byte {
+	if p.err != nil || n < 0 || len(p.data) < n {
+		p.err = errors.New("short sftp packet")
+		return nil
+	}
+	out := p.data[:n]
+	p.data = p.data[n:]
+	return out
+}
+
+func (p *parser) uint32() uint32 {
+	if b := p.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (p *parser) uint64() uint64 {
+	if b := p.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (p *parser) string() string {
+	return string(p.take(int(p.uint32())))
+}
@@ -0,0 +1,532 @@
+// Package sftpfs serves a remote directory over SFTP, so a workspace can
+// live on another machine. It runs the system ssh client with the sftp
+// subsystem, so hosts, keys and agents come from the usual ssh
+// configuration; authentication must not need a prompt.
+package sftpfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+)
+
+// chunkSize is the most data read or written per request; servers accept
+// at least 32KiB
+const chunkSize = 32 << 10
+
+// Target is a parsed sftp:// workspace URL
+type Target struct {
+	User string
+	Host string
+	Port string
+	Path string
+}
+
+// IsURL reports whether a workspace argument names a remote workspace
+func IsURL(workspace string) bool {
+	return strings.HasPrefix(workspace, "sftp://")
+}
+
+// Parse parses sftp://[user@]host[:port]/path; the path is absolute on the
+// remote host, or relative to the login directory when it starts with /~/
+func Parse(workspace string) (Target, error) {
+	u, err := url.Parse(workspace)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid workspace URL: %v", err)
+	}
+	if u.Scheme != "sftp" || u.Hostname() == "" {
+		return Target{}, fmt.Errorf("invalid workspace URL %q: expected sftp://[user@]host[:port]/path", workspace)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return Target{}, fmt.Errorf("invalid workspace URL: passwords are not supported; use ssh keys or an agent")
+	}
+	target := Target{User: u.User.Username(), Host: u.Hostname(), Port: u.Port(), Path: u.Path}
+	switch {
+	case target.Path == "" || target.Path == "/~":
+		target.Path = "."
+	case strings.HasPrefix(target.Path, "/~/"):
+		target.Path = strings.TrimPrefix(target.Path, "/~/")
+	}
+	return target, nil
+}
+
+// String formats the target as a URL
+func (t Target) String() string {
+	host := t.Host
+	if t.Port != "" {
+		host += ":" + t.Port
+	}
+	if t.User != "" {
+		host = t.User + "@" + host
+	}
+	p := t.Path
+	if !strings.HasPrefix(p, "/") {
+		p = "/~/" + strings.TrimPrefix(p, ".")
+	}
+	return "sftp://" + host + strings.TrimSuffix(p, "/")
+}
+
+// FS is a remote directory served over SFTP
+type FS struct {
+	root   string
+	client *client
+	cmd    *exec.Cmd
+	once   sync.Once
+}
+
+var _ fsys.WriteFS = (*FS)(nil)
+var _ fsys.LstatFS = (*FS)(nil)
+
+// Dial connects to the target and resolves its path, which must be a
+// directory
+func Dial(target Target) (*FS, error) {
+	args := []string{"-o", "BatchMode=yes", "-o", "ServerAliveInterval=15"}
+	if target.Port != "" {
+		args = append(args, "-p", target.Port)
+	}
+	if target.User != "" {
+		args = append(args, "-l", target.User)
+	}
+	args = append(args, "-s", target.Host, "sftp")
+
+	cmd := exec.Command("ssh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %v", err)
+	}
+
+	c, err := newClient(stdout, stdin)
+	if err != nil {
+		stdin.Close()
+		cmd.Wait()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to connect to %s: %s", target.Host, msg)
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %v", target.Host, err)
+	}
+	f := &FS{client: c, cmd: cmd}
+
+	root, err := f.realpath(target.Path)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to resolve %s: %v", target.Path, err)
+	}
+	a, err := f.stat(fxpStat, root)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to access %s: %v", root, err)
+	}
+	if !a.fileMode().IsDir() {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	f.root = root
+	return f, nil
+}
+
+// Mount connects to an sftp:// workspace URL and returns it as a workspace
+// rooted at the resolved remote path
+func Mount(workspace string) (*fsys.Workspace, error) {
+	target, err := Parse(workspace)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Dial(target)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.Mount(f.Root(), f), nil
+}
+
+// Root returns the absolute remote path of the served directory
+func (f *FS) Root() string {
+	return f.root
+}
+
+// Close ends the session
+func (f *FS) Close() error {
+	f.once.Do(func() {
+		f.client.w.Close()
+		done := make(chan struct{})
+		go func() {
+			f.cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			f.cmd.Process.Kill()
+		}
+	})
+	return nil
+}
+
+// remote returns the remote path of a name
+func (f *FS) remote(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(f.root, name), nil
+}
+
+// realpath canonicalizes a remote path
+func (f *FS) realpath(p string) (string, error) {
+	response, err := f.client.request(fxpRealpath, func(b *buffer) { b.string(p) })
+	if err != nil {
+		return "", err
+	}
+	if err := status(response, fxpName); err != nil {
+		return "", err
+	}
+	parser := parser{data: response.data}
+	if parser.uint32() < 1 {
+		return "", fmt.Errorf("empty realpath response")
+	}
+	resolved := parser.string()
+	return resolved, parser.err
+}
+
+// stat requests the attributes of a remote path
+func (f *FS) stat(kind byte, p string) (attrs, error) {
+	response, err := f.client.request(kind, func(b *buffer) { b.string(p) })
+	if err != nil {
+		return attrs{}, err
+	}
+	if err := status(response, fxpAttrs); err != nil {
+		return attrs{}, err
+	}
+	parser := parser{data: response.data}
+	a := parser.attrs()
+	return a, parser.err
+}
+
+// handleRequest sends a request answered with a handle
+func (f *FS) handleRequest(kind byte, build func(*buffer)) (string, error) {
+	response, err := f.client.request(kind, build)
+	if err != nil {
+		return "", err
+	}
+	if err := status(response, fxpHandle); err != nil {
+		return "", err
+	}
+	parser := parser{data: response.data}
+	handle := parser.string()
+	return handle, parser.err
+}
+
+// statusRequest sends a request answered with a status
+func (f *FS) statusRequest(kind byte, build func(*buffer)) error {
+	response, err := f.client.request(kind, build)
+	if err != nil {
+		return err
+	}
+	return status(response, fxpStatus)
+}
+
+// closeHandle releases a file or directory handle
+func (f *FS) closeHandle(handle string) error {
+	return f.statusRequest(fxpClose, func(b *buffer) { b.string(handle) })
+}
+
+// Stat implements fs.StatFS
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.info("stat", fxpStat, name)
+}
+
+// Lstat implements fsys.LstatFS
+func (f *FS) Lstat(name string) (fs.FileInfo, error) {
+	return f.info("lstat", fxpLstat, name)
+}
+
+// info describes a file with STAT or LSTAT
+func (f *FS) info(op string, kind byte, name string) (fs.FileInfo, error) {
+	p, err := f.remote(op, name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := f.stat(kind, p)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return fileInfo{name: path.Base(name), attrs: a}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. Entries describe symbolic links rather
+// than their targets, as os.ReadDir does.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := f.remote("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	handle, err := f.handleRequest(fxpOpendir, func(b *buffer) { b.string(p) })
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	defer f.closeHandle(handle)
+
+	var entries []fs.DirEntry
+	for {
+		response, err := f.client.request(fxpReaddir, func(b *buffer) { b.string(handle) })
+		if err == nil {
+			err = status(response, fxpName)
+		}
+		if isEOF(err) {
+			break
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		parser := parser{data: response.data}
+		for n := parser.uint32(); n > 0 && parser.err == nil; n-- {
+			filename := parser.string()
+			parser.string() // long name
+			a := parser.attrs()
+			if filename == "." || filename == ".." || parser.err != nil {
+				continue
+			}
+			entries = append(entries, fs.FileInfoToDirEntry(fileInfo{name: filename, attrs: a}))
+		}
+		if parser.err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: parser.err}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Open implements fs.FS
+func (f *FS) Open(name string) (fs.File, error) {
+	info, err := f.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := f.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return fsys.DirFile(info, entries), nil
+	}
+	p, _ := f.remote("open", name)
+	handle, err := f.handleRequest(fxpOpen, func(b *buffer) {
+		b.string(p)
+		b.uint32(fxfRead)
+		b.uint32(0) // no attributes
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{fs: f, name: name, handle: handle, info: info}, nil
+}
+
+// ReadFile implements fs.ReadFileFS
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, _ := file.Stat()
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, info.Size()))
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile implements fsys.WriteFS
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	p, err := f.remote("write", name)
+	if err != nil {
+		return err
+	}
+	handle, err := f.handleRequest(fxpOpen, func(b *buffer) {
+		b.string(p)
+		b.uint32(fxfWrite | fxfCreat | fxfTrunc)
+		b.uint32(attrPermissions)
+		b.uint32(uint32(perm.Perm()))
+	})
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	for offset := 0; offset < len(data); offset += chunkSize {
+		chunk := data[offset:min(offset+chunkSize, len(data))]
+		err := f.statusRequest(fxpWrite, func(b *buffer) {
+			b.string(handle)
+			b.uint64(uint64(offset))
+			b.bytes(chunk)
+		})
+		if err != nil {
+			f.closeHandle(handle)
+			return &fs.PathError{Op: "write", Path: name, Err: err}
+		}
+	}
+	if err := f.closeHandle(handle); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll implements fsys.WriteFS
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if info, err := f.Stat(name); err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		return nil
+	}
+	if parent := path.Dir(name); parent != name {
+		if err := f.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	p, _ := f.remote("mkdir", name)
+	err := f.statusRequest(fxpMkdir, func(b *buffer) {
+		b.string(p)
+		b.uint32(attrPermissions)
+		b.uint32(uint32(perm.Perm()))
+	})
+	if err != nil {
+		// Another client may have created it meanwhile
+		if info, statErr := f.Stat(name); statErr == nil && info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Remove implements fsys.WriteFS
+func (f *FS) Remove(name string) error {
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	kind := byte(fxpRemove)
+	if info.IsDir() {
+		kind = fxpRmdir
+	}
+	p, _ := f.remote("remove", name)
+	if err := f.statusRequest(kind, func(b *buffer) { b.string(p) }); err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename implements fsys.WriteFS. Plain SFTP renames refuse to replace an
+// existing file, so the OpenSSH extension is used where the server has it.
+func (f *FS) Rename(oldname, newname string) error {
+	oldpath, err := f.remote("rename", oldname)
+	if err != nil {
+		return err
+	}
+	newpath, err := f.remote("rename", newname)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.client.extensions["posix-rename@openssh.com"]; ok {
+		err = f.statusRequest(fxpExtended, func(b *buffer) {
+			b.string("posix-rename@openssh.com")
+			b.string(oldpath)
+			b.string(newpath)
+		})
+	} else {
+		err = f.statusRequest(fxpRename, func(b *buffer) {
+			b.string(oldpath)
+			b.string(newpath)
+		})
+	}
+	if err != nil {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: err}
+	}
+	return nil
+}
+
+// fileInfo describes a remote file
+type fileInfo struct {
+	name  string
+	attrs attrs
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.attrs.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.attrs.fileMode() }
+func (i fileInfo) ModTime() time.Time { return i.attrs.modTime }
+func (i fileInfo) IsDir() bool        { return i.Mode().IsDir() }
+func (i fileInfo) Sys() any           { return nil }
+
+// file is a remote file open for reading
+type file struct {
+	fs     *FS
+	name   string
+	handle string
+	info   fs.FileInfo
+	offset int64
+	eof    bool
+}
+
+// Stat implements fs.File
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// Read implements fs.File
+func (f *file) Read(p []byte) (int, error) {
+	if f.eof {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	response, err := f.fs.client.request(fxpRead, func(b *buffer) {
+		b.string(f.handle)
+		b.uint64(uint64(f.offset))
+		b.uint32(uint32(min(len(p), chunkSize)))
+	})
+	if err == nil {
+		err = status(response, fxpData)
+	}
+	if isEOF(err) {
+		f.eof = true
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	parser := parser{data: response.data}
+	data := parser.string()
+	if parser.err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: parser.err}
+	}
+	n := copy(p, data)
+	f.offset += int64(n)
+	return n, nil
+}
+
+// Close implements fs.File
+func (f *file) Close() error {
+	return f.fs.closeHandle(f.handle)
+}
@@ -0,0 +1,377 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Edit is a change to the value at a path of a structured file
+type Edit struct {
+	Path []string
+	// Value is set at the path, replacing the value there or adding a key
+	// or array element. "-" as the last array index appends.
+	Value any
+	// Delete removes the value at the path instead
+	Delete bool
+	// Document picks the document of a multi-document YAML stream
+	Document int
+}
+
+// EditResult is a file after an edit
+type EditResult struct {
+	Data []byte
+	// Old is the value that was at the path, nil if there was none
+	Old any
+	// Added is set when the path didn't exist and was added
+	Added bool
+	// Preserved is set when the edit only touched the changed value's
+	// text; otherwise the file was re-encoded, which keeps YAML comments
+	// but not the original layout
+	Preserved bool
+}
+
+// ParsePath parses a JSON Pointer such as "/spec/replicas" or a dotted path
+// such as ".spec.containers[0].image" into its keys and indexes
+func ParsePath(path string) ([]string, error) {
+	if path == "" || path == "/" || path == "." {
+		return nil, fmt.Errorf("path must name a value below the root")
+	}
+	if strings.HasPrefix(path, "/") {
+		var keys []string
+		for _, key := range strings.Split(path[1:], "/") {
+			keys = append(keys, strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~"))
+		}
+		return keys, nil
+	}
+
+	var keys []string
+	var key strings.Builder
+	quoted := false
+	flush := func() {
+		if key.Len() > 0 {
+			keys = append(keys, key.String())
+			key.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			key.WriteByte(c)
+		case c == '.':
+			flush()
+		case c == '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in path %q", path)
+			}
+			keys = append(keys, strings.Trim(path[i+1:i+end], `"'`))
+			i += end
+		default:
+			key.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in path %q", path)
+	}
+	flush()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("path must name a value below the root")
+	}
+	return keys, nil
+}
+
+// ParseValue parses a JSON value for an edit, keeping integers as int64
+func ParseValue(text string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("value must be JSON, such as 3, \"text\" or {\"a\": 1}: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("value must be a single JSON value")
+	}
+	return normalizeNumbers(value), nil
+}
+
+// normalizeNumbers converts JSON numbers to int64 or float64, so YAML and
+// TOML encode them as numbers
+func normalizeNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]any:
+		for k, v := range value {
+			value[k] = normalizeNumbers(v)
+		}
+	case []any:
+		for i, v := range value {
+			value[i] = normalizeNumbers(v)
+		}
+	}
+	return value
+}
+
+// Apply makes an edit to a structured file
+func Apply(data []byte, format Format, edit Edit) (*EditResult, error) {
+	if len(edit.Path) == 0 {
+		return nil, fmt.Errorf("path must name a value below the root")
+	}
+	if err := Validate(data, format); err != nil {
+		return nil, err
+	}
+	switch format {
+	case JSON:
+		return applyJSON(data, edit)
+	case YAML:
+		return applyYAML(data, edit)
+	case TOML:
+		return applyTOML(data, edit)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// pathError describes a path that doesn't lead to a value
+func pathError(path []string, depth int, problem string) error {
+	return fmt.Errorf("%s at %s", problem, "/"+strings.Join(path[:depth+1], "/"))
+}
+
+// arrayIndex parses an array index of a path; "-" is the end of the array
+func arrayIndex(key string, length int, appendOK bool) (int, bool) {
+	if key == "-" && appendOK {
+		return length, true
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i > length || (i == length && !appendOK) {
+		return 0, false
+	}
+	return i, true
+}
+
+// jsonItem is a member of a JSON object or an element of an array, by byte
+// offsets into the file
+type jsonItem struct {
+	key        string
+	start      int // of the key, or of the value in arrays
+	valueStart int
+	valueEnd   int
+}
+
+// applyJSON edits JSON text in place, so everything but the changed value
+// keeps its layout
+func applyJSON(data []byte, edit Edit) (*EditResult, error) {
+	start := skipSpace(data, 0)
+	for depth, key := range edit.Path {
+		last := depth == len(edit.Path)-1
+		open := start
+		if data[open] != '{' && data[open] != '[' {
+			return nil, pathError(edit.Path, depth, "no object or array")
+		}
+		items, close := jsonItems(data, open)
+
+		found := -1
+		if data[open] == '{' {
+			for i, item := range items {
+				if item.key == key {
+					found = i
+				}
+			}
+		} else {
+			i, ok := arrayIndex(key, len(items), last && !edit.Delete)
+			if !ok {
+				return nil, pathError(edit.Path, depth, "no such array index")
+			}
+			if i < len(items) {
+				found = i
+			}
+		}
+
+		if found < 0 {
+			if !last || edit.Delete {
+				return nil, pathError(edit.Path, depth, "no such key")
+			}
+			return &EditResult{Data: jsonInsert(data, open, close, items, key, edit.Value), Added: true, Preserved: true}, nil
+		}
+		item := items[found]
+		if !last {
+			start = item.valueStart
+			continue
+		}
+
+		var old any
+		_ = json.Unmarshal(data[item.valueStart:item.valueEnd], &old)
+		if edit.Delete {
+			return &EditResult{Data: jsonDelete(data, items, found, close), Old: old, Preserved: true}, nil
+		}
+		encoded := jsonEncode(edit.Value, lineIndent(data, item.start), indentUnit(data))
+		out := append(append(append([]byte{}, data[:item.valueStart]...), encoded...), data[item.valueEnd:]...)
+		return &EditResult{Data: out, Old: old, Preserved: true}, nil
+	}
+	return nil, fmt.Errorf("path must name a value below the root")
+}
+
+// jsonInsert adds a member or element at the end of an object or array
+func jsonInsert(data []byte, open, close int, items []jsonItem, key string, value any) []byte {
+	unit := indentUnit(data)
+	parentIndent := lineIndent(data, open)
+	multiline := bytes.IndexByte(data[open:close], '\n') >= 0
+
+	var entry []byte
+	childIndent := parentIndent + unit
+	if len(items) > 0 {
+		childIndent = lineIndent(data, items[len(items)-1].start)
+	}
+	if data[open] == '{' {
+		name, _ := json.Marshal(key)
+		entry = append(name, ": "...)
+	}
+	if multiline {
+		entry = append(entry, jsonEncode(value, childIndent, unit)...)
+	} else {
+		// Keep single-line objects and arrays on one line
+		entry = append(entry, jsonEncode(value, "", "")...)
+	}
+
+	var out []byte
+	switch {
+	case len(items) == 0 && multiline:
+		out = append(append([]byte{}, data[:open+1]...), "\n"+childIndent...)
+		out = append(append(out, entry...), "\n"+parentIndent...)
+		return append(out, data[close:]...)
+	case len(items) == 0:
+		out = append(append([]byte{}, data[:open+1]...), entry...)
+		return append(out, data[close:]...)
+	}
+	at := items[len(items)-1].valueEnd
+	out = append(append([]byte{}, data[:at]...), ',')
+	if multiline {
+		out = append(out, "\n"+childIndent...)
+	} else {
+		out = append(out, ' ')
+	}
+	out = append(out, entry...)
+	return append(out, data[at:]...)
+}
+
+// jsonDelete removes a member or element with the comma separating it
+func jsonDelete(data []byte, items []jsonItem, i, close int) []byte {
+	from, to := items[i].start, items[i].valueEnd
+	switch {
+	case i+1 < len(items):
+		to = items[i+1].start
+	case i > 0:
+		from = items[i-1].valueEnd
+	default:
+		// The only item: leave the braces or brackets empty
+		from = skipBack(data, from)
+		to = close
+	}
+	return append(append([]byte{}, data[:from]...), data[to:]...)
+}
+
+// jsonEncode encodes a value, indenting objects and arrays to continue the
+// line they start on
+func jsonEncode(value any, indent, unit string) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	switch value.(type) {
+	case map[string]any, []any:
+		encoder.SetIndent(indent, unit)
+	}
+	_ = encoder.Encode(value)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// jsonItems returns the items of the object or array opening at open, and
+// the offset of its closing brace or bracket
+func jsonItems(data []byte, open int) ([]jsonItem, int) {
+	var items []jsonItem
+	i := skipSpace(data, open+1)
+	for data[i] != '}' && data[i] != ']' {
+		item := jsonItem{start: i}
+		if data[open] == '{' {
+			end := skipValue(data, i)
+			_ = json.Unmarshal(data[i:end], &item.key)
+			i = skipSpace(data, end)
+			i = skipSpace(data, i+1) // the colon
+		}
+		item.valueStart = i
+		item.valueEnd = skipValue(data, i)
+		items = append(items, item)
+		i = skipSpace(data, item.valueEnd)
+		if data[i] == ',' {
+			i = skipSpace(data, i+1)
+		}
+	}
+	return items, i
+}
+
+// skipValue returns the offset just past the valid JSON value at i
+func skipValue(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		for i++; data[i] != '"'; i++ {
+			if data[i] == '\\' {
+				i++
+			}
+		}
+		return i + 1
+	case '{', '[':
+		_, close := jsonItems(data, i)
+		return close + 1
+	}
+	for i < len(data) && !strings.ContainsRune(",}] \t\r\n", rune(data[i])) {
+		i++
+	}
+	return i
+}
+
+// skipSpace returns the offset of the first non-space byte from i
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && strings.ContainsRune(" \t\r\n", rune(data[i])) {
+		i++
+	}
+	return i
+}
+
+// skipBack returns the offset after the last non-space byte before i
+func skipBack(data []byte, i int) int {
+	for i > 0 && strings.ContainsRune(" \t\r\n", rune(data[i-1])) {
+		i--
+	}
+	return i
+}
+
+// lineIndent returns the leading whitespace of the line holding offset i
+func lineIndent(data []byte, i int) string {
+	start := bytes.LastIndexByte(data[:i], '\n') + 1
+	end := start
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[start:end])
+}
+
+// indentUnit guesses a file's indentation step from its first indented
+// line, defaulting to two spaces
+func indentUnit(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n"))[1:] {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
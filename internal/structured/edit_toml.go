@@ -0,0 +1,90 @@
+package structured
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// applyTOML edits a TOML file by decoding and re-encoding it, which sorts
+// its keys, so files with comments are refused rather than losing them
+func applyTOML(data []byte, edit Edit) (*EditResult, error) {
+	if hasTOMLComments(data) {
+		return nil, fmt.Errorf("TOML files with comments can't be edited without losing them")
+	}
+	if edit.Value == nil && !edit.Delete {
+		return nil, fmt.Errorf("TOML has no null value")
+	}
+	var root map[string]any
+	if _, err := toml.Decode(string(data), &root); err != nil {
+		return nil, err
+	}
+
+	var container any = root
+	for depth, key := range edit.Path {
+		last := depth == len(edit.Path)-1
+		var child any
+		found := false
+		switch c := container.(type) {
+		case map[string]any:
+			child, found = c[key]
+			if last {
+				if !found && edit.Delete {
+					return nil, pathError(edit.Path, depth, "no such key")
+				}
+				if edit.Delete {
+					delete(c, key)
+				} else {
+					c[key] = edit.Value
+				}
+				result, err := encodeTOML(root, child)
+				if err == nil {
+					result.Added = !found
+				}
+				return result, err
+			}
+		case []map[string]any:
+			i, ok := arrayIndex(key, len(c), false)
+			if !ok {
+				return nil, pathError(edit.Path, depth, "no such array index")
+			}
+			if last {
+				return nil, pathError(edit.Path, depth, "can't replace or delete a table of an array of tables")
+			}
+			child, found = c[i], true
+		case []any:
+			i, ok := arrayIndex(key, len(c), false)
+			if !ok {
+				return nil, pathError(edit.Path, depth, "no such array index")
+			}
+			if last {
+				if edit.Delete {
+					return nil, pathError(edit.Path, depth, "can't delete array elements of TOML files; set the whole array")
+				}
+				old := c[i]
+				c[i] = edit.Value
+				return encodeTOML(root, old)
+			}
+			child, found = c[i], true
+		default:
+			return nil, pathError(edit.Path, depth, "no table or array")
+		}
+		if !found {
+			return nil, pathError(edit.Path, depth, "no such key")
+		}
+		container = child
+	}
+	return nil, fmt.Errorf("path must name a value below the root")
+}
+
+// encodeTOML encodes an edited TOML file
+func encodeTOML(root map[string]any, old any) (*EditResult, error) {
+	var out bytes.Buffer
+	encoder := toml.NewEncoder(&out)
+	encoder.Indent = ""
+	if err := encoder.Encode(root); err != nil {
+		return nil, fmt.Errorf("failed to encode TOML: %v", err)
+	}
+	return &EditResult{Data: out.Bytes(), Old: old}, nil
+}
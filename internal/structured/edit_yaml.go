@@ -0,0 +1,215 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyYAML edits a YAML stream. A scalar replaced by a scalar is edited in
+// the text; other edits change the node tree and re-encode it, keeping
+// comments.
+func applyYAML(data []byte, edit Edit) (*EditResult, error) {
+	documents, _ := parseYAML(data)
+	if edit.Document < 0 || edit.Document >= len(documents) {
+		return nil, fmt.Errorf("the file has %d documents; document %d doesn't exist", len(documents), edit.Document)
+	}
+	document := documents[edit.Document]
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("document %d is empty", edit.Document)
+	}
+
+	node := document.Content[0]
+	for depth, key := range edit.Path {
+		last := depth == len(edit.Path)-1
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+
+		index := -1
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					index = i + 1
+				}
+			}
+		case yaml.SequenceNode:
+			i, ok := arrayIndex(key, len(node.Content), last && !edit.Delete)
+			if !ok {
+				return nil, pathError(edit.Path, depth, "no such sequence index")
+			}
+			if i < len(node.Content) {
+				index = i
+			}
+		default:
+			return nil, pathError(edit.Path, depth, "no mapping or sequence")
+		}
+		parent := node
+		if index < 0 {
+			if !last || edit.Delete {
+				return nil, pathError(edit.Path, depth, "no such key")
+			}
+			value, err := yamlNode(edit.Value)
+			if err != nil {
+				return nil, err
+			}
+			if parent.Kind == yaml.MappingNode {
+				parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key})
+			}
+			parent.Content = append(parent.Content, value)
+			out, err := encodeYAML(documents, data)
+			return &EditResult{Data: out, Added: true}, err
+		}
+		if !last {
+			node = parent.Content[index]
+			continue
+		}
+
+		target := parent.Content[index]
+		var old any
+		_ = target.Decode(&old)
+		result := &EditResult{Old: old}
+		if edit.Delete {
+			start := index
+			if parent.Kind == yaml.MappingNode {
+				start--
+			}
+			parent.Content = append(parent.Content[:start], parent.Content[index+1:]...)
+			out, err := encodeYAML(documents, data)
+			result.Data = out
+			return result, err
+		}
+
+		if out, ok := replaceYAMLScalar(data, target, edit.Value); ok {
+			result.Data, result.Preserved = out, true
+			return result, nil
+		}
+		value, err := yamlNode(edit.Value)
+		if err != nil {
+			return nil, err
+		}
+		value.HeadComment, value.LineComment, value.FootComment = target.HeadComment, target.LineComment, target.FootComment
+		parent.Content[index] = value
+		out, err := encodeYAML(documents, data)
+		result.Data = out
+		return result, err
+	}
+	return nil, fmt.Errorf("path must name a value below the root")
+}
+
+// yamlNode encodes a value as a YAML node
+func yamlNode(value any) (*yaml.Node, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to encode value: %v", err)
+	}
+	return node, nil
+}
+
+// encodeYAML re-encodes a YAML stream with the indentation of the original
+func encodeYAML(documents []*yaml.Node, original []byte) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(max(2, len(strings.ReplaceAll(indentUnit(original), "\t", "  "))))
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %v", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// replaceYAMLScalar replaces the text of a single-line scalar with a new
+// scalar value in the same quoting style. It reports false when the node or
+// value can't be edited in place.
+func replaceYAMLScalar(data []byte, node *yaml.Node, value any) ([]byte, bool) {
+	switch value.(type) {
+	case map[string]any, []any:
+		return nil, false
+	}
+	if node.Kind != yaml.ScalarNode || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle|yaml.TaggedStyle) != 0 {
+		return nil, false
+	}
+
+	// Find the node's line, then its column, counted in characters
+	start := 0
+	for line := 1; line < node.Line; line++ {
+		next := bytes.IndexByte(data[start:], '\n')
+		if next < 0 {
+			return nil, false
+		}
+		start += next + 1
+	}
+	lineEnd := len(data)
+	if next := bytes.IndexByte(data[start:], '\n'); next >= 0 {
+		lineEnd = start + next
+	}
+	runes := []rune(string(data[start:lineEnd]))
+	pos := start + len(string(runes[:min(node.Column-1, len(runes))]))
+
+	end := -1
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := pos + 1; i < lineEnd; i++ {
+			if data[i] == '\\' {
+				i++
+			} else if data[i] == '"' {
+				end = i + 1
+				break
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := pos + 1; i < lineEnd; i++ {
+			if data[i] == '\'' {
+				if i+1 < lineEnd && data[i+1] == '\'' {
+					i++
+					continue
+				}
+				end = i + 1
+				break
+			}
+		}
+	default:
+		if strings.Contains(node.Value, "\n") || !bytes.HasPrefix(data[pos:lineEnd], []byte(node.Value)) {
+			return nil, false
+		}
+		end = pos + len(node.Value)
+	}
+	if end < 0 {
+		return nil, false
+	}
+
+	text, ok := yamlScalarText(value, node.Style)
+	if !ok {
+		return nil, false
+	}
+	out := append(append([]byte{}, data[:pos]...), text...)
+	return append(out, data[end:]...), true
+}
+
+// yamlScalarText encodes a scalar for a single line, quoting strings in
+// the style of the value it replaces
+func yamlScalarText(value any, style yaml.Style) (string, bool) {
+	if s, ok := value.(string); ok && !strings.Contains(s, "\n") {
+		switch {
+		case style&yaml.DoubleQuotedStyle != 0:
+			quoted, _ := json.Marshal(s)
+			return string(quoted), true
+		case style&yaml.SingleQuotedStyle != 0:
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'", true
+		}
+	}
+	encoded, err := yaml.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	text := strings.TrimSuffix(string(encoded), "\n")
+	return text, !strings.Contains(text, "\n")
+}
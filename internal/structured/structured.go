@@ -0,0 +1,234 @@
+// Package structured parses, validates and formats JSON, YAML and TOML
+// files, reporting syntax errors at their line and column.
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a structured file format
+type Format string
+
+// Supported formats
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// Detect returns the format of a file from its extension
+func Detect(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, true
+	case ".yaml", ".yml":
+		return YAML, true
+	case ".toml":
+		return TOML, true
+	}
+	return "", false
+}
+
+// ParseFormat parses a format name, such as "yml"
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	case "toml":
+		return TOML, nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected json, yaml or toml)", name)
+}
+
+// SyntaxError is a parse error at a position of a file. Line and Column
+// start at 1; Column is 0 when the parser doesn't report one.
+type SyntaxError struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *SyntaxError) Error() string {
+	switch {
+	case e.Line == 0:
+		return e.Message
+	case e.Column == 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// Validate parses data, returning the first syntax error or nil
+func Validate(data []byte, format Format) *SyntaxError {
+	switch format {
+	case JSON:
+		return validateJSON(data)
+	case YAML:
+		_, err := parseYAML(data)
+		return err
+	case TOML:
+		var value map[string]any
+		_, err := toml.Decode(string(data), &value)
+		return tomlError(err)
+	}
+	return &SyntaxError{Message: fmt.Sprintf("unknown format %q", format)}
+}
+
+// validateJSON parses a single JSON value, refusing trailing data
+func validateJSON(data []byte) *SyntaxError {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		var syntax *json.SyntaxError
+		switch {
+		case errors.As(err, &syntax):
+			return positionError(data, syntax.Offset-1, syntax.Error())
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return positionError(data, int64(len(data)), "unexpected end of JSON input")
+		}
+		return &SyntaxError{Message: err.Error()}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return positionError(data, decoder.InputOffset(), "unexpected data after the top-level value")
+	}
+	return nil
+}
+
+// positionError returns a syntax error at a byte offset of data
+func positionError(data []byte, offset int64, message string) *SyntaxError {
+	offset = max(0, min(offset, int64(len(data))))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len([]rune(string(before[bytes.LastIndexByte(before, '\n')+1:]))) + 1
+	return &SyntaxError{Line: line, Column: column, Message: message}
+}
+
+// yamlLinePattern finds the position in a YAML error message
+var yamlLinePattern = regexp.MustCompile(`line (\d+)(?:, column (\d+))?: (.*)`)
+
+// parseYAML parses every document of a YAML stream
+func parseYAML(data []byte) ([]*yaml.Node, *SyntaxError) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var documents []*yaml.Node
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			return documents, nil
+		}
+		if err != nil {
+			message := strings.TrimPrefix(err.Error(), "yaml: ")
+			match := yamlLinePattern.FindStringSubmatch(message)
+			if match == nil {
+				return nil, &SyntaxError{Message: message}
+			}
+			e := &SyntaxError{Message: match[3]}
+			e.Line, _ = strconv.Atoi(match[1])
+			e.Column, _ = strconv.Atoi(match[2])
+			return nil, e
+		}
+		documents = append(documents, &node)
+	}
+}
+
+// tomlError converts a TOML decoding error
+func tomlError(err error) *SyntaxError {
+	if err == nil {
+		return nil
+	}
+	var parse toml.ParseError
+	if errors.As(err, &parse) {
+		return &SyntaxError{Line: parse.Position.Line, Column: parse.Position.Col, Message: parse.Message}
+	}
+	return &SyntaxError{Message: err.Error()}
+}
+
+// Pretty reformats data with the given indentation, which TOML doesn't
+// use. JSON keeps its key
+// order and YAML its key order and comments. TOML is re-encoded with keys
+// sorted, so files with comments are refused rather than losing them.
+func Pretty(data []byte, format Format, indent int) ([]byte, error) {
+	if indent <= 0 {
+		indent = 2
+	}
+	if err := Validate(data, format); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case JSON:
+		if err := json.Indent(&out, bytes.TrimSpace(data), "", strings.Repeat(" ", indent)); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+	case YAML:
+		documents, _ := parseYAML(data)
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(indent)
+		for _, document := range documents {
+			if err := encoder.Encode(document); err != nil {
+				return nil, fmt.Errorf("failed to encode YAML: %v", err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %v", err)
+		}
+	case TOML:
+		if hasTOMLComments(data) {
+			return nil, fmt.Errorf("TOML files with comments can't be reformatted without losing them")
+		}
+		var value map[string]any
+		if _, err := toml.Decode(string(data), &value); err != nil {
+			return nil, err
+		}
+		encoder := toml.NewEncoder(&out)
+		// Keys under table headers aren't conventionally indented
+		encoder.Indent = ""
+		if err := encoder.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to encode TOML: %v", err)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// hasTOMLComments reports whether a TOML file has a comment outside its
+// strings
+func hasTOMLComments(data []byte) bool {
+	text := string(data)
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '#':
+			return true
+		case strings.HasPrefix(text[i:], `"""`), strings.HasPrefix(text[i:], "'''"):
+			end := strings.Index(text[i+3:], text[i:i+3])
+			if end < 0 {
+				return false
+			}
+			i += end + 5
+		case text[i] == '"':
+			for i++; i < len(text) && text[i] != '"' && text[i] != '\n'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case text[i] == '\'':
+			for i++; i < len(text) && text[i] != '\'' && text[i] != '\n'; i++ {
+			}
+		}
+	}
+	return false
+}
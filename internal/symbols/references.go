@@ -0,0 +1,231 @@
+package symbols
+
+import (
+	"bytes"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isaacphi/mcp-filesystem/internal/depgraph"
+)
+
+// Where an identifier occurrence is
+const (
+	InCode    = "code"
+	InComment = "comment"
+	InString  = "string"
+)
+
+// Reference is an occurrence of an identifier in a file
+type Reference struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// In is InCode, InComment or InString for languages that are
+	// tokenized, and "" for other files, which are matched as plain text
+	In string `json:"in,omitempty"`
+	// Definition is set on the occurrence that names a definition
+	Definition bool `json:"definition,omitempty"`
+}
+
+// span is a byte range of a comment or string literal
+type span struct {
+	start, end int
+	in         string
+}
+
+// identifierPattern matches the identifiers references can be searched for
+var identifierPattern = regexp.MustCompile(`^[\pL_$][\pL\pN_$]*$`)
+
+// Search finds the occurrences of one identifier
+type Search struct {
+	name []byte
+	word *regexp.Regexp
+}
+
+// NewSearch creates a search for whole-word occurrences of an identifier
+func NewSearch(name string) (*Search, error) {
+	if !identifierPattern.MatchString(name) {
+		return nil, fmt.Errorf("not an identifier: %q", name)
+	}
+	return &Search{
+		name: []byte(name),
+		word: regexp.MustCompile(`(?:^|[^\pL\pN_$])(` + regexp.QuoteMeta(name) + `)(?:$|[^\pL\pN_$])`),
+	}, nil
+}
+
+// References returns the occurrences of the identifier in a file. Go, JS/TS
+// and Python are tokenized so occurrences in comments and strings are told
+// apart from code, and the occurrence naming a definition is marked.
+func (s *Search) References(path string, data []byte) []Reference {
+	if !bytes.Contains(data, s.name) {
+		return nil
+	}
+
+	var spans []span
+	language := depgraph.LanguageOf(path)
+	switch language {
+	case depgraph.LangGo:
+		spans = goSpans(data)
+	case depgraph.LangJS:
+		spans = cStyleSpans(data, "`")
+	case depgraph.LangPython:
+		spans = pythonSpans(data)
+	}
+
+	lines := newLineIndex(data)
+
+	var refs []Reference
+	for offset := 0; offset < len(data); {
+		loc := s.word.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			break
+		}
+		start := offset + loc[2]
+		// The trailing boundary may start the next occurrence
+		offset += loc[3]
+
+		ref := Reference{}
+		ref.Line, ref.Column = lines.position(data, start)
+		if language != "" {
+			ref.In = InCode
+			i := sort.Search(len(spans), func(i int) bool { return spans[i].end > start })
+			if i < len(spans) && spans[i].start <= start {
+				ref.In = spans[i].in
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	markDefinitions(Parse(path, data), refs, string(s.name))
+	return refs
+}
+
+// markDefinitions marks the first code occurrence inside each symbol
+// defining name, which is where its declaration names it
+func markDefinitions(defined []Symbol, refs []Reference, name string) {
+	for _, symbol := range defined {
+		if symbol.Name[strings.LastIndexByte(symbol.Name, '.')+1:] != name {
+			continue
+		}
+		for i := range refs {
+			if refs[i].Line >= symbol.StartLine && refs[i].Line <= symbol.EndLine && refs[i].In == InCode {
+				refs[i].Definition = true
+				break
+			}
+		}
+	}
+}
+
+// goSpans returns the comments and string literals of Go source
+func goSpans(data []byte) []span {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(data))
+	var s scanner.Scanner
+	s.Init(file, data, nil, scanner.ScanComments)
+
+	var spans []span
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		in := ""
+		switch tok {
+		case token.COMMENT:
+			in = InComment
+		case token.STRING, token.CHAR:
+			in = InString
+		default:
+			continue
+		}
+		start := file.Offset(pos)
+		spans = append(spans, span{start: start, end: start + len(lit), in: in})
+	}
+	return spans
+}
+
+// cStyleSpans returns the // and /* */ comments and quoted strings of
+// C-like source; extraQuotes lists quotes, such as the JS backtick, whose
+// strings may span lines
+func cStyleSpans(data []byte, extraQuotes string) []span {
+	var spans []span
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				end = len(data) - i
+			}
+			spans = append(spans, span{start: i, end: i + end, in: InComment})
+			i += end
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(data) - i - 4
+			}
+			spans = append(spans, span{start: i, end: i + end + 4, in: InComment})
+			i += end + 3
+		case c == '"' || c == '\'' || strings.IndexByte(extraQuotes, c) >= 0:
+			end := skipString(data, i)
+			spans = append(spans, span{start: i, end: end + 1, in: InString})
+			i = end
+		}
+	}
+	return spans
+}
+
+// pythonSpans returns the # comments and string literals, including
+// triple-quoted ones, of Python source
+func pythonSpans(data []byte) []span {
+	var spans []span
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '#':
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 {
+				end = len(data) - i
+			}
+			spans = append(spans, span{start: i, end: i + end, in: InComment})
+			i += end
+		case '"', '\'':
+			quote := []byte{c, c, c}
+			if bytes.HasPrefix(data[i:], quote) {
+				end := bytes.Index(data[i+3:], quote)
+				if end < 0 {
+					end = len(data) - i - 6
+				}
+				spans = append(spans, span{start: i, end: i + end + 6, in: InString})
+				i += end + 5
+				continue
+			}
+			end := skipString(data, i)
+			spans = append(spans, span{start: i, end: end + 1, in: InString})
+			i = end
+		}
+	}
+	return spans
+}
+
+// lineIndex maps byte offsets to line numbers
+type lineIndex []int
+
+// newLineIndex records where each line of data starts
+func newLineIndex(data []byte) lineIndex {
+	starts := lineIndex{0}
+	for i, c := range data {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// position returns the 1-based line and character column of an offset
+func (l lineIndex) position(data []byte, offset int) (int, int) {
+	line := sort.Search(len(l), func(i int) bool { return l[i] > offset })
+	return line, utf8.RuneCount(data[l[line-1]:offset]) + 1
+}
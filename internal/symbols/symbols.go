@@ -0,0 +1,395 @@
+package symbols
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/depgraph"
+)
+
+// Symbol is a definition in a source file and the lines it spans
+type Symbol struct {
+	// Name is qualified by its enclosing type or class, as in "Server.Start"
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+}
+
+// Symbol kinds
+const (
+	KindFunction  = "function"
+	KindMethod    = "method"
+	KindType      = "type"
+	KindClass     = "class"
+	KindInterface = "interface"
+	KindConst     = "const"
+	KindVar       = "var"
+)
+
+var (
+	// Python definitions, with their indentation
+	pyDefPattern = regexp.MustCompile(`^([ \t]*)(?:async[ \t]+)?(def|class)[ \t]+(\w+)`)
+
+	// JS/TS top-level declarations, optionally exported
+	jsDeclPattern = regexp.MustCompile(`^(?:export[ \t]+(?:default[ \t]+)?)?(?:declare[ \t]+)?(?:abstract[ \t]+)?(async[ \t]+function\*?|function\*?|class|interface|enum|type|const|let|var)[ \t]+([\w$]+)`)
+	// JS/TS class members: name(...) { or name = (...) =>
+	jsMethodPattern = regexp.MustCompile(`^[ \t]+(?:(?:public|private|protected|static|async|readonly|override|get|set)[ \t]+)*\*?([\w$]+)[ \t]*(?:<[^>]*>)?[ \t]*(?:\(|=[ \t]*(?:async[ \t]*)?\()`)
+
+	// Words that look like a method call or definition inside a class body
+	jsKeywords = map[string]bool{
+		"if": true, "for": true, "while": true, "switch": true, "catch": true,
+		"return": true, "function": true, "super": true,
+		"new": true, "await": true, "typeof": true, "delete": true,
+	}
+)
+
+// Supported reports whether symbols can be parsed from a file
+func Supported(path string) bool {
+	return depgraph.LanguageOf(path) != ""
+}
+
+// Parse returns the definitions in a file in source order
+func Parse(path string, data []byte) []Symbol {
+	switch depgraph.LanguageOf(path) {
+	case depgraph.LangGo:
+		return parseGo(path, data)
+	case depgraph.LangJS:
+		return parseJS(data)
+	case depgraph.LangPython:
+		return parsePython(data)
+	}
+	return nil
+}
+
+// Lookup returns the symbols matching name, either exactly or by their
+// unqualified name, so "Start" finds "Server.Start"
+func Lookup(symbols []Symbol, name string) []Symbol {
+	var exact, short []Symbol
+	for _, symbol := range symbols {
+		switch {
+		case symbol.Name == name:
+			exact = append(exact, symbol)
+		case symbol.Name[strings.LastIndexByte(symbol.Name, '.')+1:] == name:
+			short = append(short, symbol)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return short
+}
+
+// parseGo collects functions, methods, types, constants and variables with
+// their doc comments
+func parseGo(path string, data []byte) []Symbol {
+	fset := token.NewFileSet()
+	// A file with syntax errors still yields the declarations before them
+	file, _ := parser.ParseFile(fset, path, data, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	span := func(doc *ast.CommentGroup, node ast.Node) (int, int) {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return fset.Position(start).Line, fset.Position(node.End()).Line
+	}
+
+	var symbols []Symbol
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			symbol := Symbol{Name: decl.Name.Name, Kind: KindFunction}
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				symbol.Name = receiverName(decl.Recv.List[0].Type) + "." + symbol.Name
+				symbol.Kind = KindMethod
+			}
+			symbol.StartLine, symbol.EndLine = span(decl.Doc, decl)
+			symbols = append(symbols, symbol)
+		case *ast.GenDecl:
+			// An unparenthesized declaration includes its keyword and doc
+			// comment; a grouped one is just the spec and its own comment
+			grouped := decl.Lparen.IsValid()
+			for _, spec := range decl.Specs {
+				node, doc := ast.Node(decl), decl.Doc
+				if grouped {
+					node = spec
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					kind := KindType
+					if _, ok := spec.Type.(*ast.InterfaceType); ok {
+						kind = KindInterface
+					}
+					if grouped {
+						doc = spec.Doc
+					}
+					start, end := span(doc, node)
+					symbols = append(symbols, Symbol{Name: spec.Name.Name, Kind: kind, StartLine: start, EndLine: end})
+				case *ast.ValueSpec:
+					kind := KindVar
+					if decl.Tok == token.CONST {
+						kind = KindConst
+					}
+					if grouped {
+						doc = spec.Doc
+					}
+					start, end := span(doc, node)
+					for _, name := range spec.Names {
+						if name.Name == "_" {
+							continue
+						}
+						symbols = append(symbols, Symbol{Name: name.Name, Kind: kind, StartLine: start, EndLine: end})
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// parsePython collects functions and classes; a definition runs until the
+// next non-blank line indented no deeper than it. Decorators are included.
+func parsePython(data []byte) []Symbol {
+	lines := strings.Split(string(data), "\n")
+
+	type scope struct {
+		symbol Symbol
+		indent int
+	}
+	var symbols []Symbol
+	var scopes []scope
+	for i, line := range lines {
+		match := pyDefPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		indent := indentWidth(match[1])
+		for len(scopes) > 0 && scopes[len(scopes)-1].indent >= indent {
+			scopes = scopes[:len(scopes)-1]
+		}
+
+		symbol := Symbol{Name: match[3], Kind: KindFunction, StartLine: i + 1}
+		if match[2] == "class" {
+			symbol.Kind = KindClass
+		}
+		if len(scopes) > 0 {
+			parent := scopes[len(scopes)-1].symbol
+			symbol.Name = parent.Name + "." + symbol.Name
+			if symbol.Kind == KindFunction && parent.Kind == KindClass {
+				symbol.Kind = KindMethod
+			}
+		}
+		for symbol.StartLine > 1 && strings.HasPrefix(strings.TrimSpace(lines[symbol.StartLine-2]), "@") {
+			symbol.StartLine--
+		}
+
+		symbol.EndLine = i + 1
+		for j := i + 1; j < len(lines); j++ {
+			body := strings.TrimLeft(lines[j], " \t")
+			if strings.TrimSpace(body) == "" {
+				continue
+			}
+			if indentWidth(lines[j][:len(lines[j])-len(body)]) <= indent {
+				break
+			}
+			symbol.EndLine = j + 1
+		}
+
+		symbols = append(symbols, symbol)
+		scopes = append(scopes, scope{symbol: symbol, indent: indent})
+	}
+	return symbols
+}
+
+// indentWidth measures leading whitespace, counting a tab as eight columns
+func indentWidth(s string) int {
+	width := 0
+	for _, c := range s {
+		if c == '\t' {
+			width += 8 - width%8
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// parseJS collects top-level declarations and class members, finding where
+// each ends by matching braces
+func parseJS(data []byte) []Symbol {
+	src := string(data)
+	lineStarts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		lo, hi := 0, len(lineStarts)
+		for lo+1 < hi {
+			mid := (lo + hi) / 2
+			if lineStarts[mid] <= offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return lo + 1
+	}
+
+	var symbols []Symbol
+	for i := 0; i < len(lineStarts); i++ {
+		start := lineStarts[i]
+		end := len(src)
+		if i+1 < len(lineStarts) {
+			end = lineStarts[i+1]
+		}
+		line := src[start:end]
+		match := jsDeclPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		symbol := Symbol{Name: match[2], StartLine: i + 1}
+		switch keyword := match[1]; {
+		case strings.Contains(keyword, "function"):
+			symbol.Kind = KindFunction
+		case keyword == "class":
+			symbol.Kind = KindClass
+		case keyword == "interface":
+			symbol.Kind = KindInterface
+		case keyword == "enum" || keyword == "type":
+			symbol.Kind = KindType
+		case keyword == "const":
+			symbol.Kind = KindConst
+		default:
+			symbol.Kind = KindVar
+		}
+		symbol.EndLine = lineOf(statementEnd(src, start))
+		symbols = append(symbols, symbol)
+
+		if symbol.Kind == KindClass {
+			symbols = append(symbols, jsMembers(src, lineStarts, i+1, symbol, lineOf)...)
+			i = symbol.EndLine - 1
+		}
+	}
+	return symbols
+}
+
+// jsMembers collects the methods and arrow-function fields of a class
+func jsMembers(src string, lineStarts []int, first int, class Symbol, lineOf func(int) int) []Symbol {
+	var members []Symbol
+	for i := first; i < class.EndLine-1 && i < len(lineStarts); i++ {
+		start := lineStarts[i]
+		end := len(src)
+		if i+1 < len(lineStarts) {
+			end = lineStarts[i+1]
+		}
+		match := jsMethodPattern.FindStringSubmatch(src[start:end])
+		if match == nil || jsKeywords[match[1]] {
+			continue
+		}
+		member := Symbol{
+			Name:      class.Name + "." + match[1],
+			Kind:      KindMethod,
+			StartLine: i + 1,
+			EndLine:   lineOf(statementEnd(src, start)),
+		}
+		members = append(members, member)
+		i = member.EndLine - 1
+	}
+	return members
+}
+
+// statementEnd returns the offset where a declaration starting at offset
+// ends: the brace closing its first block, or the first semicolon or blank
+// line outside brackets when it has no block
+func statementEnd(src string, offset int) int {
+	depth := 0
+	opened := false
+	for i := offset; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"', '\'', '`':
+			i = skipString(src, i)
+		case '/':
+			if i+1 < len(src) && src[i+1] == '/' {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+				i--
+			} else if i+1 < len(src) && src[i+1] == '*' {
+				if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					return len(src) - 1
+				}
+			}
+		case '{', '(', '[':
+			depth++
+			if c == '{' {
+				opened = true
+			}
+		case '}', ')', ']':
+			depth--
+			if depth == 0 && c == '}' && opened && !continuesExpression(src, i+1) {
+				return i
+			}
+		case ';':
+			if depth == 0 {
+				return i
+			}
+		case '\n':
+			if depth == 0 && i+1 < len(src) && src[i+1] == '\n' {
+				return i - 1
+			}
+		}
+	}
+	return len(src) - 1
+}
+
+// continuesExpression reports whether the text after a closing brace
+// continues the statement, as in "} else {" or "}, {" or "})"
+func continuesExpression(src string, offset int) bool {
+	rest := strings.TrimLeft(src[offset:min(len(src), offset+64)], " \t")
+	return rest != "" && strings.ContainsAny(rest[:1], ").,[")
+}
+
+// skipString returns the offset of the quote closing the string that starts at offset
+func skipString[T string | []byte](src T, offset int) int {
+	quote := src[offset]
+	for i := offset + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(src) - 1
+}
@@ -0,0 +1,122 @@
+// Package textio reads workspace files as UTF-8 text
+package textio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffLen is how much of a file is examined to tell its encoding and
+// whether it is binary
+const sniffLen = 8000
+
+// NewReader returns a reader that decodes UTF-16 text marked with a byte
+// order mark to UTF-8. Other content, including UTF-8, is passed through.
+func NewReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	bom, _ := buffered.Peek(2)
+	if len(bom) < 2 {
+		return buffered
+	}
+
+	switch {
+	case bom[0] == 0xFE && bom[1] == 0xFF:
+		decoder := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+		return transform.NewReader(buffered, decoder)
+	case bom[0] == 0xFF && bom[1] == 0xFE:
+		decoder := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+		return transform.NewReader(buffered, decoder)
+	}
+	return buffered
+}
+
+// Decode converts file content to UTF-8 for searching: UTF-16 with or
+// without a byte order mark, and Latin-1 (as Windows-1252, its superset)
+// when the content isn't valid UTF-8. It returns nil for binary content,
+// which still contains NUL bytes once decoded.
+func Decode(data []byte) []byte {
+	var decoder transform.Transformer
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+	default:
+		if order, ok := sniffUTF16(data); ok {
+			decoder = unicode.UTF16(order, unicode.IgnoreBOM).NewDecoder()
+		}
+	}
+	if decoder != nil {
+		if decoded, _, err := transform.Bytes(decoder, data); err == nil {
+			data = decoded
+		}
+	}
+
+	if bytes.IndexByte(data[:min(len(data), sniffLen)], 0) >= 0 {
+		return nil
+	}
+	if !utf8.Valid(data) {
+		if decoded, err := charmap.Windows1252.NewDecoder().Bytes(data); err == nil {
+			return decoded
+		}
+	}
+	return bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+}
+
+// sniffUTF16 recognizes UTF-16 without a byte order mark by the NUL bytes
+// that mostly ASCII text has in every other position: the high byte of each
+// character, first in big endian and second in little endian
+func sniffUTF16(data []byte) (unicode.Endianness, bool) {
+	sample := data[:min(len(data), sniffLen)&^1]
+	if len(sample) < 4 {
+		return unicode.LittleEndian, false
+	}
+	var even, odd int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case odd*10 >= pairs*4 && even*20 < pairs:
+		return unicode.LittleEndian, true
+	case even*10 >= pairs*4 && odd*20 < pairs:
+		return unicode.BigEndian, true
+	}
+	return unicode.LittleEndian, false
+}
+
+// NumberLines prefixes each line of text with its line number, starting at
+// first, in the format of "cat -n": the number right-aligned in six columns
+// followed by a tab. A final line without a newline is numbered too.
+func NumberLines(text string, first int) string {
+	if text == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + (strings.Count(text, "\n")+1)*7)
+	line := first
+	for text != "" {
+		end := strings.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		fmt.Fprintf(&b, "%6d\t%s", line, text[:end])
+		text = text[end:]
+		line++
+	}
+	return b.String()
+}
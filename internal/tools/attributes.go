@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// File flags reported in stat output
+const (
+	flagImmutable  = "immutable"
+	flagAppendOnly = "append-only"
+)
+
+// fileAttributes holds platform-specific metadata that can affect writes
+type fileAttributes struct {
+	Xattrs     []string `json:"xattrs,omitempty"`
+	Flags      []string `json:"flags,omitempty"`
+	Quarantine string   `json:"quarantine,omitempty"`
+	// Lock is a lock another process holds on the file
+	Lock *fileLock `json:"lock,omitempty"`
+}
+
+// writeWarnings explains attributes and permissions that will make writes to a path fail
+func writeWarnings(info os.FileInfo, attrs fileAttributes) []string {
+	var warnings []string
+
+	for _, flag := range attrs.Flags {
+		switch flag {
+		case flagImmutable:
+			warnings = append(warnings, fmt.Sprintf("file is immutable; writes, renames and deletes will fail until the flag is cleared (%s)", clearFlagsHint))
+		case flagAppendOnly:
+			warnings = append(warnings, fmt.Sprintf("file is append-only; only append_to_file will succeed until the flag is cleared (%s)", clearFlagsHint))
+		}
+	}
+
+	if info.Mode().IsRegular() && info.Mode().Perm()&0200 == 0 {
+		warnings = append(warnings, "file is not writable by its owner; use set_permissions with u+w before editing")
+	}
+
+	if attrs.Lock != nil {
+		warnings = append(warnings, fmt.Sprintf("file is locked (%s); writes are refused until the lock is released", attrs.Lock))
+	}
+
+	if attrs.Quarantine != "" {
+		warnings = append(warnings, "file carries the macOS quarantine attribute; executing it may be blocked by Gatekeeper")
+	}
+
+	return warnings
+}
+
+// explainWriteError adds the reasons a path can't be written to a write error,
+// so the caller can fix the cause instead of retrying. Permission errors are
+// coded READ_ONLY.
+func explainWriteError(path string, err error) error {
+	if !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	explained := err
+	if info, statErr := os.Lstat(path); statErr == nil {
+		if warnings := writeWarnings(info, readAttributes(path, info)); len(warnings) > 0 {
+			explained = fmt.Errorf("%v (%s)", err, strings.Join(warnings, "; "))
+		}
+	}
+	return &errcode.Error{Code: errcode.ReadOnly, Err: explained}
+}
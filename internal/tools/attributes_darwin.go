@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// quarantineXattr marks files downloaded from the internet
+const quarantineXattr = "com.apple.quarantine"
+
+// readAttributes collects extended attributes and BSD file flags for a path
+func readAttributes(path string, info os.FileInfo) fileAttributes {
+	attrs := fileAttributes{Xattrs: listXattrs(path)}
+
+	for _, name := range attrs.Xattrs {
+		if name == quarantineXattr {
+			buf := make([]byte, 256)
+			if size, err := unix.Lgetxattr(path, quarantineXattr, buf); err == nil {
+				attrs.Quarantine = string(buf[:size])
+			}
+		}
+	}
+
+	var stat unix.Stat_t
+	if err := unix.Lstat(path, &stat); err != nil {
+		return attrs
+	}
+	if stat.Flags&(unix.UF_IMMUTABLE|unix.SF_IMMUTABLE) != 0 {
+		attrs.Flags = append(attrs.Flags, flagImmutable)
+	}
+	if stat.Flags&(unix.UF_APPEND|unix.SF_APPEND) != 0 {
+		attrs.Flags = append(attrs.Flags, flagAppendOnly)
+	}
+
+	return attrs
+}
+
+// listXattrs returns the extended attribute names set on a path
+func listXattrs(path string) []string {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil || size <= 0 {
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// clearFlagsHint describes how to remove blocking flags on this platform
+const clearFlagsHint = "chflags nouchg,nouappnd"
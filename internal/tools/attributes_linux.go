@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Inode flags from linux/fs.h
+const (
+	fsImmutableFlag = 0x00000010
+	fsAppendFlag    = 0x00000020
+)
+
+// readAttributes collects extended attributes and inode flags for a path
+func readAttributes(path string, info os.FileInfo) fileAttributes {
+	attrs := fileAttributes{Xattrs: listXattrs(path)}
+
+	// Inode flags are only meaningful for regular files and directories, and
+	// opening anything else (such as a FIFO) could block
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return attrs
+	}
+
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return attrs
+	}
+	defer unix.Close(fd)
+
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return attrs
+	}
+	if flags&fsImmutableFlag != 0 {
+		attrs.Flags = append(attrs.Flags, flagImmutable)
+	}
+	if flags&fsAppendFlag != 0 {
+		attrs.Flags = append(attrs.Flags, flagAppendOnly)
+	}
+
+	return attrs
+}
+
+// listXattrs returns the extended attribute names set on a path
+func listXattrs(path string) []string {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil || size <= 0 {
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// clearFlagsHint describes how to remove blocking flags on this platform
+const clearFlagsHint = "chattr -i -a"
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package tools
+
+import "os"
+
+// readAttributes is not supported on this platform
+func readAttributes(path string, info os.FileInfo) fileAttributes {
+	return fileAttributes{}
+}
+
+// clearFlagsHint describes how to remove blocking flags on this platform
+const clearFlagsHint = "the platform's file attribute tools"
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+)
+
+// Audit events
+const (
+	// AuditQuotaExceeded is a write refused by a quota; its details are the
+	// quota, its limit and what the write asked for
+	AuditQuotaExceeded = "quota-exceeded"
+)
+
+// AuditRecord is a decision a tool made that people may need to review
+// later, such as a refused write
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Session string    `json:"session"`
+	Path    string    `json:"path,omitempty"`
+	Details any       `json:"details,omitempty"`
+}
+
+// Auditor keeps audit records
+type Auditor interface {
+	Audit(record AuditRecord)
+}
+
+// SetAuditor sets what tools send their audit records to
+func (tm *ToolManager) SetAuditor(auditor Auditor) {
+	tm.auditor = auditor
+}
+
+// audit sends a record for the session of ctx to the auditor, if there is one
+func (tm *ToolManager) audit(ctx context.Context, record AuditRecord) {
+	if tm.auditor == nil {
+		return
+	}
+	record.Time = time.Now()
+	record.Session, _ = session.From(ctx)
+	if record.Session == "" {
+		record.Session = "stdio"
+	}
+	tm.auditor.Audit(record)
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diff"
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// maxChangeDiffBytes caps the size of files whose changes are diffed in
+// the session changelog
+const maxChangeDiffBytes = 1 << 20
+
+// maxSessionChanges caps the changelog kept for each session; the oldest
+// entries are dropped first
+const maxSessionChanges = 500
+
+// Kinds of file changes in the session changelog
+const (
+	changeCreated  = "created"
+	changeModified = "modified"
+	changeDeleted  = "deleted"
+)
+
+// fileChange is an entry of a session's changelog
+type fileChange struct {
+	Tool   string    `json:"tool"`
+	Path   string    `json:"path"`
+	Change string    `json:"change"`
+	Time   time.Time `json:"time"`
+	Diff   string    `json:"diff,omitempty"`
+	// DiffOmitted is set when the file is binary, too large or withheld
+	// by a sensitive file policy
+	DiffOmitted bool `json:"diffOmitted,omitempty"`
+	// Simulated is set for changes made with --simulate, which were only
+	// made in memory
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// fileBefore is the state of a file before a tool changed it
+type fileBefore struct {
+	path    string
+	existed bool
+	content string
+	text    bool // content was read and can be diffed
+}
+
+// beforeChange reads a file a tool is about to change, so the change can
+// be diffed once it is made
+func (tm *ToolManager) beforeChange(path string) fileBefore {
+	before := fileBefore{path: path}
+	before.existed, before.content, before.text = tm.changeContent(path)
+	return before
+}
+
+// changeContent reports whether a file exists and returns its text, or
+// false for text when it can't be diffed
+func (tm *ToolManager) changeContent(path string) (exists bool, content string, text bool) {
+	info, err := tm.files.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, "", true
+	}
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxChangeDiffBytes {
+		return err == nil, "", false
+	}
+
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return true, "", false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(textio.NewReader(file), maxChangeDiffBytes+1))
+	if err != nil || len(data) > maxChangeDiffBytes || bytes.IndexByte(data, 0) >= 0 {
+		return true, "", false
+	}
+	if tm.masked(path) {
+		return true, dotenv.Mask(string(data)), true
+	}
+	return true, string(data), true
+}
+
+// applyChange reports a file a tool changed to the change applier, if one
+// is set
+func (tm *ToolManager) applyChange(path string) {
+	if tm.applier != nil {
+		tm.applier.FileChanged(path)
+	}
+}
+
+// recordChange adds the change a tool made to a file to the changelog of
+// the calling session and applies it to resources and the search index.
+// Calls that left the content as it was, such as touching an existing
+// file, aren't recorded.
+func (tm *ToolManager) recordChange(ctx context.Context, tool string, before fileBefore) {
+	tm.applyChange(before.path)
+	exists, content, text := tm.changeContent(before.path)
+	change := fileChange{Tool: tool, Path: tm.relPath(before.path), Time: time.Now(), Simulated: tm.config.Simulate}
+	switch {
+	case !before.existed && !exists:
+		return
+	case !before.existed:
+		change.Change = changeCreated
+	case !exists:
+		change.Change = changeDeleted
+	default:
+		change.Change = changeModified
+	}
+
+	if before.text && text {
+		change.Diff = diff.Unified("a/"+change.Path, "b/"+change.Path, before.content, content)
+		if change.Diff == "" && change.Change == changeModified {
+			return
+		}
+	} else {
+		change.DiffOmitted = true
+	}
+
+	id, _ := session.From(ctx)
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	changes := append(tm.changes[id], change)
+	if len(changes) > maxSessionChanges {
+		changes = changes[len(changes)-maxSessionChanges:]
+	}
+	tm.changes[id] = changes
+}
+
+// SessionChanges returns the changelog of a session as JSON: the files its
+// tool calls created, modified and deleted, in order, with diffs
+func (tm *ToolManager) SessionChanges(id string) (string, error) {
+	tm.mu.Lock()
+	changes := append([]fileChange{}, tm.changes[id]...)
+	tm.mu.Unlock()
+
+	data, err := json.MarshalIndent(struct {
+		Changes []fileChange `json:"changes"`
+	}{changes}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session changes: %v", err)
+	}
+	return string(data), nil
+}
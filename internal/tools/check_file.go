@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// checkerTimeout bounds how long a checker may run
+const checkerTimeout = 60 * time.Second
+
+// CheckFileArgs are the arguments for the check_file tool
+type CheckFileArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to check"`
+}
+
+// checkFileResult is the response of the check_file tool
+type checkFileResult struct {
+	Path   string        `json:"path"`
+	Checks []checkResult `json:"checks"`
+	// Clean is set when every checker exited successfully without
+	// diagnostics
+	Clean bool `json:"clean"`
+}
+
+// checkResult is what one checker reported
+type checkResult struct {
+	Command     string       `json:"command"`
+	ExitCode    int          `json:"exitCode"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+	// Output holds the lines that aren't diagnostics in the
+	// file:line[:column] form, such as summaries
+	Output   string `json:"output,omitempty"`
+	TimedOut bool   `json:"timedOut,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// diagnostic is a problem a checker reported at a position of the file
+type diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+}
+
+var (
+	// diagnosticPattern matches the file:line[:column] lines most linters
+	// and spell checkers print, such as "doc.md:3:81 MD013/line-length"
+	diagnosticPattern = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?(?::\s*|\s+)(.*)$`)
+	// severityPattern matches a severity at the start of a message
+	severityPattern = regexp.MustCompile(`(?i)^\[?(error|warning|warn|info|note|suggestion)\]?:?\s*`)
+)
+
+// handleCheckFile runs the checkers configured for a file's extension and
+// returns their diagnostics
+func (tm *ToolManager) handleCheckFile(args CheckFileArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveDiskPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	path, err = tm.resolveExistingPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if tm.matcher.Excluded(path) {
+		return nil, fmt.Errorf("path is ignored: %s", args.Path)
+	}
+	if err := tm.checkDiskAccess("open", path, tm.matcher.CanRead); err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	commands, ok := tm.config.Checkers[ext]
+	if !ok {
+		return nil, fmt.Errorf("no checkers are configured for %s files", ext)
+	}
+
+	result := checkFileResult{Path: tm.relPath(path), Checks: []checkResult{}, Clean: true}
+	for _, command := range commands {
+		check := tm.runChecker(command, path)
+		if check.ExitCode != 0 || check.Error != "" || len(check.Diagnostics) > 0 {
+			result.Clean = false
+		}
+		result.Checks = append(result.Checks, check)
+	}
+	return jsonResponse(result)
+}
+
+// checkerExtensions returns the extensions checkers are configured for, in
+// order
+func (tm *ToolManager) checkerExtensions() []string {
+	exts := make([]string, 0, len(tm.config.Checkers))
+	for ext := range tm.config.Checkers {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// runChecker runs a checker on a file and parses its output
+func (tm *ToolManager) runChecker(command, path string) checkResult {
+	check := checkResult{Command: command, Diagnostics: []diagnostic{}}
+	argv, err := fileCommand(command, path)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkerTimeout)
+	defer cancel()
+
+	var output tailBuffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = tm.workspacePath
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = 2 * time.Second
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		check.ExitCode = exitErr.ExitCode()
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		check.ExitCode = -1
+		check.TimedOut = true
+	default:
+		check.Error = fmt.Sprintf("failed to run %s: %v", argv[0], err)
+		return check
+	}
+	if tm.debug {
+		log.Printf("check_file: %s (exit %d)", strings.Join(argv, " "), check.ExitCode)
+	}
+
+	var other []string
+	scanner := bufio.NewScanner(strings.NewReader(output.String()))
+	scanner.Buffer(make([]byte, 0, 64*1024), commandOutputLimit)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if d, ok := parseDiagnostic(line); ok {
+			check.Diagnostics = append(check.Diagnostics, d)
+		} else if strings.TrimSpace(line) != "" {
+			other = append(other, line)
+		}
+	}
+	check.Output = strings.Join(other, "\n")
+	return check
+}
+
+// parseDiagnostic parses a file:line[:column] message line
+func parseDiagnostic(line string) (diagnostic, bool) {
+	match := diagnosticPattern.FindStringSubmatch(line)
+	if match == nil {
+		return diagnostic{}, false
+	}
+	// Prose such as "Checked 3 files: 2 problems" isn't a file name
+	if strings.ContainsAny(match[1], " \t") && !strings.ContainsRune(match[1], filepath.Separator) {
+		return diagnostic{}, false
+	}
+	d := diagnostic{Message: strings.TrimSpace(match[4])}
+	d.Line, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		d.Column, _ = strconv.Atoi(match[3])
+	}
+	if severity := severityPattern.FindStringSubmatch(d.Message); severity != nil {
+		d.Severity = strings.ToLower(severity[1])
+		if d.Severity == "warn" {
+			d.Severity = "warning"
+		}
+		d.Message = d.Message[len(severity[0]):]
+	}
+	return d, true
+}
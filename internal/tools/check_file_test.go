@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFileRefusesIgnoredFiles(t *testing.T) {
+	tm, _ := newTestTools(t, map[string]string{
+		".gitignore":           "secret.md\n",
+		".mcp-filesystem.yaml": "checkers:\n  .md: [\"true\"]\n",
+		"secret.md":            "# Secret\n",
+		"notes.md":             "# Notes\n",
+	})
+
+	_, err := tm.handleCheckFile(CheckFileArgs{Path: "secret.md"})
+	if err == nil || !strings.Contains(err.Error(), "path is ignored") {
+		t.Fatalf("check_file on an ignored file: %v, want it refused", err)
+	}
+	if _, err := tm.handleCheckFile(CheckFileArgs{Path: "notes.md"}); err != nil {
+		t.Fatalf("check_file on a file in the workspace: %v", err)
+	}
+}
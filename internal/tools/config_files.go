@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diff"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/structured"
+)
+
+// ValidateConfigArgs are the arguments for the validate_config tool
+type ValidateConfigArgs struct {
+	Path    string `json:"path" jsonschema:"required,description=Workspace-relative path of the JSON or YAML or TOML file"`
+	Content string `json:"content,omitempty" jsonschema:"description=Text to validate instead of the file's content; the path still gives the format"`
+	Format  string `json:"format,omitempty" jsonschema:"description=json or yaml or toml (default: from the file extension)"`
+}
+
+// FormatConfigArgs are the arguments for the format_config tool
+type FormatConfigArgs struct {
+	Path   string `json:"path" jsonschema:"required,description=Workspace-relative path of the JSON or YAML or TOML file"`
+	Format string `json:"format,omitempty" jsonschema:"description=json or yaml or toml (default: from the file extension)"`
+	Indent int    `json:"indent,omitempty" jsonschema:"description=Spaces per indentation level of JSON and YAML (default: 2)"`
+	Write  bool   `json:"write,omitempty" jsonschema:"description=Write the formatted content back to the file instead of only returning it"`
+}
+
+// EditStructuredArgs are the arguments for the edit_structured tool
+type EditStructuredArgs struct {
+	Path     string `json:"path" jsonschema:"required,description=Workspace-relative path of the JSON or YAML or TOML file"`
+	Pointer  string `json:"pointer" jsonschema:"required,description=JSON Pointer such as /spec/replicas or dotted path such as .spec.containers[0].image of the value; - as the last array index appends"`
+	Value    string `json:"value,omitempty" jsonschema:"description=New value as JSON text such as 3 or \"nginx\" or {\"a\": 1}"`
+	Delete   bool   `json:"delete,omitempty" jsonschema:"description=Remove the value instead of setting it"`
+	Document int    `json:"document,omitempty" jsonschema:"description=Index of the document to edit in a multi-document YAML file (default: 0)"`
+	Format   string `json:"format,omitempty" jsonschema:"description=json or yaml or toml (default: from the file extension)"`
+}
+
+// validateConfigResult is the response of the validate_config tool
+type validateConfigResult struct {
+	Path   string                  `json:"path"`
+	Format structured.Format       `json:"format"`
+	Valid  bool                    `json:"valid"`
+	Error  *structured.SyntaxError `json:"error,omitempty"`
+}
+
+// formatConfigResult is the response of the format_config tool
+type formatConfigResult struct {
+	Path    string            `json:"path"`
+	Format  structured.Format `json:"format"`
+	Changed bool              `json:"changed"`
+	Diff    string            `json:"diff,omitempty"`
+	// Content is the formatted text, returned when it isn't written
+	Content string `json:"content,omitempty"`
+	Written bool   `json:"written,omitempty"`
+	// Simulated is set with --simulate, when the file was only written in
+	// memory
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// editStructuredResult is the response of the edit_structured tool
+type editStructuredResult struct {
+	Path   string            `json:"path"`
+	Format structured.Format `json:"format"`
+	// Old is the value that was replaced or deleted
+	Old     any  `json:"old,omitempty"`
+	Added   bool `json:"added,omitempty"`
+	Deleted bool `json:"deleted,omitempty"`
+	// Preserved is set when only the value's text changed; otherwise the
+	// file was re-encoded
+	Preserved bool   `json:"preserved"`
+	Diff      string `json:"diff,omitempty"`
+	Simulated bool   `json:"simulated,omitempty"`
+}
+
+// handleValidateConfig parses a JSON, YAML or TOML file and reports the
+// first syntax error with its line and column
+func (tm *ToolManager) handleValidateConfig(args ValidateConfigArgs) (*mcp_golang.ToolResponse, error) {
+	path, format, err := tm.resolveStructured(args.Path, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(args.Content)
+	if args.Content == "" {
+		if data, err = tm.readStructured(path); err != nil {
+			return nil, err
+		}
+	}
+
+	result := validateConfigResult{Path: tm.relPath(path), Format: format}
+	result.Error = structured.Validate(data, format)
+	result.Valid = result.Error == nil
+	return jsonResponse(result)
+}
+
+// handleFormatConfig pretty-prints a JSON, YAML or TOML file, returning the
+// diff and optionally writing it back
+func (tm *ToolManager) handleFormatConfig(ctx context.Context, args FormatConfigArgs) (*mcp_golang.ToolResponse, error) {
+	path, format, err := tm.resolveStructured(args.Path, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	if args.Write {
+		unlock, err := tm.lockForChange(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	data, err := tm.readStructured(path)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := structured.Pretty(data, format, args.Indent)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := tm.relPath(path)
+	result := formatConfigResult{Path: rel, Format: format, Changed: string(formatted) != string(data)}
+	if result.Changed {
+		result.Diff = diff.Unified("a/"+rel, "b/"+rel, string(data), string(formatted))
+	}
+	if !args.Write {
+		result.Content = string(formatted)
+		return jsonResponse(result)
+	}
+	if result.Changed {
+		if err := tm.rewriteFile(ctx, "format_config", path, formatted); err != nil {
+			return nil, err
+		}
+		result.Written = true
+		result.Simulated = tm.config.Simulate
+	}
+	return jsonResponse(result)
+}
+
+// handleEditStructured sets or deletes the value at a path of a JSON, YAML
+// or TOML file, editing the value's text in place where it can
+func (tm *ToolManager) handleEditStructured(ctx context.Context, args EditStructuredArgs) (*mcp_golang.ToolResponse, error) {
+	path, format, err := tm.resolveStructured(args.Path, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	edit := structured.Edit{Delete: args.Delete, Document: args.Document}
+	if edit.Path, err = structured.ParsePath(args.Pointer); err != nil {
+		return nil, err
+	}
+	switch {
+	case args.Delete && args.Value != "":
+		return nil, fmt.Errorf("pass either value or delete, not both")
+	case !args.Delete && args.Value == "":
+		return nil, fmt.Errorf("value is required unless delete is set")
+	case !args.Delete:
+		if edit.Value, err = structured.ParseValue(args.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := tm.readStructured(path)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := structured.Apply(data, format, edit)
+	if err != nil {
+		return nil, errcode.Wrapf(err, "failed to edit %s: %v", args.Pointer, err)
+	}
+
+	rel := tm.relPath(path)
+	result := editStructuredResult{
+		Path:      rel,
+		Format:    format,
+		Old:       edited.Old,
+		Added:     edited.Added,
+		Deleted:   args.Delete,
+		Preserved: edited.Preserved,
+	}
+	if string(edited.Data) == string(data) {
+		return jsonResponse(result)
+	}
+	result.Diff = diff.Unified("a/"+rel, "b/"+rel, string(data), string(edited.Data))
+	if err := tm.rewriteFile(ctx, "edit_structured", path, edited.Data); err != nil {
+		return nil, err
+	}
+	result.Simulated = tm.config.Simulate
+	return jsonResponse(result)
+}
+
+// resolveStructured resolves the path of a structured file and its format,
+// given or from its extension
+func (tm *ToolManager) resolveStructured(arg, formatName string) (string, structured.Format, error) {
+	path, err := tm.resolveExistingPath(arg)
+	if err != nil {
+		return "", "", err
+	}
+	if tm.matcher.Excluded(path) {
+		return "", "", fmt.Errorf("path is ignored: %s", arg)
+	}
+	if formatName != "" {
+		format, err := structured.ParseFormat(formatName)
+		return path, format, err
+	}
+	format, ok := structured.Detect(path)
+	if !ok {
+		return "", "", fmt.Errorf("can't tell the format of %s; pass format as json, yaml or toml", arg)
+	}
+	return path, format, nil
+}
+
+// readStructured reads a structured file as text
+func (tm *ToolManager) readStructured(path string) ([]byte, error) {
+	info, err := tm.files.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("path is a directory: %s", tm.relPath(path))
+	}
+	data, err := tm.readSource(path)
+	if err != nil {
+		return nil, errcode.Wrapf(err, "failed to read file: %v", err)
+	}
+	if data == nil {
+		return nil, errcode.New(errcode.Binary, "file is binary: %s", tm.relPath(path))
+	}
+	if tm.masked(path) {
+		return nil, fmt.Errorf("the values of .env files are masked: %s", tm.relPath(path))
+	}
+	return data, nil
+}
+
+// rewriteFile replaces the content of an existing file for a tool, keeping
+// its permissions, with the quota, snapshot and changelog bookkeeping of a
+// write. The caller holds the file's lock from lockForChange.
+func (tm *ToolManager) rewriteFile(ctx context.Context, tool, path string, content []byte) error {
+	if err := tm.checkUnlocked(path); err != nil {
+		return err
+	}
+	if err := tm.checkNotConfig(path); err != nil {
+		return err
+	}
+	info, err := tm.files.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %v", err)
+	}
+
+	size := int64(len(content))
+	release, err := tm.reserveQuota(ctx, quotaWrite{path: path, bytes: size, size: size})
+	if err != nil {
+		return err
+	}
+	op, err := tm.snapshot(tool, path)
+	if err != nil {
+		release()
+		return err
+	}
+
+	before := tm.beforeChange(path)
+	if err := tm.files.WriteFile(path, content, info.Mode().Perm()); err != nil {
+		release()
+		err = explainWriteError(path, err)
+		return errcode.Wrapf(err, "failed to write file: %v", err)
+	}
+	tm.recordWrite(path)
+	tm.recordOperation(op)
+	tm.recordChange(ctx, tool, before)
+	return nil
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStructuredToolsRefuseIgnoredFiles(t *testing.T) {
+	tm, _ := newTestTools(t, map[string]string{
+		".gitignore":       "credentials.yaml\n",
+		"credentials.yaml": "password: hunter2\n",
+		"settings.yaml":    "name: app\n",
+	})
+	ctx := context.Background()
+
+	tools := []struct {
+		name string
+		call func(path string) error
+	}{
+		{"validate_config", func(path string) error {
+			_, err := tm.handleValidateConfig(ValidateConfigArgs{Path: path})
+			return err
+		}},
+		{"format_config", func(path string) error {
+			_, err := tm.handleFormatConfig(ctx, FormatConfigArgs{Path: path})
+			return err
+		}},
+		{"edit_structured", func(path string) error {
+			_, err := tm.handleEditStructured(ctx, EditStructuredArgs{Path: path, Pointer: "/name", Value: `"x"`})
+			return err
+		}},
+	}
+	for _, tool := range tools {
+		t.Run(tool.name, func(t *testing.T) {
+			err := tool.call("credentials.yaml")
+			if err == nil || !strings.Contains(err.Error(), "path is ignored") {
+				t.Fatalf("%s on an ignored file: %v, want it refused as ignored", tool.name, err)
+			}
+			if err := tool.call("settings.yaml"); err != nil {
+				t.Fatalf("%s on a served file: %v", tool.name, err)
+			}
+		})
+	}
+}
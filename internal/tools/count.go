@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// CountArgs are the arguments for the count tool
+type CountArgs struct {
+	Path   string `json:"path,omitempty" jsonschema:"description=Workspace-relative path of a file to count"`
+	Glob   string `json:"glob,omitempty" jsonschema:"description=Glob of workspace files to count instead of a single path (such as src/**/*.go)"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of files"`
+}
+
+// countResult is the response of the count tool
+type countResult struct {
+	Files      []fileCount `json:"files"`
+	Total      counts      `json:"total"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+// fileCount holds the counts of one file
+type fileCount struct {
+	Path   string `json:"path"`
+	Binary bool   `json:"binary,omitempty"`
+	counts
+}
+
+// counts are the statistics reported for files and their total
+type counts struct {
+	Lines         int   `json:"lines"`
+	NonBlankLines int   `json:"nonBlankLines"`
+	Words         int   `json:"words"`
+	Characters    int   `json:"characters"`
+	Bytes         int64 `json:"bytes"`
+}
+
+// add accumulates other into c
+func (c *counts) add(other counts) {
+	c.Lines += other.Lines
+	c.NonBlankLines += other.NonBlankLines
+	c.Words += other.Words
+	c.Characters += other.Characters
+	c.Bytes += other.Bytes
+}
+
+// handleCount counts lines, words, characters and bytes of files without
+// returning their content
+func (tm *ToolManager) handleCount(args CountArgs) (*mcp_golang.ToolResponse, error) {
+	files, err := tm.matchFiles(args.Path, args.Glob)
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := paginate(files, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := countResult{Files: []fileCount{}, NextCursor: next}
+	for _, path := range page {
+		count, err := tm.countFile(path)
+		if err != nil {
+			return nil, err
+		}
+		count.Path = tm.relPath(path)
+		result.Files = append(result.Files, count)
+		result.Total.add(count.counts)
+	}
+
+	return jsonResponse(result)
+}
+
+// countFile streams a file and counts it like wc, except that a final line
+// without a newline is counted. Binary files, which contain NUL bytes, only
+// report their size.
+func (tm *ToolManager) countFile(path string) (fileCount, error) {
+	var count fileCount
+
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return count, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return count, fmt.Errorf("failed to stat file: %v", err)
+	}
+	count.Bytes = info.Size()
+
+	reader := bufio.NewReader(textio.NewReader(file))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if bytes.IndexByte(line, 0) >= 0 {
+				return fileCount{Binary: true, counts: counts{Bytes: info.Size()}}, nil
+			}
+			count.Lines++
+			words := len(bytes.Fields(line))
+			count.Words += words
+			if words > 0 {
+				count.NonBlankLines++
+			}
+			count.Characters += utf8.RuneCount(line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read file: %v", err)
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/depgraph"
+)
+
+// DependencyGraphArgs are the arguments for the dependency_graph tool
+type DependencyGraphArgs struct {
+	Path      string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to inspect"`
+	Direction string `json:"direction,omitempty" jsonschema:"enum=imports,enum=imported_by,enum=both,description=Which edges to follow (default both)"`
+	Depth     *int   `json:"depth,omitempty" jsonschema:"description=How many levels of transitive edges to follow; 0 follows all (default 1)"`
+}
+
+// dependencyEdge is a file reached through the import graph
+type dependencyEdge struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"`
+}
+
+// dependencyGraphResult is the response of the dependency_graph tool
+type dependencyGraphResult struct {
+	Path       string           `json:"path"`
+	Imports    []dependencyEdge `json:"imports,omitempty"`
+	ImportedBy []dependencyEdge `json:"importedBy,omitempty"`
+}
+
+// handleDependencyGraph reports the files connected to a file through imports
+func (tm *ToolManager) handleDependencyGraph(args DependencyGraphArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolvePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if depgraph.LanguageOf(path) == "" {
+		return nil, fmt.Errorf("unsupported file type for dependency analysis: %s", args.Path)
+	}
+
+	direction := args.Direction
+	if direction == "" {
+		direction = "both"
+	}
+	if direction != "imports" && direction != "imported_by" && direction != "both" {
+		return nil, fmt.Errorf("invalid direction: %s", direction)
+	}
+
+	depth := 1
+	if args.Depth != nil {
+		depth = *args.Depth
+	}
+	if depth < 0 {
+		return nil, fmt.Errorf("depth must not be negative")
+	}
+
+	files, err := tm.workspaceFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %v", err)
+	}
+
+	graph := depgraph.Build(tm.workspacePath, files)
+
+	result := dependencyGraphResult{Path: tm.relPath(path)}
+	if direction == "imports" || direction == "both" {
+		result.Imports = tm.dependencyEdges(depgraph.Walk(graph.Imports, path, depth))
+	}
+	if direction == "imported_by" || direction == "both" {
+		result.ImportedBy = tm.dependencyEdges(depgraph.Walk(graph.ImportedBy, path, depth))
+	}
+
+	return jsonResponse(result)
+}
+
+// dependencyEdges converts walk distances into a sorted edge list
+func (tm *ToolManager) dependencyEdges(distances map[string]int) []dependencyEdge {
+	edges := make([]dependencyEdge, 0, len(distances))
+	for path, depth := range distances {
+		edges = append(edges, dependencyEdge{Path: tm.relPath(path), Depth: depth})
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Depth != edges[j].Depth {
+			return edges[i].Depth < edges[j].Depth
+		}
+		return edges[i].Path < edges[j].Path
+	})
+
+	return edges
+}
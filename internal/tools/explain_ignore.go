@@ -0,0 +1,34 @@
+package tools
+
+import (
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// ExplainIgnoreArgs are the arguments for the explain_ignore tool
+type ExplainIgnoreArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of a file that doesn't show up"`
+}
+
+// explainIgnoreResult is the response of the explain_ignore tool
+type explainIgnoreResult struct {
+	gitignore.Explanation
+	Summary string `json:"summary"`
+}
+
+// handleExplainIgnore reports which rule, if any, leaves a path out of the
+// workspace: the dotfile rule, a default ignore, an editor artifact filter,
+// a sensitive file class, a skipped submodule, a .gitignore line or a sparse
+// checkout
+func (tm *ToolManager) handleExplainIgnore(args ExplainIgnoreArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolvePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	explanation := tm.matcher.Explain(path)
+	if info, err := tm.files.Stat(path); err == nil && info.IsDir() {
+		explanation = tm.matcher.ExplainDir(path)
+	}
+	return jsonResponse(explainIgnoreResult{Explanation: explanation, Summary: explanation.String()})
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// maxSnippetBytes caps the source line returned with each reference
+const maxSnippetBytes = 200
+
+// FindReferencesArgs are the arguments for the find_references tool
+type FindReferencesArgs struct {
+	Name     string `json:"name" jsonschema:"required,description=Identifier to find; matched as a whole word"`
+	Glob     string `json:"glob,omitempty" jsonschema:"description=Glob of workspace files to search (default: every file)"`
+	CodeOnly bool   `json:"code_only,omitempty" jsonschema:"description=Leave out occurrences in comments and strings of Go and JS/TS and Python files"`
+	Cursor   string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of references"`
+}
+
+// findReferencesResult is the response of the find_references tool
+type findReferencesResult struct {
+	References []reference `json:"references"`
+	// Files is how many files contain references, across all pages
+	Files      int    `json:"files"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// reference is an occurrence of the identifier with its source line
+type reference struct {
+	Path string `json:"path"`
+	symbols.Reference
+	Snippet string `json:"snippet"`
+}
+
+// handleFindReferences finds the occurrences of an identifier across the
+// workspace; supported languages are tokenized so definitions, comments and
+// strings are told apart
+func (tm *ToolManager) handleFindReferences(args FindReferencesArgs) (*mcp_golang.ToolResponse, error) {
+	search, err := symbols.NewSearch(args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if args.Glob != "" {
+		files, err = tm.matchFiles("", args.Glob)
+	} else {
+		files, err = tm.workspaceFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []reference
+	result := findReferencesResult{}
+	for _, path := range files {
+		data, err := tm.readMaskedSource(path)
+		if err != nil || data == nil {
+			continue
+		}
+		found := false
+		var lines [][]byte
+		for _, ref := range search.References(path, data) {
+			if args.CodeOnly && ref.In != "" && ref.In != symbols.InCode {
+				continue
+			}
+			if lines == nil {
+				lines = bytes.Split(data, []byte("\n"))
+			}
+			refs = append(refs, reference{Path: tm.relPath(path), Reference: ref, Snippet: snippet(lines[ref.Line-1])})
+			found = true
+		}
+		if found {
+			result.Files++
+		}
+	}
+
+	page, next, err := paginate(refs, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	result.References = page
+	result.NextCursor = next
+	if result.References == nil {
+		result.References = []reference{}
+	}
+
+	return jsonResponse(result)
+}
+
+// readSource reads a file as UTF-8 text, decoding UTF-16 and Latin-1, and
+// returns nil for binary files
+func (tm *ToolManager) readSource(path string) ([]byte, error) {
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return textio.Decode(data), nil
+}
+
+// readMaskedSource reads a file like readSource, masking the values of
+// .env files
+func (tm *ToolManager) readMaskedSource(path string) ([]byte, error) {
+	data, err := tm.readSource(path)
+	if data != nil && tm.masked(path) {
+		data = []byte(dotenv.Mask(string(data)))
+	}
+	return data, err
+}
+
+// snippet returns a source line without surrounding whitespace, cut to
+// maxSnippetBytes
+func snippet(line []byte) string {
+	text := strings.TrimSpace(string(line))
+	if len(text) > maxSnippetBytes {
+		text = strings.ToValidUTF8(text[:maxSnippetBytes], "") + "..."
+	}
+	return text
+}
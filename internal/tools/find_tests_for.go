@@ -0,0 +1,286 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/depgraph"
+)
+
+// FindTestsForArgs are the arguments for the find_tests_for tool
+type FindTestsForArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of the source file to find tests for"`
+}
+
+// findTestsResult is the response of the find_tests_for tool
+type findTestsResult struct {
+	Path  string      `json:"path"`
+	Tests []testMatch `json:"tests"`
+	// Suggested is where a test of the file would go by the language's
+	// convention, given when no test is named after it
+	Suggested string `json:"suggested,omitempty"`
+}
+
+// testMatch is a test file and why it is thought to test the source file
+type testMatch struct {
+	Path    string   `json:"path"`
+	Reasons []string `json:"reasons"`
+	score   int
+}
+
+// Reasons a test file is matched to a source file, strongest first
+const (
+	testReasonName       = "name"           // named after the file, next to it
+	testReasonMirror     = "mirrored-name"  // named after the file in a test tree
+	testReasonImports    = "imports"        // imports the file
+	testReasonNameElse   = "name-elsewhere" // named after the file in another directory
+	testReasonPackage    = "same-package"   // a Go test of the file's package
+	testReasonTransitive = "imports-indirectly"
+)
+
+// testReasonScores order matches by their strongest reason
+var testReasonScores = map[string]int{
+	testReasonName:       60,
+	testReasonMirror:     50,
+	testReasonImports:    40,
+	testReasonNameElse:   30,
+	testReasonPackage:    20,
+	testReasonTransitive: 10,
+}
+
+// testDirs are directories that hold tests mirroring the source tree
+var testDirs = map[string]bool{"test": true, "tests": true, "spec": true, "__tests__": true, "testing": true}
+
+// sourceDirs are directories whose tree test directories mirror
+var sourceDirs = map[string]bool{"src": true, "lib": true, "app": true, "main": true, "java": true, "kotlin": true, "scala": true}
+
+// handleFindTestsFor finds the test files likely to cover a source file by
+// naming convention and by what they import
+func (tm *ToolManager) handleFindTestsFor(args FindTestsForArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveExistingPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if tm.matcher.Excluded(path) {
+		return nil, fmt.Errorf("path is ignored: %s", args.Path)
+	}
+
+	files, err := tm.workspaceFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %v", err)
+	}
+
+	matches := make(map[string]*testMatch)
+	add := func(test, reason string) {
+		if test == path {
+			return
+		}
+		m := matches[test]
+		if m == nil {
+			m = &testMatch{Path: tm.relPath(test)}
+			matches[test] = m
+		}
+		for _, existing := range m.Reasons {
+			if existing == reason {
+				return
+			}
+		}
+		m.Reasons = append(m.Reasons, reason)
+		m.score = max(m.score, testReasonScores[reason])
+	}
+
+	subject, _ := testSubject(path)
+	rel := tm.relPath(path)
+	var tests []string
+	for _, file := range files {
+		if !isTestFile(tm.relPath(file)) {
+			continue
+		}
+		tests = append(tests, file)
+		if fileSubject, _ := testSubject(file); fileSubject != subject || !sameTestFamily(path, file) {
+			continue
+		}
+		switch {
+		case filepath.Dir(file) == filepath.Dir(path) || filepath.Base(filepath.Dir(file)) == "__tests__" && filepath.Dir(filepath.Dir(file)) == filepath.Dir(path):
+			add(file, testReasonName)
+		case mirrorsSource(rel, tm.relPath(file)):
+			add(file, testReasonMirror)
+		default:
+			add(file, testReasonNameElse)
+		}
+	}
+
+	// Go tests can use everything in their package, not just what is
+	// declared in the file they are named after
+	if depgraph.LanguageOf(path) == depgraph.LangGo {
+		for _, test := range tests {
+			if filepath.Dir(test) == filepath.Dir(path) && strings.HasSuffix(test, "_test.go") {
+				add(test, testReasonPackage)
+			}
+		}
+	}
+
+	if depgraph.LanguageOf(path) != "" {
+		graph := depgraph.Build(tm.workspacePath, files)
+		importers := depgraph.Walk(graph.ImportedBy, path, 2)
+		for _, test := range tests {
+			switch importers[test] {
+			case 1:
+				add(test, testReasonImports)
+			case 2:
+				add(test, testReasonTransitive)
+			}
+		}
+	}
+
+	result := findTestsResult{Path: rel, Tests: []testMatch{}}
+	named := false
+	for _, m := range matches {
+		result.Tests = append(result.Tests, *m)
+		named = named || m.score >= testReasonScores[testReasonNameElse]
+	}
+	sort.Slice(result.Tests, func(i, j int) bool {
+		if result.Tests[i].score != result.Tests[j].score {
+			return result.Tests[i].score > result.Tests[j].score
+		}
+		return result.Tests[i].Path < result.Tests[j].Path
+	})
+	if !named {
+		result.Suggested = suggestTestPath(rel)
+	}
+
+	return jsonResponse(result)
+}
+
+// isTestFile reports whether a workspace-relative path looks like a test by
+// its name or a directory above it
+func isTestFile(rel string) bool {
+	if _, named := testSubject(rel); named {
+		return true
+	}
+	stem := fileStem(filepath.Base(rel))
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/") {
+		if testDirs[dir] && !strings.HasPrefix(stem, "__") && stem != "conftest" && stem != "setup" {
+			return true
+		}
+	}
+	return false
+}
+
+// testSubject returns the lower-case name a file is about: its name without
+// extensions and the affixes test files are named with, so "parser_test.go",
+// "parser.spec.ts", "test_parser.py" and "ParserTest.java" are all "parser".
+// It reports whether the name had such an affix.
+func testSubject(path string) (string, bool) {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	for _, suffix := range []string{".test", ".spec", "_test", "_spec", "_tests", "-test", "-spec", "Tests", "Test", "Spec"} {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok && trimmed != "" {
+			return strings.ToLower(fileStem(trimmed)), true
+		}
+	}
+	if trimmed, ok := strings.CutPrefix(name, "test_"); ok && trimmed != "" {
+		return strings.ToLower(fileStem(trimmed)), true
+	}
+	// TestParser, but not Testimony
+	if trimmed, ok := strings.CutPrefix(name, "Test"); ok && trimmed != "" && trimmed[0] >= 'A' && trimmed[0] <= 'Z' {
+		return strings.ToLower(fileStem(trimmed)), true
+	}
+	return strings.ToLower(fileStem(name)), false
+}
+
+// fileStem returns a name up to its first extension, so "x.d.ts" has the
+// stem "x"; a leading dot doesn't start an extension
+func fileStem(name string) string {
+	if name == "" {
+		return name
+	}
+	if i := strings.IndexByte(name[1:], '.'); i >= 0 {
+		return name[:i+1]
+	}
+	return name
+}
+
+// sameTestFamily reports whether a test file is in a language that can test
+// the source file, such as a .spec.js test of a .ts file
+func sameTestFamily(source, test string) bool {
+	family := func(path string) string {
+		ext := strings.ToLower(filepath.Ext(path))
+		if lang := depgraph.LanguageOf(path); lang != "" {
+			return lang
+		}
+		switch ext {
+		case ".java", ".kt", ".kts", ".scala", ".groovy":
+			return "jvm"
+		}
+		return ext
+	}
+	return family(source) == family(test)
+}
+
+// mirrorsSource reports whether a test is at the source file's place in a
+// test tree, such as tests/pkg/test_mod.py for src/pkg/mod.py or
+// src/test/java/a/BTest.java for src/main/java/a/B.java
+func mirrorsSource(source, test string) bool {
+	strip := func(rel string, skip map[string]bool) []string {
+		dirs := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+		var kept []string
+		for _, dir := range dirs {
+			if !skip[dir] && dir != "." {
+				kept = append(kept, dir)
+			}
+		}
+		return kept
+	}
+	skip := make(map[string]bool)
+	for dir := range testDirs {
+		skip[dir] = true
+	}
+	for dir := range sourceDirs {
+		skip[dir] = true
+	}
+	sourcePath, testPath := strip(source, skip), strip(test, skip)
+	// The test tree may start below the project the source is in
+	if len(testPath) > len(sourcePath) {
+		return false
+	}
+	return strings.Join(sourcePath[len(sourcePath)-len(testPath):], "/") == strings.Join(testPath, "/")
+}
+
+// suggestTestPath returns where a test of a workspace-relative file would
+// go by its language's convention, or "" for languages without one
+func suggestTestPath(rel string) string {
+	dir, base := filepath.Dir(rel), filepath.Base(rel)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	join := func(name string) string {
+		return filepath.ToSlash(filepath.Join(dir, name))
+	}
+	switch depgraph.LanguageOf(rel) {
+	case depgraph.LangGo:
+		return join(stem + "_test.go")
+	case depgraph.LangJS:
+		// Type declarations have nothing to test
+		if strings.HasSuffix(stem, ".d") {
+			return ""
+		}
+		return join(stem + ".test" + ext)
+	case depgraph.LangPython:
+		return join("test_" + base)
+	}
+	switch ext {
+	case ".java", ".kt", ".scala":
+		slashed := filepath.ToSlash(rel)
+		if strings.Contains(slashed, "src/main/") {
+			return strings.TrimSuffix(strings.Replace(slashed, "src/main/", "src/test/", 1), base) + stem + "Test" + ext
+		}
+		return join(stem + "Test" + ext)
+	case ".rb":
+		return join(stem + "_test.rb")
+	}
+	return ""
+}
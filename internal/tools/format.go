@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diff"
+)
+
+// formatterTimeout bounds how long a formatter may run
+const formatterTimeout = 30 * time.Second
+
+// formatResult describes what the configured formatter did to a file
+type formatResult struct {
+	Formatter string `json:"formatter,omitempty"`
+	Diff      string `json:"diff,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// formatFile runs the formatter configured for the file's extension and
+// returns the diff it produced. It returns nil if no formatter applies or
+// the file isn't on the OS filesystem.
+func (tm *ToolManager) formatFile(path string) *formatResult {
+	command, ok := tm.config.Formatters[strings.ToLower(filepath.Ext(path))]
+	if !ok || !tm.onDisk(path) {
+		return nil
+	}
+
+	result := &formatResult{Formatter: command}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read file: %v", err)
+		return result
+	}
+
+	args, err := fileCommand(command, path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), formatterTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = tm.workspacePath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		result.Error = fmt.Sprintf("%v: %s", err, strings.TrimSpace(string(output)))
+		if tm.debug {
+			log.Printf("Formatter failed for %s: %s", path, result.Error)
+		}
+		return result
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to read formatted file: %v", err)
+		return result
+	}
+
+	rel := tm.relPath(path)
+	result.Diff = diff.Unified("a/"+rel, "b/"+rel, string(before), string(after))
+	return result
+}
+
+// fileCommand splits a configured command for a file into arguments,
+// replacing "{file}" with its path or appending the path when there is none
+func fileCommand(command, path string) ([]string, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	substituted := false
+	for i, arg := range args {
+		if strings.Contains(arg, "{file}") {
+			args[i] = strings.ReplaceAll(arg, "{file}", path)
+			substituted = true
+		}
+	}
+	if !substituted {
+		args = append(args, path)
+	}
+	return args, nil
+}
+
+// splitCommand splits a command line into arguments, honoring single and
+// double quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command: %s", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
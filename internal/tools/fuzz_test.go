@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/resources"
+)
+
+// FuzzResolvePath feeds arbitrary paths and URIs to tool path resolution,
+// the server's security boundary, in a real workspace with symlinks in and
+// out of it. Every path a tool resolves and every path a URI names must be
+// in the workspace, a path resolved for writing must not lead out of it
+// through a symlink, and URIs must name the path they were made from. Run
+// it with
+//
+//	go test -fuzz FuzzResolvePath ./internal/tools
+func FuzzResolvePath(f *testing.F) {
+	root, outside := fuzzWorkspace(f)
+	cfg := config.Default()
+	cfg.Resources.URIScheme = config.URISchemeWorkspace
+	files := fsys.OS(root)
+	matcher, err := gitignore.NewMatcherFS(files, cfg)
+	if err != nil {
+		f.Fatal(err)
+	}
+	uris := resources.NewResourceManager(files, cfg, matcher, false)
+	tm := NewToolManager(files, cfg, matcher, false)
+	tm.SetURIResolver(uris)
+	alias := resources.WorkspaceAlias(cfg, root)
+
+	for _, seed := range []string{
+		"a.txt", "dir/b.txt", "new/c.txt", ".",
+		"..", "../x", "dir/../../x", "dir/./../a.txt", "a.txt/..",
+		root, root + "/a.txt", root + "/../x", outside + "/secret", "/etc/passwd", "/",
+		"file://" + root + "/a.txt", "file://" + outside + "/secret", "file://" + root + "/%2e%2e/x", "file:///etc/passwd",
+		"workspace://" + alias + "/a.txt", "workspace://" + alias + "/../x", "workspace://" + alias + "/dir%2f..%2f..%2fx", "workspace://other/a.txt",
+		"inside", "inside/b.txt", "escape", "escape/secret", "escape/new.txt", "escape-file", "dangling", "dangling/x",
+		"a\x00b", "dir\\..\\..\\x", "\xff",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if path, err := tm.resolvePath(input); err == nil {
+			checkInWorkspace(t, "resolvePath", input, root, path)
+		}
+
+		if path, err := tm.resolveWritePath(input); err == nil {
+			checkInWorkspace(t, "resolveWritePath", input, root, path)
+			checkWritable(t, input, root, path)
+		}
+
+		for _, uri := range []string{input, "file://" + input, "workspace://" + alias + "/" + input} {
+			path, ok := uris.PathFromURI(uri)
+			if !ok {
+				continue
+			}
+			checkInWorkspace(t, "PathFromURI", uri, root, path)
+			if back, ok := uris.PathFromURI(uris.GetFileURI(path)); !ok || back != path {
+				t.Fatalf("URI of %q from %q names %q", path, uri, back)
+			}
+		}
+	})
+}
+
+// fuzzWorkspace creates a workspace and a directory outside it, with
+// symlinks from the workspace into itself, out of it and to nothing
+func fuzzWorkspace(f *testing.F) (root, outside string) {
+	base, err := filepath.EvalSymlinks(f.TempDir())
+	if err != nil {
+		f.Fatal(err)
+	}
+	root, outside = filepath.Join(base, "workspace"), filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "dir"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			f.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		filepath.Join(root, "a.txt"):        "a",
+		filepath.Join(root, "dir", "b.txt"): "b",
+		filepath.Join(outside, "secret"):    "secret",
+	} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			f.Fatal(err)
+		}
+	}
+	for name, target := range map[string]string{
+		"inside":      "dir",
+		"escape":      outside,
+		"escape-file": filepath.Join(outside, "secret"),
+		"dangling":    filepath.Join(root, "missing"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			f.Skipf("can't create symlinks: %v", err)
+		}
+	}
+	return root, outside
+}
+
+// checkInWorkspace fails if a path input resolved to is outside the
+// workspace or can't be passed to the OS
+func checkInWorkspace(t *testing.T, layer, input, root, path string) {
+	t.Helper()
+	if !within(root, path) {
+		t.Fatalf("%s resolved %q outside the workspace: %q", layer, input, path)
+	}
+	if strings.IndexByte(path, 0) >= 0 {
+		t.Fatalf("%s resolved %q to a path with a NUL byte: %q", layer, input, path)
+	}
+}
+
+// checkWritable fails if writing to a path resolved for writing would
+// follow a symlink out of the workspace: through its deepest existing
+// directory, or as a symlink itself
+func checkWritable(t *testing.T, input, root, path string) {
+	t.Helper()
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("resolveWritePath resolved %q to a symlink: %q", input, path)
+	}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if !within(root, real) {
+				t.Fatalf("resolveWritePath resolved %q to %q, below %q", input, path, real)
+			}
+			return
+		}
+		if dir == filepath.Dir(dir) {
+			return
+		}
+	}
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,286 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// gitTimeout bounds how long a git command may run
+const gitTimeout = 30 * time.Second
+
+// GitStatusArgs are the arguments for the git_status tool
+type GitStatusArgs struct {
+	Path   string `json:"path,omitempty" jsonschema:"description=Workspace-relative path; only changes under it in the repository containing it are reported (default: every repository)"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of changes"`
+}
+
+// GitDiffArgs are the arguments for the git_diff tool
+type GitDiffArgs struct {
+	Path   string `json:"path,omitempty" jsonschema:"description=Workspace-relative file or directory to diff (default: every repository)"`
+	Staged bool   `json:"staged,omitempty" jsonschema:"description=Diff the index against HEAD instead of the working tree against the index"`
+}
+
+// gitStatusResult is the response of the git_status tool
+type gitStatusResult struct {
+	Repositories []repoStatus `json:"repositories"`
+	NextCursor   string       `json:"nextCursor,omitempty"`
+}
+
+// repoStatus is the status of one repository in the workspace
+type repoStatus struct {
+	Path     string      `json:"path"`
+	Branch   string      `json:"branch"`
+	Worktree bool        `json:"worktree,omitempty"`
+	Changes  []gitChange `json:"changes"`
+}
+
+// gitChange is a changed path with its two-letter porcelain status
+type gitChange struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	OrigPath string `json:"origPath,omitempty"`
+}
+
+// gitDiffResult is the response of the git_diff tool
+type gitDiffResult struct {
+	Repositories []repoDiff `json:"repositories"`
+}
+
+// repoDiff is the diff of one repository in the workspace
+type repoDiff struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"`
+}
+
+// handleGitStatus reports changed files in each repository of the workspace
+func (tm *ToolManager) handleGitStatus(args GitStatusArgs) (*mcp_golang.ToolResponse, error) {
+	repos, pathspec, err := tm.gitScope(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []repoStatus
+	for _, repo := range repos {
+		status, err := tm.repoStatus(repo, pathspec)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Changes are paginated across repositories; repositories without
+	// changes are reported on the first page
+	type repoChange struct {
+		repo   int
+		change gitChange
+	}
+	var changes []repoChange
+	unchanged := make(map[int]bool)
+	for i, status := range statuses {
+		unchanged[i] = len(status.Changes) == 0
+		for _, change := range status.Changes {
+			changes = append(changes, repoChange{repo: i, change: change})
+		}
+		statuses[i].Changes = []gitChange{}
+	}
+	page, next, err := paginate(changes, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	onPage := make(map[int]bool)
+	for _, c := range page {
+		statuses[c.repo].Changes = append(statuses[c.repo].Changes, c.change)
+		onPage[c.repo] = true
+	}
+
+	result := gitStatusResult{Repositories: []repoStatus{}, NextCursor: next}
+	for i, status := range statuses {
+		if onPage[i] || args.Cursor == "" && unchanged[i] {
+			result.Repositories = append(result.Repositories, status)
+		}
+	}
+
+	return jsonResponse(result)
+}
+
+// handleGitDiff shows unstaged or staged changes in each repository of the workspace
+func (tm *ToolManager) handleGitDiff(args GitDiffArgs) (*mcp_golang.ToolResponse, error) {
+	repos, pathspec, err := tm.gitScope(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	gitArgs := []string{"diff", "--no-color", "--no-ext-diff"}
+	if args.Staged {
+		gitArgs = append(gitArgs, "--cached")
+	}
+	gitArgs = append(gitArgs, "--", pathspec)
+
+	result := gitDiffResult{Repositories: []repoDiff{}}
+	for _, repo := range repos {
+		output, err := runGit(repo.Path, gitArgs...)
+		if err != nil {
+			return nil, err
+		}
+		output = tm.withholdDiffs(repo.GitRoot, output)
+		if output == "" {
+			continue
+		}
+		result.Repositories = append(result.Repositories, repoDiff{Path: tm.relPath(repo.Path), Diff: output})
+	}
+
+	return jsonResponse(result)
+}
+
+// repoStatus runs git status in a repository, leaving out changes that
+// belong to repositories nested in it. Extra arguments are passed to git.
+func (tm *ToolManager) repoStatus(repo gitignore.Repository, pathspec string, extra ...string) (repoStatus, error) {
+	gitArgs := append([]string{"status", "--porcelain=v1", "-z", "--branch"}, extra...)
+	output, err := runGit(repo.Path, append(gitArgs, "--", pathspec)...)
+	if err != nil {
+		return repoStatus{}, err
+	}
+
+	status := repoStatus{
+		Path:     tm.relPath(repo.Path),
+		Worktree: repo.Worktree,
+		Changes:  []gitChange{},
+	}
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if branch, ok := strings.CutPrefix(field, "## "); ok {
+			status.Branch = branch
+			continue
+		}
+		if len(field) < 4 {
+			continue
+		}
+		change := gitChange{Status: field[:2]}
+		path := filepath.Join(repo.GitRoot, filepath.FromSlash(field[3:]))
+		// Renames and copies are followed by the original path
+		if field[0] == 'R' || field[0] == 'C' {
+			if i+1 < len(fields) {
+				i++
+				change.OrigPath = tm.relPath(filepath.Join(repo.GitRoot, filepath.FromSlash(fields[i])))
+			}
+		}
+		// Nested repositories show up as untracked directories; they are
+		// reported on their own
+		if owner, ok := tm.matcher.RepositoryFor(path); !ok || owner.Path != repo.Path {
+			continue
+		}
+		if tm.matcher.CanStat(path) != nil {
+			continue
+		}
+		change.Path = tm.relPath(path)
+		status.Changes = append(status.Changes, change)
+	}
+	return status, nil
+}
+
+// withholdDiffs removes the diffs of files whose content may not be read
+// from git diff output. Hidden files are left out entirely; others keep
+// their header with a note in place of the changes. The values in the
+// diffs of masked .env files are masked.
+func (tm *ToolManager) withholdDiffs(gitRoot, output string) string {
+	var b strings.Builder
+	withheld, masked, inHunk := false, false, false
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if header, ok := strings.CutPrefix(line, "diff --git "); ok {
+			withheld, inHunk = false, false
+			path := filepath.Join(gitRoot, filepath.FromSlash(diffHeaderPath(header)))
+			masked = tm.masked(path)
+			if err := tm.matcher.CanRead(path); err != nil {
+				withheld = true
+				if tm.matcher.CanStat(path) == nil {
+					fmt.Fprintf(&b, "%s(diff withheld: %v)\n", line, err)
+				}
+				continue
+			}
+		}
+		if withheld {
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+		} else if masked && inHunk && line != "" && strings.IndexByte(" +-", line[0]) >= 0 {
+			body := strings.TrimRight(line[1:], "\r\n")
+			line = line[:1] + dotenv.MaskLine(body) + line[1+len(body):]
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// diffHeaderPath returns the new path from the "a/<old> b/<new>" part of a
+// diff header
+func diffHeaderPath(header string) string {
+	header = strings.TrimRight(header, "\n")
+	i := strings.LastIndex(header, " b/")
+	if i < 0 {
+		i = strings.LastIndex(header, " \"b/")
+	}
+	if i < 0 {
+		return ""
+	}
+	path := strings.TrimPrefix(header[i+1:], "b/")
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		path = strings.TrimPrefix(unquoted, "b/")
+	}
+	return path
+}
+
+// gitScope returns the repositories a git tool should run in and the
+// pathspec, relative to each repository's directory, limiting it. With no
+// path every repository in the workspace is used.
+func (tm *ToolManager) gitScope(path string) ([]gitignore.Repository, string, error) {
+	if path == "" {
+		repos := tm.matcher.Repositories()
+		if len(repos) == 0 {
+			return nil, "", fmt.Errorf("the workspace contains no git repositories")
+		}
+		return repos, ".", nil
+	}
+
+	absPath, err := tm.resolvePath(path)
+	if err != nil {
+		return nil, "", err
+	}
+	repo, ok := tm.matcher.RepositoryFor(absPath)
+	if !ok {
+		return nil, "", fmt.Errorf("path is not in a git repository: %s", path)
+	}
+	pathspec, err := filepath.Rel(repo.Path, absPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve path: %v", err)
+	}
+	return []gitignore.Repository{repo}, filepath.ToSlash(pathspec), nil
+}
+
+// runGit runs a git command in dir and returns its output
+func runGit(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git: %v", err)
+	}
+	return string(output), nil
+}
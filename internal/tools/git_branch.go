@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// GitBranchListArgs are the arguments for the git_branch_list tool
+type GitBranchListArgs struct {
+	Path string `json:"path,omitempty" jsonschema:"description=Workspace-relative path selecting the repository (default: every repository)"`
+}
+
+// GitCreateBranchArgs are the arguments for the git_create_branch tool
+type GitCreateBranchArgs struct {
+	Name       string `json:"name" jsonschema:"required,description=Name of the new branch"`
+	StartPoint string `json:"start_point,omitempty" jsonschema:"description=Commit or branch to start from (default: HEAD)"`
+	Checkout   bool   `json:"checkout,omitempty" jsonschema:"description=Switch to the new branch after creating it"`
+	Path       string `json:"path,omitempty" jsonschema:"description=Workspace-relative path selecting the repository (default: the repository of the workspace root)"`
+}
+
+// GitCheckoutBranchArgs are the arguments for the git_checkout_branch tool
+type GitCheckoutBranchArgs struct {
+	Name string `json:"name" jsonschema:"required,description=Branch to switch to"`
+	Path string `json:"path,omitempty" jsonschema:"description=Workspace-relative path selecting the repository (default: the repository of the workspace root)"`
+}
+
+// gitBranchListResult is the response of the git_branch_list tool
+type gitBranchListResult struct {
+	Repositories []repoBranches `json:"repositories"`
+}
+
+// repoBranches are the local branches of one repository
+type repoBranches struct {
+	Path     string      `json:"path"`
+	Branches []gitBranch `json:"branches"`
+}
+
+// gitBranch is a local branch
+type gitBranch struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit"`
+	Upstream string `json:"upstream,omitempty"`
+	Current  bool   `json:"current,omitempty"`
+	Date     string `json:"date"`
+	Subject  string `json:"subject"`
+}
+
+// gitBranchResult is the response of the branch changing tools
+type gitBranchResult struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Created    bool   `json:"created,omitempty"`
+	Current    string `json:"current"`
+}
+
+// handleGitBranchList lists the local branches of each repository
+func (tm *ToolManager) handleGitBranchList(args GitBranchListArgs) (*mcp_golang.ToolResponse, error) {
+	repos, _, err := tm.gitScope(args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := gitBranchListResult{Repositories: []repoBranches{}}
+	for _, repo := range repos {
+		output, err := runGit(repo.Path, "for-each-ref",
+			"--format=%(refname:short)%00%(objectname:short)%00%(upstream:short)%00%(HEAD)%00%(committerdate:iso-strict)%00%(subject)",
+			"refs/heads")
+		if err != nil {
+			return nil, err
+		}
+
+		branches := repoBranches{Path: tm.relPath(repo.Path), Branches: []gitBranch{}}
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 6 {
+				continue
+			}
+			branches.Branches = append(branches.Branches, gitBranch{
+				Name:     fields[0],
+				Commit:   fields[1],
+				Upstream: fields[2],
+				Current:  fields[3] == "*",
+				Date:     fields[4],
+				Subject:  fields[5],
+			})
+		}
+		result.Repositories = append(result.Repositories, branches)
+	}
+
+	return jsonResponse(result)
+}
+
+// handleGitCreateBranch creates a branch and optionally switches to it
+func (tm *ToolManager) handleGitCreateBranch(args GitCreateBranchArgs) (*mcp_golang.ToolResponse, error) {
+	repo, err := tm.branchRepo(args.Path, args.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	gitArgs := []string{"branch", "--", args.Name}
+	if args.StartPoint != "" {
+		gitArgs = append(gitArgs, args.StartPoint)
+	}
+	if _, err := runGit(repo.Path, gitArgs...); err != nil {
+		return nil, err
+	}
+	if args.Checkout {
+		if err := tm.switchBranch(repo, args.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return tm.branchResult(repo, args.Name, true)
+}
+
+// handleGitCheckoutBranch switches a repository to another branch
+func (tm *ToolManager) handleGitCheckoutBranch(args GitCheckoutBranchArgs) (*mcp_golang.ToolResponse, error) {
+	repo, err := tm.branchRepo(args.Path, args.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.switchBranch(repo, args.Name); err != nil {
+		return nil, err
+	}
+
+	return tm.branchResult(repo, args.Name, false)
+}
+
+// branchRepo validates a branch name and returns the repository a branch
+// tool acts on
+func (tm *ToolManager) branchRepo(path, name string) (gitignore.Repository, error) {
+	target := tm.workspacePath
+	if path != "" {
+		var err error
+		if target, err = tm.resolvePath(path); err != nil {
+			return gitignore.Repository{}, err
+		}
+	}
+	repo, ok := tm.matcher.RepositoryFor(target)
+	if !ok {
+		return gitignore.Repository{}, fmt.Errorf("path is not in a git repository: %s", tm.relPath(target))
+	}
+
+	if name == "" {
+		return gitignore.Repository{}, fmt.Errorf("name is required")
+	}
+	if _, err := runGit(repo.Path, "check-ref-format", "--branch", name); err != nil {
+		return gitignore.Repository{}, fmt.Errorf("invalid branch name: %s", name)
+	}
+	return repo, nil
+}
+
+// switchBranch checks out a branch. File events are paused meanwhile, so the
+// rewritten files are reconciled once instead of one event at a time.
+func (tm *ToolManager) switchBranch(repo gitignore.Repository, name string) error {
+	resume := tm.pauseEvents()
+	defer resume()
+
+	_, err := runGit(repo.Path, "switch", name)
+	return err
+}
+
+// branchResult reports the branch a repository is now on
+func (tm *ToolManager) branchResult(repo gitignore.Repository, name string, created bool) (*mcp_golang.ToolResponse, error) {
+	current, err := runGit(repo.Path, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(gitBranchResult{
+		Repository: tm.relPath(repo.Path),
+		Branch:     name,
+		Created:    created,
+		Current:    strings.TrimSpace(current),
+	})
+}
@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// GitAddArgs are the arguments for the git_add tool
+type GitAddArgs struct {
+	Paths []string `json:"paths,omitempty" jsonschema:"description=Workspace-relative files to stage; each must have been written by this server's tools"`
+	All   bool     `json:"all,omitempty" jsonschema:"description=Stage every file written by this server's tools that still has changes"`
+}
+
+// GitCommitArgs are the arguments for the git_commit tool
+type GitCommitArgs struct {
+	Message string `json:"message" jsonschema:"required,description=Commit message"`
+	Path    string `json:"path,omitempty" jsonschema:"description=Workspace-relative path selecting the repository to commit in (default: the repository of the workspace root)"`
+}
+
+// gitAddResult is the response of the git_add tool
+type gitAddResult struct {
+	Staged []string `json:"staged"`
+}
+
+// gitCommitResult is the response of the git_commit tool
+type gitCommitResult struct {
+	Repository string   `json:"repository"`
+	Commit     string   `json:"commit"`
+	Files      []string `json:"files"`
+}
+
+// handleGitAdd stages files written by the tools
+func (tm *ToolManager) handleGitAdd(args GitAddArgs) (*mcp_golang.ToolResponse, error) {
+	var paths []string
+	switch {
+	case args.All && len(args.Paths) > 0:
+		return nil, fmt.Errorf("give either paths or all, not both")
+	case args.All:
+		tm.mu.Lock()
+		for path := range tm.written {
+			paths = append(paths, path)
+		}
+		tm.mu.Unlock()
+		sort.Strings(paths)
+	case len(args.Paths) > 0:
+		for _, arg := range args.Paths {
+			path, err := tm.resolvePath(arg)
+			if err != nil {
+				return nil, err
+			}
+			if !tm.writtenByTools(path) {
+				return nil, errcode.New(errcode.Conflict, "refusing to stage %s: it wasn't written by this server's tools or has been changed since", arg)
+			}
+			paths = append(paths, path)
+		}
+	default:
+		return nil, fmt.Errorf("paths or all is required")
+	}
+
+	// Stage each file in the repository that contains it
+	byRepo := make(map[string][]string)
+	for _, path := range paths {
+		if !tm.writtenByTools(path) {
+			continue
+		}
+		repo, ok := tm.matcher.RepositoryFor(path)
+		if !ok {
+			if args.All {
+				continue
+			}
+			return nil, fmt.Errorf("path is not in a git repository: %s", tm.relPath(path))
+		}
+		rel, err := filepath.Rel(repo.Path, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %v", err)
+		}
+		byRepo[repo.Path] = append(byRepo[repo.Path], filepath.ToSlash(rel))
+	}
+
+	result := gitAddResult{Staged: []string{}}
+	roots := make([]string, 0, len(byRepo))
+	for root := range byRepo {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		if _, err := runGit(root, append([]string{"add", "--"}, byRepo[root]...)...); err != nil {
+			return nil, err
+		}
+		for _, rel := range byRepo[root] {
+			result.Staged = append(result.Staged, tm.relPath(filepath.Join(root, filepath.FromSlash(rel))))
+		}
+	}
+
+	return jsonResponse(result)
+}
+
+// handleGitCommit commits the staged changes of a repository, refusing when
+// the working tree holds changes the tools didn't make
+func (tm *ToolManager) handleGitCommit(args GitCommitArgs) (*mcp_golang.ToolResponse, error) {
+	if strings.TrimSpace(args.Message) == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+
+	target := tm.workspacePath
+	if args.Path != "" {
+		var err error
+		if target, err = tm.resolvePath(args.Path); err != nil {
+			return nil, err
+		}
+	}
+	repo, ok := tm.matcher.RepositoryFor(target)
+	if !ok {
+		return nil, fmt.Errorf("path is not in a git repository: %s", tm.relPath(target))
+	}
+
+	status, err := tm.repoStatus(repo, ".", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	var human, staged []string
+	for _, change := range status.Changes {
+		path := filepath.Join(tm.workspacePath, filepath.FromSlash(change.Path))
+		if change.OrigPath != "" || !tm.writtenByTools(path) {
+			human = append(human, change.Path)
+		}
+		if change.Status[0] != ' ' && change.Status[0] != '?' {
+			staged = append(staged, change.Path)
+		}
+	}
+	if len(human) > 0 {
+		return nil, errcode.New(errcode.Conflict, "refusing to commit: the working tree has changes not made by this server's tools: %s", strings.Join(human, ", "))
+	}
+	if len(staged) == 0 {
+		return nil, fmt.Errorf("nothing is staged; use git_add first")
+	}
+
+	gitArgs := []string{"commit", "-m", args.Message}
+	if tm.config.Git.Author != "" {
+		gitArgs = append(gitArgs, "--author="+tm.config.Git.Author)
+	}
+	if _, err := runGit(repo.Path, gitArgs...); err != nil {
+		return nil, err
+	}
+	commit, err := runGit(repo.Path, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonResponse(gitCommitResult{
+		Repository: tm.relPath(repo.Path),
+		Commit:     strings.TrimSpace(commit),
+		Files:      staged,
+	})
+}
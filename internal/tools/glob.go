@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// matchFiles resolves the files named by a tool's path or glob argument.
+// Exactly one of them must be given; a glob is matched against the
+// workspace-relative paths of every non-ignored file, in sorted order. An
+// ignored or hidden path is refused even when named explicitly.
+func (tm *ToolManager) matchFiles(path, glob string) ([]string, error) {
+	switch {
+	case path != "" && glob != "":
+		return nil, fmt.Errorf("give either path or glob, not both")
+	case path != "":
+		resolved, err := tm.resolveExistingPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if tm.matcher.Excluded(resolved) {
+			return nil, fmt.Errorf("path is ignored: %s", path)
+		}
+		info, err := tm.files.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file: %v", err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("path is a directory: %s", path)
+		}
+		return []string{resolved}, nil
+	case glob != "":
+		expr, err := gitignore.CompileGlob(filepath.ToSlash(glob))
+		if err != nil {
+			return nil, err
+		}
+		files, err := tm.workspaceFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspace files: %v", err)
+		}
+		var matched []string
+		for _, file := range files {
+			if expr.MatchString(tm.relPath(file)) {
+				matched = append(matched, file)
+			}
+		}
+		return matched, nil
+	}
+	return nil, fmt.Errorf("path or glob is required")
+}
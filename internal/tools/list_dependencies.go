@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"fmt"
+	"log"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/manifest"
+)
+
+// ListDependenciesArgs are the arguments for the list_dependencies tool
+type ListDependenciesArgs struct {
+	Path  string `json:"path,omitempty" jsonschema:"description=Workspace-relative path of a manifest; all manifests are parsed when omitted"`
+	Scope string `json:"scope,omitempty" jsonschema:"enum=runtime,enum=dev,enum=build,enum=peer,enum=optional,enum=indirect,description=Only return dependencies with this scope"`
+}
+
+// handleListDependencies parses project manifests into dependency lists
+func (tm *ToolManager) handleListDependencies(args ListDependenciesArgs) (*mcp_golang.ToolResponse, error) {
+	var paths []string
+	if args.Path != "" {
+		path, err := tm.resolveExistingPath(args.Path)
+		if err != nil {
+			return nil, err
+		}
+		if tm.matcher.Excluded(path) {
+			return nil, fmt.Errorf("path is ignored: %s", args.Path)
+		}
+		if !manifest.IsManifest(path) {
+			return nil, fmt.Errorf("not a recognized manifest: %s", args.Path)
+		}
+		paths = []string{path}
+	} else {
+		files, err := tm.workspaceFiles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workspace files: %v", err)
+		}
+		for _, file := range files {
+			if !manifest.IsManifest(file) {
+				continue
+			}
+			// Manifests are parsed from disk, which follows symlinks
+			if _, err := tm.resolveExistingPath(file); err == nil {
+				paths = append(paths, file)
+			}
+		}
+	}
+
+	manifests := make([]*manifest.Manifest, 0, len(paths))
+	for _, path := range paths {
+		m, err := manifest.Parse(path)
+		if err != nil {
+			// A single broken manifest shouldn't hide the others
+			if args.Path != "" {
+				return nil, err
+			}
+			log.Printf("Warning: %v", err)
+			continue
+		}
+
+		m.Path = tm.relPath(path)
+		if args.Scope != "" {
+			filtered := make([]manifest.Dependency, 0, len(m.Dependencies))
+			for _, dep := range m.Dependencies {
+				if dep.Scope == args.Scope {
+					filtered = append(filtered, dep)
+				}
+			}
+			m.Dependencies = filtered
+		}
+		manifests = append(manifests, m)
+	}
+
+	return jsonResponse(manifests)
+}
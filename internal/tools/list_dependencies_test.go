@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+func TestListDependenciesStaysInWorkspace(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "go.mod"), []byte("module secret.example/outside\n\nrequire secret.example/dep v1.0.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tm, root := newTestTools(t, map[string]string{
+		".gitignore":         "vendor/\n",
+		"package.json":       `{"dependencies": {"left-pad": "1.0.0"}}`,
+		"vendor/go.mod":      "module ignored.example/vendor\n",
+		"escape/placeholder": "",
+	})
+	if err := os.Symlink(filepath.Join(outside, "go.mod"), filepath.Join(root, "escape", "go.mod")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+
+	_, err := tm.handleListDependencies(ListDependenciesArgs{Path: "escape/go.mod"})
+	expectCode(t, "list_dependencies through a symlink out of the workspace", err, errcode.OutsideWorkspace)
+
+	_, err = tm.handleListDependencies(ListDependenciesArgs{Path: "vendor/go.mod"})
+	if err == nil || !strings.Contains(err.Error(), "path is ignored") {
+		t.Fatalf("list_dependencies on an ignored manifest: %v, want it refused as ignored", err)
+	}
+
+	if _, err := tm.handleListDependencies(ListDependenciesArgs{Path: "package.json"}); err != nil {
+		t.Fatalf("list_dependencies on a manifest in the workspace: %v", err)
+	}
+
+	response, err := tm.handleListDependencies(ListDependenciesArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := response.Content[0].TextContent.Text
+	if strings.Contains(text, "secret.example") || strings.Contains(text, "ignored.example") {
+		t.Fatalf("listing all manifests returned ones outside the workspace or ignored: %s", text)
+	}
+	if !strings.Contains(text, "left-pad") {
+		t.Fatalf("listing all manifests left out package.json: %s", text)
+	}
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+)
+
+// defaultListLimit is how many entries list_directory returns by default
+const defaultListLimit = 200
+
+// ListDirectoryArgs are the arguments for the list_directory tool
+type ListDirectoryArgs struct {
+	Path       string   `json:"path,omitempty" jsonschema:"description=Workspace-relative path of the directory to list (default the workspace root)"`
+	Recursive  bool     `json:"recursive,omitempty" jsonschema:"description=List everything below the directory instead of its children only"`
+	Type       string   `json:"type,omitempty" jsonschema:"enum=file,enum=directory,description=Only list files or only directories"`
+	Extensions []string `json:"extensions,omitempty" jsonschema:"description=Only list files with one of these extensions (such as go or .ts)"`
+	Sort       string   `json:"sort,omitempty" jsonschema:"enum=name,enum=size,enum=mtime,description=Order by path (the default) or by size or modification time; size and mtime list the largest and newest first"`
+	Reverse    bool     `json:"reverse,omitempty" jsonschema:"description=Reverse the order"`
+	Limit      int      `json:"limit,omitempty" jsonschema:"description=Most entries to return (default 200)"`
+}
+
+// listDirectoryResult is the response of the list_directory tool
+type listDirectoryResult struct {
+	Path    string      `json:"path"`
+	Entries []listEntry `json:"entries"`
+	// Total counts the entries that passed the filters, before the limit
+	Total     int  `json:"total"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// listEntry is a file or directory in a listing
+type listEntry struct {
+	Path    string    `json:"path"`
+	Type    string    `json:"type"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// handleListDirectory lists the non-ignored entries of a directory, or of
+// its whole subtree, filtered, sorted and limited so targeted questions such
+// as the newest Go files under a directory take one call
+func (tm *ToolManager) handleListDirectory(args ListDirectoryArgs) (*mcp_golang.ToolResponse, error) {
+	if args.Type != "" && args.Type != "file" && args.Type != "directory" {
+		return nil, fmt.Errorf("invalid type: %s (expected file or directory)", args.Type)
+	}
+	var less func(a, b listEntry) bool
+	switch args.Sort {
+	case "", "name":
+		less = func(a, b listEntry) bool { return a.Path < b.Path }
+	case "size":
+		less = func(a, b listEntry) bool { return a.Size > b.Size }
+	case "mtime":
+		less = func(a, b listEntry) bool { return a.ModTime.After(b.ModTime) }
+	default:
+		return nil, fmt.Errorf("invalid sort: %s (expected name, size or mtime)", args.Sort)
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	extensions := map[string]bool{}
+	for _, ext := range args.Extensions {
+		extensions["."+strings.ToLower(strings.TrimPrefix(ext, "."))] = true
+	}
+
+	path := args.Path
+	if path == "" {
+		path = "."
+	}
+	dir, err := tm.resolveExistingPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if dir != tm.workspacePath && tm.matcher.Excluded(dir) {
+		return nil, fmt.Errorf("path is ignored: %s", path)
+	}
+	info, err := tm.files.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", path)
+	}
+
+	var entries []listEntry
+	err = tm.files.Walk(dir, func(child string, info os.FileInfo, err error) error {
+		if err != nil || child == dir {
+			return nil // Skip entries with errors
+		}
+
+		if info.IsDir() {
+			if tm.matcher.ShouldIgnoreDir(child) {
+				return filepath.SkipDir
+			}
+			if args.Type != "file" && len(extensions) == 0 {
+				entries = append(entries, listEntry{Path: tm.relPath(child), Type: "directory", ModTime: info.ModTime()})
+			}
+			if !args.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if args.Type == "directory" || tm.matcher.ShouldIgnore(child) || diagnostics.SpecialFileType(child, info, tm.files.Stat) != "" {
+			return nil
+		}
+		if len(extensions) > 0 && !extensions[strings.ToLower(filepath.Ext(child))] {
+			return nil
+		}
+		// Symlinks to files are listed with the size of their target
+		if target, err := tm.files.Stat(child); err == nil {
+			info = target
+		}
+		entries = append(entries, listEntry{Path: tm.relPath(child), Type: "file", Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory: %v", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if args.Reverse {
+			return less(entries[j], entries[i])
+		}
+		return less(entries[i], entries[j])
+	})
+
+	result := listDirectoryResult{Path: tm.relPath(dir), Entries: []listEntry{}, Total: len(entries)}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		result.Truncated = true
+	}
+	result.Entries = append(result.Entries, entries...)
+	return jsonResponse(result)
+}
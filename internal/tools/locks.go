@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// Kinds of file lock reported in stat output
+const (
+	lockExclusive = "exclusive"
+	lockShared    = "shared"
+)
+
+// fileLock is a lock another process holds on a file, as far as the
+// platform lets it be seen
+type fileLock struct {
+	// Kind is "exclusive" or "shared"
+	Kind string `json:"kind"`
+	// Mechanism is how the lock was taken: "fcntl" or "flock" advisory
+	// locks, or "share-mode" for a file opened exclusively on Windows
+	Mechanism string `json:"mechanism"`
+	// PID is the process holding the lock, when the platform reports it
+	PID int `json:"pid,omitempty"`
+}
+
+// String describes the lock for errors and warnings
+func (l *fileLock) String() string {
+	holder := "another process"
+	if l.PID > 0 {
+		holder = fmt.Sprintf("process %d", l.PID)
+	}
+	return fmt.Sprintf("%s %s lock held by %s", l.Kind, l.Mechanism, holder)
+}
+
+// checkUnlocked refuses to change a file another process holds a lock on,
+// such as one a build is writing, instead of corrupting it. Files that
+// aren't on disk can't be locked by other processes.
+func (tm *ToolManager) checkUnlocked(path string) error {
+	if !tm.files.OnDisk() {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	lock := readLock(path)
+	if lock == nil {
+		return nil
+	}
+	return errcode.Remediate(errcode.Locked, "retry once the process holding the lock has finished with the file",
+		"file is locked: %s: %s", tm.relPath(path), lock)
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package tools
+
+// readLock is not supported on this platform
+func readLock(path string) *fileLock {
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin
+
+package tools
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// readLock reports an fcntl or flock advisory lock another process holds
+// on a file. Shared flock locks can't be seen without taking a conflicting
+// lock, so only exclusive ones are reported.
+func readLock(path string) *fileLock {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil
+	}
+	defer unix.Close(fd)
+
+	// F_GETLK reports a lock that would conflict with writing the whole file
+	query := unix.Flock_t{Type: unix.F_WRLCK, Whence: 0, Start: 0, Len: 0}
+	if err := unix.FcntlFlock(uintptr(fd), unix.F_GETLK, &query); err == nil && query.Type != unix.F_UNLCK {
+		lock := &fileLock{Kind: lockExclusive, Mechanism: "fcntl", PID: int(query.Pid)}
+		if query.Type == unix.F_RDLCK {
+			lock.Kind = lockShared
+		}
+		return lock
+	}
+
+	// A shared flock can't be taken while another process holds an
+	// exclusive one; if it can, it is released right away
+	err = unix.Flock(fd, unix.LOCK_SH|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return &fileLock{Kind: lockExclusive, Mechanism: "flock"}
+	}
+	if err == nil {
+		_ = unix.Flock(fd, unix.LOCK_UN)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// readLock reports a file another process opened without sharing it for
+// writing, which makes writes fail
+func readLock(path string) *fileLock {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil
+	}
+	share := uint32(windows.FILE_SHARE_READ | windows.FILE_SHARE_WRITE | windows.FILE_SHARE_DELETE)
+	handle, err := windows.CreateFile(name, windows.GENERIC_WRITE, share, nil, windows.OPEN_EXISTING, windows.FILE_ATTRIBUTE_NORMAL, 0)
+	if errors.Is(err, windows.ERROR_SHARING_VIOLATION) {
+		return &fileLock{Kind: lockExclusive, Mechanism: "share-mode"}
+	}
+	if err == nil {
+		_ = windows.CloseHandle(handle)
+	}
+	return nil
+}
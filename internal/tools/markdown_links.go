@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/docs"
+)
+
+// LinkGraphArgs are the arguments for the link_graph tool
+type LinkGraphArgs struct {
+	Path   string `json:"path,omitempty" jsonschema:"description=Markdown file whose links and backlinks to list (default: every link between workspace files)"`
+	Glob   string `json:"glob,omitempty" jsonschema:"description=Glob of Markdown files whose links to list (default: every Markdown file)"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of links"`
+}
+
+// CheckLinksArgs are the arguments for the check_links tool
+type CheckLinksArgs struct {
+	Glob   string `json:"glob,omitempty" jsonschema:"description=Glob of Markdown files to check (default: every Markdown file)"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of broken links"`
+}
+
+// linkGraphResult is the response of the link_graph tool
+type linkGraphResult struct {
+	Links []linkEdge `json:"links"`
+	// Backlinks are the links to path, when one is given
+	Backlinks  []linkEdge `json:"backlinks,omitempty"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// linkEdge is a link from a Markdown document to a workspace file
+type linkEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Fragment string `json:"fragment,omitempty"`
+	Line     int    `json:"line"`
+	Image    bool   `json:"image,omitempty"`
+}
+
+// checkLinksResult is the response of the check_links tool
+type checkLinksResult struct {
+	Broken []brokenLink `json:"broken"`
+	// Checked is how many workspace links were checked; links to web
+	// pages and other external URLs aren't
+	Checked    int    `json:"checked"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// brokenLink is a link whose file or heading doesn't exist
+type brokenLink struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Target  string `json:"target"`
+	Image   bool   `json:"image,omitempty"`
+	Problem string `json:"problem"`
+}
+
+// linkSet is the Markdown documents of the workspace, for resolving links
+type linkSet struct {
+	docs   map[string]*docs.Document // by path; nil until read
+	byName map[string][]string       // wiki link names to the documents they can name
+}
+
+// handleLinkGraph lists the links between Markdown documents and the files
+// they link to
+func (tm *ToolManager) handleLinkGraph(args LinkGraphArgs) (*mcp_golang.ToolResponse, error) {
+	set, err := tm.markdownDocuments()
+	if err != nil {
+		return nil, err
+	}
+
+	var path string
+	sources := set.paths()
+	if args.Path != "" {
+		if path, err = tm.resolveExistingPath(args.Path); err != nil {
+			return nil, err
+		}
+		if !docs.Supported(path) {
+			return nil, fmt.Errorf("not a Markdown file: %s", args.Path)
+		}
+	}
+	if args.Glob != "" {
+		if sources, err = tm.markdownFiles(args.Glob); err != nil {
+			return nil, err
+		}
+	}
+
+	result := linkGraphResult{}
+	var edges []linkEdge
+	for _, source := range sources {
+		for _, link := range tm.documentOf(set, source).Links {
+			target, fragment, problem := tm.resolveLink(set, source, link)
+			if target == "" || problem != "" {
+				continue
+			}
+			edge := linkEdge{From: tm.relPath(source), To: tm.relPath(target), Fragment: fragment, Line: link.Line, Image: link.Image}
+			switch {
+			case path == "" || source == path:
+				edges = append(edges, edge)
+			case target == path:
+				result.Backlinks = append(result.Backlinks, edge)
+			}
+		}
+	}
+
+	page, next, err := paginate(edges, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	result.Links = page
+	result.NextCursor = next
+	if result.Links == nil {
+		result.Links = []linkEdge{}
+	}
+	return jsonResponse(result)
+}
+
+// handleCheckLinks reports relative links and images in Markdown documents
+// whose file or heading doesn't exist
+func (tm *ToolManager) handleCheckLinks(args CheckLinksArgs) (*mcp_golang.ToolResponse, error) {
+	set, err := tm.markdownDocuments()
+	if err != nil {
+		return nil, err
+	}
+	sources := set.paths()
+	if args.Glob != "" {
+		if sources, err = tm.markdownFiles(args.Glob); err != nil {
+			return nil, err
+		}
+	}
+
+	result := checkLinksResult{}
+	var broken []brokenLink
+	for _, source := range sources {
+		for _, link := range tm.documentOf(set, source).Links {
+			if link.External() {
+				continue
+			}
+			result.Checked++
+			if _, _, problem := tm.resolveLink(set, source, link); problem != "" {
+				broken = append(broken, brokenLink{Path: tm.relPath(source), Line: link.Line, Target: link.Target, Image: link.Image, Problem: problem})
+			}
+		}
+	}
+
+	page, next, err := paginate(broken, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	result.Broken = page
+	result.NextCursor = next
+	if result.Broken == nil {
+		result.Broken = []brokenLink{}
+	}
+	return jsonResponse(result)
+}
+
+// markdownDocuments lists the workspace's Markdown documents. Each is read
+// when its links are first needed.
+func (tm *ToolManager) markdownDocuments() (*linkSet, error) {
+	files, err := tm.workspaceFiles()
+	if err != nil {
+		return nil, err
+	}
+	set := &linkSet{docs: make(map[string]*docs.Document), byName: make(map[string][]string)}
+	for _, path := range files {
+		if !docs.Supported(path) {
+			continue
+		}
+		set.docs[path] = nil
+		// [[Page]] names a document by its file name, and [[dir/Page]]
+		// by its path, either without the extension
+		rel := strings.ToLower(tm.relPath(path))
+		stem := strings.TrimSuffix(rel, filepath.Ext(rel))
+		set.byName[stem] = append(set.byName[stem], path)
+		if i := strings.LastIndex(stem, "/"); i >= 0 {
+			set.byName[stem[i+1:]] = append(set.byName[stem[i+1:]], path)
+		}
+	}
+	return set, nil
+}
+
+// paths returns the documents in order
+func (set *linkSet) paths() []string {
+	paths := make([]string, 0, len(set.docs))
+	for path := range set.docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// markdownFiles returns the Markdown files matching a glob
+func (tm *ToolManager) markdownFiles(glob string) ([]string, error) {
+	files, err := tm.matchFiles("", glob)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, path := range files {
+		if docs.Supported(path) {
+			matched = append(matched, path)
+		}
+	}
+	return matched, nil
+}
+
+// documentOf returns the metadata of a document of the set, reading it
+// the first time
+func (tm *ToolManager) documentOf(set *linkSet, path string) *docs.Document {
+	if doc := set.docs[path]; doc != nil {
+		return doc
+	}
+	doc := tm.document(path)
+	if doc == nil {
+		doc = &docs.Document{}
+	}
+	set.docs[path] = doc
+	return doc
+}
+
+// resolveLink returns the file a link in source points to and the heading
+// fragment, or why it is broken. External links resolve to "".
+func (tm *ToolManager) resolveLink(set *linkSet, source string, link docs.Link) (target, fragment, problem string) {
+	if link.External() {
+		return "", "", ""
+	}
+	file, fragment, err := link.Split()
+	if err != nil {
+		return "", "", err.Error()
+	}
+
+	switch {
+	case file == "":
+		target = source
+	case link.Wiki:
+		name := strings.ToLower(filepath.ToSlash(file))
+		if docs.Supported(file) {
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+		}
+		matches := set.byName[name]
+		if len(matches) == 0 {
+			return "", fragment, fmt.Sprintf("no document is named %s", file)
+		}
+		sort.Strings(matches)
+		target = matches[0]
+	default:
+		if strings.HasPrefix(file, "/") {
+			target = filepath.Join(tm.workspacePath, filepath.FromSlash(file))
+		} else {
+			target = filepath.Join(filepath.Dir(source), filepath.FromSlash(file))
+		}
+		rel, err := filepath.Rel(tm.workspacePath, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fragment, "target is outside the workspace"
+		}
+		if _, err := tm.files.Stat(target); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", fragment, "file does not exist"
+			}
+			return "", fragment, fmt.Sprintf("failed to stat target: %v", err)
+		}
+	}
+
+	if fragment != "" && docs.Supported(target) {
+		var doc *docs.Document
+		if _, ok := set.docs[target]; ok {
+			doc = tm.documentOf(set, target)
+		} else {
+			doc = tm.document(target)
+		}
+		if doc != nil && !doc.Anchors()[docs.Slug(fragment)] && !doc.Anchors()[strings.ToLower(fragment)] {
+			return target, fragment, fmt.Sprintf("no heading for #%s", fragment)
+		}
+	}
+	return target, fragment, ""
+}
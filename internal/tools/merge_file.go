@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// MergeFileArgs are the arguments for the merge_file tool
+type MergeFileArgs struct {
+	Base   string `json:"base,omitempty" jsonschema:"description=Common ancestor content"`
+	Ours   string `json:"ours,omitempty" jsonschema:"description=Our version of the content"`
+	Theirs string `json:"theirs,omitempty" jsonschema:"description=Their version of the content"`
+	// Instead of content, a file and two revisions of it
+	Path      string `json:"path,omitempty" jsonschema:"description=Workspace-relative file to merge revisions of instead of passing content"`
+	OursRev   string `json:"ours_rev,omitempty" jsonschema:"description=Revision for our side when path is given (default: the working tree file)"`
+	TheirsRev string `json:"theirs_rev,omitempty" jsonschema:"description=Revision for their side when path is given"`
+	Style     string `json:"style,omitempty" jsonschema:"enum=merge,enum=diff3,enum=zdiff3,description=Conflict marker style (default: merge)"`
+	Favor     string `json:"favor,omitempty" jsonschema:"enum=ours,enum=theirs,enum=union,description=Resolve conflicts automatically in favor of one side or by keeping both"`
+	WriteBack bool   `json:"write,omitempty" jsonschema:"description=Write the merged result to path (only with path)"`
+}
+
+// mergeFileResult is the response of the merge_file tool
+type mergeFileResult struct {
+	Merged    string `json:"merged"`
+	Conflicts int    `json:"conflicts"`
+	Base      string `json:"baseRev,omitempty"`
+	Written   bool   `json:"written,omitempty"`
+}
+
+// handleMergeFile performs a three-way merge with git merge-file
+func (tm *ToolManager) handleMergeFile(ctx context.Context, args MergeFileArgs) (*mcp_golang.ToolResponse, error) {
+	result := mergeFileResult{}
+	base, ours, theirs := args.Base, args.Ours, args.Theirs
+	labels := []string{"ours", "base", "theirs"}
+
+	var path string
+	if args.Path != "" {
+		if args.TheirsRev == "" {
+			return nil, fmt.Errorf("theirs_rev is required with path")
+		}
+		var err error
+		path, err = tm.resolveExistingPath(args.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := tm.checkDiskAccess("open", path, tm.matcher.CanRead); err != nil {
+			return nil, err
+		}
+		if args.WriteBack {
+			if err := tm.checkDiskAccess("write", path, tm.matcher.CanWrite); err != nil {
+				return nil, err
+			}
+			if err := tm.checkNotConfig(path); err != nil {
+				return nil, err
+			}
+			unlock, err := tm.lockForChange(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+			defer unlock()
+			if err := tm.checkUnlocked(path); err != nil {
+				return nil, err
+			}
+		}
+		repo, ok := tm.matcher.RepositoryFor(path)
+		if !ok {
+			return nil, fmt.Errorf("path is not in a git repository: %s", args.Path)
+		}
+		rel, err := filepath.Rel(repo.GitRoot, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %v", err)
+		}
+		object := filepath.ToSlash(rel)
+
+		oursRev := args.OursRev
+		if oursRev == "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %v", err)
+			}
+			ours = string(data)
+			oursRev = "HEAD"
+			labels[0] = "working tree"
+		} else {
+			if ours, err = runGit(repo.Path, "show", oursRev+":"+object); err != nil {
+				return nil, err
+			}
+			labels[0] = oursRev
+		}
+		if theirs, err = runGit(repo.Path, "show", args.TheirsRev+":"+object); err != nil {
+			return nil, err
+		}
+		labels[2] = args.TheirsRev
+
+		mergeBase, err := runGit(repo.Path, "merge-base", oursRev, args.TheirsRev)
+		if err != nil {
+			return nil, err
+		}
+		result.Base = strings.TrimSpace(mergeBase)
+		labels[1] = result.Base[:min(len(result.Base), 12)]
+		// A file added on both sides has an empty base
+		base, _ = runGit(repo.Path, "show", result.Base+":"+object)
+	} else if args.WriteBack {
+		return nil, fmt.Errorf("write needs path")
+	}
+
+	merged, conflicts, err := mergeContent(base, ours, theirs, labels, args.Style, args.Favor)
+	if err != nil {
+		return nil, err
+	}
+	result.Merged = merged
+	result.Conflicts = conflicts
+	if path != "" && tm.masked(path) {
+		result.Merged = maskMerged(merged)
+	}
+
+	if args.WriteBack {
+		size := int64(len(merged))
+		release, err := tm.reserveQuota(ctx, quotaWrite{path: path, bytes: size, size: size})
+		if err != nil {
+			return nil, err
+		}
+		op, err := tm.snapshot("merge_file", path)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		before := tm.beforeChange(path)
+		if err := os.WriteFile(path, []byte(merged), mode); err != nil {
+			release()
+			err = explainWriteError(path, err)
+			return nil, errcode.Wrapf(err, "failed to write file: %v", err)
+		}
+		result.Written = true
+		tm.recordWrite(path)
+		tm.recordOperation(op)
+		tm.recordChange(ctx, "merge_file", before)
+	}
+
+	return jsonResponse(result)
+}
+
+// maskMerged masks the values in a merge of a .env file, keeping its
+// conflict markers
+func maskMerged(merged string) string {
+	lines := strings.SplitAfter(merged, "\n")
+	masked := strings.SplitAfter(dotenv.Mask(merged), "\n")
+	for i, line := range lines {
+		for _, marker := range []string{"<<<<<<<", "|||||||", "=======", ">>>>>>>"} {
+			if strings.HasPrefix(line, marker) {
+				masked[i] = line
+			}
+		}
+	}
+	return strings.Join(masked, "")
+}
+
+// mergeContent runs git merge-file on temporary copies of the three versions
+// and returns the result with the number of conflicts
+func mergeContent(base, ours, theirs string, labels []string, style, favor string) (string, int, error) {
+	dir, err := os.MkdirTemp("", "mcp-merge-")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := make([]string, 3)
+	for i, content := range []string{ours, base, theirs} {
+		files[i] = filepath.Join(dir, []string{"ours", "base", "theirs"}[i])
+		if err := os.WriteFile(files[i], []byte(content), 0600); err != nil {
+			return "", 0, fmt.Errorf("failed to write temporary file: %v", err)
+		}
+	}
+
+	gitArgs := []string{"merge-file", "-p", "-L", labels[0], "-L", labels[1], "-L", labels[2]}
+	switch style {
+	case "", "merge":
+	case "diff3", "zdiff3":
+		gitArgs = append(gitArgs, "--"+style)
+	default:
+		return "", 0, fmt.Errorf("unknown style %q (expected merge, diff3 or zdiff3)", style)
+	}
+	switch favor {
+	case "":
+	case "ours", "theirs", "union":
+		gitArgs = append(gitArgs, "--"+favor)
+	default:
+		return "", 0, fmt.Errorf("unknown favor %q (expected ours, theirs or union)", favor)
+	}
+	gitArgs = append(gitArgs, files...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+
+	// merge-file exits with the number of conflicts, or negative on errors
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return string(output), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		if exitErr != nil {
+			return "", 0, fmt.Errorf("git merge-file failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", 0, fmt.Errorf("failed to run git: %v", err)
+	}
+	return string(output), 0, nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package tools
+
+// oNoFollow is not supported on this platform; resolveWritePath refuses
+// symlinks before files are opened
+const oNoFollow = 0
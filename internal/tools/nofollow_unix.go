@@ -0,0 +1,8 @@
+//go:build linux || darwin
+
+package tools
+
+import "golang.org/x/sys/unix"
+
+// oNoFollow makes opening a file fail if it is a symlink
+const oNoFollow = unix.O_NOFOLLOW
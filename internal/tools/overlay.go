@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/diff"
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+)
+
+// OverlayDiffArgs are the arguments for the overlay_diff tool
+type OverlayDiffArgs struct {
+	Path string `json:"path,omitempty" jsonschema:"description=Workspace-relative file or directory to diff (default: the whole workspace)"`
+}
+
+// OverlayPathsArgs are the arguments for the overlay_apply and
+// overlay_discard tools
+type OverlayPathsArgs struct {
+	Paths []string `json:"paths,omitempty" jsonschema:"description=Workspace-relative files or directories whose changes to take (default: every change)"`
+}
+
+// overlayDiffResult is the response of the overlay_diff tool
+type overlayDiffResult struct {
+	Changes []overlayChange `json:"changes"`
+	Diff    string          `json:"diff,omitempty"`
+}
+
+// overlayChangesResult is the response of the overlay_apply and
+// overlay_discard tools
+type overlayChangesResult struct {
+	Changes []overlayChange `json:"changes"`
+}
+
+// overlayChange is a file that differs between the overlay and the workspace
+type overlayChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+}
+
+// overlay returns the workspace's overlay, for tools that only exist in
+// overlay mode
+func (tm *ToolManager) overlay() (*fsys.Overlay, error) {
+	overlay, ok := tm.files.Overlay()
+	if !ok {
+		return nil, fmt.Errorf("the server isn't running with --overlay")
+	}
+	return overlay, nil
+}
+
+// overlayNames converts workspace-relative paths to the overlay's names
+func (tm *ToolManager) overlayNames(paths []string) ([]string, error) {
+	var names []string
+	for _, path := range paths {
+		absPath, err := tm.resolvePath(path)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, tm.relPath(absPath))
+	}
+	return names, nil
+}
+
+// handleOverlayDiff lists the changes held in the overlay with a unified
+// diff against the workspace on disk
+func (tm *ToolManager) handleOverlayDiff(args OverlayDiffArgs) (*mcp_golang.ToolResponse, error) {
+	overlay, err := tm.overlay()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if args.Path != "" {
+		if names, err = tm.overlayNames([]string{args.Path}); err != nil {
+			return nil, err
+		}
+	}
+	changes, err := overlay.Changes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the overlay: %v", err)
+	}
+
+	result := overlayDiffResult{Changes: []overlayChange{}}
+	var patch bytes.Buffer
+	for _, change := range changes {
+		if !fsys.Selected(change.Name, names) {
+			continue
+		}
+		result.Changes = append(result.Changes, overlayChange{Path: change.Name, Change: change.Kind})
+
+		var before, after []byte
+		if change.Kind != fsys.ChangeAdded {
+			if before, err = overlay.Base(change.Name); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", change.Name, err)
+			}
+		}
+		if change.Kind != fsys.ChangeDeleted {
+			if after, err = overlay.ReadFile(change.Name); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %v", change.Name, err)
+			}
+		}
+		oldName, newName := "a/"+change.Name, "b/"+change.Name
+		switch change.Kind {
+		case fsys.ChangeAdded:
+			oldName = "/dev/null"
+		case fsys.ChangeDeleted:
+			newName = "/dev/null"
+		}
+		if bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+			fmt.Fprintf(&patch, "Binary files %s and %s differ\n", oldName, newName)
+			continue
+		}
+		if tm.masked(change.Name) {
+			before, after = []byte(dotenv.Mask(string(before))), []byte(dotenv.Mask(string(after)))
+		}
+		patch.WriteString(diff.Unified(oldName, newName, string(before), string(after)))
+	}
+	result.Diff = patch.String()
+
+	return jsonResponse(result)
+}
+
+// handleOverlayApply writes changes held in the overlay to the workspace
+func (tm *ToolManager) handleOverlayApply(args OverlayPathsArgs) (*mcp_golang.ToolResponse, error) {
+	overlay, err := tm.overlay()
+	if err != nil {
+		return nil, err
+	}
+	names, err := tm.overlayNames(args.Paths)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := overlay.Apply(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the overlay after %d changes: %v", len(applied), err)
+	}
+	return jsonResponse(overlayChangesResult{Changes: overlayChanges(applied)})
+}
+
+// handleOverlayDiscard drops changes held in the overlay
+func (tm *ToolManager) handleOverlayDiscard(args OverlayPathsArgs) (*mcp_golang.ToolResponse, error) {
+	overlay, err := tm.overlay()
+	if err != nil {
+		return nil, err
+	}
+	names, err := tm.overlayNames(args.Paths)
+	if err != nil {
+		return nil, err
+	}
+	discarded, err := overlay.Discard(names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discard overlay changes: %v", err)
+	}
+	// Discarded files read from the disk again
+	for _, change := range discarded {
+		tm.applyChange(filepath.Join(tm.workspacePath, filepath.FromSlash(change.Name)))
+	}
+	return jsonResponse(overlayChangesResult{Changes: overlayChanges(discarded)})
+}
+
+// overlayChanges converts changes to their JSON form
+func overlayChanges(changes []fsys.Change) []overlayChange {
+	result := []overlayChange{}
+	for _, change := range changes {
+		result = append(result, overlayChange{Path: change.Name, Change: change.Kind})
+	}
+	return result
+}
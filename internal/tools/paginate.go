@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// paginate returns the page of items starting at a cursor returned by a
+// previous call, and the cursor of the following page ("" on the last page).
+// A page size of 0 returns everything.
+func paginate[T any](items []T, cursor string, size int) ([]T, string, error) {
+	start := 0
+	if cursor != "" {
+		data, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			start, err = strconv.Atoi(string(data))
+		}
+		if err != nil || start < 0 {
+			return nil, "", fmt.Errorf("invalid cursor: %s", cursor)
+		}
+		start = min(start, len(items))
+	}
+
+	end := len(items)
+	if size > 0 && start+size < end {
+		end = start + size
+	}
+
+	next := ""
+	if end < len(items) {
+		next = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+	return items[start:end], next, nil
+}
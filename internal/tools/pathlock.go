@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+)
+
+// pathLockWait is how long a tool waits for paths another call or session
+// holds before giving up
+const pathLockWait = 30 * time.Second
+
+// LockPathsArgs are the arguments for the lock_paths tool
+type LockPathsArgs struct {
+	Paths []string `json:"paths" jsonschema:"required,description=Workspace-relative paths of the files to lock for this session"`
+}
+
+// UnlockPathsArgs are the arguments for the unlock_paths tool
+type UnlockPathsArgs struct {
+	Paths []string `json:"paths,omitempty" jsonschema:"description=Paths to unlock; all of this session's locks when empty"`
+}
+
+// lockPathsResult lists the paths a session holds after locking or unlocking
+type lockPathsResult struct {
+	Held []string `json:"held"`
+}
+
+// pathLocks serializes changes to files: a tool call changing a path waits
+// for other calls changing it, and for other sessions that locked it with
+// lock_paths
+type pathLocks struct {
+	held    map[string]*pathLock
+	changed chan struct{} // closed and replaced whenever a path is released
+	mu      sync.Mutex
+}
+
+// pathLock is the state of a path being changed or locked
+type pathLock struct {
+	busy    bool   // a tool call is changing the path
+	session string // the session holding it through lock_paths
+	locked  bool
+}
+
+// newPathLocks returns an empty set of path locks
+func newPathLocks() *pathLocks {
+	return &pathLocks{held: make(map[string]*pathLock), changed: make(chan struct{})}
+}
+
+// blocker returns a description of what keeps a session from a path, or ""
+// if it is free. Called with the lock held.
+func (l *pathLocks) blocker(path, id string, explicit bool) string {
+	lock := l.held[path]
+	switch {
+	case lock == nil:
+		return ""
+	case lock.locked && lock.session != id:
+		return "locked by another session with lock_paths"
+	case lock.busy && !explicit:
+		return "being changed by another tool call"
+	}
+	return ""
+}
+
+// wait blocks until every path is free for the session, then claims them:
+// for a tool call when explicit is false, or for the session otherwise
+func (l *pathLocks) wait(ctx context.Context, id string, paths []string, explicit bool) error {
+	timeout := time.NewTimer(pathLockWait)
+	defer timeout.Stop()
+
+	for {
+		l.mu.Lock()
+		blocked, reason := "", ""
+		for _, path := range paths {
+			if reason = l.blocker(path, id, explicit); reason != "" {
+				blocked = path
+				break
+			}
+		}
+		if blocked == "" {
+			for _, path := range paths {
+				lock := l.held[path]
+				if lock == nil {
+					lock = &pathLock{}
+					l.held[path] = lock
+				}
+				if explicit {
+					lock.locked, lock.session = true, id
+				} else {
+					lock.busy = true
+				}
+			}
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout.C:
+			return &pathLockedError{path: blocked, reason: reason}
+		}
+	}
+}
+
+// release frees paths claimed by wait
+func (l *pathLocks) release(paths []string, explicit bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, path := range paths {
+		lock := l.held[path]
+		if lock == nil {
+			continue
+		}
+		if explicit {
+			lock.locked, lock.session = false, ""
+		} else {
+			lock.busy = false
+		}
+		if !lock.busy && !lock.locked {
+			delete(l.held, path)
+		}
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// sessionPaths returns the paths a session locked, sorted
+func (l *pathLocks) sessionPaths(id string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	paths := []string{}
+	for path, lock := range l.held {
+		if lock.locked && lock.session == id {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// pathLockedError reports a path that stayed locked for pathLockWait
+type pathLockedError struct {
+	path   string
+	reason string
+}
+
+func (e *pathLockedError) Error() string {
+	return fmt.Sprintf("path is %s: %s", e.reason, e.path)
+}
+
+// ErrorCode codes the error CONFLICT
+func (e *pathLockedError) ErrorCode() errcode.Code {
+	return errcode.Conflict
+}
+
+// ErrorRemediation tells the client what to do about the lock
+func (e *pathLockedError) ErrorRemediation() string {
+	return "retry once the other session calls unlock_paths or disconnects"
+}
+
+// lockForChange waits until no other tool call or session holds the given
+// files, then holds them until the returned release is called
+func (tm *ToolManager) lockForChange(ctx context.Context, paths ...string) (release func(), err error) {
+	id, _ := session.From(ctx)
+	paths = uniqueSorted(paths)
+	if err := tm.locks.wait(ctx, id, paths, false); err != nil {
+		return nil, err
+	}
+	return func() { tm.locks.release(paths, false) }, nil
+}
+
+// uniqueSorted sorts paths and drops duplicates, so calls claiming several
+// paths claim them all at once in the same order
+func uniqueSorted(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, path := range sorted {
+		if i == 0 || path != sorted[i-1] {
+			unique = append(unique, path)
+		}
+	}
+	return unique
+}
+
+// handleLockPaths locks files for the calling session until it unlocks
+// them or disconnects, so its multi-file changes aren't interleaved with
+// another session's
+func (tm *ToolManager) handleLockPaths(ctx context.Context, args LockPathsArgs) (*mcp_golang.ToolResponse, error) {
+	if len(args.Paths) == 0 {
+		return nil, fmt.Errorf("paths is required")
+	}
+	var paths []string
+	for _, arg := range args.Paths {
+		path, err := tm.resolvePath(arg)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	id, _ := session.From(ctx)
+	if err := tm.locks.wait(ctx, id, uniqueSorted(paths), true); err != nil {
+		return nil, err
+	}
+	return jsonResponse(tm.heldPaths(id))
+}
+
+// handleUnlockPaths releases files the calling session locked
+func (tm *ToolManager) handleUnlockPaths(ctx context.Context, args UnlockPathsArgs) (*mcp_golang.ToolResponse, error) {
+	id, _ := session.From(ctx)
+	paths := tm.locks.sessionPaths(id)
+	if len(args.Paths) > 0 {
+		held := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			held[path] = true
+		}
+		paths = nil
+		for _, arg := range args.Paths {
+			path, err := tm.resolvePath(arg)
+			if err != nil {
+				return nil, err
+			}
+			if !held[path] {
+				return nil, fmt.Errorf("path is not locked by this session: %s", arg)
+			}
+			paths = append(paths, path)
+		}
+	}
+	tm.locks.release(paths, true)
+	return jsonResponse(tm.heldPaths(id))
+}
+
+// heldPaths lists the paths a session locked, relative to the workspace
+func (tm *ToolManager) heldPaths(id string) lockPathsResult {
+	result := lockPathsResult{Held: []string{}}
+	for _, path := range tm.locks.sessionPaths(id) {
+		result.Held = append(result.Held, tm.relPath(path))
+	}
+	return result
+}
@@ -0,0 +1,243 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// StatArgs are the arguments for the stat tool
+type StatArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of the file or directory"`
+}
+
+// SetPermissionsArgs are the arguments for the set_permissions tool
+type SetPermissionsArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of the file or directory"`
+	Mode string `json:"mode" jsonschema:"required,description=Octal mode such as 755 or 0644 or a symbolic mode such as +x or u+x or go-w"`
+}
+
+// fileStat is the metadata reported for a path
+type fileStat struct {
+	Path        string    `json:"path"`
+	Type        string    `json:"type"`
+	Size        int64     `json:"size"`
+	Mode        string    `json:"mode"`
+	Permissions string    `json:"permissions"`
+	ModTime     time.Time `json:"modTime"`
+	fileAttributes
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// handleStat reports metadata for a file or directory
+func (tm *ToolManager) handleStat(args StatArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveDiskPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.checkDiskAccess("stat", path, tm.matcher.CanStat); err != nil {
+		return nil, err
+	}
+
+	stat, err := tm.statFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonResponse(stat)
+}
+
+// handleSetPermissions changes the permission bits of a path
+func (tm *ToolManager) handleSetPermissions(ctx context.Context, args SetPermissionsArgs) (*mcp_golang.ToolResponse, error) {
+	if _, err := tm.resolveDiskPath(args.Path); err != nil {
+		return nil, err
+	}
+	path, err := tm.resolveExistingPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.checkDiskAccess("chmod", path, tm.matcher.CanWrite); err != nil {
+		return nil, err
+	}
+	if err := tm.checkNotConfig(path); err != nil {
+		return nil, err
+	}
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+
+	mode, err := parseMode(args.Mode, info.Mode().Perm())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSafeMode(mode); err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		err = explainWriteError(path, err)
+		return nil, errcode.Wrapf(err, "failed to set permissions: %v", err)
+	}
+
+	if tm.debug {
+		log.Printf("Set permissions of %s to %04o", path, mode)
+	}
+	tm.recordWrite(path)
+
+	stat, err := tm.statFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonResponse(stat)
+}
+
+// statFile collects metadata for a path without following a final symlink
+func (tm *ToolManager) statFile(path string) (*fileStat, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errcode.New(errcode.NotFound, "file does not exist: %s", tm.relPath(path))
+		}
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+
+	attrs := readAttributes(path, info)
+	if info.Mode().IsRegular() {
+		attrs.Lock = readLock(path)
+	}
+
+	return &fileStat{
+		Path:           tm.relPath(path),
+		Type:           fileType(info.Mode()),
+		Size:           info.Size(),
+		Mode:           fmt.Sprintf("%04o", info.Mode().Perm()),
+		Permissions:    info.Mode().String(),
+		ModTime:        info.ModTime(),
+		fileAttributes: attrs,
+		Warnings:       writeWarnings(info, attrs),
+	}, nil
+}
+
+// fileType returns a short name for the type bits of a file mode
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "file"
+	case mode.IsDir():
+		return "directory"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode&os.ModeNamedPipe != 0:
+		return "fifo"
+	case mode&os.ModeSocket != 0:
+		return "socket"
+	case mode&os.ModeDevice != 0:
+		return "device"
+	}
+	return "other"
+}
+
+// checkSafeMode rejects modes that could weaken security or lock the server out
+func checkSafeMode(mode os.FileMode) error {
+	if mode&0002 != 0 {
+		return fmt.Errorf("refusing to make a path world-writable (mode %04o)", mode)
+	}
+	if mode&0400 == 0 {
+		return fmt.Errorf("refusing to remove the owner's read permission (mode %04o)", mode)
+	}
+	return nil
+}
+
+// parseMode parses an octal or symbolic chmod mode relative to the current permissions
+func parseMode(spec string, current os.FileMode) (os.FileMode, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, fmt.Errorf("mode is required")
+	}
+
+	// Octal modes
+	if spec[0] >= '0' && spec[0] <= '7' {
+		value, err := strconv.ParseUint(spec, 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid octal mode: %s", spec)
+		}
+		if value&^0777 != 0 {
+			return 0, fmt.Errorf("only permission bits (0777) may be set; setuid, setgid and sticky bits are not allowed: %s", spec)
+		}
+		return os.FileMode(value), nil
+	}
+
+	// Symbolic modes: [ugoa]*[+-=][rwx]* separated by commas
+	mode := current
+	for _, clause := range strings.Split(spec, ",") {
+		i := 0
+		var who os.FileMode
+		for i < len(clause) && strings.ContainsRune("ugoa", rune(clause[i])) {
+			switch clause[i] {
+			case 'u':
+				who |= 0700
+			case 'g':
+				who |= 0070
+			case 'o':
+				who |= 0007
+			case 'a':
+				who |= 0777
+			}
+			i++
+		}
+		if who == 0 {
+			// Like chmod, an omitted "who" applies to everyone except bits
+			// masked by the usual 022 umask, so "+w" means "u+w"
+			who = 0755
+		}
+
+		if i >= len(clause) || !strings.ContainsRune("+-=", rune(clause[i])) {
+			return 0, fmt.Errorf("invalid symbolic mode: %s", spec)
+		}
+		op := clause[i]
+		i++
+
+		var perms os.FileMode
+		for ; i < len(clause); i++ {
+			switch clause[i] {
+			case 'r':
+				perms |= 0444
+			case 'w':
+				perms |= 0222
+			case 'x':
+				perms |= 0111
+			default:
+				return 0, fmt.Errorf("unsupported permission %q in mode %s (only r, w and x are allowed)", clause[i], spec)
+			}
+		}
+		perms &= who
+
+		switch op {
+		case '+':
+			mode |= perms
+		case '-':
+			mode &^= perms
+		case '=':
+			mode = (mode &^ who) | perms
+		}
+	}
+
+	return mode, nil
+}
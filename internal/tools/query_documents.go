@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/docs"
+)
+
+// QueryDocumentsArgs are the arguments for the query_documents tool
+type QueryDocumentsArgs struct {
+	Query  string `json:"query" jsonschema:"required,description=Filter such as 'tag:design AND modified:>2024-01-01'. Terms are bare words (matched in titles and headings and excerpts) or field:value with an optional >/>=/</<= before the value; fields are modified and title and heading and tag and has and any front matter key. Join terms with AND/OR/NOT and parentheses"`
+	Glob   string `json:"glob,omitempty" jsonschema:"description=Glob of Markdown files to query (default: every Markdown file)"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"description=nextCursor from a previous call to get the next page of documents"`
+}
+
+// queryDocumentsResult is the response of the query_documents tool
+type queryDocumentsResult struct {
+	Documents []documentMatch `json:"documents"`
+	// Total is how many documents match, across all pages
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// documentMatch is a document matching a query
+type documentMatch struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Modified time.Time `json:"modified"`
+	Excerpt  string    `json:"excerpt,omitempty"`
+}
+
+// handleQueryDocuments finds the Markdown documents whose front matter,
+// headings and modification time match a query
+func (tm *ToolManager) handleQueryDocuments(args QueryDocumentsArgs) (*mcp_golang.ToolResponse, error) {
+	query, err := docs.ParseQuery(args.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if args.Glob != "" {
+		files, err = tm.matchFiles("", args.Glob)
+	} else {
+		files, err = tm.workspaceFiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []documentMatch{}
+	for _, path := range files {
+		if !docs.Supported(path) {
+			continue
+		}
+		info, err := tm.files.Stat(path)
+		if err != nil {
+			continue
+		}
+		doc := tm.document(path)
+		if doc == nil || !query.Match(docs.Target{Doc: doc, Modified: info.ModTime()}) {
+			continue
+		}
+		matches = append(matches, documentMatch{
+			Path:     tm.relPath(path),
+			Title:    doc.Title,
+			Tags:     append(doc.Fields["tags"], doc.Fields["tag"]...),
+			Modified: info.ModTime(),
+			Excerpt:  doc.Excerpt,
+		})
+	}
+
+	page, next, err := paginate(matches, args.Cursor, tm.config.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResponse(queryDocumentsResult{Documents: page, Total: len(matches), NextCursor: next})
+}
+
+// document returns the metadata of a Markdown document, from the index if
+// it is up to date there, or nil if the file can't be read as text
+func (tm *ToolManager) document(path string) *docs.Document {
+	if tm.index != nil {
+		if doc, ok := tm.index.Document(path); ok {
+			return doc
+		}
+	}
+	data, err := tm.readSource(path)
+	if err != nil || data == nil {
+		return nil
+	}
+	return docs.Parse(data)
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+)
+
+// quotaUsage is what a session has written through tools
+type quotaUsage struct {
+	bytes    int64
+	newFiles int
+}
+
+// quotaWrite is a write checked against the quotas
+type quotaWrite struct {
+	path    string
+	bytes   int64 // bytes the write adds
+	size    int64 // size of the file afterwards
+	created bool
+}
+
+// quotaError is a write refused by a quota. Its message ends with the
+// details as JSON so agents can tell which limit they hit.
+type quotaError struct {
+	Quota     string `json:"quota"` // maxBytesPerSession, maxNewFiles or maxFileSize
+	Limit     int64  `json:"limit"`
+	Used      int64  `json:"used,omitempty"`
+	Requested int64  `json:"requested"`
+	Path      string `json:"path"`
+}
+
+func (e *quotaError) Error() string {
+	data, _ := json.Marshal(e)
+	return "quota exceeded: " + string(data)
+}
+
+// ErrorCode reports files over the size cap as too large and the session
+// quotas as rate limits
+func (e *quotaError) ErrorCode() errcode.Code {
+	if e.Quota == "maxFileSize" {
+		return errcode.TooLarge
+	}
+	return errcode.RateLimited
+}
+
+// ErrorRemediation suggests splitting content too large for a single file
+func (e *quotaError) ErrorRemediation() string {
+	if e.Quota == "maxFileSize" {
+		return "split the content across smaller files"
+	}
+	return ""
+}
+
+// reserveQuota checks a write against the quotas of the session making it
+// and counts it. The returned release undoes the count if the write then
+// fails.
+func (tm *ToolManager) reserveQuota(ctx context.Context, w quotaWrite) (release func(), err error) {
+	id, _ := session.From(ctx)
+	maxBytes, _ := config.ParseSize(tm.config.Quotas.MaxBytesPerSession)
+	maxFileSize, _ := config.ParseSize(tm.config.Quotas.MaxFileSize)
+	maxNewFiles := tm.config.Quotas.MaxNewFiles
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	usage := tm.usage[id]
+	if usage == nil {
+		usage = &quotaUsage{}
+		tm.usage[id] = usage
+	}
+
+	rel := tm.relPath(w.path)
+	switch {
+	case maxFileSize > 0 && w.size > maxFileSize:
+		err = &quotaError{Quota: "maxFileSize", Limit: maxFileSize, Requested: w.size, Path: rel}
+	case maxBytes > 0 && usage.bytes+w.bytes > maxBytes:
+		err = &quotaError{Quota: "maxBytesPerSession", Limit: maxBytes, Used: usage.bytes, Requested: w.bytes, Path: rel}
+	case maxNewFiles > 0 && w.created && usage.newFiles >= maxNewFiles:
+		err = &quotaError{Quota: "maxNewFiles", Limit: int64(maxNewFiles), Used: int64(usage.newFiles), Requested: 1, Path: rel}
+	}
+	if err != nil {
+		tm.audit(ctx, AuditRecord{Event: AuditQuotaExceeded, Path: rel, Details: err})
+		return nil, err
+	}
+
+	newFiles := 0
+	if w.created {
+		newFiles = 1
+	}
+	usage.bytes += w.bytes
+	usage.newFiles += newFiles
+	return func() {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		usage.bytes -= w.bytes
+		usage.newFiles -= newFiles
+	}, nil
+}
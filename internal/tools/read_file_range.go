@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// ReadFileRangeArgs are the arguments for the read_file_range tool
+type ReadFileRangeArgs struct {
+	Path      string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to read"`
+	StartLine int    `json:"start_line,omitempty" jsonschema:"description=First line to return (1-based). Defaults to 1"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema:"description=Last line to return (inclusive). Defaults to as many lines as fit in the size limit"`
+	// LineNumbers is always on when resources.lineNumbers is set
+	LineNumbers bool  `json:"line_numbers,omitempty" jsonschema:"description=Prefix each line with its number right-aligned in six columns and a tab (as cat -n does)"`
+	Blob        bool  `json:"blob,omitempty" jsonschema:"description=Read bytes instead of lines: content is the base64 of the bytes from offset. Binary files can only be read this way"`
+	Offset      int64 `json:"offset,omitempty" jsonschema:"description=With blob: the first byte to return (0-based)"`
+}
+
+// readFileRangeResult is the response of the read_file_range tool
+type readFileRangeResult struct {
+	Path       string `json:"path"`
+	StartLine  int    `json:"startLine"`
+	EndLine    int    `json:"endLine"`
+	TotalLines int    `json:"totalLines"`
+	Content    string `json:"content"`
+	// Truncated is set when the size limit ended the range early; continue
+	// from NextLine
+	Truncated bool `json:"truncated,omitempty"`
+	NextLine  int  `json:"nextLine,omitempty"`
+}
+
+// readBlobResult is the response of the read_file_range tool in blob mode
+type readBlobResult struct {
+	Path     string `json:"path"`
+	Offset   int64  `json:"offset"`
+	Length   int    `json:"length"`
+	Size     int64  `json:"size"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+	// Truncated is set when the size limit ended the read before the end of
+	// the file; continue from NextOffset
+	Truncated  bool  `json:"truncated,omitempty"`
+	NextOffset int64 `json:"nextOffset,omitempty"`
+}
+
+// handleReadFileRange returns a range of lines from a file, bounded by the
+// configured preview threshold so large files can be read piece by piece
+func (tm *ToolManager) handleReadFileRange(args ReadFileRangeArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveExistingPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if tm.matcher.Excluded(path) {
+		return nil, fmt.Errorf("path is ignored: %s", args.Path)
+	}
+	if tm.index != nil {
+		tm.index.Prioritize(path)
+	}
+
+	start := args.StartLine
+	if start == 0 {
+		start = 1
+	}
+	if start < 1 {
+		return nil, fmt.Errorf("start_line must be at least 1: %d", args.StartLine)
+	}
+	if args.EndLine != 0 && args.EndLine < start {
+		return nil, fmt.Errorf("end_line %d is before start_line %d", args.EndLine, start)
+	}
+
+	info, err := tm.files.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file: %s", args.Path)
+	}
+
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// A range may hold as much as a resource read returns unabridged
+	limit := max(tm.config.Resources.PreviewThreshold, tm.config.Resources.PreviewSize)
+	if tm.config.Resources.PreviewThreshold == 0 {
+		limit = -1
+	}
+
+	if args.Blob {
+		if tm.masked(path) {
+			return nil, fmt.Errorf("the values of .env files are masked, so their bytes can't be read; read them as text instead")
+		}
+		return tm.readBlob(path, file, info.Size(), args.Offset, limit)
+	}
+	reader := bufio.NewReader(textio.NewReader(file))
+	if head, _ := reader.Peek(sniffBytes); bytes.IndexByte(head, 0) >= 0 {
+		return nil, errcode.Remediate(errcode.Binary, "request blob=true to read its bytes as base64", "file is binary: %s", args.Path)
+	}
+
+	result := readFileRangeResult{Path: tm.relPath(path), StartLine: start}
+	var content strings.Builder
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		if text == "" && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		result.TotalLines = line
+
+		if line < start || (args.EndLine != 0 && line > args.EndLine) || result.Truncated {
+			continue
+		}
+		if limit >= 0 && int64(content.Len()+len(text)) > limit {
+			result.Truncated = true
+			if content.Len() > 0 {
+				result.NextLine = line
+				continue
+			}
+			// A single line over the limit is cut so reading still makes progress
+			text = strings.ToValidUTF8(text[:limit], "")
+			result.NextLine = line + 1
+		}
+		content.WriteString(text)
+		result.EndLine = line
+	}
+
+	if start > result.TotalLines && result.TotalLines > 0 {
+		return nil, fmt.Errorf("start_line %d is past the end of the file (%d lines)", start, result.TotalLines)
+	}
+	result.Content = content.String()
+	if tm.masked(path) {
+		result.Content = dotenv.Mask(result.Content)
+	}
+	if args.LineNumbers || tm.config.Resources.LineNumbers {
+		result.Content = textio.NumberLines(result.Content, start)
+	}
+
+	tm.recordRead(path)
+	return jsonResponse(result)
+}
+
+// readBlob returns the bytes of a file from offset as base64, at most limit
+// of them unless limit is negative
+func (tm *ToolManager) readBlob(path string, file io.Reader, size, offset, limit int64) (*mcp_golang.ToolResponse, error) {
+	if offset < 0 || offset > size {
+		return nil, fmt.Errorf("offset %d is outside the file (%d bytes)", offset, size)
+	}
+	// Files of remote filesystems can't always seek
+	if seeker, ok := file.(io.Seeker); ok {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek file: %v", err)
+		}
+	} else if _, err := io.CopyN(io.Discard, file, offset); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	length := size - offset
+	if limit >= 0 && length > limit {
+		length = limit
+	}
+	data, err := io.ReadAll(io.LimitReader(file, length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	result := readBlobResult{
+		Path:     tm.relPath(path),
+		Offset:   offset,
+		Length:   len(data),
+		Size:     size,
+		Encoding: "base64",
+		Content:  base64.StdEncoding.EncodeToString(data),
+	}
+	if next := offset + int64(len(data)); next < size {
+		result.Truncated = true
+		result.NextOffset = next
+	}
+	tm.recordRead(path)
+	return jsonResponse(result)
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// ReadFilesArgs are the arguments for the read_files tool
+type ReadFilesArgs struct {
+	Paths        []string `json:"paths,omitempty" jsonschema:"description=Workspace-relative paths of the files to read"`
+	Glob         string   `json:"glob,omitempty" jsonschema:"description=Glob of workspace files to read instead of a list of paths (such as docs/**/*.md)"`
+	MaxFileBytes int64    `json:"max_file_bytes,omitempty" jsonschema:"description=Most bytes returned per file; longer files are truncated (default: the preview size)"`
+	MaxBytes     int64    `json:"max_bytes,omitempty" jsonschema:"description=Most bytes returned in total; files past the budget are listed without content (default: the preview threshold)"`
+	LineNumbers  bool     `json:"line_numbers,omitempty" jsonschema:"description=Prefix each line with its number right-aligned in six columns and a tab (as cat -n does)"`
+}
+
+// readFilesResult is the response of the read_files tool
+type readFilesResult struct {
+	Files []readFile `json:"files"`
+	Bytes int64      `json:"bytes"`
+}
+
+// readFile is the content of one file, or why it has none
+type readFile struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Content   string `json:"content,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+	// Omitted is set when the total budget ran out before this file
+	Omitted bool   `json:"omitted,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleReadFiles returns the contents of several files in one response,
+// capping each file and the total so large selections stay bounded
+func (tm *ToolManager) handleReadFiles(args ReadFilesArgs) (*mcp_golang.ToolResponse, error) {
+	if len(args.Paths) > 0 && args.Glob != "" {
+		return nil, fmt.Errorf("give either paths or glob, not both")
+	}
+
+	perFile := args.MaxFileBytes
+	if perFile <= 0 {
+		perFile = tm.config.Resources.PreviewSize
+	}
+	budget := args.MaxBytes
+	if budget <= 0 {
+		budget = max(tm.config.Resources.PreviewThreshold, tm.config.Resources.PreviewSize)
+	}
+
+	result := readFilesResult{Files: []readFile{}}
+	add := func(path string) {
+		file := readFile{Path: tm.relPath(path)}
+		if info, err := tm.files.Stat(path); err == nil {
+			file.Size = info.Size()
+		}
+
+		remaining := budget - result.Bytes
+		if remaining <= 0 {
+			file.Omitted = true
+			result.Files = append(result.Files, file)
+			return
+		}
+
+		content, truncated, binary, err := tm.readHead(path, min(perFile, remaining))
+		switch {
+		case err != nil:
+			file.Error = err.Error()
+		case binary:
+			file.Binary = true
+		default:
+			if tm.masked(path) {
+				content = dotenv.Mask(content)
+			}
+			file.Content = content
+			file.Truncated = truncated
+			tm.recordRead(path)
+			result.Bytes += int64(len(content))
+			if args.LineNumbers || tm.config.Resources.LineNumbers {
+				file.Content = textio.NumberLines(content, 1)
+			}
+		}
+		result.Files = append(result.Files, file)
+	}
+
+	if args.Glob != "" {
+		files, err := tm.matchFiles("", args.Glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			add(path)
+		}
+		return jsonResponse(result)
+	}
+
+	if len(args.Paths) == 0 {
+		return nil, fmt.Errorf("paths or glob is required")
+	}
+	for _, arg := range args.Paths {
+		files, err := tm.matchFiles(arg, "")
+		if err != nil {
+			result.Files = append(result.Files, readFile{Path: arg, Error: err.Error()})
+			continue
+		}
+		add(files[0])
+	}
+
+	return jsonResponse(result)
+}
+
+// readHead reads at most limit bytes of a file as UTF-8, ending on a whole
+// character, and reports whether more remained or the file is binary
+func (tm *ToolManager) readHead(path string, limit int64) (content string, truncated, binary bool, err error) {
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	// Read one byte past the limit to learn whether anything was cut
+	data, err := io.ReadAll(io.LimitReader(textio.NewReader(file), limit+1))
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to read file: %v", err)
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", false, true, nil
+	}
+	if int64(len(data)) > limit {
+		truncated = true
+		data = data[:limit]
+		for len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+	}
+	return string(data), truncated, false, nil
+}
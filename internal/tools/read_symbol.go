@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// maxListedSymbols caps the symbol names suggested when a lookup fails
+const maxListedSymbols = 50
+
+// ReadSymbolArgs are the arguments for the read_symbol tool
+type ReadSymbolArgs struct {
+	Path        string `json:"path" jsonschema:"required,description=Workspace-relative path of a Go or JS/TS or Python file"`
+	Name        string `json:"name,omitempty" jsonschema:"description=Function or type or class name; methods can be qualified as Type.method. Omit to list the file's symbols"`
+	LineNumbers bool   `json:"line_numbers,omitempty" jsonschema:"description=Prefix each line with its number right-aligned in six columns and a tab (as cat -n does)"`
+}
+
+// readSymbolResult is the response of the read_symbol tool
+type readSymbolResult struct {
+	Path       string           `json:"path"`
+	TotalLines int              `json:"totalLines"`
+	Symbols    []symbolSource   `json:"symbols,omitempty"`
+	Outline    []symbols.Symbol `json:"outline,omitempty"`
+}
+
+// symbolSource is a definition with its source text
+type symbolSource struct {
+	symbols.Symbol
+	Content string `json:"content"`
+}
+
+// handleReadSymbol returns the source of a definition instead of the whole
+// file, or the file's outline when no name is given
+func (tm *ToolManager) handleReadSymbol(args ReadSymbolArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveExistingPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if tm.matcher.Excluded(path) {
+		return nil, fmt.Errorf("path is ignored: %s", args.Path)
+	}
+	if !symbols.Supported(path) {
+		return nil, fmt.Errorf("unsupported file type for symbol lookup: %s", args.Path)
+	}
+	if tm.index != nil {
+		tm.index.Prioritize(path)
+	}
+
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(textio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	result := readSymbolResult{Path: tm.relPath(path), TotalLines: len(lines)}
+
+	// The index has the symbols of files that haven't changed since it read them
+	var defined []symbols.Symbol
+	cached := false
+	if tm.index != nil {
+		defined, cached = tm.index.Symbols(path)
+	}
+	if !cached {
+		defined = symbols.Parse(path, data)
+	}
+	if args.Name == "" {
+		result.Outline = defined
+		if result.Outline == nil {
+			result.Outline = []symbols.Symbol{}
+		}
+		return jsonResponse(result)
+	}
+
+	matches := symbols.Lookup(defined, args.Name)
+	if len(matches) == 0 {
+		var names []string
+		for _, symbol := range defined {
+			if len(names) == maxListedSymbols {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, symbol.Name)
+		}
+		if len(names) == 0 {
+			return nil, errcode.New(errcode.NotFound, "symbol not found: %s (the file defines no symbols)", args.Name)
+		}
+		return nil, errcode.New(errcode.NotFound, "symbol not found: %s (defined: %s)", args.Name, strings.Join(names, ", "))
+	}
+
+	for _, symbol := range matches {
+		end := min(symbol.EndLine, len(lines))
+		content := strings.Join(lines[symbol.StartLine-1:end], "")
+		if args.LineNumbers || tm.config.Resources.LineNumbers {
+			content = textio.NumberLines(content, symbol.StartLine)
+		}
+		result.Symbols = append(result.Symbols, symbolSource{Symbol: symbol, Content: content})
+	}
+	tm.recordRead(path)
+
+	return jsonResponse(result)
+}
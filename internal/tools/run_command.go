@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// commandOutputLimit is how much of each output stream run_command returns;
+// the end of the output, where build and test failures are reported, is kept
+const commandOutputLimit = 64 * 1024
+
+// RunCommandArgs are the arguments for the run_command tool
+type RunCommandArgs struct {
+	Command        string   `json:"command" jsonschema:"required,description=Program to run; it must be on the operator's allow list"`
+	Args           []string `json:"args,omitempty" jsonschema:"description=Arguments passed to the program (no shell is involved)"`
+	Dir            string   `json:"dir,omitempty" jsonschema:"description=Workspace-relative directory to run in (default: the workspace root)"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty" jsonschema:"description=Stop the command after this many seconds (default and maximum: the configured timeout)"`
+}
+
+// runCommandResult is the response of the run_command tool
+type runCommandResult struct {
+	Command   string `json:"command"`
+	Dir       string `json:"dir"`
+	ExitCode  int    `json:"exitCode"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Duration  string `json:"duration"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// tailBuffer keeps the last bytes written to it
+type tailBuffer struct {
+	data      []byte
+	truncated bool
+}
+
+// Write appends to the buffer, dropping the oldest bytes past the limit
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if over := len(b.data) - commandOutputLimit; over > 0 {
+		b.data = append(b.data[:0], b.data[over:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the kept output, valid UTF-8 even when cut mid-character
+func (b *tailBuffer) String() string {
+	return strings.ToValidUTF8(string(b.data), "")
+}
+
+// handleRunCommand runs an allowed command in the workspace and captures its output
+func (tm *ToolManager) handleRunCommand(args RunCommandArgs) (*mcp_golang.ToolResponse, error) {
+	argv := append(strings.Fields(args.Command), args.Args...)
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+	if !tm.commandAllowed(argv) {
+		return nil, fmt.Errorf("command not allowed: %s (allowed: %s)", strings.Join(argv, " "), strings.Join(tm.config.Exec.Allow, ", "))
+	}
+
+	dir := tm.workspacePath
+	if args.Dir != "" {
+		var err error
+		dir, err = tm.resolveExistingPath(args.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("not a directory: %s", args.Dir)
+		}
+	}
+
+	timeout := tm.config.Exec.Timeout
+	if args.TimeoutSeconds > 0 {
+		timeout = min(timeout, time.Duration(args.TimeoutSeconds)*time.Second)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr tailBuffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Children that inherit the output pipes mustn't keep the call waiting
+	cmd.WaitDelay = 2 * time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := runCommandResult{
+		Command:  strings.Join(argv, " "),
+		Dir:      tm.relPath(dir),
+		Duration: time.Since(start).Round(time.Millisecond).String(),
+		TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		return nil, fmt.Errorf("failed to run %s: %v", argv[0], err)
+	}
+
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+	if tm.debug {
+		log.Printf("run_command: %s (exit %d, %s)", result.Command, result.ExitCode, result.Duration)
+	}
+
+	return jsonResponse(result)
+}
+
+// commandAllowed reports whether a command starts with an allowed program
+// and leading arguments
+func (tm *ToolManager) commandAllowed(argv []string) bool {
+	for _, entry := range tm.config.Exec.Allow {
+		allowed := strings.Fields(entry)
+		if len(allowed) <= len(argv) && slices.Equal(allowed, argv[:len(allowed)]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// ScaffoldProjectArgs are the arguments for the scaffold_project tool
+type ScaffoldProjectArgs struct {
+	Scaffold  string            `json:"scaffold" jsonschema:"required,description=Name of a scaffold from the server config"`
+	Dir       string            `json:"dir" jsonschema:"required,description=Workspace-relative directory to create the skeleton in"`
+	Vars      map[string]string `json:"vars,omitempty" jsonschema:"description=Values for the scaffold's variables, referenced in its paths and files as {{.Vars.name}}"`
+	Overwrite bool              `json:"overwrite,omitempty" jsonschema:"description=Replace files that already exist"`
+}
+
+// scaffoldProjectResult is the response of the scaffold_project tool
+type scaffoldProjectResult struct {
+	Scaffold    string                   `json:"scaffold"`
+	Dir         string                   `json:"dir"`
+	Created     []string                 `json:"created"`
+	Overwritten []string                 `json:"overwritten,omitempty"`
+	Dirs        []string                 `json:"dirs,omitempty"` // directories created without files in them
+	Bytes       int                      `json:"bytes"`
+	Format      map[string]*formatResult `json:"format,omitempty"`
+	// Simulated is set with --simulate, when the files were only written
+	// in memory
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// scaffoldFile is a file of a scaffold, filled in for a call
+type scaffoldFile struct {
+	path    string
+	content string
+	exists  bool
+}
+
+// handleScaffoldProject creates a directory skeleton from a configured
+// scaffold. Every path is filled in and checked before anything is written,
+// so a bad variable or an existing file leaves the workspace untouched.
+func (tm *ToolManager) handleScaffoldProject(ctx context.Context, args ScaffoldProjectArgs) (*mcp_golang.ToolResponse, error) {
+	scaffold, ok := tm.config.Scaffolds[args.Scaffold]
+	if !ok {
+		return nil, fmt.Errorf("unknown scaffold %q (available: %s)", args.Scaffold, strings.Join(tm.scaffoldNames(), ", "))
+	}
+	var missing []string
+	for _, name := range scaffold.Vars {
+		if _, ok := args.Vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("scaffold %s needs vars: %s", args.Scaffold, strings.Join(missing, ", "))
+	}
+
+	dir, err := tm.resolveWritePath(args.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := tm.files.Stat(dir); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("path is not a directory: %s", args.Dir)
+	}
+
+	// Paths are filled in with the target directory as .Path, .Name and so on
+	dirData := tm.templateData(dir, args.Vars)
+	var dirs []string
+	for _, pattern := range scaffold.Dirs {
+		path, err := tm.scaffoldPath(dir, pattern, dirData)
+		if err != nil {
+			return nil, fmt.Errorf("scaffold %s: %v", args.Scaffold, err)
+		}
+		dirs = append(dirs, path)
+	}
+
+	files, err := tm.scaffoldFiles(args, scaffold, dir, dirData)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.path)
+	}
+	unlock, err := tm.lockForChange(ctx, paths...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	for _, path := range paths {
+		if err := tm.checkUnlocked(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var releases []func()
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+	for _, file := range files {
+		size := int64(len(file.content))
+		release, err := tm.reserveQuota(ctx, quotaWrite{path: file.path, bytes: size, size: size, created: !file.exists})
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+
+	op, err := tm.snapshot("scaffold_project", paths...)
+	if err != nil {
+		releaseAll()
+		return nil, err
+	}
+
+	result := scaffoldProjectResult{
+		Scaffold:  args.Scaffold,
+		Dir:       tm.relPath(dir),
+		Created:   []string{},
+		Simulated: tm.config.Simulate,
+	}
+	for _, path := range dirs {
+		if _, err := tm.files.Stat(path); err == nil {
+			continue
+		}
+		if err := tm.files.MkdirAll(path, 0755); err != nil {
+			releaseAll()
+			return nil, errcode.Wrapf(err, "failed to create directory %s: %v", tm.relPath(path), err)
+		}
+		result.Dirs = append(result.Dirs, tm.relPath(path))
+	}
+
+	for i, file := range files {
+		rel := tm.relPath(file.path)
+		before := tm.beforeChange(file.path)
+		if err := tm.files.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			releaseAll()
+			return nil, errcode.Wrapf(err, "failed to create parent directories of %s: %v", rel, err)
+		}
+		if err := tm.files.WriteFile(file.path, []byte(file.content), 0644); err != nil {
+			for _, release := range releases[i:] {
+				release()
+			}
+			err = explainWriteError(file.path, err)
+			return nil, errcode.Wrapf(err, "failed to write %s: %v", rel, err)
+		}
+
+		if file.exists {
+			result.Overwritten = append(result.Overwritten, rel)
+		} else {
+			result.Created = append(result.Created, rel)
+		}
+		result.Bytes += len(file.content)
+		if format := tm.formatFile(file.path); format != nil {
+			if result.Format == nil {
+				result.Format = make(map[string]*formatResult)
+			}
+			result.Format[rel] = format
+		}
+		tm.recordWrite(file.path)
+		tm.recordChange(ctx, "scaffold_project", before)
+	}
+	tm.recordOperation(op)
+
+	return jsonResponse(result)
+}
+
+// scaffoldFiles fills in the paths and contents of a scaffold's files,
+// refusing existing files unless they may be overwritten
+func (tm *ToolManager) scaffoldFiles(args ScaffoldProjectArgs, scaffold config.Scaffold, dir string, dirData templateData) ([]scaffoldFile, error) {
+	var files []scaffoldFile
+	seen := make(map[string]bool)
+	for _, spec := range scaffold.Files {
+		path, err := tm.scaffoldPath(dir, spec.Path, dirData)
+		if err != nil {
+			return nil, fmt.Errorf("scaffold %s: %v", args.Scaffold, err)
+		}
+		rel := tm.relPath(path)
+		if seen[path] {
+			return nil, fmt.Errorf("scaffold %s: two files fill in to %s", args.Scaffold, rel)
+		}
+		seen[path] = true
+
+		file := scaffoldFile{path: path}
+		info, err := tm.files.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("path is a directory: %s", rel)
+		case err == nil && !args.Overwrite:
+			return nil, errcode.New(errcode.Conflict, "file already exists: %s (set overwrite to replace it)", rel)
+		case err == nil:
+			file.exists = true
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("failed to stat file: %v", err)
+		}
+
+		data := tm.templateData(path, args.Vars)
+		if spec.Template != "" {
+			file.content, err = tm.renderTemplate(spec.Template, data)
+		} else {
+			file.content, err = executeTemplate(spec.Path, spec.Content, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scaffold %s: file %s: %v", args.Scaffold, rel, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// scaffoldPath fills in a path of a scaffold and resolves it in dir,
+// refusing paths that leave dir
+func (tm *ToolManager) scaffoldPath(dir, pattern string, data templateData) (string, error) {
+	rel, err := executeTemplate(pattern, pattern, data)
+	if err != nil {
+		return "", fmt.Errorf("path %q: %v", pattern, err)
+	}
+	if strings.TrimSpace(rel) == "" {
+		return "", fmt.Errorf("path %q fills in empty", pattern)
+	}
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	inDir, err := filepath.Rel(dir, path)
+	if err != nil || filepath.IsAbs(rel) || inDir == ".." || strings.HasPrefix(inDir, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q fills in outside the target directory: %s", pattern, rel)
+	}
+	return tm.resolveWritePath(path)
+}
+
+// scaffoldNames returns the configured scaffold names in order
+func (tm *ToolManager) scaffoldNames() []string {
+	names := make([]string, 0, len(tm.config.Scaffolds))
+	for name := range tm.config.Scaffolds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scaffoldSummary lists the scaffolds, their descriptions and variables for
+// the tool description
+func (tm *ToolManager) scaffoldSummary() string {
+	var parts []string
+	for _, name := range tm.scaffoldNames() {
+		scaffold := tm.config.Scaffolds[name]
+		part := name
+		if scaffold.Description != "" {
+			part += " (" + scaffold.Description + ")"
+		}
+		if len(scaffold.Vars) > 0 {
+			part += " with vars " + strings.Join(scaffold.Vars, ", ")
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/session"
+)
+
+// ScratchName is the top-level directory the sessions' scratch areas are
+// under
+const ScratchName = "scratch"
+
+// ScratchDirArgs are the arguments for the scratch_dir tool
+type ScratchDirArgs struct{}
+
+// scratchDirResult is the response of the scratch_dir tool
+type scratchDirResult struct {
+	Path string `json:"path"`
+	URI  string `json:"uri"`
+}
+
+// scratchArea gives each client session a private directory on disk
+type scratchArea struct {
+	root  string // temporary directory holding a directory per session
+	stdio string // session of a client on stdio, whose requests carry no ID
+}
+
+// EnableScratch creates a temporary directory for the sessions' scratch
+// areas and mounts it, hidden, at scratch/. Each area is named by its
+// session's unguessable ID, so sessions can't list or reach each other's.
+func (tm *ToolManager) EnableScratch() error {
+	root, err := os.MkdirTemp("", "mcp-filesystem-scratch-")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch directory: %v", err)
+	}
+	if err := tm.files.AttachHidden(ScratchName, fsys.OS(root)); err != nil {
+		os.RemoveAll(root)
+		return fmt.Errorf("failed to mount scratch directory: %v", err)
+	}
+	tm.scratch = &scratchArea{root: root, stdio: session.NewID()}
+	return nil
+}
+
+// EndSession forgets the quota usage and changelog of a client that
+// disconnected, releases the paths it locked and deletes its scratch area
+func (tm *ToolManager) EndSession(id string) {
+	tm.mu.Lock()
+	delete(tm.usage, id)
+	delete(tm.changes, id)
+	tm.mu.Unlock()
+	tm.locks.release(tm.locks.sessionPaths(id), true)
+
+	if tm.scratch == nil {
+		return
+	}
+	if err := os.RemoveAll(filepath.Join(tm.scratch.root, id)); err != nil {
+		log.Printf("Warning: failed to delete scratch area: %v", err)
+	}
+}
+
+// CloseScratch deletes every scratch area
+func (tm *ToolManager) CloseScratch() {
+	if tm.scratch == nil {
+		return
+	}
+	if err := os.RemoveAll(tm.scratch.root); err != nil {
+		log.Printf("Warning: failed to delete scratch directory: %v", err)
+	}
+}
+
+// handleScratchDir creates the calling session's scratch area if needed and
+// returns where it is
+func (tm *ToolManager) handleScratchDir(ctx context.Context, args ScratchDirArgs) (*mcp_golang.ToolResponse, error) {
+	if tm.scratch == nil {
+		return nil, fmt.Errorf("scratch areas aren't enabled")
+	}
+	id, ok := session.From(ctx)
+	if !ok {
+		id = tm.scratch.stdio
+	}
+	if err := os.MkdirAll(filepath.Join(tm.scratch.root, id), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create scratch area: %v", err)
+	}
+	rel := ScratchName + "/" + id
+	return jsonResponse(scratchDirResult{
+		Path: rel,
+		URI:  "workspace://" + rel + "/",
+	})
+}
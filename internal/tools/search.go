@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/index"
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+)
+
+// defaultMaxResults is how many matches the search tool returns by default
+const defaultMaxResults = 100
+
+// SearchArgs are the arguments for the search tool
+type SearchArgs struct {
+	Query      string `json:"query" jsonschema:"required,description=Text to find; a regular expression (RE2 syntax) when regex is set"`
+	Regex      bool   `json:"regex,omitempty" jsonschema:"description=Treat query as a regular expression"`
+	IgnoreCase bool   `json:"ignore_case,omitempty" jsonschema:"description=Match without regard to case"`
+	Glob       string `json:"glob,omitempty" jsonschema:"description=Only search workspace files matching this glob (such as src/**/*.ts)"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema:"description=Most matching lines to return (default 100)"`
+	Multiline  bool   `json:"multiline,omitempty" jsonschema:"description=Match the query against whole files so matches can span lines; ^ and $ match at line breaks and (?s) makes . match newlines"`
+	Kind       string `json:"kind,omitempty" jsonschema:"description=Match the query against the names of Go or JS/TS or Python definitions of this kind instead of file text: function (including methods) or method or class or type or interface or const or var or any"`
+}
+
+// definitionKinds are the kinds a search can match definitions of, and
+// the symbol kinds each takes in
+var definitionKinds = map[string][]string{
+	"function":  {symbols.KindFunction, symbols.KindMethod},
+	"method":    {symbols.KindMethod},
+	"class":     {symbols.KindClass},
+	"type":      {symbols.KindType},
+	"interface": {symbols.KindInterface},
+	"const":     {symbols.KindConst},
+	"var":       {symbols.KindVar},
+	"any":       nil,
+}
+
+// searchResult is the response of the search tool
+type searchResult struct {
+	Matches []searchMatch `json:"matches"`
+	// Truncated is set when more lines matched than max_results
+	Truncated bool `json:"truncated,omitempty"`
+	// FilesSearched counts the files read, after the index narrowed them down
+	FilesSearched int `json:"filesSearched"`
+	// Indexed is set when the search index picked the files to read
+	Indexed bool `json:"indexed"`
+}
+
+// searchMatch is a line containing a match, or the lines of a multiline
+// match or matching definition
+type searchMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	// EndLine is the last line of a match spanning several lines
+	EndLine int          `json:"endLine,omitempty"`
+	Text    string       `json:"text"`
+	Ranges  []matchRange `json:"ranges"`
+	// Symbol and Kind name a matching definition
+	Symbol string `json:"symbol,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+}
+
+// matchRange is where a match is in a file, for highlighting and editing.
+// Offsets are in the file's text as UTF-8, after decoding UTF-16 or Latin-1.
+type matchRange struct {
+	// Offset is the byte offset of the match from the start of the file
+	Offset int `json:"offset"`
+	// Column is the 1-based character column of the match in its line,
+	// before the line is trimmed into text
+	Column int `json:"column"`
+	// Length is the length of the match in bytes
+	Length int `json:"length"`
+	// Match is the matched text
+	Match string `json:"match"`
+}
+
+// SetIndex sets the search index the search tool narrows files with
+func (tm *ToolManager) SetIndex(idx *index.Index) {
+	tm.index = idx
+}
+
+// handleSearch finds the lines of workspace files matching a literal or
+// regular expression, using the search index to skip files that can't match.
+// In multiline mode the expression is matched against whole files, and with
+// a kind against the names of definitions.
+func (tm *ToolManager) handleSearch(args SearchArgs) (*mcp_golang.ToolResponse, error) {
+	if args.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+
+	kinds, ok := definitionKinds[args.Kind]
+	if args.Kind != "" && !ok {
+		return nil, fmt.Errorf("invalid kind: %s (expected function, method, class, type, interface, const, var or any)", args.Kind)
+	}
+
+	expr := queryExpr(args.Query, args.Regex, args.IgnoreCase)
+	if args.Multiline {
+		expr = "(?m)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	var glob *regexp.Regexp
+	if args.Glob != "" {
+		if glob, err = gitignore.CompileGlob(filepath.ToSlash(args.Glob)); err != nil {
+			return nil, err
+		}
+	}
+
+	result := searchResult{Matches: []searchMatch{}}
+	var files []string
+	if tm.index != nil {
+		literal := index.RequiredLiteral(parsed.Simplify())
+		if args.Kind != "" {
+			// Qualified names such as Server.Start aren't written that way
+			// in the source, but each of their parts is
+			literal = longestPart(literal, ".")
+		}
+		files, result.Indexed = tm.index.Candidates(literal)
+	}
+	if !result.Indexed {
+		if files, err = tm.workspaceFiles(); err != nil {
+			return nil, fmt.Errorf("failed to list workspace files: %v", err)
+		}
+	}
+
+	for _, path := range files {
+		if glob != nil && !glob.MatchString(tm.relPath(path)) {
+			continue
+		}
+		// The index may still hold files ignored since they were indexed
+		if tm.matcher.Excluded(path) {
+			continue
+		}
+		data, err := tm.readMaskedSource(path)
+		if err != nil || data == nil {
+			continue
+		}
+		result.FilesSearched++
+
+		var matches []searchMatch
+		switch {
+		case args.Kind != "":
+			matches = tm.definitionMatches(path, data, re, kinds)
+		case !re.Match(data):
+			continue
+		case args.Multiline:
+			matches = multilineMatches(data, re)
+		default:
+			matches = lineMatches(data, re)
+		}
+		for _, match := range matches {
+			if len(result.Matches) == maxResults {
+				result.Truncated = true
+				return jsonResponse(result)
+			}
+			match.Path = tm.relPath(path)
+			result.Matches = append(result.Matches, match)
+		}
+	}
+
+	return jsonResponse(result)
+}
+
+// lineMatches returns the lines of data containing a match
+func lineMatches(data []byte, re *regexp.Regexp) []searchMatch {
+	var matches []searchMatch
+	offset := 0
+	for number, line := range bytes.Split(data, []byte("\n")) {
+		start := offset
+		offset += len(line) + 1
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		found := re.FindAllIndex(line, -1)
+		if found == nil {
+			continue
+		}
+		matches = append(matches, searchMatch{
+			Line:   number + 1,
+			Text:   snippet(line),
+			Ranges: matchRanges(line, start, found),
+		})
+	}
+	return matches
+}
+
+// multilineMatches returns the matches of an expression against the whole
+// of data, each with the lines it spans
+func multilineMatches(data []byte, re *regexp.Regexp) []searchMatch {
+	var matches []searchMatch
+	line, counted := 1, 0
+	for _, loc := range re.FindAllIndex(data, -1) {
+		line += bytes.Count(data[counted:loc[0]], []byte("\n"))
+		counted = loc[0]
+		// The match ends on the line of its last byte, which may be a newline
+		last := max(loc[1]-1, loc[0])
+		start := bytes.LastIndexByte(data[:loc[0]], '\n') + 1
+		end := len(data)
+		if i := bytes.IndexByte(data[last:], '\n'); i >= 0 {
+			end = last + i
+		}
+		match := searchMatch{
+			Line: line,
+			Text: snippet(data[start:end]),
+			Ranges: []matchRange{{
+				Offset: loc[0],
+				Column: utf8.RuneCount(data[start:loc[0]]) + 1,
+				Length: loc[1] - loc[0],
+				Match:  string(data[loc[0]:loc[1]]),
+			}},
+		}
+		if spanned := bytes.Count(data[loc[0]:last], []byte("\n")); spanned > 0 {
+			match.EndLine = line + spanned
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// definitionMatches returns the definitions in a file of one of kinds, or
+// any kind if kinds is empty, whose name matches an expression
+func (tm *ToolManager) definitionMatches(path string, data []byte, re *regexp.Regexp, kinds []string) []searchMatch {
+	if !symbols.Supported(path) {
+		return nil
+	}
+	var defined []symbols.Symbol
+	cached := false
+	if tm.index != nil {
+		defined, cached = tm.index.Symbols(path)
+	}
+	if !cached {
+		defined = symbols.Parse(path, data)
+	}
+
+	var matches []searchMatch
+	var lineStarts []int
+	for _, symbol := range defined {
+		if len(kinds) > 0 && !slices.Contains(kinds, symbol.Kind) || !re.MatchString(symbol.Name) {
+			continue
+		}
+		if lineStarts == nil {
+			lineStarts = []int{0}
+			for i, c := range data {
+				if c == '\n' {
+					lineStarts = append(lineStarts, i+1)
+				}
+			}
+		}
+		// Spans of Go definitions start at their doc comment; report the
+		// line declaring the name
+		name := symbol.Name[strings.LastIndexByte(symbol.Name, '.')+1:]
+		number, line, column := declarationLine(data, lineStarts, symbol, name)
+		match := searchMatch{
+			Line:    number,
+			EndLine: symbol.EndLine,
+			Text:    snippet(line),
+			Ranges:  []matchRange{},
+			Symbol:  symbol.Name,
+			Kind:    symbol.Kind,
+		}
+		if column >= 0 {
+			match.Ranges = matchRanges(line, lineStarts[number-1], [][]int{{column, column + len(name)}})
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// declarationLine returns the number and text of the first line of a
+// definition naming it outside a comment, and the byte column of the name
+// in it, or -1 if no line does
+func declarationLine(data []byte, lineStarts []int, symbol symbols.Symbol, name string) (int, []byte, int) {
+	first := min(symbol.StartLine, len(lineStarts))
+	last := min(max(symbol.EndLine, first), len(lineStarts))
+	var firstLine []byte
+	for number := first; number <= last; number++ {
+		line := data[lineStarts[number-1]:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if number == first {
+			firstLine = line
+		}
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("//")) || bytes.HasPrefix(trimmed, []byte("#")) || bytes.HasPrefix(trimmed, []byte("*")) || bytes.HasPrefix(trimmed, []byte("/*")) {
+			continue
+		}
+		if i := bytes.Index(line, []byte(name)); i >= 0 {
+			return number, line, i
+		}
+	}
+	return first, firstLine, -1
+}
+
+// longestPart returns the longest of the parts of s split by sep
+func longestPart(s, sep string) string {
+	longest := ""
+	for _, part := range strings.Split(s, sep) {
+		if len(part) > len(longest) {
+			longest = part
+		}
+	}
+	return longest
+}
+
+// queryExpr returns the regular expression for a search query, which is
+// literal text unless regex is set
+func queryExpr(query string, regex, ignoreCase bool) string {
+	if !regex {
+		query = regexp.QuoteMeta(query)
+	}
+	if ignoreCase {
+		query = "(?i)" + query
+	}
+	return query
+}
+
+// matchRanges describes the matches found in a line starting at offset
+func matchRanges(line []byte, offset int, found [][]int) []matchRange {
+	ranges := make([]matchRange, 0, len(found))
+	for _, loc := range found {
+		ranges = append(ranges, matchRange{
+			Offset: offset + loc[0],
+			Column: utf8.RuneCount(line[:loc[0]]) + 1,
+			Length: loc[1] - loc[0],
+			Match:  string(line[loc[0]:loc[1]]),
+		})
+	}
+	return ranges
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/textio"
+)
+
+// maxLineBytes is how much of a line search_in_file matches; the rest of a
+// longer line is skipped
+const maxLineBytes = 1 << 20
+
+// sniffBytes is how much of the start of a file is checked for NUL bytes
+// to refuse binary files
+const sniffBytes = 8000
+
+// maxContextLines caps the context lines around each search_in_file match
+const maxContextLines = 20
+
+// SearchInFileArgs are the arguments for the search_in_file tool
+type SearchInFileArgs struct {
+	Path       string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to search"`
+	Query      string `json:"query" jsonschema:"required,description=Text to find; a regular expression (RE2 syntax) when regex is set"`
+	Regex      bool   `json:"regex,omitempty" jsonschema:"description=Treat query as a regular expression"`
+	IgnoreCase bool   `json:"ignore_case,omitempty" jsonschema:"description=Match without regard to case"`
+	MaxMatches int    `json:"max_matches,omitempty" jsonschema:"description=Most matching lines to return (default 100)"`
+	Context    int    `json:"context,omitempty" jsonschema:"description=Lines to show before and after each match (at most 20)"`
+	StartLine  int    `json:"start_line,omitempty" jsonschema:"description=Line to start scanning at (1-based) to continue after a truncated search"`
+}
+
+// searchInFileResult is the response of the search_in_file tool
+type searchInFileResult struct {
+	Path    string      `json:"path"`
+	Matches []fileMatch `json:"matches"`
+	// LinesScanned and BytesScanned count what was read from StartLine on
+	LinesScanned int   `json:"linesScanned"`
+	BytesScanned int64 `json:"bytesScanned"`
+	// Truncated is set when max_matches ended the scan early; continue
+	// from NextLine
+	Truncated bool `json:"truncated,omitempty"`
+	NextLine  int  `json:"nextLine,omitempty"`
+}
+
+// fileMatch is a matching line of a file with the lines around it
+type fileMatch struct {
+	Line   int          `json:"line"`
+	Text   string       `json:"text"`
+	Ranges []matchRange `json:"ranges"`
+	Before []string     `json:"before,omitempty"`
+	After  []string     `json:"after,omitempty"`
+}
+
+// handleSearchInFile scans one file line by line for a literal or regular
+// expression. The file is streamed rather than read whole, so logs of
+// hundreds of megabytes can be searched before reading ranges of them.
+func (tm *ToolManager) handleSearchInFile(ctx context.Context, args SearchInFileArgs) (*mcp_golang.ToolResponse, error) {
+	if args.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	re, err := regexp.Compile(queryExpr(args.Query, args.Regex, args.IgnoreCase))
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	maxMatches := args.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = defaultMaxResults
+	}
+	contextLines := min(max(args.Context, 0), maxContextLines)
+	startLine := max(args.StartLine, 1)
+
+	path, err := tm.resolveExistingPath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if tm.matcher.Excluded(path) {
+		return nil, fmt.Errorf("path is ignored: %s", args.Path)
+	}
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(textio.NewReader(file), 64<<10)
+	if head, _ := reader.Peek(sniffBytes); bytes.IndexByte(head, 0) >= 0 {
+		return nil, errcode.Remediate(errcode.Binary, "use read_file_range with blob=true to read its bytes", "file is binary: %s", args.Path)
+	}
+
+	result := searchInFileResult{Path: tm.relPath(path), Matches: []fileMatch{}}
+	masked := tm.masked(path)
+	var before []string // the last lines before the current one, for context
+	var open []int      // matches still collecting lines after them
+	offset := 0
+	for number := 1; ; number++ {
+		if number%10000 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		raw, n, err := readLine(reader, maxLineBytes)
+		if n == 0 && err != nil {
+			if err != io.EOF {
+				return nil, fmt.Errorf("failed to read file: %v", err)
+			}
+			break
+		}
+		start := offset
+		offset += n
+		if number < startLine {
+			continue
+		}
+		result.LinesScanned++
+		result.BytesScanned += int64(n)
+
+		line := bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+		if decoded := textio.Decode(line); decoded != nil {
+			line = decoded
+		}
+		if masked {
+			line = []byte(dotenv.MaskLine(string(line)))
+		}
+		text := snippet(line)
+
+		// This line is context after earlier matches
+		kept := open[:0]
+		for _, i := range open {
+			result.Matches[i].After = append(result.Matches[i].After, text)
+			if len(result.Matches[i].After) < contextLines {
+				kept = append(kept, i)
+			}
+		}
+		open = kept
+
+		if result.Truncated {
+			if len(open) == 0 {
+				break
+			}
+			continue
+		}
+
+		if found := re.FindAllIndex(line, -1); found != nil {
+			if len(result.Matches) == maxMatches {
+				result.Truncated = true
+				result.NextLine = number
+				if len(open) == 0 {
+					break
+				}
+				continue
+			}
+			result.Matches = append(result.Matches, fileMatch{
+				Line:   number,
+				Text:   text,
+				Ranges: matchRanges(line, start, found),
+				Before: append([]string(nil), before...),
+			})
+			if contextLines > 0 {
+				open = append(open, len(result.Matches)-1)
+			}
+		}
+
+		if contextLines > 0 {
+			before = append(before, text)
+			if len(before) > contextLines {
+				before = before[1:]
+			}
+		}
+	}
+
+	return jsonResponse(result)
+}
+
+// readLine reads a line with its newline, keeping at most limit bytes of
+// it; n counts every byte read
+func readLine(reader *bufio.Reader, limit int) (line []byte, n int, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		n += len(chunk)
+		if room := limit - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, n, err
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// RevertLastOperationArgs are the arguments for the revert_last_operation tool
+type RevertLastOperationArgs struct{}
+
+// operation is the git snapshot taken before a tool modified files
+type operation struct {
+	tool   string
+	repo   gitignore.Repository
+	commit string // commit holding the working tree state before the operation
+	ref    string // stash entry message or tag naming the snapshot
+	files  []snapshotFile
+}
+
+// snapshotFile is the state of a file before an operation
+type snapshotFile struct {
+	path    string
+	existed bool
+	tracked bool // present in the snapshot commit, so it can be restored from it
+}
+
+// revertResult is the response of the revert_last_operation tool
+type revertResult struct {
+	Tool       string   `json:"tool"`
+	Snapshot   string   `json:"snapshot"`
+	Restored   []string `json:"restored"`
+	Removed    []string `json:"removed"`
+	Unrestored []string `json:"unrestored,omitempty"`
+}
+
+// snapshot saves the state of the repository containing paths before a tool
+// modifies them, as configured. It returns nil when snapshots are off or the
+// files aren't in a repository.
+func (tm *ToolManager) snapshot(tool string, paths ...string) (*operation, error) {
+	mode := tm.config.Git.Snapshots
+	if mode == "" || mode == config.SnapshotOff || len(paths) == 0 {
+		return nil, nil
+	}
+	repo, ok := tm.matcher.RepositoryFor(paths[0])
+	if !ok {
+		return nil, nil
+	}
+
+	message := fmt.Sprintf("mcp-filesystem: before %s", tool)
+	output, err := runGit(repo.Path, "stash", "create", message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot repository: %v", err)
+	}
+	op := &operation{tool: tool, repo: repo, commit: strings.TrimSpace(output)}
+
+	switch {
+	case op.commit == "":
+		// A clean working tree is already recorded by HEAD
+		head, err := runGit(repo.Path, "rev-parse", "--verify", "HEAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot repository: %v", err)
+		}
+		op.commit = strings.TrimSpace(head)
+		op.ref = op.commit[:min(len(op.commit), 12)]
+	case mode == config.SnapshotStash:
+		if _, err := runGit(repo.Path, "stash", "store", "-m", message, op.commit); err != nil {
+			return nil, fmt.Errorf("failed to store snapshot: %v", err)
+		}
+		op.ref = "stash: " + message
+	case mode == config.SnapshotTag:
+		op.ref = fmt.Sprintf("mcp-filesystem/before-%s-%d", tool, time.Now().UnixNano())
+		if _, err := runGit(repo.Path, "tag", op.ref, op.commit); err != nil {
+			return nil, fmt.Errorf("failed to tag snapshot: %v", err)
+		}
+	}
+
+	for _, path := range paths {
+		file := snapshotFile{path: path}
+		if _, err := os.Lstat(path); err == nil {
+			file.existed = true
+		}
+		if rel, err := filepath.Rel(repo.GitRoot, path); err == nil {
+			_, err := runGit(repo.Path, "cat-file", "-e", op.commit+":"+filepath.ToSlash(rel))
+			file.tracked = err == nil
+		}
+		op.files = append(op.files, file)
+	}
+
+	if tm.debug {
+		log.Printf("Snapshot before %s: %s (%s)", tool, op.ref, op.commit)
+	}
+	return op, nil
+}
+
+// recordOperation makes op the operation revert_last_operation undoes
+func (tm *ToolManager) recordOperation(op *operation) {
+	if op == nil {
+		return
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.lastOperation = op
+}
+
+// handleRevertLastOperation restores the files changed by the last tool
+// operation from the snapshot taken before it
+func (tm *ToolManager) handleRevertLastOperation(ctx context.Context, args RevertLastOperationArgs) (*mcp_golang.ToolResponse, error) {
+	tm.mu.Lock()
+	op := tm.lastOperation
+	tm.lastOperation = nil
+	tm.mu.Unlock()
+	if op == nil {
+		return nil, fmt.Errorf("there is no operation to revert")
+	}
+
+	paths := make([]string, 0, len(op.files))
+	for _, file := range op.files {
+		paths = append(paths, file.path)
+	}
+	unlock, err := tm.lockForChange(ctx, paths...)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := revertResult{Tool: op.tool, Snapshot: op.ref, Restored: []string{}, Removed: []string{}}
+	for _, file := range op.files {
+		rel := tm.relPath(file.path)
+		before := tm.beforeChange(file.path)
+		switch {
+		case file.tracked:
+			gitRel, err := filepath.Rel(op.repo.Path, file.path)
+			if err == nil {
+				_, err = runGit(op.repo.Path, "restore", "--source="+op.commit, "--worktree", "--", filepath.ToSlash(gitRel))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore %s: %v", rel, err)
+			}
+			result.Restored = append(result.Restored, rel)
+		case !file.existed:
+			if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove %s: %v", rel, err)
+			}
+			result.Removed = append(result.Removed, rel)
+		default:
+			// Untracked files aren't part of git snapshots
+			result.Unrestored = append(result.Unrestored, rel)
+		}
+		tm.mu.Lock()
+		delete(tm.written, file.path)
+		tm.mu.Unlock()
+		tm.recordChange(ctx, "revert_last_operation", before)
+	}
+
+	return jsonResponse(result)
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/index"
+	"github.com/isaacphi/mcp-filesystem/internal/memory"
+)
+
+// StatusArgs are the arguments for the status tool
+type StatusArgs struct{}
+
+// statusResult is the response of the status tool
+type statusResult struct {
+	Uptime string        `json:"uptime"`
+	Index  *index.Status `json:"index,omitempty"`
+	Memory *memory.Usage `json:"memory,omitempty"`
+}
+
+// SetMemoryMonitor sets the monitor whose memory use the status tool reports
+func (tm *ToolManager) SetMemoryMonitor(monitor *memory.Monitor) {
+	tm.memory = monitor
+}
+
+// handleStatus reports how long the server has run, how far background
+// indexing has got and how much memory is in use
+func (tm *ToolManager) handleStatus(args StatusArgs) (*mcp_golang.ToolResponse, error) {
+	result := statusResult{Uptime: time.Since(tm.started).Round(time.Second).String()}
+	if tm.index != nil {
+		status := tm.index.Status()
+		result.Index = &status
+	}
+	if tm.memory != nil {
+		usage := tm.memory.Usage()
+		result.Memory = &usage
+	}
+	return jsonResponse(result)
+}
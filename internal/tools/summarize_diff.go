@@ -0,0 +1,434 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/symbols"
+)
+
+// SummarizeDiffArgs are the arguments for the summarize_diff tool
+type SummarizeDiffArgs struct {
+	Diff string `json:"diff,omitempty" jsonschema:"description=Unified diff to summarize with paths relative to the workspace such as git diff output; it is taken to apply to the files in the workspace. Omit to diff revisions"`
+	From string `json:"from,omitempty" jsonschema:"description=Revision to diff from when no diff is given (default: HEAD)"`
+	To   string `json:"to,omitempty" jsonschema:"description=Revision to diff to (default: the working tree)"`
+	Path string `json:"path,omitempty" jsonschema:"description=Workspace-relative file or directory to limit the revision diff to (default: every repository)"`
+}
+
+// summarizeDiffResult is the response of the summarize_diff tool
+type summarizeDiffResult struct {
+	Files []fileSummary `json:"files"`
+}
+
+// fileSummary is what a diff changes in one file
+type fileSummary struct {
+	Path         string `json:"path"`
+	OrigPath     string `json:"origPath,omitempty"`
+	Change       string `json:"change"`
+	LinesAdded   int    `json:"linesAdded"`
+	LinesRemoved int    `json:"linesRemoved"`
+	// Symbols are the definitions containing changed lines, in the order
+	// they are defined
+	Symbols []touchedSymbol `json:"symbols,omitempty"`
+	// OutsideSymbols counts changed lines outside any definition, such as
+	// imports and package clauses
+	OutsideSymbols int  `json:"outsideSymbols,omitempty"`
+	Binary         bool `json:"binary,omitempty"`
+	Withheld       bool `json:"withheld,omitempty"`
+}
+
+// touchedSymbol is a definition a diff changes
+type touchedSymbol struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Change string `json:"change"`
+	// Lines counts the added and removed lines in the definition
+	Lines int `json:"lines"`
+	line  int // where it is defined, to order symbols
+}
+
+// Kinds of change to a file or symbol in a diff summary, besides
+// changeModified
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeRenamed = "renamed"
+)
+
+// fileDiff is the diff of one file parsed from a unified diff
+type fileDiff struct {
+	oldPath, newPath string // "" for /dev/null
+	binary           bool
+	removed, added   []int // changed line numbers in the old and new file
+	// removedAfter is the line in the new file each removed line follows,
+	// where a deletion is placed when only the new file is known
+	removedAfter []int
+}
+
+// diffSide is one version of a file a diff compares, read to find the
+// definitions its changed lines are in
+type diffSide struct {
+	rev  string // "" for the working tree
+	path string // absolute
+}
+
+// handleSummarizeDiff reports which definitions a diff touches instead of
+// its hunks
+func (tm *ToolManager) handleSummarizeDiff(args SummarizeDiffArgs) (*mcp_golang.ToolResponse, error) {
+	result := summarizeDiffResult{Files: []fileSummary{}}
+
+	if args.Diff != "" {
+		if args.From != "" || args.To != "" || args.Path != "" {
+			return nil, fmt.Errorf("give either a diff or revisions to diff, not both")
+		}
+		// Only the files the diff applies to are known, not the ones it
+		// applies against
+		for _, file := range parseUnifiedDiff(args.Diff) {
+			summary, ok, err := tm.summarizeFile(file, tm.workspacePath, nil, &diffSide{})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result.Files = append(result.Files, summary)
+			}
+		}
+		return jsonResponse(result)
+	}
+
+	from := args.From
+	if from == "" {
+		from = "HEAD"
+	}
+	for _, rev := range []string{from, args.To} {
+		if strings.HasPrefix(rev, "-") {
+			return nil, fmt.Errorf("invalid revision: %s", rev)
+		}
+	}
+	repos, pathspec, err := tm.gitScope(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	gitArgs := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames", "--unified=0", from}
+	if args.To != "" {
+		gitArgs = append(gitArgs, args.To)
+	}
+	gitArgs = append(gitArgs, "--", pathspec)
+
+	for _, repo := range repos {
+		output, err := runGit(repo.Path, gitArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range parseUnifiedDiff(output) {
+			// Changes in nested repositories are theirs to report
+			if owner, ok := tm.matcher.RepositoryFor(filepath.Join(repo.GitRoot, filepath.FromSlash(file.path()))); !ok || owner.Path != repo.Path {
+				continue
+			}
+			summary, ok, err := tm.summarizeFile(file, repo.GitRoot, &diffSide{rev: from}, &diffSide{rev: args.To})
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result.Files = append(result.Files, summary)
+			}
+		}
+	}
+
+	return jsonResponse(result)
+}
+
+// summarizeFile maps a file's changed lines to the definitions they are in.
+// Paths in the diff are relative to root. Old is nil when the version the
+// diff applies against is unknown; deletions are then placed by where they
+// land in the new version. It returns false for files that may not be seen.
+func (tm *ToolManager) summarizeFile(file fileDiff, root string, old, new *diffSide) (fileSummary, bool, error) {
+	absPath, err := tm.resolvePath(filepath.Join(root, filepath.FromSlash(file.path())))
+	if err != nil {
+		return fileSummary{}, false, err
+	}
+	if tm.matcher.CanStat(absPath) != nil {
+		return fileSummary{}, false, nil
+	}
+
+	summary := fileSummary{
+		Path:         tm.relPath(absPath),
+		Change:       changeModified,
+		LinesAdded:   len(file.added),
+		LinesRemoved: len(file.removed),
+		Binary:       file.binary,
+	}
+	switch {
+	case file.oldPath == "":
+		summary.Change = changeAdded
+	case file.newPath == "":
+		summary.Change = changeRemoved
+	case file.oldPath != file.newPath:
+		summary.Change = changeRenamed
+		summary.OrigPath = tm.relPath(filepath.Join(root, filepath.FromSlash(file.oldPath)))
+	}
+	if tm.matcher.CanRead(absPath) != nil {
+		summary.Withheld = true
+		return summary, true, nil
+	}
+	if file.binary || !symbols.Supported(absPath) {
+		return summary, true, nil
+	}
+
+	var oldSymbols, newSymbols []symbols.Symbol
+	if file.newPath != "" {
+		new.path = absPath
+		newSymbols = tm.sideSymbols(new, root)
+	}
+	if old != nil && file.oldPath != "" {
+		old.path = filepath.Join(root, filepath.FromSlash(file.oldPath))
+		oldSymbols = tm.sideSymbols(old, root)
+	}
+
+	touched := make(map[string]*touchedSymbol)
+	count := func(defined []symbols.Symbol, lines []int) {
+		for _, line := range lines {
+			symbol, ok := innermostSymbol(defined, line)
+			if !ok {
+				summary.OutsideSymbols++
+				continue
+			}
+			key := symbol.Kind + " " + symbol.Name
+			if touched[key] == nil {
+				touched[key] = &touchedSymbol{Name: symbol.Name, Kind: symbol.Kind, Change: changeModified, line: symbol.StartLine}
+			}
+			touched[key].Lines++
+		}
+	}
+	count(newSymbols, file.added)
+	if old == nil {
+		count(newSymbols, file.removedAfter)
+	} else {
+		count(oldSymbols, file.removed)
+	}
+
+	for key, symbol := range touched {
+		inOld, inNew := hasSymbol(oldSymbols, key), hasSymbol(newSymbols, key)
+		switch {
+		case old != nil && inOld && !inNew:
+			symbol.Change = changeRemoved
+		case old != nil && inNew && !inOld:
+			symbol.Change = changeAdded
+		case old == nil && file.addsAll(newSymbols, key):
+			symbol.Change = changeAdded
+		}
+		summary.Symbols = append(summary.Symbols, *symbol)
+	}
+	sort.SliceStable(summary.Symbols, func(i, j int) bool {
+		return summary.Symbols[i].line < summary.Symbols[j].line
+	})
+	return summary, true, nil
+}
+
+// sideSymbols returns the definitions in one version of a file, or none if
+// it can't be read
+func (tm *ToolManager) sideSymbols(side *diffSide, root string) []symbols.Symbol {
+	if side.rev == "" {
+		if tm.index != nil {
+			if defined, ok := tm.index.Symbols(side.path); ok {
+				return defined
+			}
+		}
+		data, err := tm.files.ReadFile(side.path)
+		if err != nil {
+			return nil
+		}
+		return symbols.Parse(side.path, data)
+	}
+	rel, err := filepath.Rel(root, side.path)
+	if err != nil {
+		return nil
+	}
+	data, err := runGit(root, "show", side.rev+":"+filepath.ToSlash(rel))
+	if err != nil {
+		return nil
+	}
+	return symbols.Parse(side.path, []byte(data))
+}
+
+// innermostSymbol returns the shortest definition containing a line, so a
+// method is chosen over its class
+func innermostSymbol(defined []symbols.Symbol, line int) (symbols.Symbol, bool) {
+	var best symbols.Symbol
+	found := false
+	for _, symbol := range defined {
+		if line < symbol.StartLine || line > symbol.EndLine {
+			continue
+		}
+		if !found || symbol.EndLine-symbol.StartLine < best.EndLine-best.StartLine {
+			best, found = symbol, true
+		}
+	}
+	return best, found
+}
+
+// hasSymbol reports whether a "kind name" key is defined
+func hasSymbol(defined []symbols.Symbol, key string) bool {
+	for _, symbol := range defined {
+		if symbol.Kind+" "+symbol.Name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// path returns the path a file diff is reported under: its new path, or
+// its old one if it was deleted
+func (f fileDiff) path() string {
+	if f.newPath != "" {
+		return f.newPath
+	}
+	return f.oldPath
+}
+
+// addsAll reports whether every line of a definition in the new file was
+// added
+func (f fileDiff) addsAll(defined []symbols.Symbol, key string) bool {
+	added := make(map[int]bool, len(f.added))
+	for _, line := range f.added {
+		added[line] = true
+	}
+	for _, symbol := range defined {
+		if symbol.Kind+" "+symbol.Name != key {
+			continue
+		}
+		for line := symbol.StartLine; line <= symbol.EndLine; line++ {
+			if !added[line] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseUnifiedDiff splits a unified diff, with or without git's headers,
+// into the lines changed in each file
+func parseUnifiedDiff(text string) []fileDiff {
+	var files []fileDiff
+	var file *fileDiff
+	// Lines of the current hunk still to come; lines outside hunks are
+	// headers even if they start with "-" or "+"
+	oldLine, newLine, oldLeft, newLeft := 0, 0, 0, 0
+
+	hunk := func() bool { return file != nil && (oldLeft > 0 || newLeft > 0) }
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, fileDiff{})
+			file = &files[len(files)-1]
+			oldLeft, newLeft = 0, 0
+			if path := diffHeaderPath(strings.TrimPrefix(line, "diff --git ")); path != "" {
+				file.oldPath, file.newPath = path, path
+			}
+		case hunk() && strings.HasPrefix(line, "-"):
+			file.removed = append(file.removed, oldLine)
+			file.removedAfter = append(file.removedAfter, max(newLine-1, 1))
+			oldLine++
+			oldLeft--
+		case hunk() && strings.HasPrefix(line, "+"):
+			file.added = append(file.added, newLine)
+			newLine++
+			newLeft--
+		case hunk() && strings.HasPrefix(line, " "):
+			oldLine++
+			newLine++
+			oldLeft--
+			newLeft--
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+		case strings.HasPrefix(line, "--- "):
+			// Without git's headers, each file starts here
+			if file == nil || len(file.added) > 0 || len(file.removed) > 0 {
+				files = append(files, fileDiff{})
+				file = &files[len(files)-1]
+			}
+			file.oldPath = diffFilePath(strings.TrimPrefix(line, "--- "), "a/")
+		case strings.HasPrefix(line, "+++ ") && file != nil:
+			file.newPath = diffFilePath(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ ") && file != nil:
+			oldLine, oldLeft, newLine, newLeft = parseHunkHeader(line)
+		case file == nil:
+			continue
+		case strings.HasPrefix(line, "new file mode"):
+			file.oldPath = ""
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.newPath = ""
+		case strings.HasPrefix(line, "rename from "):
+			file.oldPath = unquoteDiffPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.newPath = unquoteDiffPath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.binary = true
+		}
+	}
+
+	var parsed []fileDiff
+	for _, file := range files {
+		if file.path() != "" {
+			parsed = append(parsed, file)
+		}
+	}
+	return parsed
+}
+
+// diffFilePath returns the path of a "---" or "+++" line, without its
+// a/ or b/ prefix and any timestamp, or "" for /dev/null
+func diffFilePath(field, prefix string) string {
+	if i := strings.IndexByte(field, '\t'); i >= 0 {
+		field = field[:i]
+	}
+	path := unquoteDiffPath(field)
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// unquoteDiffPath decodes a path git quoted for having unusual characters
+func unquoteDiffPath(path string) string {
+	if unquoted, err := strconv.Unquote(path); err == nil {
+		return unquoted
+	}
+	return path
+}
+
+// parseHunkHeader parses the start and length of the old and new ranges of
+// a "@@ -a,b +c,d @@" header. An empty range starts after the line given.
+func parseHunkHeader(header string) (oldStart, oldCount, newStart, newCount int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0, 0, 0
+	}
+	parse := func(field, sign string) (int, int) {
+		start, count, hasCount := strings.Cut(strings.TrimPrefix(field, sign), ",")
+		n, err := strconv.Atoi(start)
+		if err != nil {
+			return 0, 0
+		}
+		lines := 1
+		if hasCount {
+			if lines, err = strconv.Atoi(count); err != nil {
+				return 0, 0
+			}
+		}
+		if lines == 0 {
+			n++
+		}
+		return n, lines
+	}
+	oldStart, oldCount = parse(fields[1], "-")
+	newStart, newCount = parse(fields[2], "+")
+	return oldStart, oldCount, newStart, newCount
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// CreateFromTemplateArgs are the arguments for the create_from_template tool
+type CreateFromTemplateArgs struct {
+	Path      string            `json:"path" jsonschema:"required,description=Workspace-relative path of the file to create"`
+	Template  string            `json:"template" jsonschema:"required,description=Name of a template from the server config"`
+	Vars      map[string]string `json:"vars,omitempty" jsonschema:"description=Values for template variables referenced as {{.Vars.name}}"`
+	Overwrite bool              `json:"overwrite,omitempty" jsonschema:"description=Replace the file if it already exists"`
+}
+
+// createFromTemplateResult is the response of the create_from_template tool
+type createFromTemplateResult struct {
+	Path     string        `json:"path"`
+	Template string        `json:"template"`
+	Bytes    int           `json:"bytes"`
+	Format   *formatResult `json:"format,omitempty"`
+	// Simulated is set with --simulate, when the file was only written in
+	// memory
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// templateData is what templates can refer to
+type templateData struct {
+	Path    string            // workspace-relative path of the new file
+	Dir     string            // its directory, "." for the workspace root
+	Name    string            // its file name
+	Stem    string            // its file name without the extension
+	Ext     string            // its extension, such as ".go"
+	Package string            // Go package name for the directory
+	Year    int               // current year, for license headers
+	Date    string            // current date as YYYY-MM-DD
+	Vars    map[string]string // values passed with the call
+}
+
+// handleCreateFromTemplate creates a file from a configured template
+func (tm *ToolManager) handleCreateFromTemplate(ctx context.Context, args CreateFromTemplateArgs) (*mcp_golang.ToolResponse, error) {
+	if _, ok := tm.config.Templates[args.Template]; !ok {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", args.Template, strings.Join(tm.templateNames(), ", "))
+	}
+
+	path, err := tm.resolveWritePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := tm.files.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return nil, fmt.Errorf("path is a directory: %s", args.Path)
+		}
+		if !args.Overwrite {
+			return nil, errcode.New(errcode.Conflict, "file already exists: %s (set overwrite to replace it)", args.Path)
+		}
+	}
+
+	rel := tm.relPath(path)
+	content, err := tm.renderTemplate(args.Template, tm.templateData(path, args.Vars))
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := tm.checkUnlocked(path); err != nil {
+		return nil, err
+	}
+
+	size := int64(len(content))
+	release, err := tm.reserveQuota(ctx, quotaWrite{path: path, bytes: size, size: size, created: info == nil})
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := tm.snapshot("create_from_template", path)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	before := tm.beforeChange(path)
+	if err := tm.files.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		release()
+		return nil, errcode.Wrapf(err, "failed to create parent directories: %v", err)
+	}
+	if err := tm.files.WriteFile(path, []byte(content), 0644); err != nil {
+		release()
+		err = explainWriteError(path, err)
+		return nil, errcode.Wrapf(err, "failed to write file: %v", err)
+	}
+
+	result := createFromTemplateResult{
+		Path:      rel,
+		Template:  args.Template,
+		Bytes:     len(content),
+		Format:    tm.formatFile(path),
+		Simulated: tm.config.Simulate,
+	}
+	tm.recordWrite(path)
+	tm.recordOperation(op)
+	tm.recordChange(ctx, "create_from_template", before)
+
+	return jsonResponse(result)
+}
+
+// templateData returns what templates creating the file at path refer to
+func (tm *ToolManager) templateData(path string, vars map[string]string) templateData {
+	now := time.Now()
+	rel := tm.relPath(path)
+	data := templateData{
+		Path:    rel,
+		Dir:     filepath.ToSlash(filepath.Dir(rel)),
+		Name:    filepath.Base(path),
+		Stem:    strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Ext:     filepath.Ext(path),
+		Package: tm.goPackageName(path),
+		Year:    now.Year(),
+		Date:    now.Format("2006-01-02"),
+		Vars:    vars,
+	}
+	if data.Vars == nil {
+		data.Vars = map[string]string{}
+	}
+	return data
+}
+
+// renderTemplate fills in a template and the header template it names
+func (tm *ToolManager) renderTemplate(name string, data templateData) (string, error) {
+	tmpl := tm.config.Templates[name]
+
+	var out bytes.Buffer
+	if tmpl.Header != "" {
+		header, err := tm.renderTemplate(tmpl.Header, data)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(header)
+	}
+
+	text := tmpl.Content
+	if tmpl.File != "" {
+		file, err := tm.resolveExistingPath(tmpl.File)
+		if err != nil {
+			return "", fmt.Errorf("template %s: %v", name, err)
+		}
+		raw, err := tm.files.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("template %s: failed to read template file: %v", name, err)
+		}
+		text = string(raw)
+	}
+
+	body, err := executeTemplate(name, text, data)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %v", name, err)
+	}
+	out.WriteString(body)
+	return out.String(), nil
+}
+
+// executeTemplate fills in template text, failing on variables the data
+// doesn't have
+func executeTemplate(name, text string, data templateData) (string, error) {
+	parsed, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := parsed.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// templateNames returns the configured template names in order
+func (tm *ToolManager) templateNames() []string {
+	names := make([]string, 0, len(tm.config.Templates))
+	for name := range tm.config.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateSummary lists the templates and their descriptions for the tool
+// description
+func (tm *ToolManager) templateSummary() string {
+	var parts []string
+	for _, name := range tm.templateNames() {
+		if description := tm.config.Templates[name].Description; description != "" {
+			parts = append(parts, fmt.Sprintf("%s (%s)", name, description))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// goPackageName infers the package of a Go file from the other Go files in
+// its directory, falling back to the directory name
+func (tm *ToolManager) goPackageName(path string) string {
+	dir := filepath.Dir(path)
+	entries, _ := tm.files.ReadDir(dir)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if pkg := tm.readPackageClause(filepath.Join(dir, name)); pkg != "" {
+			return pkg
+		}
+	}
+
+	pkg := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, filepath.Base(dir))
+	if pkg == "" || unicode.IsDigit(rune(pkg[0])) {
+		pkg = "_" + pkg
+	}
+	return pkg
+}
+
+// readPackageClause returns the package named by a Go file's package clause
+func (tm *ToolManager) readPackageClause(path string) string {
+	file, err := tm.files.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if pkg, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "package "); ok {
+			pkg, _, _ = strings.Cut(strings.TrimSpace(pkg), " ")
+			return pkg
+		}
+	}
+	return ""
+}
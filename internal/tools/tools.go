@@ -0,0 +1,549 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/dotenv"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/index"
+	"github.com/isaacphi/mcp-filesystem/internal/memory"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// ToolManager registers and serves the filesystem tools
+type ToolManager struct {
+	workspacePath string
+	files         *fsys.Workspace
+	config        *config.Config
+	matcher       *gitignore.Matcher
+	written       map[string]string // files written by tools, to their state afterwards
+	pauser        EventPauser
+	reads         ReadRecorder
+	applier       ChangeApplier
+	uris          URIResolver
+	auditor       Auditor
+	lastOperation *operation // undone by revert_last_operation
+	index         *index.Index
+	memory        *memory.Monitor
+	scratch       *scratchArea            // nil unless scratch areas are enabled
+	usage         map[string]*quotaUsage  // by session
+	changes       map[string][]fileChange // by session
+	locks         *pathLocks
+	registered    []string // names of the tools offered, in registration order
+	started       time.Time
+	mu            sync.Mutex
+	debug         bool
+}
+
+// NewToolManager creates a new tool manager for the files of a workspace
+func NewToolManager(files *fsys.Workspace, cfg *config.Config, matcher *gitignore.Matcher, debug bool) *ToolManager {
+	return &ToolManager{
+		workspacePath: files.Root(),
+		files:         files,
+		config:        cfg,
+		matcher:       matcher,
+		written:       make(map[string]string),
+		usage:         make(map[string]*quotaUsage),
+		changes:       make(map[string][]fileChange),
+		locks:         newPathLocks(),
+		started:       time.Now(),
+		debug:         debug,
+	}
+}
+
+// EventPauser suspends processing of file change events while a tool
+// rewrites many files at once
+type EventPauser interface {
+	PauseEvents()
+	ResumeEvents()
+}
+
+// SetEventPauser sets what bulk operations such as branch checkouts pause
+func (tm *ToolManager) SetEventPauser(pauser EventPauser) {
+	tm.pauser = pauser
+}
+
+// ReadRecorder counts the files clients read, for the hot files resource
+type ReadRecorder interface {
+	RecordRead(path string)
+}
+
+// SetReadRecorder sets what the reading tools report the files they read to
+func (tm *ToolManager) SetReadRecorder(reads ReadRecorder) {
+	tm.reads = reads
+}
+
+// ChangeApplier brings resources and the search index up to date with a
+// file a tool changed, without waiting for the file watcher
+type ChangeApplier interface {
+	FileChanged(path string)
+}
+
+// SetChangeApplier sets what tools report the files they change to as soon
+// as they change them, so the session reads its own writes
+func (tm *ToolManager) SetChangeApplier(applier ChangeApplier) {
+	tm.applier = applier
+}
+
+// URIResolver maps resource URIs to the files they name
+type URIResolver interface {
+	PathFromURI(uri string) (string, bool)
+}
+
+// SetURIResolver lets tools take resource URIs, as listed by the server,
+// wherever they take paths
+func (tm *ToolManager) SetURIResolver(uris URIResolver) {
+	tm.uris = uris
+}
+
+// recordRead reports a file read by a tool. Scratch files are private to a
+// session, so they aren't reported.
+func (tm *ToolManager) recordRead(path string) {
+	if tm.reads == nil {
+		return
+	}
+	if mount, _, ok := tm.files.MountPoint(path); ok && mount == ScratchName {
+		return
+	}
+	tm.reads.RecordRead(path)
+}
+
+// pauseEvents pauses file events if a pauser is set and returns the
+// function that resumes them
+func (tm *ToolManager) pauseEvents() func() {
+	if tm.pauser == nil {
+		return func() {}
+	}
+	tm.pauser.PauseEvents()
+	return tm.pauser.ResumeEvents
+}
+
+// diskOnlyTools need the workspace on the OS filesystem: they run git or
+// inspect OS file attributes
+var diskOnlyTools = map[string]bool{
+	"workspace_info":    true,
+	"dependency_graph":  true,
+	"list_dependencies": true,
+	"find_tests_for":    true,
+	"touch_file":        true,
+	"append_to_file":    true,
+	"stat":              true,
+	"set_permissions":   true,
+	"git_status":        true,
+	"merge_file":        true,
+	"git_diff":          true,
+	"summarize_diff":    true,
+	"check_file":        true,
+}
+
+// writeTools change files, so they aren't offered for read-only workspaces
+var writeTools = map[string]bool{
+	"write_file":           true,
+	"touch_file":           true,
+	"append_to_file":       true,
+	"set_permissions":      true,
+	"create_from_template": true,
+	"scaffold_project":     true,
+	"edit_structured":      true,
+}
+
+// toolSpec is a tool as registered with the MCP server
+type toolSpec struct {
+	name        string
+	description string
+	handler     any
+}
+
+// RegisterTools registers all tools with the MCP server
+func (tm *ToolManager) RegisterTools(server *mcp_golang.Server) error {
+	tools := []toolSpec{
+		{"status", "Report server status: uptime, the progress of background indexing (files indexed and still queued, symbols extracted) and memory use", tm.handleStatus},
+		{"workspace_info", "Describe the environment: OS, path separator, whether the filesystem is case-sensitive, git branches and remotes, and the resolved ignore configuration", tm.handleWorkspaceInfo},
+		{"dependency_graph", "Show which workspace files a file imports and which files import it (Go, JS/TS and Python)", tm.handleDependencyGraph},
+		{"find_tests_for", "Find the test files likely to cover a source file, by the naming conventions of Go, JS/TS, Python, Java/Kotlin and Ruby (next to it or in a mirrored test tree) and by which tests import it, with where a new test would go when none is named after it", tm.handleFindTestsFor},
+		{"list_dependencies", "Parse project manifests (go.mod, package.json, pyproject.toml, Cargo.toml, ...) into structured dependency lists with versions", tm.handleListDependencies},
+		{"explain_ignore", "Explain why a path is left out of the workspace: which rule excludes it (the dotfile rule, a default ignore, an editor artifact filter, a sensitive file class, a skipped submodule or a .gitignore pattern with its file and line) or which negated pattern re-includes it", tm.handleExplainIgnore},
+		{"list_directory", "List the files and directories in a directory or its whole subtree, filtered by type and extension, sorted by name, size or modification time and limited, such as the 10 most recently modified Go files under internal/", tm.handleListDirectory},
+		{"read_file_range", "Read a range of lines from a file, with the total line count; use it for files whose resource read is only a preview", tm.handleReadFileRange},
+		{"read_symbol", "Read just the definition of a function, method, type or class from a Go, JS/TS or Python file instead of the whole file; without a name it lists the file's symbols with their line ranges", tm.handleReadSymbol},
+		{"search", "Search the contents of workspace files for text or a regular expression, returning matching lines with their paths and line numbers and the offset, column and text of each match; multiline mode matches across lines and kind matches the names of definitions (such as functions named like X) instead of text", tm.handleSearch},
+		{"search_in_file", "Search one file line by line for text or a regular expression, streaming it so logs of hundreds of megabytes can be scanned; returns matching lines with context and offsets to locate regions before reading ranges", tm.handleSearchInFile},
+		{"find_references", "Find where an identifier is used across the workspace, with file, line and source snippet; Go, JS/TS and Python occurrences are marked as definitions or as in code, comments or strings", tm.handleFindReferences},
+		{"query_documents", "Find Markdown documents by their front matter, headings and modification time with a filter such as 'tag:design AND modified:>2024-01-01', returning paths with titles, tags and excerpts", tm.handleQueryDocuments},
+		{"link_graph", "List the links between Markdown documents and the workspace files they link to, or the links and backlinks of one document", tm.handleLinkGraph},
+		{"check_links", "Report relative links, images and [[wiki links]] in Markdown documents whose file or heading doesn't exist", tm.handleCheckLinks},
+		{"read_files", "Read several files (a list of paths or a glob) in one call, with a per-file size cap and a total budget", tm.handleReadFiles},
+		{"count", "Count lines, non-blank lines, words, characters and bytes of a file or glob of files without reading their content", tm.handleCount},
+		{"validate_config", "Parse a JSON, YAML or TOML file (or given text) and report the first syntax error with its line and column", tm.handleValidateConfig},
+		{"format_config", "Pretty-print a JSON, YAML or TOML file with consistent indentation, returning the diff and optionally writing it back; JSON and YAML keep their key order and YAML its comments", tm.handleFormatConfig},
+		{"edit_structured", "Set or delete the value at a JSON Pointer (/spec/replicas) or dotted path (.spec.replicas) of a JSON, YAML or TOML file, such as setting replicas to 3; scalar edits keep the rest of the file as it was and YAML keeps its comments", tm.handleEditStructured},
+		{"write_file", "Create or overwrite a file with the given content; the configured formatter for its extension is run afterwards and its changes are reported as a diff", tm.handleWriteFile},
+		{"touch_file", "Create an empty file or update the modification time of an existing file", tm.handleTouchFile},
+		{"append_to_file", "Append text to a file, creating it (and parent directories) if missing", tm.handleAppendToFile},
+		{"stat", "Report metadata for a file or directory: type, size, permissions, modification time, extended attributes, flags and locks held by other processes, with warnings when they will block writes", tm.handleStat},
+		{"set_permissions", "Change permission bits of a workspace path (octal like 755 or symbolic like +x); setuid/setgid/sticky and world-writable modes are refused", tm.handleSetPermissions},
+		{"git_status", "Show changed files in each git repository of the workspace (nested repositories, submodules and worktrees are reported separately) with branch information", tm.handleGitStatus},
+		{"merge_file", "Three-way merge of base/ours/theirs content, or of two git revisions of a file, returning the merged text or conflict markers", tm.handleMergeFile},
+		{"lock_paths", "Lock files for this session so other sessions' tool calls can't change them until unlock_paths is called or the session disconnects; use it around multi-file changes. Waits for files another session holds", tm.handleLockPaths},
+		{"unlock_paths", "Release files this session locked with lock_paths, or all of them when no paths are given", tm.handleUnlockPaths},
+		{"git_diff", "Show the diff of unstaged (or staged) changes, run in the git repository that contains each path", tm.handleGitDiff},
+		{"summarize_diff", "Summarize a diff, or the changes between two git revisions (default: HEAD against the working tree), as the functions, methods, types and classes each file's changes touch, marked added, removed or modified, instead of raw hunks, for structured change summaries in reviews", tm.handleSummarizeDiff},
+	}
+
+	// Running commands needs --enable-exec as well as an allow list
+	if tm.config.Exec.Enabled {
+		tools = append(tools, toolSpec{"run_command", fmt.Sprintf("Run an allowed command (%s) in the workspace without a shell, returning its exit code and the end of its stdout and stderr", strings.Join(tm.config.Exec.Allow, ", ")), tm.handleRunCommand})
+	}
+
+	if tm.config.Git.WriteEnabled {
+		tools = append(tools,
+			toolSpec{"git_add", "Stage files written by this server's tools; files changed by anyone else are refused", tm.handleGitAdd},
+			toolSpec{"git_commit", "Commit staged changes with a message; refused while the working tree has changes not made by this server's tools", tm.handleGitCommit},
+			toolSpec{"git_branch_list", "List the local branches of each repository with their commit, upstream and whether they are checked out", tm.handleGitBranchList},
+			toolSpec{"git_create_branch", "Create a branch, optionally switching to it, to keep work isolated", tm.handleGitCreateBranch},
+			toolSpec{"git_checkout_branch", "Switch a repository to another branch; changed files are re-indexed once the checkout finishes", tm.handleGitCheckoutBranch},
+		)
+	}
+
+	if tm.config.Git.Snapshots != config.SnapshotOff {
+		tools = append(tools, toolSpec{"revert_last_operation", "Undo the last file-modifying tool call by restoring the files it changed from the git snapshot taken before it", tm.handleRevertLastOperation})
+	}
+
+	if len(tm.config.Checkers) > 0 {
+		tools = append(tools, toolSpec{"check_file", fmt.Sprintf("Run the lint, spell and style checkers configured for a file's type (%s) and return their diagnostics with line and column, to validate a file after writing it", strings.Join(tm.checkerExtensions(), ", ")), tm.handleCheckFile})
+	}
+
+	if len(tm.config.Templates) > 0 {
+		tools = append(tools, toolSpec{"create_from_template", fmt.Sprintf("Create a file from a project template so it follows the project's conventions. Templates: %s", tm.templateSummary()), tm.handleCreateFromTemplate})
+	}
+
+	if len(tm.config.Scaffolds) > 0 {
+		tools = append(tools, toolSpec{"scaffold_project", fmt.Sprintf("Create a directory skeleton for a new module or service from a project scaffold, filling in variables in its paths and files. Scaffolds: %s", tm.scaffoldSummary()), tm.handleScaffoldProject})
+	}
+
+	if tm.scratch != nil {
+		tools = append(tools, toolSpec{"scratch_dir", "Return this session's private scratch directory for temporary files; it isn't listed or searched with the workspace and is deleted when the session disconnects", tm.handleScratchDir})
+	}
+
+	if _, ok := tm.files.Overlay(); ok {
+		tools = append(tools,
+			toolSpec{"overlay_diff", "List the changes held in the overlay (files added, modified or deleted by tools) with a unified diff against the workspace on disk", tm.handleOverlayDiff},
+			toolSpec{"overlay_discard", "Drop changes held in the overlay, for all files or the given paths, so they read from the workspace on disk again", tm.handleOverlayDiscard},
+		)
+		// Simulated changes never reach the disk
+		if !tm.config.Simulate {
+			tools = append(tools, toolSpec{"overlay_apply", "Write changes held in the overlay to the workspace on disk, for all files or the given paths", tm.handleOverlayApply})
+		}
+	}
+
+	for _, tool := range tools {
+		if diskOnlyTools[tool.name] && !tm.files.OnDisk() {
+			continue
+		}
+		if writeTools[tool.name] && !tm.files.Writable() {
+			continue
+		}
+		if !tm.config.Tools.Offers(tool.name) {
+			continue
+		}
+		if err := server.RegisterTool(tool.name, tool.description, tagErrors(tool.handler)); err != nil {
+			return fmt.Errorf("failed to register tool %s: %v", tool.name, err)
+		}
+		tm.registered = append(tm.registered, tool.name)
+		if tm.debug {
+			log.Printf("Registered tool: %s", tool.name)
+		}
+	}
+
+	return nil
+}
+
+// Registered returns the names of the tools RegisterTools offered
+func (tm *ToolManager) Registered() []string {
+	return tm.registered
+}
+
+// tagErrors wraps a tool handler so the errors it returns carry their code
+// in their message, where the server's transport finds it
+func tagErrors(handler any) any {
+	value := reflect.ValueOf(handler)
+	return reflect.MakeFunc(value.Type(), func(args []reflect.Value) []reflect.Value {
+		results := value.Call(args)
+		if err, ok := results[1].Interface().(error); ok {
+			tagged := errcode.Tagged(err)
+			results[1] = reflect.ValueOf(&tagged).Elem()
+		}
+		return results
+	}).Interface()
+}
+
+// resolvePath converts a workspace-relative or absolute path into an absolute
+// path, rejecting anything outside the workspace
+func (tm *ToolManager) resolvePath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	// No filesystem call takes a NUL byte; some truncate the path there
+	if strings.IndexByte(path, 0) >= 0 {
+		return "", fmt.Errorf("path contains a NUL byte: %q", path)
+	}
+
+	absPath := path
+	if tm.uris != nil {
+		uriPath, ok := tm.uris.PathFromURI(path)
+		if ok {
+			absPath = uriPath
+		} else if strings.HasPrefix(path, "file://") || strings.HasPrefix(path, "workspace://") {
+			// Not the name of a file in the workspace called "file:"
+			return "", errcode.New(errcode.OutsideWorkspace, "URI isn't of a file in the workspace: %s", path)
+		}
+	}
+	if !filepath.IsAbs(absPath) {
+		absPath = filepath.Join(tm.workspacePath, absPath)
+	}
+	absPath = filepath.Clean(absPath)
+
+	relPath, err := filepath.Rel(tm.workspacePath, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", errcode.New(errcode.OutsideWorkspace, "path is outside the workspace: %s", path)
+	}
+
+	// Clients may send either Unicode form; use whichever exists on disk
+	absPath = pathnorm.Normalize(tm.workspacePath, absPath)
+	if !tm.onDisk(absPath) {
+		return absPath, nil
+	}
+	return pathnorm.OnDisk(absPath), nil
+}
+
+// onDisk reports whether a resolved path is on the OS filesystem; paths in
+// remote workspaces and mounts aren't
+func (tm *ToolManager) onDisk(path string) bool {
+	if !tm.files.OnDisk() {
+		return false
+	}
+	_, _, mounted := tm.files.MountPoint(path)
+	return !mounted
+}
+
+// resolveDiskPath resolves a path like resolvePath for tools that use the
+// OS filesystem directly, refusing paths in mounts
+func (tm *ToolManager) resolveDiskPath(path string) (string, error) {
+	absPath, err := tm.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if !tm.onDisk(absPath) {
+		return "", fmt.Errorf("path is in a mount, which this tool doesn't support: %s", path)
+	}
+	return absPath, nil
+}
+
+// resolveWritePath resolves a path like resolvePath for tools that create
+// or replace files. Writes follow symlinks, so on disk the directory the
+// file goes in must resolve inside the workspace and the file itself can't
+// be a symlink.
+func (tm *ToolManager) resolveWritePath(path string) (string, error) {
+	absPath, err := tm.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	if !tm.onDisk(absPath) {
+		return absPath, nil
+	}
+
+	realWorkspace, err := filepath.EvalSymlinks(tm.workspacePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %v", err)
+	}
+
+	// Missing directories are created below the deepest one that exists
+	for dir := filepath.Dir(absPath); ; dir = filepath.Dir(dir) {
+		realDir, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if relPath, err := filepath.Rel(realWorkspace, realDir); err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+				return "", errcode.New(errcode.OutsideWorkspace, "path resolves outside the workspace: %s", path)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve path: %v", err)
+		}
+		// A dangling symlink would be followed when the directory is created
+		if _, err := os.Lstat(dir); err == nil {
+			return "", errcode.New(errcode.OutsideWorkspace, "path is below a broken symlink: %s", path)
+		}
+		if dir == tm.workspacePath || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	if info, err := os.Lstat(absPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("path is a symlink, which tools don't write through: %s", path)
+	}
+	if err := tm.checkNotConfig(absPath); err != nil {
+		return "", err
+	}
+	return absPath, nil
+}
+
+// checkNotConfig refuses changes to the server's config file, and to the
+// workspace's default one it would load on the next start. The config sets
+// the commands formatters and checkers run and the sensitive file
+// policies, so writing it would let an agent run commands or unhide files.
+func (tm *ToolManager) checkNotConfig(path string) error {
+	for _, configPath := range []string{tm.config.Path, config.DefaultPath(tm.workspacePath)} {
+		if configPath == "" {
+			continue
+		}
+		if !filepath.IsAbs(configPath) {
+			abs, err := filepath.Abs(configPath)
+			if err != nil {
+				continue
+			}
+			configPath = abs
+		}
+		// Names differing in case may be the same file
+		if strings.EqualFold(filepath.Clean(configPath), path) || sameFile(configPath, path) {
+			return errcode.New(errcode.ReadOnly, "the server's config file can't be changed by tools: %s", tm.relPath(path))
+		}
+	}
+	return nil
+}
+
+// resolveDiskWritePath resolves a path like resolveWritePath for tools
+// that write to the OS filesystem directly, refusing paths in mounts
+func (tm *ToolManager) resolveDiskWritePath(path string) (string, error) {
+	absPath, err := tm.resolveWritePath(path)
+	if err != nil {
+		return "", err
+	}
+	if !tm.onDisk(absPath) {
+		return "", fmt.Errorf("path is in a mount, which this tool doesn't support: %s", path)
+	}
+	return absPath, nil
+}
+
+// checkDiskAccess applies the sensitive file policies the workspace applies
+// to its own reads and writes, for tools that use the OS filesystem directly
+func (tm *ToolManager) checkDiskAccess(op, path string, check func(string) error) error {
+	if err := check(path); err != nil {
+		return &fs.PathError{Op: op, Path: tm.relPath(path), Err: err}
+	}
+	return nil
+}
+
+// masked reports whether a file is a .env file whose values are masked
+// wherever tools return its content
+func (tm *ToolManager) masked(path string) bool {
+	return tm.config.DotEnv == config.DotEnvMask && dotenv.Is(path)
+}
+
+// resolveExistingPath resolves a path like resolvePath and additionally
+// follows symlinks, rejecting links that point outside the workspace
+func (tm *ToolManager) resolveExistingPath(path string) (string, error) {
+	absPath, err := tm.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Other filesystems have no symlinks to escape through
+	if !tm.onDisk(absPath) {
+		if _, err := tm.files.Stat(absPath); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", errcode.New(errcode.NotFound, "file does not exist: %s", path)
+			}
+			return "", fmt.Errorf("failed to stat file: %v", err)
+		}
+		return absPath, nil
+	}
+
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errcode.New(errcode.NotFound, "file does not exist: %s", path)
+		}
+		return "", fmt.Errorf("failed to resolve path: %v", err)
+	}
+
+	realWorkspace, err := filepath.EvalSymlinks(tm.workspacePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %v", err)
+	}
+
+	relPath, err := filepath.Rel(realWorkspace, realPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", errcode.New(errcode.OutsideWorkspace, "path resolves outside the workspace: %s", path)
+	}
+
+	return absPath, nil
+}
+
+// relPath returns a path relative to the workspace using forward slashes
+func (tm *ToolManager) relPath(path string) string {
+	relPath, err := filepath.Rel(tm.workspacePath, path)
+	if err != nil {
+		return path
+	}
+	return pathnorm.NFC(filepath.ToSlash(relPath))
+}
+
+// workspaceFiles returns every non-ignored file in the workspace
+func (tm *ToolManager) workspaceFiles() ([]string, error) {
+	var files []string
+
+	err := tm.files.Walk(tm.workspacePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip files with errors
+		}
+
+		if info.IsDir() {
+			if tm.matcher.ShouldIgnoreDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !tm.matcher.ShouldIgnore(path) && diagnostics.SpecialFileType(path, info, tm.files.Stat) == "" {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// jsonResponse returns v as indented JSON text. Characters such as "<" are
+// left unescaped so conflict markers and diffs stay readable.
+func jsonResponse(v any) (*mcp_golang.ToolResponse, error) {
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %v", err)
+	}
+	return mcp_golang.NewToolResponse(mcp_golang.NewTextContent(strings.TrimSuffix(buf.String(), "\n"))), nil
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+)
+
+// newTestTools creates files in a new workspace and a tool manager for it,
+// with the workspace's config file loaded as the server does
+func newTestTools(t *testing.T, files map[string]string) (*ToolManager, string) {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := config.Load(config.DefaultPath(root), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := fsys.OS(root)
+	matcher, err := gitignore.NewMatcherFS(workspace, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewToolManager(workspace, cfg, matcher, false), root
+}
+
+// expectCode fails unless err carries code
+func expectCode(t *testing.T, what string, err error, code errcode.Code) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s succeeded, want %s", what, code)
+	}
+	if got := errcode.Of(err); got != code {
+		t.Fatalf("%s: %v (code %q), want %s", what, err, got, code)
+	}
+}
+
+func TestConfigFileNotWritable(t *testing.T) {
+	original := "formatters:\n  .go: gofmt -w\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+		path  string
+	}{
+		{"existing config", map[string]string{config.DefaultFileName: original}, config.DefaultFileName},
+		{"config to be created", nil, config.DefaultFileName},
+		{"config in another case", map[string]string{config.DefaultFileName: original}, ".MCP-Filesystem.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, root := newTestTools(t, tt.files)
+			ctx := context.Background()
+			content := "formatters:\n  .go: sh -c 'touch pwned'\n"
+
+			_, err := tm.handleWriteFile(ctx, WriteFileArgs{Path: tt.path, Content: content})
+			expectCode(t, "write_file", err, errcode.ReadOnly)
+			_, err = tm.handleAppendToFile(ctx, AppendToFileArgs{Path: tt.path, Content: content})
+			expectCode(t, "append_to_file", err, errcode.ReadOnly)
+			_, err = tm.handleTouchFile(ctx, TouchFileArgs{Path: tt.path})
+			expectCode(t, "touch_file", err, errcode.ReadOnly)
+
+			data, err := os.ReadFile(filepath.Join(root, config.DefaultFileName))
+			switch {
+			case tt.files == nil && !errors.Is(err, os.ErrNotExist):
+				t.Fatalf("config was created: %q, %v", data, err)
+			case tt.files != nil && string(data) != original:
+				t.Fatalf("config changed to %q (%v)", data, err)
+			}
+		})
+	}
+
+	// A config given with --config may be a file the tools otherwise serve
+	tm, root := newTestTools(t, map[string]string{"server.yaml": original})
+	tm.config.Path = filepath.Join(root, "server.yaml")
+	_, err := tm.handleEditStructured(context.Background(), EditStructuredArgs{Path: "server.yaml", Pointer: "/formatters/.go", Value: `"sh -c 'touch pwned'"`})
+	expectCode(t, "edit_structured", err, errcode.ReadOnly)
+	_, err = tm.handleWriteFile(context.Background(), WriteFileArgs{Path: "server.yaml", Content: ""})
+	expectCode(t, "write_file", err, errcode.ReadOnly)
+	if data, _ := os.ReadFile(tm.config.Path); string(data) != original {
+		t.Fatalf("config changed to %q", data)
+	}
+}
@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// TouchFileArgs are the arguments for the touch_file tool
+type TouchFileArgs struct {
+	Path string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to touch"`
+}
+
+// AppendToFileArgs are the arguments for the append_to_file tool
+type AppendToFileArgs struct {
+	Path    string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to append to"`
+	Content string `json:"content" jsonschema:"required,description=Text to append"`
+	Newline bool   `json:"ensure_newline,omitempty" jsonschema:"description=Insert a newline first if the file doesn't already end with one"`
+}
+
+// touchFileResult is the response of the touch_file tool
+type touchFileResult struct {
+	Path    string    `json:"path"`
+	Created bool      `json:"created"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// appendToFileResult is the response of the append_to_file tool
+type appendToFileResult struct {
+	Path    string        `json:"path"`
+	Created bool          `json:"created"`
+	Bytes   int           `json:"bytesAppended"`
+	Size    int64         `json:"size"`
+	Format  *formatResult `json:"format,omitempty"`
+}
+
+// handleTouchFile creates an empty file or updates the modification time of an existing one
+func (tm *ToolManager) handleTouchFile(ctx context.Context, args TouchFileArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveDiskWritePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.checkDiskAccess("touch", path, tm.matcher.CanWrite); err != nil {
+		return nil, err
+	}
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := tm.checkUnlocked(path); err != nil {
+		return nil, err
+	}
+
+	before := tm.beforeChange(path)
+	created := false
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		release, err := tm.reserveQuota(ctx, quotaWrite{path: path, created: true})
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			release()
+			return nil, errcode.Wrapf(err, "failed to create parent directories: %v", err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|oNoFollow, 0644)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("failed to create file: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			release()
+			return nil, fmt.Errorf("failed to create file: %v", err)
+		}
+		created = true
+	case err != nil:
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	case info.IsDir():
+		return nil, fmt.Errorf("path is a directory: %s", args.Path)
+	default:
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			err = explainWriteError(path, err)
+			return nil, errcode.Wrapf(err, "failed to update modification time: %v", err)
+		}
+	}
+
+	info, err = os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	tm.recordWrite(path)
+	tm.recordChange(ctx, "touch_file", before)
+
+	return jsonResponse(touchFileResult{
+		Path:    tm.relPath(path),
+		Created: created,
+		ModTime: info.ModTime(),
+	})
+}
+
+// handleAppendToFile appends text to a file, creating it if missing
+func (tm *ToolManager) handleAppendToFile(ctx context.Context, args AppendToFileArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveDiskWritePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := tm.checkDiskAccess("append", path, tm.matcher.CanWrite); err != nil {
+		return nil, err
+	}
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := tm.checkUnlocked(path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	created := os.IsNotExist(err)
+	if err != nil && !created {
+		return nil, fmt.Errorf("failed to stat file: %v", err)
+	}
+	if info != nil && info.IsDir() {
+		return nil, fmt.Errorf("path is a directory: %s", args.Path)
+	}
+
+	content := args.Content
+	if args.Newline && info != nil && info.Size() > 0 {
+		last, err := lastByte(path)
+		if err != nil {
+			return nil, err
+		}
+		if last != '\n' {
+			content = "\n" + content
+		}
+	}
+
+	size := int64(len(content))
+	if info != nil {
+		size += info.Size()
+	}
+	release, err := tm.reserveQuota(ctx, quotaWrite{path: path, bytes: int64(len(content)), size: size, created: created})
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := tm.snapshot("append_to_file", path)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if created {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			release()
+			return nil, errcode.Wrapf(err, "failed to create parent directories: %v", err)
+		}
+	}
+
+	before := tm.beforeChange(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY|oNoFollow, 0644)
+	if err != nil {
+		release()
+		err = explainWriteError(path, err)
+		return nil, errcode.Wrapf(err, "failed to open file: %v", err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to append to file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to append to file: %v", err)
+	}
+
+	result := appendToFileResult{
+		Path:    tm.relPath(path),
+		Created: created,
+		Bytes:   len(content),
+		Format:  tm.formatFile(path),
+	}
+	if info, err := os.Stat(path); err == nil {
+		result.Size = info.Size()
+	}
+	tm.recordWrite(path)
+	tm.recordOperation(op)
+	tm.recordChange(ctx, "append_to_file", before)
+
+	return jsonResponse(result)
+}
+
+// lastByte returns the final byte of a non-empty file
+func lastByte(path string) (byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	buf := make([]byte, 1)
+	if _, err := file.Seek(-1, io.SeekEnd); err != nil {
+		return 0, fmt.Errorf("failed to seek file: %v", err)
+	}
+	if _, err := file.Read(buf); err != nil {
+		return 0, fmt.Errorf("failed to read file: %v", err)
+	}
+	return buf[0], nil
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// WorkspaceInfoArgs are the arguments for the workspace_info tool
+type WorkspaceInfoArgs struct{}
+
+// workspaceInfoResult is the response of the workspace_info tool
+type workspaceInfoResult struct {
+	OS            string           `json:"os"`
+	Arch          string           `json:"arch"`
+	PathSeparator string           `json:"pathSeparator"`
+	CaseSensitive *bool            `json:"caseSensitive,omitempty"` // nil if unknown
+	Workspace     string           `json:"workspace"`
+	Profile       string           `json:"profile,omitempty"`
+	ReadOnly      bool             `json:"readOnly,omitempty"`
+	Repositories  []repositoryInfo `json:"repositories"`
+	Ignore        ignoreInfo       `json:"ignore"`
+}
+
+// repositoryInfo describes a git repository in the workspace
+type repositoryInfo struct {
+	Path     string            `json:"path"`
+	Branch   string            `json:"branch,omitempty"`
+	Remotes  map[string]string `json:"remotes,omitempty"`
+	Worktree bool              `json:"worktree,omitempty"`
+	Sparse   bool              `json:"sparseCheckout,omitempty"`
+}
+
+// ignoreInfo is the resolved ignore configuration
+type ignoreInfo struct {
+	Dotfiles          bool     `json:"dotfilesIgnored"`
+	DefaultPatterns   []string `json:"defaultPatterns"`
+	EditorArtifacts   []string `json:"editorArtifacts"`
+	ConfigPatterns    []string `json:"configPatterns,omitempty"`
+	GitignoreFiles    []string `json:"gitignoreFiles"`
+	Submodules        string   `json:"submodules"`
+	SkippedSubmodules []string `json:"skippedSubmodules,omitempty"`
+}
+
+// caseProbeEntries is how many workspace entries caseSensitive looks at
+// for a name with letters
+const caseProbeEntries = 100
+
+// handleWorkspaceInfo describes the environment the workspace is served from
+func (tm *ToolManager) handleWorkspaceInfo(args WorkspaceInfoArgs) (*mcp_golang.ToolResponse, error) {
+	result := workspaceInfoResult{
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		PathSeparator: string(filepath.Separator),
+		CaseSensitive: tm.caseSensitive(),
+		Workspace:     tm.workspacePath,
+		Profile:       tm.config.Profile,
+		ReadOnly:      tm.config.ReadOnly,
+		Repositories:  []repositoryInfo{},
+		Ignore: ignoreInfo{
+			Dotfiles:        true,
+			DefaultPatterns: tm.matcher.DefaultIgnores(),
+			EditorArtifacts: append([]string{}, tm.config.Watch.EditorArtifacts...),
+			ConfigPatterns:  tm.config.Ignore,
+			GitignoreFiles:  []string{},
+			Submodules:      tm.config.Git.Submodules,
+		},
+	}
+
+	for _, repo := range tm.matcher.Repositories() {
+		info := repositoryInfo{
+			Path:     tm.relPath(repo.Path),
+			Worktree: repo.Worktree,
+			Sparse:   repo.Sparse,
+		}
+		// Unborn or detached branches leave the branch empty or "HEAD"
+		if branch, err := runGit(repo.Path, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+			info.Branch = strings.TrimSpace(branch)
+		}
+		if output, err := runGit(repo.Path, "remote", "-v"); err == nil {
+			for _, line := range strings.Split(output, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 3 && fields[2] == "(fetch)" {
+					if info.Remotes == nil {
+						info.Remotes = make(map[string]string)
+					}
+					info.Remotes[fields[0]] = fields[1]
+				}
+			}
+		}
+		result.Repositories = append(result.Repositories, info)
+	}
+
+	for _, path := range tm.matcher.IgnoreFiles() {
+		result.Ignore.GitignoreFiles = append(result.Ignore.GitignoreFiles, tm.relPath(path))
+	}
+	for _, submodule := range tm.matcher.SkippedSubmodules() {
+		result.Ignore.SkippedSubmodules = append(result.Ignore.SkippedSubmodules, tm.relPath(submodule))
+	}
+
+	return jsonResponse(result)
+}
+
+// caseSensitive reports whether the workspace's filesystem distinguishes
+// names differing only in case, by looking up an existing name with its
+// case swapped: the workspace directory's own, or else one of its entries.
+// It returns nil if no name has letters to swap, or the workspace isn't on
+// disk, rather than creating a file to find out.
+func (tm *ToolManager) caseSensitive() *bool {
+	if !tm.files.OnDisk() {
+		return nil
+	}
+	if swapped, ok := swapCase(filepath.Base(tm.workspacePath)); ok {
+		sensitive := !sameFile(tm.workspacePath, filepath.Join(filepath.Dir(tm.workspacePath), swapped))
+		return &sensitive
+	}
+
+	dir, err := os.Open(tm.workspacePath)
+	if err != nil {
+		return nil
+	}
+	defer dir.Close()
+	entries, _ := dir.ReadDir(caseProbeEntries)
+	for _, entry := range entries {
+		if swapped, ok := swapCase(entry.Name()); ok {
+			sensitive := !sameFile(filepath.Join(tm.workspacePath, entry.Name()), filepath.Join(tm.workspacePath, swapped))
+			return &sensitive
+		}
+	}
+	return nil
+}
+
+// swapCase inverts the case of every letter, reporting whether there were any
+func swapCase(name string) (string, bool) {
+	changed := false
+	swapped := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsUpper(r):
+			changed = true
+			return unicode.ToLower(r)
+		case unicode.IsLower(r):
+			changed = true
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, name)
+	return swapped, changed
+}
+
+// sameFile reports whether two paths name the same existing file
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+
+	"github.com/isaacphi/mcp-filesystem/internal/errcode"
+)
+
+// WriteFileArgs are the arguments for the write_file tool
+type WriteFileArgs struct {
+	Path    string `json:"path" jsonschema:"required,description=Workspace-relative path of the file to write"`
+	Content string `json:"content" jsonschema:"required,description=Full new content of the file"`
+}
+
+// writeFileResult is the response of the write_file tool
+type writeFileResult struct {
+	Path    string        `json:"path"`
+	Created bool          `json:"created"`
+	Bytes   int           `json:"bytes"`
+	Format  *formatResult `json:"format,omitempty"`
+	// Simulated is set with --simulate, when the file was only written in
+	// memory
+	Simulated bool `json:"simulated,omitempty"`
+}
+
+// handleWriteFile creates or replaces a file, then runs the configured formatter
+func (tm *ToolManager) handleWriteFile(ctx context.Context, args WriteFileArgs) (*mcp_golang.ToolResponse, error) {
+	path, err := tm.resolveWritePath(args.Path)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := tm.lockForChange(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	info, err := tm.files.Stat(path)
+	created := errors.Is(err, fs.ErrNotExist)
+	if err == nil && info.IsDir() {
+		return nil, fmt.Errorf("path is a directory: %s", args.Path)
+	}
+	if err := tm.checkUnlocked(path); err != nil {
+		return nil, err
+	}
+
+	mode := os.FileMode(0644)
+	if info != nil {
+		mode = info.Mode().Perm()
+	}
+
+	size := int64(len(args.Content))
+	release, err := tm.reserveQuota(ctx, quotaWrite{path: path, bytes: size, size: size, created: created})
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := tm.snapshot("write_file", path)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	before := tm.beforeChange(path)
+	if err := tm.files.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		release()
+		return nil, errcode.Wrapf(err, "failed to create parent directories: %v", err)
+	}
+
+	if err := tm.files.WriteFile(path, []byte(args.Content), mode); err != nil {
+		release()
+		err = explainWriteError(path, err)
+		return nil, errcode.Wrapf(err, "failed to write file: %v", err)
+	}
+
+	result := writeFileResult{
+		Path:      tm.relPath(path),
+		Created:   created,
+		Bytes:     len(args.Content),
+		Format:    tm.formatFile(path),
+		Simulated: tm.config.Simulate,
+	}
+	tm.recordWrite(path)
+	tm.recordOperation(op)
+	tm.recordChange(ctx, "write_file", before)
+
+	return jsonResponse(result)
+}
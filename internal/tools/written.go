@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// recordWrite remembers the state a tool left a file in, so git tools can
+// tell the agent's changes from changes made by people
+func (tm *ToolManager) recordWrite(path string) {
+	state, err := tm.fileState(path)
+	if err != nil {
+		return
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.written[path] = state
+}
+
+// writtenByTools reports whether a file is still exactly as a tool left it
+func (tm *ToolManager) writtenByTools(path string) bool {
+	tm.mu.Lock()
+	recorded, ok := tm.written[path]
+	tm.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	state, err := tm.fileState(path)
+	return err == nil && state == recorded
+}
+
+// fileState fingerprints a file's permissions and content
+func (tm *ToolManager) fileState(path string) (string, error) {
+	info, err := tm.files.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%o\n", info.Mode().Perm())
+	if info.Mode().IsRegular() {
+		file, err := tm.files.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
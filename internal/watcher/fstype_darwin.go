@@ -0,0 +1,26 @@
+package watcher
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// networkFilesystem reports whether path is on a filesystem that doesn't
+// deliver FSEvents for changes made by other machines
+func networkFilesystem(path string) (string, bool) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+
+	name := unix.ByteSliceToString(stat.Fstypename[:])
+	switch {
+	case name == "nfs", name == "smbfs", name == "afpfs", name == "webdav", name == "cifs":
+		return name, true
+	case strings.Contains(name, "fuse"):
+		// sshfs and other remote mounts via macFUSE
+		return name, true
+	}
+	return "", false
+}
@@ -0,0 +1,31 @@
+package watcher
+
+import "golang.org/x/sys/unix"
+
+// networkFilesystem reports whether path is on a filesystem that doesn't
+// deliver inotify events for changes made by other machines
+func networkFilesystem(path string) (string, bool) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+
+	switch uint32(stat.Type) {
+	case unix.NFS_SUPER_MAGIC:
+		return "nfs", true
+	case unix.SMB_SUPER_MAGIC, unix.SMB2_SUPER_MAGIC, unix.CIFS_SUPER_MAGIC:
+		return "smb", true
+	case unix.FUSE_SUPER_MAGIC:
+		// sshfs, rclone and most other remote mounts are FUSE filesystems
+		return "fuse", true
+	case unix.V9FS_MAGIC:
+		return "9p", true
+	case unix.AFS_SUPER_MAGIC:
+		return "afs", true
+	case unix.CEPH_SUPER_MAGIC:
+		return "ceph", true
+	case unix.CODA_SUPER_MAGIC:
+		return "coda", true
+	}
+	return "", false
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package watcher
+
+// networkFilesystem is not detected on this platform; use poll mode explicitly
+func networkFilesystem(path string) (string, bool) {
+	return "", false
+}
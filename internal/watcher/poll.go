@@ -0,0 +1,130 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileState is what poll mode compares between scans
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// snapshot records the state of every non-ignored path under the given
+// directories; paced rescans list directories within the background I/O
+// budget
+func (fw *FileWatcher) snapshot(roots []string, paced bool) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	for _, root := range roots {
+		if err := fw.snapshotTree(root, paced, states); err != nil {
+			return nil, err
+		}
+	}
+	return states, nil
+}
+
+// snapshotTree adds the paths under root to a snapshot
+func (fw *FileWatcher) snapshotTree(root string, paced bool, states map[string]fileState) error {
+	return fw.files.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return fw.skipInaccessible(path, info, err)
+		}
+		if path == root {
+			return nil
+		}
+
+		if info.IsDir() {
+			if fw.matcher.ShouldIgnoreDir(path) {
+				return filepath.SkipDir
+			}
+			if paced {
+				fw.scheduler.Op()
+			}
+		} else if fw.matcher.ShouldIgnore(path) {
+			return nil
+		}
+
+		states[path] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+			isDir:   info.IsDir(),
+		}
+		return nil
+	})
+}
+
+// pollLoop rescans the given directories on an interval and reports differences
+// between scans as if they were filesystem notifications
+func (fw *FileWatcher) pollLoop(ctx context.Context, roots []string, previous map[string]fileState) {
+	ticker := time.NewTicker(fw.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-fw.done:
+			return
+		case <-ticker.C:
+			current, err := fw.snapshot(roots, true)
+			if err != nil {
+				// Keep the previous snapshot so a briefly unavailable mount
+				// isn't reported as every file being deleted
+				log.Printf("Error polling workspace: %v", err)
+				continue
+			}
+			fw.compareSnapshots(previous, current)
+			previous = current
+		}
+	}
+}
+
+// compareSnapshots emits events for paths created, modified or removed between two scans
+func (fw *FileWatcher) compareSnapshots(previous, current map[string]fileState) {
+	// Removals first so a path that changed between file and directory is
+	// removed before it is recreated
+	for _, path := range sortedPaths(previous) {
+		prev := previous[path]
+		cur, ok := current[path]
+		if ok && cur.isDir == prev.isDir {
+			continue
+		}
+		if prev.isDir {
+			fw.stopWatching(path)
+		} else {
+			fw.handleFsEvent(fsnotify.Event{Name: path, Op: fsnotify.Remove})
+		}
+	}
+
+	for _, path := range sortedPaths(current) {
+		cur := current[path]
+		prev, ok := previous[path]
+		switch {
+		case !ok || prev.isDir != cur.isDir:
+			fw.handleFsEvent(fsnotify.Event{Name: path, Op: fsnotify.Create})
+		case !cur.isDir && (!cur.modTime.Equal(prev.modTime) || cur.size != prev.size):
+			fw.handleFsEvent(fsnotify.Event{Name: path, Op: fsnotify.Write})
+		}
+	}
+}
+
+// sortedPaths returns the keys of a snapshot so parents come before children
+func sortedPaths(states map[string]fileState) []string {
+	paths := make([]string, 0, len(states))
+	for path := range states {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
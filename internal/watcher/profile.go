@@ -0,0 +1,77 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ScanProfile breaks down the initial scan of the workspace so slow starts
+// can be traced to large or badly ignored directories
+type ScanProfile struct {
+	// Walk is the whole scan, including Matching
+	Walk time.Duration
+	// Matching is the time spent deciding what is ignored
+	Matching     time.Duration
+	Dirs         int
+	Files        int
+	IgnoredDirs  int
+	IgnoredFiles int
+	// TopLevel counts the files kept below each top-level directory
+	TopLevel map[string]int
+}
+
+// ProfileScan makes the next GetInitialFiles fill in the returned profile
+func (fw *FileWatcher) ProfileScan() *ScanProfile {
+	fw.profile = &ScanProfile{TopLevel: make(map[string]int)}
+	return fw.profile
+}
+
+// ignoreDir reports whether the initial scan skips a directory, timing the
+// decision when profiling
+func (fw *FileWatcher) ignoreDir(path string) bool {
+	if fw.profile == nil {
+		return fw.matcher.ShouldIgnoreDir(path)
+	}
+	start := time.Now()
+	ignored := fw.matcher.ShouldIgnoreDir(path)
+	fw.profile.Matching += time.Since(start)
+	if ignored {
+		fw.profile.IgnoredDirs++
+	} else {
+		fw.profile.Dirs++
+	}
+	return ignored
+}
+
+// ignoreFile reports whether the initial scan skips a file, timing the
+// decision when profiling
+func (fw *FileWatcher) ignoreFile(path string) bool {
+	if fw.profile == nil {
+		return fw.matcher.ShouldIgnore(path)
+	}
+	start := time.Now()
+	ignored := fw.matcher.ShouldIgnore(path)
+	fw.profile.Matching += time.Since(start)
+	if ignored {
+		fw.profile.IgnoredFiles++
+	}
+	return ignored
+}
+
+// countFile records a file the initial scan keeps under its top-level directory
+func (fw *FileWatcher) countFile(path string) {
+	if fw.profile == nil {
+		return
+	}
+	fw.profile.Files++
+	rel, err := filepath.Rel(fw.workspacePath, path)
+	if err != nil {
+		return
+	}
+	top, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+	if !nested {
+		top = "."
+	}
+	fw.profile.TopLevel[top]++
+}
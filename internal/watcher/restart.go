@@ -0,0 +1,104 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Restarts of a failed watcher back off from minRestartDelay to
+// maxRestartDelay while it keeps failing within restartWindow
+const (
+	minRestartDelay = time.Second
+	maxRestartDelay = time.Minute
+	restartWindow   = time.Minute
+)
+
+// restart replaces a watcher whose channels closed, watches the workspace
+// again and asks for a rescan of the changes missed meanwhile. It reports
+// false if the watcher was stopped instead.
+func (fw *FileWatcher) restart(ctx context.Context) bool {
+	if fw.stopped(ctx) {
+		return false
+	}
+	log.Printf("Warning: file watcher stopped unexpectedly; restarting it")
+
+	// Back off if the last restart didn't last
+	if fw.clock.Now().Sub(fw.lastRestart) > restartWindow {
+		fw.restartDelay = 0
+	}
+	for {
+		if fw.restartDelay > 0 && !fw.sleep(ctx, fw.restartDelay) {
+			return false
+		}
+		fw.restartDelay = min(max(2*fw.restartDelay, minRestartDelay), maxRestartDelay)
+
+		watcher, err := fw.newSource()
+		if err != nil {
+			log.Printf("Error restarting file watcher: %v", err)
+			continue
+		}
+
+		fw.mu.Lock()
+		old := fw.watcher
+		fw.watcher = watcher
+		fw.watchedDirs = make(map[string]bool)
+		fw.mu.Unlock()
+		_ = old.Close()
+		break
+	}
+	fw.lastRestart = fw.clock.Now()
+
+	// Every subtree is watched again; the rescan covers what changed in
+	// cold ones
+	if fw.tiers != nil {
+		fw.tiers.mu.Lock()
+		fw.tiers.subtrees = make(map[string]*subtree)
+		fw.tiers.mu.Unlock()
+	}
+	if err := fw.scanWorkspace(); err != nil {
+		log.Printf("Error watching workspace after restart: %v", err)
+	}
+	fw.requestRescan()
+	if fw.onRestarted != nil {
+		fw.onRestarted()
+	}
+	return true
+}
+
+// requestRescan asks the server to reconcile its files with the workspace
+// after events may have been lost
+func (fw *FileWatcher) requestRescan() {
+	fw.send(FileEvent{EventType: EventRescan})
+}
+
+// handled tells a test's hook that an event or error was handled
+func (fw *FileWatcher) handled() {
+	if fw.onHandled != nil {
+		fw.onHandled()
+	}
+}
+
+// stopped reports whether the watcher is shutting down
+func (fw *FileWatcher) stopped(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-fw.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d, reporting false if the watcher is stopped first
+func (fw *FileWatcher) sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-fw.done:
+		return false
+	case <-fw.clock.After(d):
+		return true
+	}
+}
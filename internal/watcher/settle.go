@@ -0,0 +1,99 @@
+package watcher
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// settler holds back file changes until the file stops changing, so a
+// file still being written isn't reported, and then read, half-written
+type settler struct {
+	delay   time.Duration
+	pending map[string]*pendingChange
+	mu      sync.Mutex
+}
+
+// pendingChange is a change waiting for its file to settle
+type pendingChange struct {
+	eventType int
+	diskPath  string
+	state     fileState
+	timer     Timer
+}
+
+// emit sends a file event, holding back creations and modifications until
+// the file has settled. A deletion drops the change waiting for the file.
+func (fw *FileWatcher) emit(path, diskPath string, eventType int, info os.FileInfo) {
+	if fw.settle.delay <= 0 {
+		fw.send(FileEvent{Path: path, EventType: eventType})
+		return
+	}
+
+	fw.settle.mu.Lock()
+	change, waiting := fw.settle.pending[path]
+	if eventType == EventDelete {
+		if waiting {
+			change.timer.Stop()
+			delete(fw.settle.pending, path)
+		}
+		fw.settle.mu.Unlock()
+		fw.send(FileEvent{Path: path, EventType: eventType})
+		return
+	}
+	if info == nil {
+		// The file is already gone; its delete event follows
+		fw.settle.mu.Unlock()
+		return
+	}
+	state := fileState{modTime: info.ModTime(), size: info.Size()}
+	if waiting {
+		// A creation stays one until it is reported
+		if eventType == EventCreate {
+			change.eventType = EventCreate
+		}
+		change.state = state
+		change.timer.Reset(fw.settle.delay)
+	} else {
+		change = &pendingChange{eventType: eventType, diskPath: diskPath, state: state}
+		change.timer = fw.clock.AfterFunc(fw.settle.delay, func() { fw.settled(path) })
+		fw.settle.pending[path] = change
+	}
+	fw.settle.mu.Unlock()
+}
+
+// settled reports a pending change if its file hasn't changed since the
+// last event, and otherwise waits again
+func (fw *FileWatcher) settled(path string) {
+	fw.settle.mu.Lock()
+	change, ok := fw.settle.pending[path]
+	if !ok {
+		fw.settle.mu.Unlock()
+		return
+	}
+	info, err := fw.files.Stat(change.diskPath)
+	if err != nil {
+		// Removed meanwhile; the delete event decides what is reported
+		fw.settle.mu.Unlock()
+		return
+	}
+	state := fileState{modTime: info.ModTime(), size: info.Size()}
+	if !state.modTime.Equal(change.state.modTime) || state.size != change.state.size {
+		change.state = state
+		change.timer.Reset(fw.settle.delay)
+		fw.settle.mu.Unlock()
+		return
+	}
+	delete(fw.settle.pending, path)
+	fw.settle.mu.Unlock()
+
+	fw.send(FileEvent{Path: path, EventType: change.eventType})
+}
+
+// send passes an event on unless the watcher is stopped
+func (fw *FileWatcher) send(event FileEvent) {
+	select {
+	case fw.events <- event:
+	case <-fw.done:
+	}
+}
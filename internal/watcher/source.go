@@ -0,0 +1,92 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventSource is the stream of change notifications the watcher reads in
+// notify mode. It is fsnotify outside of the watchertest harness, which
+// injects synthetic events instead.
+type EventSource interface {
+	Add(path string) error
+	Remove(path string) error
+	// Events and Errors are closed when the source fails or is closed
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// fsnotifySource is an EventSource backed by fsnotify
+type fsnotifySource struct {
+	*fsnotify.Watcher
+}
+
+// newFSNotifySource creates an fsnotify watcher
+func newFSNotifySource() (EventSource, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return fsnotifySource{watcher}, nil
+}
+
+// Events returns fsnotify's event channel
+func (s fsnotifySource) Events() <-chan fsnotify.Event {
+	return s.Watcher.Events
+}
+
+// Errors returns fsnotify's error channel
+func (s fsnotifySource) Errors() <-chan error {
+	return s.Watcher.Errors
+}
+
+// Clock is the time the watcher settles changes and backs off restarts by.
+// It is the system clock outside of the watchertest harness, which advances
+// a fake one instead.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine once d has passed
+	AfterFunc(d time.Duration, f func()) Timer
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a pending call made by Clock.AfterFunc
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// systemClock is the Clock of the system
+type systemClock struct{}
+
+// Now returns the current time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc calls f after d with time.AfterFunc
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// After returns time.After(d)
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Fakes replace the sources of events and time of a watcher, for
+// deterministic tests of how it handles them
+type Fakes struct {
+	// Source is called for the first source and again for each restart
+	Source func() (EventSource, error)
+	Clock  Clock
+	// Handled, if set, is called once each event or error read from a
+	// source has been handled, so a test can wait for it
+	Handled func()
+	// Restarted, if set, is called once a failed source has been replaced
+	// and the rescan after it requested
+	Restarted func()
+}
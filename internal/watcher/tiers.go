@@ -0,0 +1,263 @@
+package watcher
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+)
+
+// tierState tracks which subtrees of the workspace are watched (hot) and
+// which are only checked when a client reads from them (cold)
+type tierState struct {
+	cfg      config.TiersConfig
+	subtrees map[string]*subtree
+	mu       sync.Mutex
+}
+
+// subtree is a directory at the tier depth, promoted and demoted as a whole
+type subtree struct {
+	// cold is the state of the subtree when it was demoted, updated as its
+	// files are read; nil while the subtree is hot
+	cold       map[string]fileState
+	accesses   []time.Time // reads within DemoteAfter
+	lastAccess time.Time
+}
+
+// newTierState returns the tier state for a notify mode watcher, or nil
+// when tiered watching is disabled
+func newTierState(cfg config.TiersConfig) *tierState {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &tierState{cfg: cfg, subtrees: make(map[string]*subtree)}
+}
+
+// subtreeOf returns the subtree a path is in, if it is deeper than the tier
+// depth; a path at the tier depth is its own subtree when self is set
+func (fw *FileWatcher) subtreeOf(path string, self bool) (string, bool) {
+	rel, err := filepath.Rel(fw.workspacePath, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	depth := fw.tiers.cfg.Depth
+	if len(parts) < depth || (len(parts) == depth && !self) {
+		return "", false
+	}
+	return filepath.Join(fw.workspacePath, filepath.FromSlash(strings.Join(parts[:depth], "/"))), true
+}
+
+// trackSubtree starts tracking a newly watched directory if it is at the
+// tier depth. Called with fw.mu held.
+func (fw *FileWatcher) trackSubtree(path string) {
+	if fw.tiers == nil {
+		return
+	}
+	if root, ok := fw.subtreeOf(path, true); !ok || root != path {
+		return
+	}
+	if _, _, mounted := fw.files.MountPoint(path); mounted {
+		return
+	}
+
+	fw.tiers.mu.Lock()
+	defer fw.tiers.mu.Unlock()
+	st, ok := fw.tiers.subtrees[path]
+	if !ok {
+		fw.tiers.subtrees[path] = &subtree{lastAccess: fw.clock.Now()}
+		return
+	}
+	// A cold subtree recreated by a new directory is watched again
+	if st.cold != nil {
+		cold := st.cold
+		st.cold = nil
+		go fw.promote(path, cold)
+	}
+}
+
+// Cold reports whether a path is in a subtree that isn't watched but
+// checked when read
+func (fw *FileWatcher) Cold(path string) bool {
+	if fw.tiers == nil {
+		return false
+	}
+	root, ok := fw.subtreeOf(path, false)
+	if !ok {
+		return false
+	}
+	fw.tiers.mu.Lock()
+	defer fw.tiers.mu.Unlock()
+	st := fw.tiers.subtrees[root]
+	return st != nil && st.cold != nil
+}
+
+// Access tells the watcher a client read a file. A file in a cold subtree
+// is checked against the state recorded for it, and a cold subtree read
+// often enough is watched again.
+func (fw *FileWatcher) Access(path string) {
+	if fw.tiers == nil {
+		return
+	}
+	root, ok := fw.subtreeOf(path, false)
+	if !ok {
+		return
+	}
+
+	now := fw.clock.Now()
+	fw.tiers.mu.Lock()
+	st := fw.tiers.subtrees[root]
+	if st == nil {
+		fw.tiers.mu.Unlock()
+		return
+	}
+	st.lastAccess = now
+	recent := st.accesses[:0]
+	for _, at := range st.accesses {
+		if now.Sub(at) < fw.tiers.cfg.DemoteAfter {
+			recent = append(recent, at)
+		}
+	}
+	st.accesses = append(recent, now)
+	if st.cold == nil {
+		fw.tiers.mu.Unlock()
+		return
+	}
+
+	if len(st.accesses) >= fw.tiers.cfg.PromoteAfter {
+		cold := st.cold
+		st.cold = nil
+		fw.tiers.mu.Unlock()
+		fw.promote(root, cold)
+		return
+	}
+	op := fw.checkCold(st.cold, path)
+	fw.tiers.mu.Unlock()
+
+	if op != 0 {
+		fw.handleFsEvent(fsnotify.Event{Name: path, Op: op})
+	}
+}
+
+// checkCold stats a file in a cold subtree and updates its recorded state,
+// returning the operation to report for it, if any. Called with the tier
+// lock held.
+func (fw *FileWatcher) checkCold(cold map[string]fileState, path string) fsnotify.Op {
+	prev, known := cold[path]
+	info, err := fw.files.Stat(path)
+	if err != nil {
+		if !known {
+			return 0
+		}
+		delete(cold, path)
+		if prev.isDir {
+			return 0
+		}
+		return fsnotify.Remove
+	}
+	if info.IsDir() || fw.matcher.ShouldIgnore(path) {
+		return 0
+	}
+
+	cur := fileState{modTime: info.ModTime(), size: info.Size()}
+	cold[path] = cur
+	switch {
+	case !known || prev.isDir:
+		return fsnotify.Create
+	case !cur.modTime.Equal(prev.modTime) || cur.size != prev.size:
+		return fsnotify.Write
+	}
+	return 0
+}
+
+// promote watches a cold subtree again and reports what changed in it since
+// it was demoted
+func (fw *FileWatcher) promote(root string, cold map[string]fileState) {
+	if fw.debug {
+		log.Printf("Promoting %s to watched", root)
+	}
+	if err := fw.scanTree(root); err != nil {
+		log.Printf("Error watching %s: %v", root, err)
+	}
+	current := make(map[string]fileState)
+	if err := fw.snapshotTree(root, false, current); err != nil {
+		log.Printf("Error scanning %s: %v", root, err)
+	}
+	fw.compareSnapshots(cold, current)
+}
+
+// tierLoop demotes subtrees that haven't been read for DemoteAfter
+func (fw *FileWatcher) tierLoop(ctx context.Context) {
+	interval := fw.tiers.cfg.DemoteAfter / 2
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-fw.done:
+			return
+		case <-ticker.C:
+			fw.demoteIdle()
+		}
+	}
+}
+
+// demoteIdle records the state of idle hot subtrees and stops watching them
+func (fw *FileWatcher) demoteIdle() {
+	cutoff := fw.clock.Now().Add(-fw.tiers.cfg.DemoteAfter)
+	var idle []string
+	fw.tiers.mu.Lock()
+	for root, st := range fw.tiers.subtrees {
+		if st.cold == nil && st.lastAccess.Before(cutoff) {
+			idle = append(idle, root)
+		}
+	}
+	fw.tiers.mu.Unlock()
+
+	for _, root := range idle {
+		// Record the state before unwatching so no change is missed
+		states := make(map[string]fileState)
+		err := fw.snapshotTree(root, true, states)
+
+		fw.tiers.mu.Lock()
+		st := fw.tiers.subtrees[root]
+		switch {
+		case st == nil || st.cold != nil:
+			fw.tiers.mu.Unlock()
+			continue
+		case err != nil:
+			// The subtree is gone; it is tracked again if it is recreated
+			delete(fw.tiers.subtrees, root)
+			fw.tiers.mu.Unlock()
+			continue
+		case !st.lastAccess.Before(cutoff):
+			fw.tiers.mu.Unlock()
+			continue
+		}
+		st.cold = states
+		st.accesses = nil
+		fw.tiers.mu.Unlock()
+
+		if fw.debug {
+			log.Printf("Demoting %s to checked on access", root)
+		}
+		fw.mu.RLock()
+		var dirs []string
+		for dir := range fw.watchedDirs {
+			if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+				dirs = append(dirs, dir)
+			}
+		}
+		fw.mu.RUnlock()
+		for _, dir := range dirs {
+			fw.stopWatching(dir)
+		}
+	}
+}
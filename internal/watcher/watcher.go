@@ -2,14 +2,23 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
 	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/iosched"
+	"github.com/isaacphi/mcp-filesystem/internal/longpath"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
 )
 
 // Event types for file system changes
@@ -17,6 +26,9 @@ const (
 	EventCreate int = iota
 	EventModify
 	EventDelete
+	// EventRescan asks for the whole workspace to be rescanned because
+	// events were lost; it has no path
+	EventRescan
 )
 
 // FileEvent represents a file system event
@@ -28,36 +40,155 @@ type FileEvent struct {
 // FileWatcher watches a workspace for file changes
 type FileWatcher struct {
 	workspacePath string
+	files         *fsys.Workspace
 	matcher       *gitignore.Matcher
-	watcher       *fsnotify.Watcher
+	diagnostics   *diagnostics.Diagnostics
+	watcher       EventSource // nil in poll and watchman modes
+	newSource     func() (EventSource, error)
+	clock         Clock
+	onHandled     func() // test hooks from Fakes; nil otherwise
+	onRestarted   func()
+	watchman      *watchmanClient // nil unless in watchman mode
+	pollInterval  time.Duration
+	scheduler     *iosched.Scheduler // paces poll mode rescans
+	profile       *ScanProfile       // filled in by the initial scan when profiling
 	events        chan FileEvent
 	done          chan struct{}
 	watchedDirs   map[string]bool
+	roots         []string   // watched subtrees of the workspace; nil watches all of it
+	tiers         *tierState // nil unless tiered watching is enabled in notify mode
+	lastRestart   time.Time  // when a failed fsnotify watcher was last replaced
+	restartDelay  time.Duration
+	settle        settler
 	mu            sync.RWMutex
 	debug         bool
 }
 
-// NewFileWatcher creates a new file watcher
-func NewFileWatcher(workspacePath string, debug bool) (*FileWatcher, error) {
-	matcher, err := gitignore.NewMatcher(workspacePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gitignore matcher: %v", err)
-	}
+// NewFileWatcher creates a new file watcher using the configured watch mode;
+// rescans in poll mode list directories within the scheduler's budget.
+// Workspaces that aren't on disk are always polled.
+func NewFileWatcher(files *fsys.Workspace, cfg *config.Config, diag *diagnostics.Diagnostics, scheduler *iosched.Scheduler, debug bool) (*FileWatcher, error) {
+	return newFileWatcher(files, cfg, diag, scheduler, Fakes{Source: newFSNotifySource, Clock: systemClock{}}, debug)
+}
 
-	watcher, err := fsnotify.NewWatcher()
+// NewFileWatcherWithFakes creates a file watcher in notify mode that reads
+// events from a fake source and settles changes by a fake clock; see the
+// watchertest package
+func NewFileWatcherWithFakes(files *fsys.Workspace, cfg *config.Config, diag *diagnostics.Diagnostics, fakes Fakes, debug bool) (*FileWatcher, error) {
+	cfg.Watch.Mode = config.WatchModeNotify
+	return newFileWatcher(files, cfg, diag, iosched.New(0, 0), fakes, debug)
+}
+
+// newFileWatcher creates a file watcher with the given sources of events
+// and time
+func newFileWatcher(files *fsys.Workspace, cfg *config.Config, diag *diagnostics.Diagnostics, scheduler *iosched.Scheduler, fakes Fakes, debug bool) (*FileWatcher, error) {
+	workspacePath := files.Root()
+	matcher, err := gitignore.NewMatcherFS(files, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %v", err)
+		return nil, fmt.Errorf("failed to create gitignore matcher: %v", err)
 	}
 
-	return &FileWatcher{
+	fw := &FileWatcher{
 		workspacePath: workspacePath,
+		files:         files,
 		matcher:       matcher,
-		watcher:       watcher,
+		diagnostics:   diag,
+		newSource:     fakes.Source,
+		clock:         fakes.Clock,
+		onHandled:     fakes.Handled,
+		onRestarted:   fakes.Restarted,
+		pollInterval:  cfg.Watch.PollInterval,
+		scheduler:     scheduler,
 		events:        make(chan FileEvent),
 		done:          make(chan struct{}),
 		watchedDirs:   make(map[string]bool),
+		settle:        settler{delay: cfg.Watch.SettleDelay, pending: make(map[string]*pendingChange)},
 		debug:         debug,
-	}, nil
+	}
+	for _, path := range cfg.Watch.Paths {
+		root := filepath.Join(workspacePath, filepath.FromSlash(path))
+		if _, err := files.Stat(root); err != nil {
+			log.Printf("Warning: watch path %s can't be watched: %v", path, err)
+		}
+		fw.roots = append(fw.roots, root)
+	}
+
+	mode, fsType, err := ResolveMode(workspacePath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !files.OnDisk() {
+		mode, fsType = config.WatchModePoll, ""
+	}
+	if fsType != "" {
+		log.Printf("Workspace is on a network filesystem (%s); polling for changes every %v", fsType, fw.pollInterval)
+	}
+
+	switch mode {
+	case config.WatchModeNotify:
+		fw.watcher, err = fw.newSource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create watcher: %v", err)
+		}
+		fw.tiers = newTierState(cfg.Watch.Tiers)
+	case config.WatchModeWatchman:
+		fw.watchman, err = newWatchmanClient(workspacePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fw.debug {
+		log.Printf("Watch mode: %s", mode)
+	}
+
+	return fw, nil
+}
+
+// ResolveMode returns the watch mode that will be used for a workspace. When
+// "auto" falls back to polling, fsType names the network filesystem detected.
+func ResolveMode(workspacePath string, cfg *config.Config) (mode string, fsType string, err error) {
+	switch cfg.Watch.Mode {
+	case "", config.WatchModeAuto:
+		if fsType, ok := networkFilesystem(workspacePath); ok {
+			return config.WatchModePoll, fsType, nil
+		}
+		return config.WatchModeNotify, "", nil
+	case config.WatchModeNotify, config.WatchModePoll, config.WatchModeWatchman:
+		return cfg.Watch.Mode, "", nil
+	}
+	return "", "", fmt.Errorf("unknown watch mode %q (expected auto, notify, poll or watchman)", cfg.Watch.Mode)
+}
+
+// Matcher returns the ignore matcher used by the watcher
+func (fw *FileWatcher) Matcher() *gitignore.Matcher {
+	return fw.matcher
+}
+
+// Watched reports whether a path is in a watched subtree or a mount, so
+// its file is listed and its changes are reported
+func (fw *FileWatcher) Watched(path string) bool {
+	if fw.roots == nil {
+		return true
+	}
+	if _, _, mounted := fw.files.MountPoint(path); mounted {
+		return true
+	}
+	for _, root := range fw.roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanRoots returns the directories the initial listing and poll mode walk:
+// the whole workspace, or the watched subtrees and the mounts
+func (fw *FileWatcher) scanRoots() []string {
+	if fw.roots == nil {
+		return []string{fw.workspacePath}
+	}
+	return append(append([]string{}, fw.roots...), fw.files.MountPaths()...)
 }
 
 // startWatching adds a directory to the watcher
@@ -70,12 +201,15 @@ func (fw *FileWatcher) startWatching(path string) error {
 		return nil
 	}
 
-	// Add to watcher
-	if err := fw.watcher.Add(path); err != nil {
-		return err
+	// Add to watcher; in poll mode directories are only tracked
+	if fw.watcher != nil {
+		if err := fw.watcher.Add(longpath.Fix(path)); err != nil {
+			return err
+		}
 	}
 
 	fw.watchedDirs[path] = true
+	fw.trackSubtree(path)
 	if fw.debug {
 		log.Printf("Started watching: %s", path)
 	}
@@ -89,7 +223,9 @@ func (fw *FileWatcher) stopWatching(path string) {
 	defer fw.mu.Unlock()
 
 	if fw.watchedDirs[path] {
-		_ = fw.watcher.Remove(path)
+		if fw.watcher != nil {
+			_ = fw.watcher.Remove(longpath.Fix(path))
+		}
 		delete(fw.watchedDirs, path)
 		if fw.debug {
 			log.Printf("Stopped watching: %s", path)
@@ -99,6 +235,19 @@ func (fw *FileWatcher) stopWatching(path string) {
 
 // Start begins watching the workspace for changes
 func (fw *FileWatcher) Start(ctx context.Context) (<-chan FileEvent, error) {
+	if fw.watchman != nil {
+		decoder, err := fw.watchman.subscribe()
+		if err != nil {
+			return nil, err
+		}
+		go fw.watchmanLoop(decoder)
+		return fw.events, fw.startPolling(ctx, fw.files.MountPaths())
+	}
+
+	if fw.watcher == nil {
+		return fw.events, fw.startPolling(ctx, fw.scanRoots())
+	}
+
 	// Perform an initial scan of the workspace
 	if err := fw.scanWorkspace(); err != nil {
 		return nil, err
@@ -106,27 +255,82 @@ func (fw *FileWatcher) Start(ctx context.Context) (<-chan FileEvent, error) {
 
 	// Start the event loop
 	go fw.eventLoop(ctx)
+	if fw.tiers != nil {
+		go fw.tierLoop(ctx)
+	}
 
-	return fw.events, nil
+	// Mounted filesystems don't send notifications
+	return fw.events, fw.startPolling(ctx, fw.files.MountPaths())
+}
+
+// startPolling takes a first snapshot of the given directories and polls
+// them for changes
+func (fw *FileWatcher) startPolling(ctx context.Context, roots []string) error {
+	if len(roots) == 0 {
+		return nil
+	}
+	states, err := fw.snapshot(roots, false)
+	if err != nil {
+		return err
+	}
+	go fw.pollLoop(ctx, roots, states)
+	return nil
 }
 
 // Stop stops watching for changes
 func (fw *FileWatcher) Stop() {
 	close(fw.done)
-	if err := fw.watcher.Close(); err != nil {
+	if fw.watchman != nil {
+		fw.watchman.close()
+	}
+	fw.mu.RLock()
+	watcher := fw.watcher
+	fw.mu.RUnlock()
+	if watcher == nil {
+		return
+	}
+	if err := watcher.Close(); err != nil {
 		log.Printf("Error closing watcher: %v", err)
 	}
 }
 
-// scanWorkspace recursively adds all directories in the workspace to the watcher
+// scanWorkspace recursively adds all directories in the workspace, or in
+// its watched subtrees, to the watcher. Paths that can't be read are
+// recorded in diagnostics instead of aborting the scan.
 func (fw *FileWatcher) scanWorkspace() error {
-	return filepath.Walk(fw.workspacePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	roots := fw.roots
+	if roots == nil {
+		roots = []string{fw.workspacePath}
+	}
+	for _, root := range roots {
+		if err := fw.scanTree(root); err != nil {
 			return err
 		}
+	}
+
+	if count := fw.diagnostics.Count(diagnostics.PermissionDenied); count > 0 {
+		log.Printf("Warning: %d paths could not be accessed; see workspace://diagnostics", count)
+	}
+
+	return nil
+}
+
+// scanTree adds the directories under root to the watcher
+func (fw *FileWatcher) scanTree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The workspace root itself must be readable
+			if path == fw.workspacePath {
+				return err
+			}
+			return fw.skipInaccessible(path, info, err)
+		}
 
-		// Skip ignored directories
+		// Skip ignored directories, and directories hidden by a mount
 		if info.IsDir() {
+			if _, _, mounted := fw.files.MountPoint(path); mounted {
+				return filepath.SkipDir
+			}
 			if fw.matcher.ShouldIgnoreDir(path) {
 				if fw.debug {
 					log.Printf("Skipping ignored directory: %s", path)
@@ -136,6 +340,9 @@ func (fw *FileWatcher) scanWorkspace() error {
 
 			// Add directory to watcher
 			if err := fw.startWatching(path); err != nil {
+				if os.IsPermission(err) {
+					return fw.skipInaccessible(path, info, err)
+				}
 				return err
 			}
 		}
@@ -144,30 +351,73 @@ func (fw *FileWatcher) scanWorkspace() error {
 	})
 }
 
-// eventLoop processes fsnotify events
+// skipInaccessible records a path that couldn't be read and tells the walk
+// to continue past it. Errors other than permission problems (such as files
+// vanishing mid-scan) are skipped without being recorded.
+func (fw *FileWatcher) skipInaccessible(path string, info os.FileInfo, err error) error {
+	if os.IsPermission(err) {
+		fw.diagnostics.Add(diagnostics.PermissionDenied, path, err.Error())
+		if fw.debug {
+			log.Printf("Skipping inaccessible path: %v", err)
+		}
+	}
+
+	if info != nil && info.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// eventLoop processes fsnotify events, replacing the watcher if it fails
 func (fw *FileWatcher) eventLoop(ctx context.Context) {
 	for {
+		fw.mu.RLock()
+		watcher := fw.watcher
+		fw.mu.RUnlock()
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-fw.done:
 			return
-		case event, ok := <-fw.watcher.Events:
+		case event, ok := <-watcher.Events():
 			if !ok {
-				return
+				if !fw.restart(ctx) {
+					return
+				}
+				continue
 			}
-			fw.handleFsEvent(event)
-		case err, ok := <-fw.watcher.Errors:
+			if _, _, mounted := fw.files.MountPoint(longpath.Strip(event.Name)); !mounted {
+				fw.handleFsEvent(event)
+			}
+			fw.handled()
+		case err, ok := <-watcher.Errors():
 			if !ok {
-				return
+				if !fw.restart(ctx) {
+					return
+				}
+				continue
 			}
 			log.Printf("Error: %v", err)
+			// The kernel dropped events when its queue overflowed
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				fw.requestRescan()
+			}
+			fw.handled()
 		}
 	}
 }
 
 // handleFsEvent processes a single fsnotify event
 func (fw *FileWatcher) handleFsEvent(event fsnotify.Event) {
+	// Directories watched with an extended-length prefix report it back
+	event.Name = longpath.Strip(event.Name)
+
+	// Events carry the on-disk spelling, which is decomposed on macOS; report
+	// composed names and keep the on-disk one for filesystem calls
+	diskPath := pathnorm.OnDisk(event.Name)
+	event.Name = pathnorm.Normalize(fw.workspacePath, event.Name)
+
 	// Check if this path should be ignored
 	if fw.matcher.ShouldIgnore(event.Name) {
 		return
@@ -178,38 +428,53 @@ func (fw *FileWatcher) handleFsEvent(event fsnotify.Event) {
 	}
 
 	// Get file info
-	fileInfo, err := os.Stat(event.Name)
+	fileInfo, err := fw.files.Stat(diskPath)
 	isDir := err == nil && fileInfo.IsDir()
 
+	// Patterns ending in "/" only match directories
+	if isDir && fw.matcher.ShouldIgnoreDir(event.Name) {
+		return
+	}
+
 	// Handle directory events
 	if isDir {
 		if event.Op&fsnotify.Create != 0 {
 			// New directory - add to watcher
-			if err := fw.startWatching(event.Name); err != nil {
+			if err := fw.startWatching(diskPath); err != nil {
 				log.Printf("Error watching new directory: %v", err)
 				return
 			}
 
 			// Scan the new directory for sub-directories
-			_ = filepath.Walk(event.Name, func(path string, info os.FileInfo, err error) error {
+			_ = fw.files.Walk(diskPath, func(path string, info os.FileInfo, err error) error {
 				if err != nil {
-					return nil
+					return fw.skipInaccessible(path, info, err)
 				}
-				if info.IsDir() && path != event.Name {
+				if info.IsDir() && path != diskPath {
 					if fw.matcher.ShouldIgnoreDir(path) {
 						return filepath.SkipDir
 					}
-					_ = fw.startWatching(path)
+					if err := fw.startWatching(path); os.IsPermission(err) {
+						return fw.skipInaccessible(path, info, err)
+					}
 				}
 				return nil
 			})
 		} else if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
 			// Directory removed - remove from watcher
-			fw.stopWatching(event.Name)
+			fw.stopWatching(diskPath)
 		}
 		return
 	}
 
+	// Never register FIFOs, sockets or devices; reading them can block forever
+	if err == nil {
+		if special := diagnostics.SpecialFileType(diskPath, fileInfo, fw.files.Stat); special != "" {
+			fw.skipSpecialFile(event.Name, special)
+			return
+		}
+	}
+
 	// Handle file events
 	var eventType int
 	if event.Op&fsnotify.Create != 0 {
@@ -218,46 +483,88 @@ func (fw *FileWatcher) handleFsEvent(event fsnotify.Event) {
 		eventType = EventModify
 	} else if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
 		eventType = EventDelete
+		fw.diagnostics.Remove(diagnostics.SpecialFile, event.Name)
+		fw.diagnostics.Remove(diagnostics.PermissionDenied, event.Name)
 	} else {
 		// Ignore other event types
 		return
 	}
 
-	// Send event to channel
-	select {
-	case fw.events <- FileEvent{Path: event.Name, EventType: eventType}:
-	case <-fw.done:
-		return
+	// Send event to channel once the file has settled
+	if err != nil {
+		fileInfo = nil
 	}
+	fw.emit(event.Name, diskPath, eventType, fileInfo)
 }
 
 // GetInitialFiles returns a list of all existing files in the workspace
 func (fw *FileWatcher) GetInitialFiles() ([]string, error) {
+	start := time.Now()
+	if fw.watchman != nil {
+		files, err := fw.watchmanInitialFiles()
+		if fw.profile != nil {
+			fw.profile.Walk = time.Since(start)
+			fw.profile.Files = len(files)
+		}
+		return files, err
+	}
+
+	var files []string
+	for _, root := range fw.scanRoots() {
+		walked, err := fw.walkFiles(root)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, walked...)
+	}
+
+	if fw.profile != nil {
+		fw.profile.Walk = time.Since(start)
+	}
+	return files, nil
+}
+
+// walkFiles lists the files under root that aren't ignored
+func (fw *FileWatcher) walkFiles(root string) ([]string, error) {
 	var files []string
 
-	err := filepath.Walk(fw.workspacePath, func(path string, info os.FileInfo, err error) error {
+	err := fw.files.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return nil // Skip files with errors
+			return fw.skipInaccessible(path, info, err)
 		}
 
 		// Skip directories and ignored files
 		if info.IsDir() {
-			if fw.matcher.ShouldIgnoreDir(path) {
+			if fw.ignoreDir(path) {
 				return filepath.SkipDir
 			}
 			return nil
 		}
 
-		if !fw.matcher.ShouldIgnore(path) {
-			files = append(files, path)
+		if fw.ignoreFile(path) {
+			return nil
+		}
+
+		if special := diagnostics.SpecialFileType(path, info, fw.files.Stat); special != "" {
+			fw.skipSpecialFile(path, special)
+			return nil
 		}
 
+		fw.countFile(path)
+		files = append(files, pathnorm.Normalize(fw.workspacePath, path))
 		return nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
-
 	return files, nil
 }
+
+// skipSpecialFile records a non-regular file that won't be registered
+func (fw *FileWatcher) skipSpecialFile(path, fileType string) {
+	fw.diagnostics.Add(diagnostics.SpecialFile, path, fileType)
+	if fw.debug {
+		log.Printf("Skipping special file (%s): %s", fileType, path)
+	}
+}
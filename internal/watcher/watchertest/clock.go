@@ -0,0 +1,150 @@
+package watchertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// Clock is a fake watcher.Clock whose time only moves when Advance is
+// called, so settle delays and restart backoff happen exactly when a test
+// says
+type Clock struct {
+	now    time.Time
+	timers []*timer
+	mu     sync.Mutex
+}
+
+// timer is a call or channel send due at a time on a Clock
+type timer struct {
+	clock  *Clock
+	due    time.Time
+	fire   func()
+	active bool
+}
+
+// NewClock creates a fake clock starting at an arbitrary fixed time
+func NewClock() *Clock {
+	return &Clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc calls f in its own goroutine once the clock has advanced by d
+func (c *Clock) AfterFunc(d time.Duration, f func()) watcher.Timer {
+	return c.add(d, func() { go f() })
+}
+
+// After sends the fake time on the returned channel once the clock has
+// advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.add(d, func() { ch <- c.Now() })
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers that fall due in
+// the order they are due
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		next := c.next(end)
+		if next == nil {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		c.now = next.due
+		next.active = false
+		c.mu.Unlock()
+		next.fire()
+	}
+}
+
+// Pending returns how many timers are waiting to fire
+func (c *Clock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+// BlockUntil waits until n timers are pending, for a timer started by
+// another goroutine, reporting false if there aren't within Wait
+func (c *Clock) BlockUntil(n int) bool {
+	deadline := time.Now().Add(Wait)
+	for c.Pending() < n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// add schedules fire after d
+func (c *Clock) add(d time.Duration, fire func()) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{clock: c, due: c.now.Add(d), fire: fire, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// next returns the earliest active timer due by end, dropping inactive
+// ones; the caller holds the lock
+func (c *Clock) next(end time.Time) *timer {
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.active {
+			active = append(active, t)
+		}
+	}
+	c.timers = active
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].due.Before(c.timers[j].due) })
+	if len(c.timers) == 0 || c.timers[0].due.After(end) {
+		return nil
+	}
+	return c.timers[0]
+}
+
+// Stop cancels the timer, reporting whether it was still pending
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+// Reset makes the timer fire d after the clock's current time, reporting
+// whether it was still pending
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.active
+	t.due = t.clock.now.Add(d)
+	t.active = true
+	for _, scheduled := range t.clock.timers {
+		if scheduled == t {
+			return wasActive
+		}
+	}
+	t.clock.timers = append(t.clock.timers, t)
+	return wasActive
+}
@@ -0,0 +1,228 @@
+// Package watchertest drives a watcher.FileWatcher with synthetic events
+// and a fake clock, so how it settles changes, reports renames and deletions
+// and recovers from overflows and failures can be checked deterministically.
+//
+// A harness watches a real directory, since the watcher stats the files
+// events name, but nothing reaches it unless injected:
+//
+//	h, err := watchertest.New(dir, config.Default())
+//	...
+//	defer h.Close()
+//	h.Write("a.go", "package a")        // writes the file and injects Create
+//	h.Clock.Advance(config.DefaultSettleDelay)
+//	event, ok := h.Next()                // {dir/a.go, EventCreate}
+//
+// Injections return once the watcher has handled them, so a following
+// Advance fires the timers they started. After a source fails, WaitRestart
+// waits for the watcher to replace it before more is injected.
+package watchertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// Wait is how long Next waits for an event, and WaitRestart and
+// Clock.BlockUntil for theirs. Events are delivered by
+// goroutines the fake clock starts, so they take a moment even though no
+// fake time passes.
+var Wait = time.Second
+
+// Harness is a FileWatcher wired to a fake event source and clock
+type Harness struct {
+	Root    string
+	Clock   *Clock
+	Watcher *watcher.FileWatcher
+	// sources are the event sources created so far; the last is current,
+	// and a new one is created each time the watcher restarts
+	sources []*Source
+	// reported holds the events the watcher reported, read as they are
+	// sent since the watcher blocks until they are
+	reported chan watcher.FileEvent
+	// handled is shared by the sources to hear that an injection was
+	// handled, and restarts hears of each restart
+	handled  chan struct{}
+	restarts chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+	close    sync.Once
+	mu       sync.Mutex
+}
+
+// New starts a watcher of root in notify mode with a fake event source and
+// clock
+func New(root string, cfg *config.Config) (*Harness, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{
+		Root:     root,
+		Clock:    NewClock(),
+		reported: make(chan watcher.FileEvent, 1024),
+		handled:  make(chan struct{}),
+		restarts: make(chan struct{}, 64),
+		done:     make(chan struct{}),
+	}
+	fakes := watcher.Fakes{
+		Source: h.newSource,
+		Clock:  h.Clock,
+		Handled: func() {
+			select {
+			case h.handled <- struct{}{}:
+			case <-h.done:
+			}
+		},
+		Restarted: func() { h.restarts <- struct{}{} },
+	}
+	h.Watcher, err = watcher.NewFileWatcherWithFakes(fsys.OS(root), cfg, diagnostics.New(root), fakes, false)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	events, err := h.Watcher.Start(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		for {
+			select {
+			case event := <-events:
+				h.reported <- event
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return h, nil
+}
+
+// newSource creates the watcher's next event source
+func (h *Harness) newSource() (watcher.EventSource, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	source := NewSource()
+	source.handled = h.handled
+	h.sources = append(h.sources, source)
+	return source, nil
+}
+
+// Source returns the current event source
+func (h *Harness) Source() *Source {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sources[len(h.sources)-1]
+}
+
+// Sources returns the event sources created so far, oldest first
+func (h *Harness) Sources() []*Source {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*Source(nil), h.sources...)
+}
+
+// WaitRestart waits for the watcher to replace a failed source and request
+// a rescan, reporting false if it doesn't within Wait. A restart soon after
+// another backs off first: wait for its timer with Clock.BlockUntil and
+// advance the clock past it.
+func (h *Harness) WaitRestart() bool {
+	select {
+	case <-h.restarts:
+		return true
+	case <-time.After(Wait):
+		return false
+	}
+}
+
+// Path returns the absolute path of a slash-separated name in the root
+func (h *Harness) Path(name string) string {
+	return filepath.Join(h.Root, filepath.FromSlash(name))
+}
+
+// Write writes a file and injects the event a write makes: Create for a new
+// file, Write for an existing one
+func (h *Harness) Write(name, content string) error {
+	path := h.Path(name)
+	op := fsnotify.Write
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		op = fsnotify.Create
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	return h.Source().Inject(path, op)
+}
+
+// Remove removes a file and injects Remove
+func (h *Harness) Remove(name string) error {
+	path := h.Path(name)
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return h.Source().Inject(path, fsnotify.Remove)
+}
+
+// Rename renames a file and injects what fsnotify reports: Rename for the
+// old name and Create for the new one
+func (h *Harness) Rename(oldName, newName string) error {
+	oldPath, newPath := h.Path(oldName), h.Path(newName)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if err := h.Source().Inject(oldPath, fsnotify.Rename); err != nil {
+		return err
+	}
+	return h.Source().Inject(newPath, fsnotify.Create)
+}
+
+// Overflow injects the error fsnotify reports when the kernel drops events
+func (h *Harness) Overflow() error {
+	return h.Source().InjectError(fsnotify.ErrEventOverflow)
+}
+
+// Next returns the next event the watcher reports, or false if there is
+// none within Wait
+func (h *Harness) Next() (watcher.FileEvent, bool) {
+	select {
+	case event := <-h.reported:
+		return event, true
+	case <-time.After(Wait):
+		return watcher.FileEvent{}, false
+	}
+}
+
+// Drain returns the events reported until none arrives within Wait
+func (h *Harness) Drain() []watcher.FileEvent {
+	var events []watcher.FileEvent
+	for {
+		event, ok := h.Next()
+		if !ok {
+			return events
+		}
+		events = append(events, event)
+	}
+}
+
+// Close stops the watcher; closing again does nothing
+func (h *Harness) Close() {
+	h.close.Do(func() {
+		close(h.done)
+		h.cancel()
+		h.Watcher.Stop()
+	})
+}
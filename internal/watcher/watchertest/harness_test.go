@@ -0,0 +1,161 @@
+package watchertest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher/watchertest"
+)
+
+// start runs a harness on a fresh directory, stopped when the test ends
+func start(t *testing.T) *watchertest.Harness {
+	t.Helper()
+	h, err := watchertest.New(t.TempDir(), config.Default())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+// expect reads the next event and checks it
+func expect(t *testing.T, h *watchertest.Harness, want watcher.FileEvent) {
+	t.Helper()
+	got, ok := h.Next()
+	if !ok {
+		t.Fatalf("no event, want %+v", want)
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// expectNone checks that nothing more is reported
+func expectNone(t *testing.T, h *watchertest.Harness) {
+	t.Helper()
+	if events := h.Drain(); len(events) > 0 {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestCreateSettles(t *testing.T) {
+	// Repeated so that an injection returning before the watcher started
+	// the settle timer would show up as a lost event
+	for i := 0; i < 50; i++ {
+		h := start(t)
+		if err := h.Write("a.go", "package a"); err != nil {
+			t.Fatal(err)
+		}
+		if h.Clock.Pending() != 1 {
+			t.Fatalf("run %d: %d timers pending after the write, want 1", i, h.Clock.Pending())
+		}
+		h.Clock.Advance(config.DefaultSettleDelay)
+		expect(t, h, watcher.FileEvent{Path: h.Path("a.go"), EventType: watcher.EventCreate})
+		h.Close()
+	}
+}
+
+func TestWritesCoalesce(t *testing.T) {
+	h := start(t)
+	for _, content := range []string{"a", "ab", "abc"} {
+		if err := h.Write("a.txt", content); err != nil {
+			t.Fatal(err)
+		}
+		h.Clock.Advance(config.DefaultSettleDelay / 2)
+	}
+	h.Clock.Advance(config.DefaultSettleDelay)
+	// Still a creation, since it wasn't reported before the later writes
+	expect(t, h, watcher.FileEvent{Path: h.Path("a.txt"), EventType: watcher.EventCreate})
+
+	if err := h.Write("a.txt", "abcd"); err != nil {
+		t.Fatal(err)
+	}
+	h.Clock.Advance(config.DefaultSettleDelay)
+	expect(t, h, watcher.FileEvent{Path: h.Path("a.txt"), EventType: watcher.EventModify})
+	expectNone(t, h)
+}
+
+func TestRemoveDropsPendingChange(t *testing.T) {
+	h := start(t)
+	if err := h.Write("a.txt", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Remove("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, h, watcher.FileEvent{Path: h.Path("a.txt"), EventType: watcher.EventDelete})
+	h.Clock.Advance(config.DefaultSettleDelay)
+	expectNone(t, h)
+}
+
+func TestRename(t *testing.T) {
+	h := start(t)
+	if err := h.Write("old.txt", "a"); err != nil {
+		t.Fatal(err)
+	}
+	h.Clock.Advance(config.DefaultSettleDelay)
+	expect(t, h, watcher.FileEvent{Path: h.Path("old.txt"), EventType: watcher.EventCreate})
+
+	if err := h.Rename("old.txt", "new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, h, watcher.FileEvent{Path: h.Path("old.txt"), EventType: watcher.EventDelete})
+	h.Clock.Advance(config.DefaultSettleDelay)
+	expect(t, h, watcher.FileEvent{Path: h.Path("new.txt"), EventType: watcher.EventCreate})
+}
+
+func TestOverflowRequestsRescan(t *testing.T) {
+	h := start(t)
+	if err := h.Overflow(); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, h, watcher.FileEvent{EventType: watcher.EventRescan})
+}
+
+func TestRestart(t *testing.T) {
+	h := start(t)
+	failed := h.Source()
+	failed.Fail()
+	if !h.WaitRestart() {
+		t.Fatal("watcher didn't restart")
+	}
+	expect(t, h, watcher.FileEvent{EventType: watcher.EventRescan})
+	if got := len(h.Sources()); got != 2 {
+		t.Fatalf("%d sources after a restart, want 2", got)
+	}
+	if h.Source() == failed {
+		t.Fatal("the failed source is still current")
+	}
+	if err := failed.Inject(h.Path("a.txt"), 0); err == nil {
+		t.Fatal("injecting into a failed source succeeded")
+	}
+	if watched := h.Source().Watched(); len(watched) == 0 || watched[0] != h.Root {
+		t.Fatalf("new source watches %v, want the root", watched)
+	}
+
+	// The new source's events are delivered
+	if err := h.Write("a.txt", "a"); err != nil {
+		t.Fatal(err)
+	}
+	h.Clock.Advance(config.DefaultSettleDelay)
+	expect(t, h, watcher.FileEvent{Path: h.Path("a.txt"), EventType: watcher.EventCreate})
+
+	// Failing again straight away backs off before the next restart
+	h.Source().Fail()
+	if !h.Clock.BlockUntil(1) {
+		t.Fatal("no backoff before the second restart")
+	}
+	if h.WaitRestart() {
+		t.Fatal("restarted before the backoff passed")
+	}
+	h.Clock.Advance(time.Second)
+	if !h.WaitRestart() {
+		t.Fatal("watcher didn't restart after the backoff")
+	}
+	expect(t, h, watcher.FileEvent{EventType: watcher.EventRescan})
+	if got := len(h.Sources()); got != 3 {
+		t.Fatalf("%d sources after two restarts, want 3", got)
+	}
+}
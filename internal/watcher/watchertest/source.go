@@ -0,0 +1,124 @@
+package watchertest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Source is a fake watcher.EventSource that delivers only the events a test
+// injects
+type Source struct {
+	events  chan fsnotify.Event
+	errors  chan error
+	watched map[string]bool
+	closed  bool
+	mu      sync.Mutex
+	// handled receives once the watcher has handled each injection; nil
+	// outside of a harness, where injections only wait to be read
+	handled chan struct{}
+	// sending is held while injecting, so Close waits rather than closing
+	// the channels under a send
+	sending sync.Mutex
+}
+
+// NewSource creates a fake event source
+func NewSource() *Source {
+	return &Source{
+		events:  make(chan fsnotify.Event),
+		errors:  make(chan error),
+		watched: make(map[string]bool),
+	}
+}
+
+// Add records a watched directory
+func (s *Source) Add(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("source is closed")
+	}
+	s.watched[path] = true
+	return nil
+}
+
+// Remove forgets a watched directory
+func (s *Source) Remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.watched, path)
+	return nil
+}
+
+// Events returns the channel injected events are delivered on
+func (s *Source) Events() <-chan fsnotify.Event {
+	return s.events
+}
+
+// Errors returns the channel injected errors are delivered on
+func (s *Source) Errors() <-chan error {
+	return s.errors
+}
+
+// Close closes the channels, as fsnotify does
+func (s *Source) Close() error {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+		close(s.errors)
+	}
+	return nil
+}
+
+// Watched returns the directories being watched, sorted
+func (s *Source) Watched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dirs := make([]string, 0, len(s.watched))
+	for dir := range s.watched {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Inject delivers an event, waiting until the watcher has handled it. It
+// fails once the source is closed.
+func (s *Source) Inject(path string, op fsnotify.Op) error {
+	return s.inject(func() { s.events <- fsnotify.Event{Name: path, Op: op} })
+}
+
+// InjectError delivers an error, such as fsnotify.ErrEventOverflow, waiting
+// until the watcher has handled it. It fails once the source is closed.
+func (s *Source) InjectError(err error) error {
+	return s.inject(func() { s.errors <- err })
+}
+
+// inject makes one delivery and waits for the watcher to handle it
+func (s *Source) inject(deliver func()) error {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return fmt.Errorf("source is closed")
+	}
+	deliver()
+	if s.handled != nil {
+		<-s.handled
+	}
+	return nil
+}
+
+// Fail closes the source as a failing fsnotify watcher does, which makes
+// the watcher replace it
+func (s *Source) Fail() {
+	_ = s.Close()
+}
@@ -0,0 +1,275 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/isaacphi/mcp-filesystem/internal/diagnostics"
+	"github.com/isaacphi/mcp-filesystem/internal/pathnorm"
+)
+
+// watchmanSubscription is the name the server subscribes under
+const watchmanSubscription = "mcp-filesystem"
+
+// watchmanClient talks to a running Watchman daemon through the watchman CLI
+type watchmanClient struct {
+	root         string // watched project root, may be an ancestor of the workspace
+	relativePath string // workspace path relative to root, "" if they are the same
+	clock        string // position after the last query
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+}
+
+// watchmanFile is a file entry in query results and subscription updates
+type watchmanFile struct {
+	Name   string `json:"name"`
+	Exists bool   `json:"exists"`
+	New    bool   `json:"new"`
+	Type   string `json:"type"`
+}
+
+// watchmanResponse covers the fields used from Watchman responses
+type watchmanResponse struct {
+	Error           string         `json:"error"`
+	Watch           string         `json:"watch"`
+	RelativePath    string         `json:"relative_path"`
+	Clock           string         `json:"clock"`
+	Subscription    string         `json:"subscription"`
+	IsFreshInstance bool           `json:"is_fresh_instance"`
+	Files           []watchmanFile `json:"files"`
+}
+
+// newWatchmanClient asks Watchman to watch the project containing the workspace
+func newWatchmanClient(workspacePath string) (*watchmanClient, error) {
+	if _, err := exec.LookPath("watchman"); err != nil {
+		return nil, fmt.Errorf("watchman watch mode requires the watchman binary: %v", err)
+	}
+
+	resp, err := watchmanCommand("watch-project", workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &watchmanClient{
+		root:         resp.Watch,
+		relativePath: resp.RelativePath,
+	}, nil
+}
+
+// watchmanCommand runs a single Watchman command and decodes its response
+func watchmanCommand(command ...any) (*watchmanResponse, error) {
+	input, err := json.Marshal(command)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode watchman command: %v", err)
+	}
+
+	cmd := exec.Command("watchman", "-j", "--no-pretty")
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("watchman %v failed: %v: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp watchmanResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse watchman response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("watchman %v failed: %s", command[0], resp.Error)
+	}
+	return &resp, nil
+}
+
+// queryParams returns the query shared by enumeration and the subscription:
+// regular files and symlinks under the workspace
+func (c *watchmanClient) queryParams() map[string]any {
+	params := map[string]any{
+		"expression": []any{"anyof", []any{"type", "f"}, []any{"type", "l"}},
+		"fields":     []string{"name", "exists", "new", "type"},
+	}
+	if c.relativePath != "" {
+		params["relative_root"] = c.relativePath
+	}
+	return params
+}
+
+// files lists all files Watchman knows about and remembers the clock so the
+// subscription only reports later changes
+func (c *watchmanClient) files() ([]watchmanFile, error) {
+	resp, err := watchmanCommand("query", c.root, c.queryParams())
+	if err != nil {
+		return nil, err
+	}
+	c.clock = resp.Clock
+	return resp.Files, nil
+}
+
+// subscribe starts a persistent watchman session and returns a decoder for
+// its unilateral subscription updates
+func (c *watchmanClient) subscribe() (*json.Decoder, error) {
+	params := c.queryParams()
+	if c.clock == "" {
+		resp, err := watchmanCommand("clock", c.root)
+		if err != nil {
+			return nil, err
+		}
+		c.clock = resp.Clock
+	}
+	params["since"] = c.clock
+
+	input, err := json.Marshal([]any{"subscribe", c.root, watchmanSubscription, params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode watchman command: %v", err)
+	}
+
+	cmd := exec.Command("watchman", "-j", "-p", "--no-pretty")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watchman stdin: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watchman stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start watchman: %v", err)
+	}
+
+	// Persistent mode ends the session when stdin closes, so it stays open
+	// until the watcher stops
+	if _, err := stdin.Write(append(input, '\n')); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("failed to subscribe: %v", err)
+	}
+
+	decoder := json.NewDecoder(stdout)
+	var ack watchmanResponse
+	if err := decoder.Decode(&ack); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("failed to read watchman subscribe response: %v", err)
+	}
+	if ack.Error != "" {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, fmt.Errorf("watchman subscribe failed: %s", ack.Error)
+	}
+
+	c.cmd = cmd
+	c.stdin = stdin
+	return decoder, nil
+}
+
+// close ends the persistent watchman session
+func (c *watchmanClient) close() {
+	if c.cmd == nil {
+		return
+	}
+	_ = c.stdin.Close()
+	_ = c.cmd.Process.Kill()
+	_ = c.cmd.Wait()
+	c.cmd = nil
+}
+
+// watchmanInitialFiles enumerates the workspace through Watchman instead of walking it
+func (fw *FileWatcher) watchmanInitialFiles() ([]string, error) {
+	entries, err := fw.watchman.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		path := filepath.Join(fw.workspacePath, filepath.FromSlash(entry.Name))
+		if _, _, mounted := fw.files.MountPoint(path); mounted || !fw.Watched(path) {
+			continue
+		}
+		if fw.matcher.ShouldIgnore(path) {
+			continue
+		}
+
+		// Watchman reports symlinks by type only; check what they point at
+		if entry.Type == "l" {
+			info, err := os.Lstat(path)
+			if err != nil {
+				continue
+			}
+			if special := diagnostics.SpecialFileType(path, info, os.Stat); special != "" {
+				fw.skipSpecialFile(path, special)
+				continue
+			}
+		}
+
+		files = append(files, pathnorm.Normalize(fw.workspacePath, path))
+	}
+
+	// Watchman only sees the workspace directory, so walk mounts instead
+	for _, mountPath := range fw.files.MountPaths() {
+		mounted, err := fw.walkFiles(mountPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, mounted...)
+	}
+
+	return files, nil
+}
+
+// watchmanLoop turns subscription updates into file events
+func (fw *FileWatcher) watchmanLoop(decoder *json.Decoder) {
+	for {
+		var resp watchmanResponse
+		if err := decoder.Decode(&resp); err != nil {
+			select {
+			case <-fw.done:
+			default:
+				if !errors.Is(err, io.EOF) {
+					log.Printf("Error reading watchman subscription: %v", err)
+				}
+				log.Printf("Watchman subscription ended; changes will no longer be detected")
+			}
+			return
+		}
+
+		if resp.Error != "" {
+			log.Printf("Watchman error: %s", resp.Error)
+			continue
+		}
+		if resp.Subscription != watchmanSubscription {
+			continue
+		}
+
+		// After a Watchman restart the update lists every existing file
+		// rather than the changes, so treat them all as created
+		if resp.IsFreshInstance && fw.debug {
+			log.Printf("Watchman restarted; re-registering %d files", len(resp.Files))
+		}
+
+		for _, file := range resp.Files {
+			path := filepath.Join(fw.workspacePath, filepath.FromSlash(file.Name))
+			if _, _, mounted := fw.files.MountPoint(path); mounted || !fw.Watched(path) {
+				continue
+			}
+			op := fsnotify.Write
+			switch {
+			case !file.Exists:
+				op = fsnotify.Remove
+			case file.New || resp.IsFreshInstance:
+				op = fsnotify.Create
+			}
+			fw.handleFsEvent(fsnotify.Event{Name: path, Op: op})
+		}
+	}
+}
@@ -1,39 +1,101 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"syscall"
-	"time"
-
-	"github.com/isaacphi/mcp-filesystem/internal/server"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/dockerfs"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/resources"
+	"github.com/isaacphi/mcp-filesystem/internal/s3fs"
+	"github.com/isaacphi/mcp-filesystem/internal/sftpfs"
 )
 
 var (
 	debug = os.Getenv("DEBUG") != ""
 )
 
+const usage = `Usage: mcp-filesystem [command] [flags]
+
+Commands:
+  serve     Run the MCP server (default)
+  init      Write a config for a workspace and print client config snippets
+  print-client-config
+            Print the config that adds the server to MCP clients
+  inspect   Show what would be exposed for a workspace
+  validate  Check the config and ignore files
+  selftest  Check that this build can serve a scratch workspace
+
+Run "mcp-filesystem <command> -h" for the flags of a command.
+`
+
 func main() {
-	// Parse command line arguments
-	workspaceDir := flag.String("workspace", "", "Path to workspace directory")
-	debugFlag := flag.Bool("debug", debug, "Enable debug output")
-	flag.Parse()
+	// Flags without a command run the server, as before subcommands existed
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		runServe(args)
+	case "init":
+		os.Exit(runInit(args))
+	case "print-client-config":
+		os.Exit(runPrintClientConfig(args))
+	case "inspect":
+		runInspect(args)
+	case "validate":
+		os.Exit(runValidate(args))
+	case "selftest":
+		os.Exit(runSelftest(args))
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// workspaceFlags are the flags shared by every command
+type workspaceFlags struct {
+	workspaceDir *string
+	configPath   *string
+	profile      *string
+	debug        *bool
+}
 
+// addWorkspaceFlags registers the shared flags on a command's flag set
+func addWorkspaceFlags(flags *flag.FlagSet) *workspaceFlags {
+	return &workspaceFlags{
+		workspaceDir: flags.String("workspace", "", "Path to workspace directory, sftp://[user@]host[:port]/path to serve one over SSH, s3://bucket/prefix to serve a bucket read-only, or docker://container/path to serve a directory in a running container"),
+		configPath:   flags.String("config", "", "Path to config file (default: <workspace>/"+config.DefaultFileName+")"),
+		profile:      flags.String("profile", "", "Config profile to apply, such as a read-only one, bundling tools, ignore patterns and policies"),
+		debug:        flags.Bool("debug", debug, "Enable debug output"),
+	}
+}
+
+// workspace validates the workspace flag and returns its absolute path
+func (f *workspaceFlags) workspace() string {
 	// Set debug flag if specified on command line
-	if *debugFlag {
+	if *f.debug {
 		debug = true
 	}
 
 	// Validate workspace directory
-	if *workspaceDir == "" {
+	if *f.workspaceDir == "" {
 		log.Fatal("workspace directory is required")
 	}
 
 	// Get absolute path to workspace directory
-	absWorkspaceDir, err := filepath.Abs(*workspaceDir)
+	absWorkspaceDir, err := filepath.Abs(*f.workspaceDir)
 	if err != nil {
 		log.Fatalf("Failed to get absolute path for workspace: %v", err)
 	}
@@ -43,86 +105,115 @@ func main() {
 		log.Fatalf("Workspace directory does not exist: %s", absWorkspaceDir)
 	}
 
-	// Create done channel for shutdown signal
-	done := make(chan struct{})
-
-	// Set up signal handling for clean shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Create and start MCP server
-	mcpServer, err := server.NewMCPServer(absWorkspaceDir, debug)
-	if err != nil {
-		log.Fatalf("Failed to create MCP server: %v", err)
-	}
+	return absWorkspaceDir
+}
 
-	if debug {
-		log.Printf("Starting MCP server for workspace: %s", absWorkspaceDir)
+// remote returns the URL of a remote workspace when the workspace flag
+// names one instead of a local directory
+func (f *workspaceFlags) remote() (string, bool) {
+	if !isRemoteURL(*f.workspaceDir) {
+		return "", false
 	}
-
-	if err := mcpServer.Start(); err != nil {
-		log.Fatalf("Failed to start MCP server: %v", err)
+	if *f.debug {
+		debug = true
 	}
+	return *f.workspaceDir, true
+}
 
-	// Monitor parent process termination
-	// Claude desktop does not properly kill child processes for MCP servers
-	go monitorParentProcess(done)
+// isRemoteURL reports whether a workspace or mount source is a URL rather
+// than a directory
+func isRemoteURL(source string) bool {
+	return sftpfs.IsURL(source) || s3fs.IsURL(source) || dockerfs.IsURL(source)
+}
 
-	// Handle signals for clean shutdown
-	go func() {
-		select {
-		case sig := <-sigChan:
-			log.Printf("Received signal %v, shutting down...", sig)
-			cleanup(mcpServer, done)
-		case <-done:
-			// Channel already closed
+// attachMounts opens the mounts listed in the config and attaches them to
+// the workspace; containers are only writable with containerWrite, and
+// nothing is writable in overlay mode, where writes must stay in memory
+func attachMounts(files *fsys.Workspace, cfg *config.Config, containerWrite, overlay bool) error {
+	alias := resources.WorkspaceAlias(cfg, files.Root())
+	for _, m := range cfg.Mounts {
+		if m.Name == alias {
+			return fmt.Errorf("mount %s has the name of the workspace alias; set resources.alias", m.Name)
 		}
-	}()
-
-	// Keep running until done
-	<-done
-	log.Printf("Server shutdown complete")
+		var mounted *fsys.Workspace
+		if isRemoteURL(m.Source) {
+			var err error
+			mounted, err = mountRemote(m.Source, containerWrite && !m.ReadOnly && !overlay)
+			if err != nil {
+				return fmt.Errorf("failed to mount %s: %v", m.Name, err)
+			}
+		} else {
+			dir := m.Source
+			if !filepath.IsAbs(dir) {
+				if !files.OnDisk() {
+					return fmt.Errorf("failed to mount %s: a relative source needs a workspace on disk", m.Name)
+				}
+				dir = filepath.Join(files.Root(), dir)
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("failed to mount %s: %s is not a directory", m.Name, dir)
+			}
+			mounted = fsys.OS(dir)
+		}
+		if err := files.Attach(m.Name, mounted, m.ReadOnly || overlay); err != nil {
+			mounted.Close()
+			return fmt.Errorf("failed to mount %s: %v", m.Name, err)
+		}
+	}
+	return nil
 }
 
-// monitorParentProcess watches for parent process death
-func monitorParentProcess(done chan struct{}) {
-	ppid := os.Getppid()
-
-	if debug {
-		log.Printf("Monitoring parent process: %d", ppid)
+// mountRemote connects to a remote workspace URL; containers are only
+// writable with containerWrite
+func mountRemote(workspaceURL string, containerWrite bool) (*fsys.Workspace, error) {
+	switch {
+	case s3fs.IsURL(workspaceURL):
+		return s3fs.Mount(workspaceURL)
+	case dockerfs.IsURL(workspaceURL):
+		return dockerfs.Mount(workspaceURL, containerWrite)
 	}
+	return sftpfs.Mount(workspaceURL)
+}
 
-	// Create a ticker to check parent process periodically
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			currentPpid := os.Getppid()
-			if currentPpid != ppid && (currentPpid == 1 || ppid == 1) {
-				log.Printf("Parent process %d terminated, initiating shutdown", ppid)
-				close(done)
-				return
-			}
-		case <-done:
-			return
-		}
+// loadRemote loads the config of a remote workspace: the --config file if
+// given, otherwise the config file in the workspace itself
+func (f *workspaceFlags) loadRemote(files *fsys.Workspace) (*config.Config, error) {
+	if *f.configPath != "" {
+		return f.withProfile(config.Load(*f.configPath, files.Root()))
+	}
+	path := config.DefaultPath(files.Root())
+	data, err := files.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg := config.Default()
+		cfg.Path = path
+		return f.withProfile(cfg, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
 	}
+	return f.withProfile(config.Parse(data, path, files.Root()))
 }
 
-// cleanup performs cleanup before shutdown
-func cleanup(s *server.MCPServer, done chan struct{}) {
-	log.Printf("Cleanup initiated")
+// configFile returns the config path in effect for a workspace
+func (f *workspaceFlags) configFile(workspacePath string) string {
+	if *f.configPath != "" {
+		return *f.configPath
+	}
+	return config.DefaultPath(workspacePath)
+}
 
-	// Stop MCP server
-	s.Stop()
+// load loads the config in effect for a workspace
+func (f *workspaceFlags) load(workspacePath string) (*config.Config, error) {
+	return f.withProfile(config.Load(f.configFile(workspacePath), workspacePath))
+}
 
-	// Close done channel if not already closed
-	select {
-	case <-done:
-		// Already closed
-	default:
-		close(done)
+// withProfile applies the --profile flag to a loaded config
+func (f *workspaceFlags) withProfile(cfg *config.Config, err error) (*config.Config, error) {
+	if err != nil || *f.profile == "" {
+		return cfg, err
+	}
+	if err := cfg.ApplyProfile(*f.profile); err != nil {
+		return nil, err
 	}
+	return cfg, nil
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/selftest"
+)
+
+// runSelftest checks that this binary can serve a scratch workspace and
+// returns the exit code
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 10*time.Second, "How long to wait for each response")
+	keep := flags.Bool("keep", false, "Keep the scratch workspace")
+	watchMode := flags.String("watch-mode", "", "Watch mode to test: auto, notify, poll or watchman")
+	_ = flags.Parse(args)
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find executable: %v", err)
+	}
+
+	var serveArgs []string
+	if *watchMode != "" {
+		serveArgs = append(serveArgs, "--watch-mode", *watchMode)
+	}
+
+	if !selftest.Run(selftest.Options{
+		Executable: executable,
+		Args:       serveArgs,
+		Timeout:    *timeout,
+		Keep:       *keep,
+	}, os.Stdout) {
+		fmt.Println("Self-test failed")
+		return 1
+	}
+	fmt.Println("Self-test passed")
+	return 0
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/daemon"
+	"github.com/isaacphi/mcp-filesystem/internal/dockerfs"
+	"github.com/isaacphi/mcp-filesystem/internal/fsys"
+	"github.com/isaacphi/mcp-filesystem/internal/index"
+	"github.com/isaacphi/mcp-filesystem/internal/s3fs"
+	"github.com/isaacphi/mcp-filesystem/internal/sandbox"
+	"github.com/isaacphi/mcp-filesystem/internal/server"
+)
+
+// handoffWait is how long a process handing its client off waits for the
+// process holding the workspace lock to start listening
+const handoffWait = 10 * time.Second
+
+// runServe runs the MCP server, on stdio or as a daemon
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	opts := addWorkspaceFlags(flags)
+	watchMode := flags.String("watch-mode", "", "How to watch for changes: auto, notify, poll or watchman (default: from config, or auto)")
+	daemonFlag := flags.Bool("daemon", false, "Run in the background and serve clients on --listen instead of stdio")
+	listenAddr := flags.String("listen", "", "Daemon address: unix:PATH or tcp:HOST:PORT; TCP clients need the daemon's token (default: a unix socket in the user's runtime directory)")
+	pidFile := flags.String("pid-file", "", "Daemon pid file (default: next to the default socket)")
+	connectFlag := flags.Bool("connect", false, "Bridge stdio to the daemon on --listen")
+	enableExec := flags.Bool("enable-exec", false, "Enable the run_command tool for the commands allowed in the config")
+	enableGitWrite := flags.Bool("enable-git-write", false, "Enable the git tools that change repositories: staging, committing and branches")
+	overlayFlag := flags.Bool("overlay", false, "Keep changes made by tools in memory instead of writing them to the workspace, for review with overlay_diff and overlay_apply")
+	simulateFlag := flags.Bool("simulate", false, "Dry-run every change: tools change an in-memory overlay that is never written to the workspace, and each change is logged in workspace://session-changes")
+	enableContainerWrite := flags.Bool("enable-container-write", false, "Let tools change files in a docker:// workspace, which is read-only otherwise")
+	memoryLimit := flags.String("memory-limit", "", "Memory to stay under, such as 512MiB; caches are dropped as it is approached (default: from config, or unlimited)")
+	profileStartup := flags.Bool("profile-startup", false, "Log how long startup took: walking the workspace, gitignore matching and registration, and memory after the scan")
+	recordSession := flags.String("record-session", "", "Record all JSON-RPC traffic to this file with timestamps")
+	idleTimeout := flags.Duration("idle-timeout", 0, "Shut down after this long without a request, notification or ping from any client, such as 30m (default: never)")
+	shareFlag := flags.Bool("share", false, "Share one server process per workspace: the first serves its client and hands later ones the same watcher and index over --listen; later ones bridge their client to it")
+	strictGitignore := flags.Bool("strict-gitignore", false, "Ask git check-ignore which files are ignored instead of matching .gitignore patterns in the server, for patterns it matches differently than git (slower)")
+	sandboxFlag := flags.Bool("sandbox", false, "Confine the server and the commands it runs to the workspace, its config and state directories (Landlock on Linux, sandbox-exec on macOS)")
+	_ = flags.Parse(args)
+
+	workspaceURL, remote := opts.remote()
+	// Simulation is an overlay without overlay_apply
+	if *simulateFlag {
+		*overlayFlag = true
+	}
+	if remote && *overlayFlag {
+		log.Fatal("--overlay and --simulate need a workspace on this machine")
+	}
+	absWorkspaceDir := workspaceURL
+	if !remote {
+		absWorkspaceDir = opts.workspace()
+	}
+
+	// Serve from a listener instead of stdio under systemd socket activation
+	serveDaemon := *daemonFlag || daemon.SocketActivated()
+
+	// The daemon's files are in the user's runtime directory unless given,
+	// which is only created when they are needed
+	var tokenFile string
+	if serveDaemon || *shareFlag || *connectFlag {
+		var err error
+		if *listenAddr == "" {
+			if *listenAddr, err = daemon.DefaultAddress(absWorkspaceDir); err != nil {
+				log.Fatalf("Failed to find the daemon's socket: %v", err)
+			}
+		}
+		if tokenFile, err = daemon.DefaultTokenFile(absWorkspaceDir); err != nil {
+			log.Fatalf("Failed to find the daemon's token file: %v", err)
+		}
+	}
+	if serveDaemon && *pidFile == "" {
+		var err error
+		if *pidFile, err = daemon.DefaultPidFile(absWorkspaceDir); err != nil {
+			log.Fatalf("Failed to find the daemon's pid file: %v", err)
+		}
+	}
+
+	// Bridge a short-lived client to a running daemon
+	if *connectFlag {
+		if err := daemon.Connect(*listenAddr, tokenFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Detach into the background unless this is already the background process
+	if serveDaemon && !daemon.IsChild() && !daemon.SocketActivated() {
+		logFile, err := daemon.DefaultLogFile(absWorkspaceDir)
+		if err != nil {
+			log.Fatalf("Failed to start daemon: %v", err)
+		}
+		pid, err := daemon.Detach(logFile)
+		if err != nil {
+			log.Fatalf("Failed to start daemon: %v", err)
+		}
+		fmt.Printf("Started daemon (pid %d) on %s, logging to %s\n", pid, *listenAddr, logFile)
+		return
+	}
+
+	// One process per workspace should watch and write it; the others warn,
+	// or with --share hand their client off to it
+	var lock *daemon.InstanceLock
+	lockPath, lockErr := daemon.DefaultLockFile(absWorkspaceDir)
+	if lockErr == nil {
+		lock, lockErr = daemon.Lock(lockPath)
+	}
+	switch {
+	case errors.Is(lockErr, daemon.ErrLocked) && *shareFlag && !serveDaemon:
+		conn, err := daemon.DialWhenReady(*listenAddr, handoffWait, tokenFile)
+		if err == nil {
+			if debug {
+				log.Printf("Handing off to the server process for %s on %s", absWorkspaceDir, *listenAddr)
+			}
+			if err := daemon.Bridge(conn); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		log.Printf("Warning: the server process holding %s (pid %d) doesn't share the workspace, so this one serves it separately: %v", lockPath, daemon.LockHolder(lockPath), err)
+	case errors.Is(lockErr, daemon.ErrLocked):
+		log.Printf("Warning: another server process (pid %d) already serves %s; its watcher and writes aren't coordinated with this one. Start both with --share to use one process", daemon.LockHolder(lockPath), absWorkspaceDir)
+	case lockErr != nil:
+		log.Printf("Warning: failed to take the workspace lock: %v", lockErr)
+	default:
+		defer lock.Release()
+	}
+	shared := lock != nil && *shareFlag && !serveDaemon
+
+	// Connect to a remote workspace and load configuration
+	files := fsys.OS(absWorkspaceDir)
+	var cfg *config.Config
+	var err error
+	if remote {
+		files, err = mountRemote(workspaceURL, *enableContainerWrite)
+		if err != nil {
+			log.Fatalf("Failed to open workspace: %v", err)
+		}
+		cfg, err = opts.loadRemote(files)
+		// Remote paths mean nothing as file:// URIs on this machine
+		if err == nil {
+			cfg.Resources.URIScheme = config.URISchemeWorkspace
+		}
+	} else {
+		cfg, err = opts.load(absWorkspaceDir)
+		if *overlayFlag {
+			files = fsys.Mount(absWorkspaceDir, fsys.NewOverlay(fsys.Dir(absWorkspaceDir)))
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := attachMounts(files, cfg, *enableContainerWrite, *overlayFlag); err != nil {
+		log.Fatal(err)
+	}
+	if *watchMode != "" {
+		cfg.Watch.Mode = *watchMode
+	}
+	if *memoryLimit != "" {
+		if _, err := config.ParseSize(*memoryLimit); err != nil {
+			log.Fatalf("Invalid --memory-limit: %v", err)
+		}
+		cfg.MemoryLimit = *memoryLimit
+	}
+	cfg.Exec.Enabled = *enableExec
+	cfg.Git.WriteEnabled = *enableGitWrite
+	if *strictGitignore {
+		cfg.Git.StrictIgnore = true
+	}
+	cfg.Simulate = *simulateFlag
+
+	// Restart confined to what the server needs; the restarted server gets
+	// here again and carries on inside the sandbox
+	if *sandboxFlag {
+		rules := sandboxRules(absWorkspaceDir, remote, cfg, opts.configFile(absWorkspaceDir), *listenAddr, *pidFile, tokenFile, lockPath, *recordSession)
+		if err := sandbox.Enter(rules); err != nil {
+			log.Fatalf("Failed to sandbox the server: %v", err)
+		}
+		if debug {
+			log.Printf("Running sandboxed")
+		}
+	}
+
+	// Create done channel for shutdown signal
+	done := make(chan struct{})
+
+	// Set up signal handling for clean shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Create and start MCP server
+	mcpServer, err := server.NewMCPServerFS(files, cfg, debug)
+	if err != nil {
+		log.Fatalf("Failed to create MCP server: %v", err)
+	}
+
+	if debug {
+		log.Printf("Starting MCP server for workspace: %s", absWorkspaceDir)
+	}
+
+	if *profileStartup {
+		mcpServer.ProfileStartup()
+	}
+
+	if *recordSession != "" {
+		if err := mcpServer.RecordSession(*recordSession); err != nil {
+			log.Fatalf("Failed to start MCP server: %v", err)
+		}
+	}
+
+	if *idleTimeout > 0 {
+		mcpServer.ShutdownWhenIdle(*idleTimeout, func() { cleanup(mcpServer, done) })
+	}
+
+	if serveDaemon {
+		listener, err := daemon.Listen(*listenAddr, tokenFile)
+		if err != nil {
+			log.Fatalf("Failed to start daemon: %v", err)
+		}
+		if err := daemon.WritePidFile(*pidFile); err != nil {
+			log.Fatalf("Failed to start daemon: %v", err)
+		}
+		defer daemon.RemovePidFile(*pidFile)
+
+		if err := mcpServer.StartOnListener(listener); err != nil {
+			log.Fatalf("Failed to start MCP server: %v", err)
+		}
+		log.Printf("Serving workspace %s on %s (pid %d)", absWorkspaceDir, listener.Addr(), os.Getpid())
+		if listener.Addr().Network() == "tcp" && os.Getenv(daemon.TokenEnv) == "" {
+			log.Printf("TCP clients authenticate with the token in %s", tokenFile)
+		}
+	} else if shared {
+		listener, err := daemon.Listen(*listenAddr, tokenFile)
+		if err != nil {
+			log.Fatalf("Failed to share the workspace: %v", err)
+		}
+		if err := mcpServer.StartShared(listener, func() { cleanup(mcpServer, done) }); err != nil {
+			log.Fatalf("Failed to start MCP server: %v", err)
+		}
+		if debug {
+			log.Printf("Sharing workspace %s on %s", absWorkspaceDir, listener.Addr())
+		}
+
+		// Clients handed off to this process keep it running after its
+		// own client is gone
+		go monitorParentProcess(done, mcpServer.DisconnectStdio)
+	} else {
+		if err := mcpServer.Start(); err != nil {
+			log.Fatalf("Failed to start MCP server: %v", err)
+		}
+
+		// Monitor parent process termination
+		// Claude desktop does not properly kill child processes for MCP servers
+		go monitorParentProcess(done, func() { close(done) })
+	}
+
+	// Handle signals for clean shutdown
+	go func() {
+		select {
+		case sig := <-sigChan:
+			log.Printf("Received signal %v, shutting down...", sig)
+			cleanup(mcpServer, done)
+		case <-done:
+			// Channel already closed
+		}
+	}()
+
+	// Keep running until done
+	<-done
+	log.Printf("Server shutdown complete")
+}
+
+// monitorParentProcess watches for parent process death, calling
+// parentGone when it happens
+func monitorParentProcess(done chan struct{}, parentGone func()) {
+	ppid := os.Getppid()
+
+	if debug {
+		log.Printf("Monitoring parent process: %d", ppid)
+	}
+
+	// Create a ticker to check parent process periodically
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			currentPpid := os.Getppid()
+			if currentPpid != ppid && (currentPpid == 1 || ppid == 1) {
+				log.Printf("Parent process %d terminated, initiating shutdown", ppid)
+				parentGone()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// cleanup performs cleanup before shutdown
+func cleanup(s *server.MCPServer, done chan struct{}) {
+	log.Printf("Cleanup initiated")
+
+	// Stop MCP server
+	s.Stop()
+
+	// Close done channel if not already closed
+	select {
+	case <-done:
+		// Already closed
+	default:
+		close(done)
+	}
+}
+
+// sandboxRules returns the paths a sandboxed server needs: the workspace and
+// its mounts, the config, state in the temp and cache directories, git's
+// config, the credentials of remote workspaces and the files given, such as
+// the daemon's socket and pid file
+func sandboxRules(workspace string, remote bool, cfg *config.Config, configFile string, files ...string) sandbox.Rules {
+	rules := sandbox.System()
+	home, _ := os.UserHomeDir()
+
+	// Remote workspaces read credentials from the home directory
+	credentials := func(source string) {
+		if home == "" {
+			return
+		}
+		switch {
+		case s3fs.IsURL(source):
+			rules.ReadOnly = append(rules.ReadOnly, filepath.Join(home, ".aws"))
+		case dockerfs.IsURL(source):
+			rules.ReadOnly = append(rules.ReadOnly, filepath.Join(home, ".docker"))
+		default:
+			rules.ReadOnly = append(rules.ReadOnly, filepath.Join(home, ".ssh"))
+		}
+	}
+
+	if remote {
+		credentials(workspace)
+	} else {
+		rules.ReadWrite = append(rules.ReadWrite, workspace)
+	}
+	for _, m := range cfg.Mounts {
+		if isRemoteURL(m.Source) {
+			credentials(m.Source)
+			continue
+		}
+		dir := m.Source
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workspace, dir)
+		}
+		if m.ReadOnly {
+			rules.ReadOnly = append(rules.ReadOnly, dir)
+		} else {
+			rules.ReadWrite = append(rules.ReadWrite, dir)
+		}
+	}
+
+	rules.ReadOnly = append(rules.ReadOnly, configFile)
+	if home != "" {
+		rules.ReadOnly = append(rules.ReadOnly, filepath.Join(home, ".gitconfig"), filepath.Join(home, ".config", "git"))
+	}
+	rules.ReadWrite = append(rules.ReadWrite, os.TempDir())
+	if cachePath, err := index.CachePath(workspace, cfg.Index.CacheDir); err == nil {
+		dir := filepath.Dir(cachePath)
+		// Create the cache directory now, since its parent isn't writable
+		// inside the sandbox
+		_ = os.MkdirAll(dir, 0755)
+		rules.ReadWrite = append(rules.ReadWrite, dir)
+	}
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "unix:")
+		if file == "" || strings.HasPrefix(file, "tcp:") {
+			continue
+		}
+		if abs, err := filepath.Abs(file); err == nil {
+			rules.ReadWrite = append(rules.ReadWrite, filepath.Dir(abs))
+		}
+	}
+
+	if cfg.AuditLog != "" {
+		rules.ReadWrite = append(rules.ReadWrite, filepath.Dir(cfg.AuditLog))
+	}
+
+	rules.Exec = append(rules.Exec, cfg.Sandbox.Read...)
+	rules.ReadWrite = append(rules.ReadWrite, cfg.Sandbox.Write...)
+	return rules
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/mcp-filesystem/internal/config"
+	"github.com/isaacphi/mcp-filesystem/internal/gitignore"
+	"github.com/isaacphi/mcp-filesystem/internal/watcher"
+)
+
+// runValidate checks the config and ignore files of a workspace and returns
+// the exit code: 1 if there are errors, 0 if there are only warnings or none
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	opts := addWorkspaceFlags(flags)
+	_ = flags.Parse(args)
+
+	workspacePath := opts.workspace()
+	errors, warnings := 0, 0
+	report := func(level, location, message string) {
+		if level == "error" {
+			errors++
+		} else {
+			warnings++
+		}
+		fmt.Printf("%s: %s: %s\n", location, level, message)
+	}
+
+	// Config
+	configPath := opts.configFile(workspacePath)
+	cfg, err := opts.load(workspacePath)
+	switch {
+	case err != nil:
+		report("error", configPath, err.Error())
+	default:
+		if _, statErr := os.Stat(configPath); statErr != nil {
+			fmt.Printf("%s: not found, using defaults\n", configPath)
+		}
+		for _, ext := range sortedKeys(cfg.Formatters) {
+			command := strings.Fields(cfg.Formatters[ext])
+			if len(command) > 0 {
+				if _, err := exec.LookPath(command[0]); err != nil {
+					report("warning", configPath, fmt.Sprintf("formatter for %s: %s not found in PATH", ext, command[0]))
+				}
+			}
+		}
+		if mode, _, err := watcher.ResolveMode(workspacePath, cfg); err != nil {
+			report("error", configPath, err.Error())
+		} else if mode == config.WatchModeWatchman {
+			if _, err := exec.LookPath("watchman"); err != nil {
+				report("error", configPath, "watch mode is watchman but watchman is not in PATH")
+			}
+		}
+	}
+
+	// Ignore files: the workspace's and those found walking it
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	gitignorePath := filepath.Join(workspacePath, ".gitignore")
+	matcher, err := gitignore.NewMatcher(workspacePath, cfg)
+	if err != nil {
+		report("error", gitignorePath, err.Error())
+		matcher = nil
+	}
+	applied := map[string]bool{gitignorePath: true}
+	if matcher != nil {
+		for _, path := range matcher.IgnoreFiles() {
+			applied[path] = true
+		}
+	}
+	for _, path := range sortedKeys(applied) {
+		if data, err := os.ReadFile(path); err == nil {
+			for _, issue := range gitignore.Lint(string(data)) {
+				report("warning", fmt.Sprintf("%s:%d", path, issue.Line), fmt.Sprintf("%q: %s", issue.Pattern, issue.Message))
+			}
+		} else if !os.IsNotExist(err) {
+			report("error", path, err.Error())
+		}
+	}
+
+	fmt.Printf("%d errors, %d warnings\n", errors, warnings)
+	if errors > 0 {
+		return 1
+	}
+	return 0
+}